@@ -39,6 +39,27 @@ func (c AppContext) App() *model.App {
 	return c.app
 }
 
+// RemoteLibs returns the remote library bundles of the app in the form required by the VM.
+func (c AppContext) RemoteLibs() []vm.RemoteLib {
+	var ret []vm.RemoteLib
+	for _, l := range c.app.RemoteLibs() {
+		ret = append(ret, vm.RemoteLib{Name: l.Name, URL: l.URL, SHA256: l.SHA256})
+	}
+	return ret
+}
+
+// valueVar returns a variable for the supplied value, a string variable for strings and a code variable otherwise.
+func valueVar(name string, value interface{}) (vm.Var, error) {
+	if s, ok := value.(string); ok {
+		return vm.NewVar(name, s), nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return vm.Var{}, fmt.Errorf("json marshal: unexpected error marshaling value for variable %s, %v", name, err)
+	}
+	return vm.NewCodeVar(name, string(b)), nil
+}
+
 func (c *AppContext) init() error {
 	var msgs []string
 	c.ext = c.ext.WithLibPaths(c.app.LibPaths())
@@ -95,16 +116,24 @@ func (c *AppContext) init() error {
 			sio.Warnf("no/ nil default specified for variable %q\n", k)
 			continue
 		}
-		switch t := v.(type) {
-		case string:
-			addVars = append(addVars, vm.NewVar(k, t))
-		default:
-			b, err := json.Marshal(v)
-			if err != nil {
-				return fmt.Errorf("json marshal: unexpected error marshaling default for variable %s, %v", k, err)
-			}
-			addVars = append(addVars, vm.NewCodeVar(k, string(b)))
+		v, err := valueVar(k, v)
+		if err != nil {
+			return err
 		}
+		addVars = append(addVars, v)
+	}
+
+	// apply default values for top-level vars, no warnings are needed since the component code may have defaults
+	var addTLAs []vm.Var
+	for k, v := range c.app.DeclaredTopLevelDefaults() {
+		if vs.HasTopLevelVar(k) {
+			continue
+		}
+		v, err := valueVar(k, v)
+		if err != nil {
+			return err
+		}
+		addTLAs = append(addTLAs, v)
 	}
 
 	// add an 'error' variable for every computed var until they are replaced for real
@@ -112,9 +141,14 @@ func (c *AppContext) init() error {
 		addVars = append(addVars, vm.NewCodeVar(c.Name, fmt.Sprintf(`error 'variable %s has not yet been computed'`, c.Name)))
 	}
 
-	c.vars = vs.WithVars(addVars...)
+	c.vars = vs.WithVars(addVars...).WithTopLevelVars(addTLAs...)
 	c.vmc = vm.Config{
-		LibPaths: c.ext.LibPaths,
+		LibPaths:    c.ext.LibPaths,
+		HelmCommand: c.app.HelmCommand(),
+		RemoteLibs:  c.RemoteLibs(),
+		LibCacheDir: c.LibCacheDir(),
+		Seed:        c.EvalSeed(),
+		Now:         c.EvalTime(),
 	}
 	return nil
 }
@@ -142,6 +176,9 @@ func (c AppContext) EnvContext(env string) (EnvContext, error) {
 	if ret.clp == nil {
 		ret.clp = sp.Client
 	}
+	if ret.cclp == nil {
+		ret.cclp = sp.ContextClient
+	}
 	if ret.attrsp == nil {
 		ret.attrsp = sp.Attrs
 	}
@@ -20,6 +20,7 @@ import (
 	"testing"
 
 	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/vm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -51,11 +52,35 @@ func TestConfigStrictVarsPass(t *testing.T) {
 		"--vm:ext-str=extBar=yyy",
 		"--vm:ext-str=noDefault=boo",
 		"--vm:tla-str=tlaFoo=xxx",
+		"--vm:tla-str=tlaBar=yyy",
 	})
 	_, err = ctx.AppContext(app)
 	require.NoError(t, err)
 }
 
+func TestConfigTopLevelDefaults(t *testing.T) {
+	fn := setPwd(t, "testdata")
+	defer fn()
+	app, err := model.NewApp("qbec.yaml", nil, "")
+	require.NoError(t, err)
+
+	tlaVars := func(args ...string) map[string]vm.Var {
+		ctx := getContext(t, Options{}, args)
+		ac, err := ctx.AppContext(app)
+		require.NoError(t, err)
+		ret := map[string]vm.Var{}
+		for _, v := range ac.vars.TopLevelVars() {
+			ret[v.Name] = v
+		}
+		return ret
+	}
+	a := assert.New(t)
+	vars := tlaVars()
+	a.Equal(map[string]vm.Var{"tlaBar": vm.NewVar("tlaBar", "bar")}, vars)
+	vars = tlaVars("--vm:tla-str=tlaBar=yyy", "--vm:tla-str=tlaFoo=xxx")
+	a.Equal(map[string]vm.Var{"tlaBar": vm.NewVar("tlaBar", "yyy"), "tlaFoo": vm.NewVar("tlaFoo", "xxx")}, vars)
+}
+
 func TestConfigStrictVarsFail(t *testing.T) {
 	fn := setPwd(t, "testdata")
 	defer fn()
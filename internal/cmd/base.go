@@ -18,6 +18,7 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"io/ioutil"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/mattn/go-isatty"
@@ -52,21 +54,31 @@ type KubeClient interface {
 	Delete(context.Context, model.K8sMeta, remote.DeleteOptions) (*remote.SyncResult, error)
 	ObjectKey(obj model.K8sMeta) string
 	ResourceInterface(obj schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error)
+	ServerSideDryRun(ctx context.Context, obj model.K8sLocalObject, fieldManager string, force bool) (*unstructured.Unstructured, error)
+	DryRunCreate(ctx context.Context, obj model.K8sLocalObject) error
 }
 
 // ClientProvider returns a kubernetes client for the specific environment
 type ClientProvider func(env string) (KubeClient, error)
 
+// ContextClientProvider returns a kubernetes client for the specific environment connected to the supplied
+// context. It is used for environments that target multiple contexts.
+type ContextClientProvider func(env string, context string) (KubeClient, error)
+
 // KubeAttrsProvider provides k8s attributes of the supplied environment
 type KubeAttrsProvider func(env string) (*remote.KubeAttributes, error)
 
 // Options are optional attributes to create a context, mostly used for testing.
 type Options struct {
-	Stdout            io.Writer
-	Stderr            io.Writer
-	SkipConfirm       bool
-	ClientProvider    ClientProvider
-	KubeAttrsProvider KubeAttrsProvider
+	Stdin                 io.Reader
+	StdinIsTerminal       func() bool // reports whether prompts can be answered, defaults to a terminal check of Stdin
+	Stdout                io.Writer
+	Stderr                io.Writer
+	SkipConfirm           bool
+	SkipSignalHandlers    bool // do not install handlers that exit the process on interrupts
+	ClientProvider        ClientProvider
+	ContextClientProvider ContextClientProvider
+	KubeAttrsProvider     KubeAttrsProvider
 }
 
 // Context is the global context of the qbec command that handles all global options supported by
@@ -74,17 +86,25 @@ type Options struct {
 type Context struct {
 	root            string                       // qbec root directory
 	appTag          string                       // tag for GC scope
+	gcTag           string                       // label value that replaces the app name for GC scope
 	envFile         string                       // additional environment file
+	libCacheDir     string                       // cache directory for remote library bundles
+	seed            string                       // seed for the random native functions
+	now             time.Time                    // time returned by the now native function
+	appFile         string                       // app file, URL or "-" for stdin
 	remote          *remote.Config               // remote config
 	forceOptsFn     func() (ForceOptions, error) // options to force cluster/ namespace
 	ext             vmexternals.Externals        // external config
 	clp             ClientProvider               // the client provider
+	cclp            ContextClientProvider        // the client provider for specific contexts
 	attrsp          KubeAttrsProvider            // the kubernetes attribute provider
 	colors          bool                         // colorize output
+	logFormat       string                       // format of progress output
 	yes             bool                         // auto-confirm
 	evalConcurrency int                          // concurrency of component eval
 	verbose         int                          // verbosity level
 	stdin           io.Reader                    // standard input
+	stdinTerminal   func() bool                  // true if standard input is a terminal
 	stdout          io.Writer                    // standard output
 	stderr          io.Writer                    // standard error
 	strictVars      bool                         // strict vars
@@ -105,10 +125,47 @@ func defaultRoot() string {
 	return envOrDefault("QBEC_ROOT", "")
 }
 
+func defaultAppFile() string {
+	return envOrDefault("QBEC_APP_FILE", "")
+}
+
 func defaultEnvironmentFile() string {
 	return envOrDefault("QBEC_ENV_FILE", "")
 }
 
+func defaultLibCacheDir() string {
+	return envOrDefault("QBEC_LIB_CACHE_DIR", "")
+}
+
+const (
+	colorAlways = "always"
+	colorAuto   = "auto"
+	colorNever  = "never"
+)
+
+// resolveColors returns whether output should be colorized for the supplied color mode. In auto mode, output is
+// colorized for terminals unless the NO_COLOR environment variable has a non-empty value.
+func resolveColors(mode string, noColor string, terminal bool) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		return noColor == "" && terminal, nil
+	default:
+		return false, NewUsageError(fmt.Sprintf("invalid color mode %q, must be one of %s, %s or %s", mode, colorAlways, colorAuto, colorNever))
+	}
+}
+
+func defaultSeed() string {
+	return envOrDefault("QBEC_SEED", "")
+}
+
+func defaultFreezeTime() string {
+	return envOrDefault("QBEC_FREEZE_TIME", "")
+}
+
 func skipPrompts() bool {
 	return os.Getenv("QBEC_YES") == "true"
 }
@@ -138,15 +195,27 @@ func NewContext(root *cobra.Command, opts Options) func() (Context, error) {
 	// path that changes if qbec changes directory to the qbec root. Historically any override kubeconfigs with relative
 	// path evaluated w.r.t to the qbec root and the lazy eval of the force function preserves this behavior./
 	cf := Context{
-		remote:      remoteConfig,
-		clp:         opts.ClientProvider,
-		forceOptsFn: memoizeForceFn(forceOptsFn),
-		attrsp:      opts.KubeAttrsProvider,
-		stdout:      opts.Stdout,
-		stderr:      opts.Stderr,
-		yes:         opts.SkipConfirm || skipPrompts(),
+		remote:        remoteConfig,
+		clp:           opts.ClientProvider,
+		cclp:          opts.ContextClientProvider,
+		forceOptsFn:   memoizeForceFn(forceOptsFn),
+		attrsp:        opts.KubeAttrsProvider,
+		stdin:         opts.Stdin,
+		stdinTerminal: opts.StdinIsTerminal,
+		stdout:        opts.Stdout,
+		stderr:        opts.Stderr,
+		yes:           opts.SkipConfirm || skipPrompts(),
+	}
+	if cf.stdin == nil {
+		cf.stdin = os.Stdin
+	}
+	if cf.stdinTerminal == nil {
+		stdin := cf.stdin
+		cf.stdinTerminal = func() bool {
+			f, ok := stdin.(*os.File)
+			return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+		}
 	}
-	cf.stdin = os.Stdin
 	if cf.stdout == nil {
 		cf.stdout = os.Stdout
 	}
@@ -155,17 +224,51 @@ func NewContext(root *cobra.Command, opts Options) func() (Context, error) {
 	}
 
 	root.PersistentFlags().StringVar(&cf.root, "root", defaultRoot(), "root directory of repo (from QBEC_ROOT or auto-detect)")
+	var appRoot string
+	root.PersistentFlags().StringVar(&appRoot, "app-root", "", "directory containing qbec.yaml, same as --root")
 	root.PersistentFlags().IntVarP(&cf.verbose, "verbose", "v", cf.verbose, "verbosity level")
-	root.PersistentFlags().BoolVar(&cf.colors, "colors", cf.colors, "colorize output (set automatically if not specified)")
+	root.PersistentFlags().BoolVar(&cf.colors, "colors", cf.colors, "colorize output, same as --color=always or --color=never when set to false")
+	var colorMode string
+	root.PersistentFlags().StringVar(&colorMode, "color", colorAuto, "when to colorize output, one of always, auto or never. auto colorizes output for terminals unless NO_COLOR is set")
+	root.PersistentFlags().StringVar(&cf.logFormat, "log-format", "text", "format of progress and error output, one of text or json")
 	root.PersistentFlags().BoolVar(&cf.yes, "yes", cf.yes, "do not prompt for confirmation. The default value can be overridden by setting QBEC_YES=true")
 	root.PersistentFlags().BoolVar(&cf.strictVars, "strict-vars", cf.strictVars, "require declared variables to be specified, do not allow undeclared variables")
 	root.PersistentFlags().IntVar(&cf.evalConcurrency, "eval-concurrency", cf.evalConcurrency, "concurrency with which to evaluate components")
 	root.PersistentFlags().StringVar(&cf.appTag, "app-tag", "", "build tag to create suffixed objects, indicates GC scope")
+	root.PersistentFlags().StringVar(&cf.gcTag, "gc-tag", "", "label value that scopes garbage collection instead of the app name, overrides gcTag in qbec.yaml")
+	root.PersistentFlags().StringVar(&cf.appFile, "app-file", defaultAppFile(), "app file to use instead of qbec.yaml in the root directory, an http(s) URL or - for stdin (from QBEC_APP_FILE)")
 	root.PersistentFlags().StringVarP(&cf.envFile, "env-file", "E", defaultEnvironmentFile(), "use additional environment file not declared in qbec.yaml")
+	root.PersistentFlags().StringVar(&cf.libCacheDir, "lib-cache-dir", defaultLibCacheDir(), "cache directory for remote library bundles (from QBEC_LIB_CACHE_DIR or the user cache directory)")
+	root.PersistentFlags().StringVar(&cf.seed, "seed", defaultSeed(), "seed for the random and randomString native functions, for reproducible output (from QBEC_SEED)")
+	var freezeTime string
+	root.PersistentFlags().StringVar(&freezeTime, "freeze-time", defaultFreezeTime(), "RFC3339 timestamp returned by the now native function, for reproducible output (from QBEC_FREEZE_TIME)")
 
 	return func() (_ Context, err error) {
-		if !root.Flags().Changed("colors") {
-			cf.colors = isatty.IsTerminal(os.Stdout.Fd())
+		if root.Flags().Changed("app-root") {
+			if root.Flags().Changed("root") {
+				return cf, NewUsageError("--app-root cannot be used together with --root")
+			}
+			cf.root = appRoot
+		}
+		if root.Flags().Changed("colors") {
+			if root.Flags().Changed("color") {
+				return cf, NewUsageError("--colors cannot be used together with --color")
+			}
+		} else {
+			cf.colors, err = resolveColors(colorMode, os.Getenv("NO_COLOR"), isatty.IsTerminal(os.Stdout.Fd()))
+			if err != nil {
+				return cf, err
+			}
+		}
+		if cf.logFormat != "text" && cf.logFormat != "json" {
+			return cf, NewUsageError(fmt.Sprintf("invalid log format %q, must be one of text or json", cf.logFormat))
+		}
+		cf.now = time.Now()
+		if freezeTime != "" {
+			cf.now, err = time.Parse(time.RFC3339, freezeTime)
+			if err != nil {
+				return cf, NewUsageError(fmt.Sprintf("invalid freeze time %q, must be an RFC3339 timestamp", freezeTime))
+			}
 		}
 		cf.ext, err = extConfigFn()
 		if err != nil {
@@ -180,12 +283,80 @@ func NewContext(root *cobra.Command, opts Options) func() (Context, error) {
 	}
 }
 
+// LibraryOptions are the attributes of a context created for programs that use qbec as a library.
+type LibraryOptions struct {
+	Root     string            // qbec root directory
+	AppTag   string            // tag for GC scope
+	EnvFile  string            // additional environment file
+	ExtStrs  map[string]string // external string variables
+	ExtCodes map[string]string // external code variables
+	Stdout   io.Writer         // standard output, discarded when nil
+	Stderr   io.Writer         // standard error, discarded when nil
+}
+
+// errNoCluster is returned for cluster operations of contexts created for libraries.
+var errNoCluster = errors.New("no cluster access for library contexts")
+
+// NewLibraryContext returns a context that is not set up from command line flags, for programs that evaluate
+// components through qbec as a library. Such a context does not change process state, never prompts and
+// does not provide access to clusters.
+func NewLibraryContext(opts LibraryOptions) Context {
+	vars := map[string]vmexternals.UserVal{}
+	for k, v := range opts.ExtStrs {
+		vars[k] = vmexternals.UserVal{Value: v}
+	}
+	for k, v := range opts.ExtCodes {
+		vars[k] = vmexternals.UserVal{Value: v, Code: true}
+	}
+	cf := Context{
+		root:        opts.Root,
+		appTag:      opts.AppTag,
+		envFile:     opts.EnvFile,
+		libCacheDir: defaultLibCacheDir(),
+		seed:        defaultSeed(),
+		now:         time.Now(),
+		forceOptsFn: func() (ForceOptions, error) { return ForceOptions{}, nil },
+		ext:         vmexternals.Externals{Variables: vmexternals.UserVariables{Vars: vars}},
+		clp:         func(string) (KubeClient, error) { return nil, errNoCluster },
+		cclp:        func(string, string) (KubeClient, error) { return nil, errNoCluster },
+		attrsp:      func(string) (*remote.KubeAttributes, error) { return nil, errNoCluster },
+		logFormat:   "text",
+		yes:         true,
+		stdin:       bytes.NewReader(nil),
+		stdout:      opts.Stdout,
+		stderr:      opts.Stderr,
+	}
+	if cf.stdout == nil {
+		cf.stdout = ioutil.Discard
+	}
+	if cf.stderr == nil {
+		cf.stderr = ioutil.Discard
+	}
+	return cf
+}
+
 // RootDir returns an overridden root dir or blank
 func (c Context) RootDir() string { return c.root }
 
+// AppFile returns the app file specified, a URL or "-" for standard input, or blank
+func (c Context) AppFile() string { return c.appFile }
+
 // AppTag returns the app tag specified
 func (c Context) AppTag() string { return c.appTag }
 
+// GCTag returns the GC tag specified
+func (c Context) GCTag() string { return c.gcTag }
+
+// LibCacheDir returns the cache directory for remote library bundles, or blank for the default.
+func (c Context) LibCacheDir() string { return c.libCacheDir }
+
+// EvalSeed returns the seed for the random native functions, or blank for a different seed on every evaluation.
+func (c Context) EvalSeed() string { return c.seed }
+
+// EvalTime returns the time returned by the now native function, which is either the frozen time specified on
+// the command line or the time at which the command was started.
+func (c Context) EvalTime() time.Time { return c.now }
+
 // ListPageSize returns the page size for kubernetes list operations
 func (c Context) ListPageSize() int64 { return c.remote.ListPageSize }
 
@@ -200,12 +371,18 @@ func (c Context) EnvFiles() []string {
 // Colorize returns true if output needs to be colorized.
 func (c Context) Colorize() bool { return c.colors }
 
+// LogFormat returns the format of progress and error output, either text or json.
+func (c Context) LogFormat() string { return c.logFormat }
+
 // Verbosity returns the log verbosity level
 func (c Context) Verbosity() int { return c.verbose }
 
 // EvalConcurrency returns the concurrency to be used for evaluating components.
 func (c Context) EvalConcurrency() int { return c.evalConcurrency }
 
+// Stdin returns the standard input configured for the command.
+func (c Context) Stdin() io.Reader { return c.stdin }
+
 // Stdout returns the standard output configured for the command.
 func (c Context) Stdout() io.Writer { return c.stdout }
 
@@ -223,6 +400,15 @@ func (c Context) KubeContextInfo() (*remote.ContextInfo, error) {
 	return c.remote.CurrentContextInfo()
 }
 
+// AutoConfirm returns true if prompts for confirmation are skipped.
+func (c Context) AutoConfirm() bool { return c.yes }
+
+// StdinIsTerminal returns true if standard input is a terminal from which prompts can be answered.
+func (c Context) StdinIsTerminal() bool { return c.stdinTerminal != nil && c.stdinTerminal() }
+
+// ErrConfirmCanceled is returned by Confirm when the user declines to continue.
+var ErrConfirmCanceled = errors.New("canceled")
+
 // Confirm prompts for confirmation if needed.
 func (c Context) Confirm(action string) error {
 	_, _ = fmt.Fprintln(c.stderr)
@@ -253,7 +439,7 @@ func (c Context) Confirm(action string) error {
 			return nil
 		}
 		if s == "n" {
-			return errors.New("canceled")
+			return ErrConfirmCanceled
 		}
 	}
 }
@@ -279,6 +465,8 @@ func (c Context) BasicEvalContext() (eval.BaseContext, error) {
 		LibPaths: c.ext.LibPaths,
 		Vars:     c.ext.ToVariableSet(),
 		Verbose:  c.verbose > 1,
+		Seed:     c.seed,
+		Now:      c.now,
 	}
 
 	ctx.DataSources = sources
@@ -18,8 +18,10 @@ package cmd
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -36,6 +38,7 @@ func TestContextCreateSimple(t *testing.T) {
 	a.Equal("", ctx.RootDir())
 	a.Nil(ctx.EnvFiles())
 	a.Equal(0, ctx.EvalConcurrency())
+	a.Equal("text", ctx.LogFormat())
 	a.Equal(os.Stdout, ctx.Stdout())
 	a.Equal(os.Stdin, ctx.stdin)
 	a.Equal(os.Stderr, ctx.Stderr())
@@ -53,6 +56,7 @@ func TestContextCreate(t *testing.T) {
 		"--env-file=testdata/extra-env.yaml",
 		"--eval-concurrency=7",
 		"--k8s:kubeconfig=./kubeconfig.yaml",
+		"--log-format=json",
 		"--force:k8s-context=minikube",
 		"--force:k8s-namespace=ns1",
 		"--root=testdata",
@@ -69,6 +73,7 @@ func TestContextCreate(t *testing.T) {
 	a.Equal("testdata", ctx.RootDir())
 	a.Equal([]string{"testdata/extra-env.yaml"}, ctx.EnvFiles())
 	a.Equal(7, ctx.EvalConcurrency())
+	a.Equal("json", ctx.LogFormat())
 	a.Equal(os.Stdout, ctx.Stdout())
 	a.Equal(os.Stdin, ctx.stdin)
 	a.Equal(os.Stderr, ctx.Stderr())
@@ -99,6 +104,24 @@ func TestContextCreate(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestContextKubeAliases(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	ctx := getContext(t, Options{}, []string{
+		"--kubeconfig=./kubeconfig.yaml",
+		"--kube-context=minikube",
+	})
+	f, err := ctx.ForceOptions()
+	require.NoError(t, err)
+	a.Equal("minikube", f.K8sContext)
+
+	info, err := ctx.KubeContextInfo()
+	require.NoError(t, err)
+	a.Equal("prod", info.ContextName)
+	a.Equal("https://prod-server", info.ServerURL)
+}
+
 func TestContextBadExt(t *testing.T) {
 	a := assert.New(t)
 	fn := setPwd(t, "testdata")
@@ -109,6 +132,99 @@ func TestContextBadExt(t *testing.T) {
 	a.Contains(err.Error(), "no value found from environment for non-existent-env-var")
 }
 
+func TestContextBadLogFormat(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	err := getBadContext(t, Options{}, []string{
+		"--log-format=xml",
+	})
+	a.True(IsUsageError(err))
+	a.Equal(`invalid log format "xml", must be one of text or json`, err.Error())
+}
+
+func TestContextReproducible(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	ctx := getContext(t, Options{}, []string{
+		"--seed=s1",
+		"--freeze-time=2021-01-01T10:00:00Z",
+	})
+	a.Equal("s1", ctx.EvalSeed())
+	a.Equal(time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), ctx.EvalTime().UTC())
+	ec, err := ctx.BasicEvalContext()
+	require.NoError(t, err)
+	a.Equal("s1", ec.Seed)
+	a.True(ctx.EvalTime().Equal(ec.Now))
+}
+
+func TestContextBadFreezeTime(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	err := getBadContext(t, Options{}, []string{
+		"--freeze-time=yesterday",
+	})
+	a.True(IsUsageError(err))
+	a.Equal(`invalid freeze time "yesterday", must be an RFC3339 timestamp`, err.Error())
+}
+
+func TestResolveColors(t *testing.T) {
+	tests := []struct {
+		mode     string
+		noColor  string
+		terminal bool
+		expected bool
+	}{
+		{mode: "always", expected: true},
+		{mode: "always", noColor: "1", expected: true},
+		{mode: "never", terminal: true},
+		{mode: "auto", terminal: true, expected: true},
+		{mode: "auto"},
+		{mode: "auto", noColor: "1", terminal: true},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s-%s-%v", test.mode, test.noColor, test.terminal), func(t *testing.T) {
+			colors, err := resolveColors(test.mode, test.noColor, test.terminal)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, colors)
+		})
+	}
+	_, err := resolveColors("sometimes", "", true)
+	require.Error(t, err)
+	assert.True(t, IsUsageError(err))
+	assert.Equal(t, `invalid color mode "sometimes", must be one of always, auto or never`, err.Error())
+}
+
+func TestContextColorFlags(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	ctx := getContext(t, Options{}, []string{"--color=always"})
+	a.True(ctx.Colorize())
+	ctx = getContext(t, Options{}, []string{"--color=never"})
+	a.False(ctx.Colorize())
+	ctx = getContext(t, Options{}, []string{"--colors=false"})
+	a.False(ctx.Colorize())
+	err := getBadContext(t, Options{}, []string{"--colors", "--color=never"})
+	a.True(IsUsageError(err))
+	a.Equal("--colors cannot be used together with --color", err.Error())
+	err = getBadContext(t, Options{}, []string{"--color=sometimes"})
+	a.True(IsUsageError(err))
+}
+
+func TestContextAppRoot(t *testing.T) {
+	a := assert.New(t)
+	fn := setPwd(t, "testdata")
+	defer fn()
+	ctx := getContext(t, Options{}, []string{"--app-root=testdata"})
+	a.Equal("testdata", ctx.RootDir())
+	err := getBadContext(t, Options{}, []string{"--app-root=testdata", "--root=testdata"})
+	a.True(IsUsageError(err))
+	a.Equal("--app-root cannot be used together with --root", err.Error())
+}
+
 func TestContextBadProfile(t *testing.T) {
 	a := assert.New(t)
 	fn := setPwd(t, "testdata")
@@ -143,4 +259,5 @@ func TestContextConfirm(t *testing.T) {
 	err = ctx.Confirm("we will destroy you")
 	require.NotNil(t, err)
 	a.Equal("canceled", err.Error())
+	a.Equal(ErrConfirmCanceled, err)
 }
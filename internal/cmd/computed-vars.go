@@ -0,0 +1,140 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"github.com/google/go-jsonnet/toolutils"
+	"github.com/splunk/qbec/internal/model"
+)
+
+// extVarRefs returns the sorted names of the external variables that the supplied code refers to using
+// std.extVar calls with literal names. Code that cannot be parsed has no references, it fails later on in eval.
+func extVarRefs(name, code string) []string {
+	node, err := jsonnet.SnippetToAST(fmt.Sprintf("<%s>", name), code)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var visit func(n ast.Node)
+	visit = func(n ast.Node) {
+		if ref, ok := extVarRef(n); ok {
+			seen[ref] = true
+		}
+		for _, c := range toolutils.Children(n) {
+			visit(c)
+		}
+	}
+	visit(node)
+	var ret []string
+	for ref := range seen {
+		ret = append(ret, ref)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// extVarRef returns the variable name if the supplied node is a call of std.extVar with a literal name.
+func extVarRef(n ast.Node) (string, bool) {
+	apply, ok := n.(*ast.Apply)
+	if !ok || len(apply.Arguments.Positional) != 1 {
+		return "", false
+	}
+	index, ok := apply.Target.(*ast.Index)
+	if !ok {
+		return "", false
+	}
+	target, ok := index.Target.(*ast.Var)
+	if !ok || target.Id != "std" {
+		return "", false
+	}
+	switch {
+	case index.Id != nil && *index.Id == "extVar":
+	case isLiteralString(index.Index, "extVar"):
+	default:
+		return "", false
+	}
+	arg, ok := apply.Arguments.Positional[0].Expr.(*ast.LiteralString)
+	if !ok {
+		return "", false
+	}
+	return arg.Value, true
+}
+
+func isLiteralString(n ast.Node, value string) bool {
+	s, ok := n.(*ast.LiteralString)
+	return ok && s.Value == value
+}
+
+// orderComputedVars returns the supplied computed variables in an order such that every variable follows
+// the computed variables that it refers to. Variables retain their declared order otherwise. An error is
+// returned if the references have a cycle.
+func orderComputedVars(vars []model.ComputedVar) ([]model.ComputedVar, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var ret []model.ComputedVar
+	var path []string
+	var visit func(v model.ComputedVar) error
+	visit = func(v model.ComputedVar) error {
+		switch state[v.Name] {
+		case done:
+			return nil
+		case visiting:
+			var start int
+			for i, p := range path {
+				if p == v.Name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), v.Name)
+			return fmt.Errorf("computed variables have a cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[v.Name] = visiting
+		path = append(path, v.Name)
+		refs := map[string]bool{}
+		for _, ref := range extVarRefs(v.Name, v.Code) {
+			refs[ref] = true
+		}
+		for _, dep := range vars {
+			if !refs[dep.Name] {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[v.Name] = done
+		ret = append(ret, v)
+		return nil
+	}
+	for _, v := range vars {
+		if err := visit(v); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
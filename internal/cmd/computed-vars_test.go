@@ -0,0 +1,98 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtVarRefs(t *testing.T) {
+	a := assert.New(t)
+	refs := extVarRefs("c", `
+local b = std.extVar('b');
+{
+  a: std.extVar("a"),
+  b: b,
+  c: std['extVar']('c'),
+  d: std.extVar('d' + 'e'),
+  e: std.length('e'),
+}`)
+	a.Equal([]string{"a", "b", "c"}, refs)
+	a.Nil(extVarRefs("bad", `{ foo: `))
+}
+
+func cv(name, code string) model.ComputedVar {
+	return model.ComputedVar{Var: model.Var{Name: name}, Code: code}
+}
+
+func TestOrderComputedVars(t *testing.T) {
+	names := func(vars []model.ComputedVar) []string {
+		var ret []string
+		for _, v := range vars {
+			ret = append(ret, v.Name)
+		}
+		return ret
+	}
+	vars := []model.ComputedVar{
+		cv("c", `std.extVar('b') + std.extVar('a')`),
+		cv("a", `{ env: std.extVar('qbec.io/env') }`),
+		cv("b", `std.extVar('a') + { b: 1 }`),
+		cv("d", `{}`),
+	}
+	ordered, err := orderComputedVars(vars)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, names(ordered))
+
+	ordered, err = orderComputedVars(vars[1:])
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, names(ordered))
+}
+
+func TestOrderComputedVarsCycle(t *testing.T) {
+	tests := []struct {
+		name string
+		vars []model.ComputedVar
+		msg  string
+	}{
+		{
+			name: "self",
+			vars: []model.ComputedVar{cv("a", `std.extVar('a')`)},
+			msg:  "computed variables have a cycle: a -> a",
+		},
+		{
+			name: "indirect",
+			vars: []model.ComputedVar{
+				cv("x", `std.extVar('a')`),
+				cv("a", `std.extVar('b')`),
+				cv("b", `std.extVar('c')`),
+				cv("c", `std.extVar('a')`),
+			},
+			msg: "computed variables have a cycle: a -> b -> c -> a",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := orderComputedVars(test.vars)
+			require.Error(t, err)
+			assert.Equal(t, test.msg, err.Error())
+		})
+	}
+}
@@ -54,11 +54,16 @@ func (c *EnvContext) createDataSources() error {
 }
 
 func (c *EnvContext) computeVars() error {
-	cVars := c.App().DeclaredComputedVars()
+	cVars, err := orderComputedVars(c.App().DeclaredComputedVars())
+	if err != nil {
+		return err
+	}
 	for _, varObj := range cVars {
 		name := varObj.Name
 		baseCtx := c.EvalContext(false).BaseContext
-		jsonData, err := eval.Code(fmt.Sprintf("<%s>", name), vm.MakeCode(varObj.Code), baseCtx)
+		// imports in the code are relative to the root of the app
+		file := c.App().ResolvePath(fmt.Sprintf("<%s>", name))
+		jsonData, err := eval.Code(file, vm.MakeFileCode(varObj.Code), baseCtx)
 		if err != nil {
 			return errors.Wrapf(err, "eval computed var %s", name)
 		}
@@ -67,7 +72,48 @@ func (c *EnvContext) computeVars() error {
 	return nil
 }
 
+// loadVarsFile sets the external variables defined by the vars file of the environment, if any. Values in the file
+// override defaults declared by the app but not variables that are specified on the command line.
+func (c *EnvContext) loadVarsFile() error {
+	file, err := c.App().VarsFile(c.env)
+	if err != nil {
+		return err
+	}
+	if file == "" {
+		return nil
+	}
+	jsonData, err := eval.File(file, c.EvalContext(false).BaseContext)
+	if err != nil {
+		return errors.Wrapf(err, "eval vars file %s", file)
+	}
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonData), &values); err != nil {
+		return errors.Wrapf(err, "vars file %s must evaluate to an object", file)
+	}
+	cliVars := c.ext.ToVariableSet()
+	declared := c.App().DeclaredVars()
+	var addVars []vm.Var
+	for k, v := range values {
+		if _, ok := declared[k]; !ok && c.strictVars {
+			return fmt.Errorf("vars file %s: variable '%s' not declared for app", file, k)
+		}
+		if cliVars.HasVar(k) {
+			continue
+		}
+		v, err := valueVar(k, v)
+		if err != nil {
+			return err
+		}
+		addVars = append(addVars, v)
+	}
+	c.vars = c.vars.WithVars(addVars...)
+	return nil
+}
+
 func (c *EnvContext) initEnv() error {
+	if err := c.loadVarsFile(); err != nil {
+		return err
+	}
 	if err := c.createDataSources(); err != nil {
 		return err
 	}
@@ -80,6 +126,9 @@ func (c *EnvContext) initEnv() error {
 // Env returns the environment name for this context.
 func (c EnvContext) Env() string { return c.env }
 
+// baselineEnvName is the environment name returned by the qbecEnvName native function for the baseline environment.
+const baselineEnvName = "baseline"
+
 // EvalContext returns the evaluation context for the supplied environment.
 func (c EnvContext) EvalContext(cleanMode bool) eval.Context {
 	p, err := json.Marshal(c.props)
@@ -97,15 +146,29 @@ func (c EnvContext) EvalContext(cleanMode bool) eval.Context {
 		vm.NewVar(model.QbecNames.CleanModeVarName, cm),
 		vm.NewCodeVar(model.QbecNames.EnvPropsVarName, string(p)),
 	)
+	envName := c.env
+	if envName == model.Baseline {
+		envName = baselineEnvName
+	}
 	return eval.Context{
 		BaseContext: eval.BaseContext{
 			Vars:        baseVars,
 			LibPaths:    c.ext.LibPaths,
 			DataSources: c.dataSources,
 			Verbose:     c.Verbosity() > 1,
+			HelmCommand: c.App().HelmCommand(),
+			EnvName:     envName,
+			RemoteLibs:  c.RemoteLibs(),
+			LibCacheDir: c.LibCacheDir(),
+			Seed:        c.EvalSeed(),
+			Now:         c.EvalTime(),
 		},
-		Concurrency:      c.EvalConcurrency(),
-		PostProcessFiles: c.App().PostProcessors(),
+		Concurrency:        c.EvalConcurrency(),
+		PostProcessFiles:   c.App().PostProcessors(),
+		StreamProcessFiles: c.App().StreamProcessors(),
+		ImagePullSecrets:   c.App().ImagePullSecrets(),
+		KindLabels:         c.App().KindLabels(),
+		DefaultNamespace:   c.App().DefaultNamespace(c.env),
 	}
 }
 
@@ -116,6 +179,7 @@ func (c EnvContext) ObjectProducer() eval.LocalObjectProducer {
 		return model.NewK8sLocalObject(data, model.LocalAttrs{
 			App:               app.Name(),
 			Tag:               app.Tag(),
+			GCTag:             app.GCTag(),
 			Component:         component,
 			Env:               c.env,
 			SetComponentLabel: app.AddComponentLabel(),
@@ -123,11 +187,70 @@ func (c EnvContext) ObjectProducer() eval.LocalObjectProducer {
 	}
 }
 
-// Client returns a kubernetes client for the supplied environment
+// ComputedVars returns the values of the computed variables declared by the app, evaluated for the environment
+// and keyed by variable name. Values of secret variables are redacted.
+func (c EnvContext) ComputedVars() (map[string]interface{}, error) {
+	ret := map[string]interface{}{}
+	for _, v := range c.App().DeclaredComputedVars() {
+		if v.Secret {
+			ret[v.Name] = "<redacted>"
+			continue
+		}
+		s, err := c.configProvider(v.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "eval computed var %s", v.Name)
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return nil, errors.Wrapf(err, "unmarshal computed var %s", v.Name)
+		}
+		ret[v.Name] = value
+	}
+	return ret, nil
+}
+
+// Client returns a kubernetes client for the supplied environment. For environments that target multiple
+// contexts, this is the client for the first context.
 func (c EnvContext) Client() (KubeClient, error) {
 	return c.clp(c.env)
 }
 
+// TargetClient is a client for one of the clusters targeted by an environment.
+type TargetClient struct {
+	Context string // the context of the cluster, empty when the environment targets a single cluster
+	Client  KubeClient
+}
+
+// Clients returns one client for every cluster targeted by the environment. Environments that declare multiple
+// contexts return a client per context unless the context is forced from the command line, in which case
+// a single client is returned as for any other environment.
+func (c EnvContext) Clients() ([]TargetClient, error) {
+	contexts, err := c.App().Contexts(c.env)
+	if err != nil {
+		return nil, err
+	}
+	fc, err := c.forceOptsFn()
+	if err != nil {
+		return nil, err
+	}
+	if len(contexts) < 2 || fc.K8sContext != "" {
+		client, err := c.Client()
+		if err != nil {
+			return nil, err
+		}
+		return []TargetClient{{Client: client}}, nil
+	}
+	var ret []TargetClient
+	for _, ctx := range contexts {
+		client, err := c.cclp(c.env, ctx)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, TargetClient{Context: ctx, Client: client})
+	}
+	return ret, nil
+}
+
 // KubeAttributes returns the kubernetes attributes for the supplied environment
 func (c EnvContext) KubeAttributes() (*remote.KubeAttributes, error) {
 	return c.attrsp(c.env)
@@ -48,6 +48,11 @@ func TestEnvContextBasic(t *testing.T) {
 	a.True(ect.Vars.HasVar("qbec.io/tag"))
 	a.True(ect.Vars.HasVar("compFoo"))
 	a.True(ect.Vars.HasVar("compBar"))
+	a.Equal("dev", ect.EnvName)
+
+	bc, err := ac.EnvContext(model.Baseline)
+	require.NoError(t, err)
+	a.Equal("baseline", bc.EvalContext(true).EnvName)
 
 	attrs, err := ec.KubeAttributes()
 	require.NoError(t, err)
@@ -92,11 +97,28 @@ func TestEnvContextBadCompute(t *testing.T) {
 	a.Contains(err.Error(), `eval computed var compFoo: <compFoo>:1:2 Unexpected: end of file`)
 }
 
-func TestEnvContextBadCompute2(t *testing.T) {
+func TestEnvContextComputeOrder(t *testing.T) {
+	fn := setPwd(t, "testdata")
+	defer fn()
+	app, err := model.NewApp("qbec-computed-order.yaml", nil, "")
+	require.NoError(t, err)
+	ctx := getContext(t, Options{}, []string{
+		"--k8s:kubeconfig=kubeconfig.yaml",
+	})
+	ac, err := ctx.AppContext(app)
+	require.NoError(t, err)
+	ec, err := ac.EnvContext("dev")
+	require.NoError(t, err)
+	vars, err := ec.ComputedVars()
+	require.NoError(t, err)
+	assert.EqualValues(t, map[string]interface{}{"bar": map[string]interface{}{"baz": float64(10)}}, vars["compFoo"])
+}
+
+func TestEnvContextBadComputeCycle(t *testing.T) {
 	a := assert.New(t)
 	fn := setPwd(t, "testdata")
 	defer fn()
-	app, err := model.NewApp("qbec-bad2.yaml", nil, "")
+	app, err := model.NewApp("qbec-computed-cycle.yaml", nil, "")
 	require.NoError(t, err)
 	ctx := getContext(t, Options{}, []string{
 		"--k8s:kubeconfig=kubeconfig.yaml",
@@ -105,7 +127,7 @@ func TestEnvContextBadCompute2(t *testing.T) {
 	require.NoError(t, err)
 	_, err = ac.EnvContext("dev")
 	require.Error(t, err)
-	a.Contains(err.Error(), `eval computed var compFoo: RUNTIME ERROR: variable compBar has not yet been computed`)
+	a.Equal(`computed variables have a cycle: compFoo -> compBar -> compFoo`, err.Error())
 }
 
 func TestEnvContextForceContext(t *testing.T) {
@@ -190,3 +212,120 @@ func TestEnvContextBadDataSourceComputeOrder(t *testing.T) {
 	require.Error(t, err)
 	a.Contains(err.Error(), `eval computed var c1: RUNTIME ERROR: data source foo, target=/: init data source foo: RUNTIME ERROR: variable c2 has not yet been computed`)
 }
+
+func TestEnvContextVarsFile(t *testing.T) {
+	fn := setPwd(t, "testdata")
+	defer fn()
+	app, err := model.NewApp("qbec-vars-file.yaml", nil, "")
+	require.NoError(t, err)
+	tests := []struct {
+		name     string
+		env      string
+		args     []string
+		expected map[string]interface{}
+	}{
+		{
+			name:     "no-file",
+			env:      "minikube",
+			expected: map[string]interface{}{"foo": "baz", "bar": map[string]interface{}{"bar": "quux"}},
+		},
+		{
+			name:     "file",
+			env:      "dev",
+			expected: map[string]interface{}{"foo": "foo-dev", "bar": map[string]interface{}{"bar": "from-file"}},
+		},
+		{
+			name:     "cli-override",
+			env:      "dev",
+			args:     []string{"--vm:ext-str=extFoo=cli"},
+			expected: map[string]interface{}{"foo": "cli", "bar": map[string]interface{}{"bar": "from-file"}},
+		},
+		{
+			name:     "undeclared",
+			env:      "undeclared",
+			expected: map[string]interface{}{"foo": "baz", "bar": map[string]interface{}{"bar": "quux"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := getContext(t, Options{}, test.args)
+			ac, err := ctx.AppContext(app)
+			require.NoError(t, err)
+			ec, err := ac.EnvContext(test.env)
+			require.NoError(t, err)
+			vars, err := ec.ComputedVars()
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, vars["compFoo"])
+		})
+	}
+}
+
+func TestEnvContextVarsFileNegative(t *testing.T) {
+	fn := setPwd(t, "testdata")
+	defer fn()
+	app, err := model.NewApp("qbec-vars-file.yaml", nil, "")
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		msg  string
+	}{
+		{
+			name: "strict",
+			env:  "undeclared",
+			args: []string{"--strict-vars", "--vm:ext-str=extFoo=foo", "--vm:ext-code=extBar={}"},
+			msg:  "vars file vars/undeclared.libsonnet: variable 'extBaz' not declared for app",
+		},
+		{
+			name: "not-object",
+			env:  "scalar",
+			msg:  "vars file vars/scalar.libsonnet must evaluate to an object",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := getContext(t, Options{}, test.args)
+			ac, err := ctx.AppContext(app)
+			require.NoError(t, err)
+			_, err = ac.EnvContext(test.env)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.msg)
+		})
+	}
+}
+
+func TestEnvContextLibrary(t *testing.T) {
+	a := assert.New(t)
+	app, err := model.NewApp("testdata/qbec-vars-file.yaml", nil, "")
+	require.NoError(t, err)
+	ctx := NewLibraryContext(LibraryOptions{
+		Root:     "testdata",
+		ExtStrs:  map[string]string{"extFoo": "lib"},
+		ExtCodes: map[string]string{"extBar": "{ bar: 'code' }"},
+	})
+	a.Equal("testdata", ctx.RootDir())
+	a.True(ctx.AutoConfirm())
+	a.NoError(ctx.Confirm("do it"))
+	ac, err := ctx.AppContext(app)
+	require.NoError(t, err)
+	ec, err := ac.EnvContext("dev")
+	require.NoError(t, err)
+	vars, err := ec.ComputedVars()
+	require.NoError(t, err)
+	a.EqualValues(map[string]interface{}{"foo": "lib", "bar": map[string]interface{}{"bar": "code"}}, vars["compFoo"])
+
+	ctx = NewLibraryContext(LibraryOptions{Root: "testdata"})
+	ac, err = ctx.AppContext(app)
+	require.NoError(t, err)
+	ec, err = ac.EnvContext("dev")
+	require.NoError(t, err)
+	vars, err = ec.ComputedVars()
+	require.NoError(t, err)
+	a.EqualValues(map[string]interface{}{"foo": "foo-dev", "bar": map[string]interface{}{"bar": "from-file"}}, vars["compFoo"])
+	_, err = ec.Client()
+	require.Error(t, err)
+	a.Contains(err.Error(), "no cluster access")
+	_, err = ec.KubeAttributes()
+	require.Error(t, err)
+}
@@ -59,12 +59,33 @@ func IsRuntimeError(err error) bool {
 	return ok
 }
 
-// WrapError passes through usage errors and wraps all other errors with a runtime marker.
+// DiffExitCode is the exit code used when a command reports differences instead of a failure.
+const DiffExitCode = 2
+
+// diffError indicates that a command completed successfully but found differences.
+type diffError struct {
+	error
+}
+
+// NewDiffError returns an error that signals differences were found.
+func NewDiffError(msg string) error {
+	return &diffError{
+		error: errors.New(msg),
+	}
+}
+
+// IsDiffError returns if the supplied error signals differences rather than a failure.
+func IsDiffError(err error) bool {
+	_, ok := err.(*diffError)
+	return ok
+}
+
+// WrapError passes through usage and diff errors and wraps all other errors with a runtime marker.
 func WrapError(err error) error {
 	if err == nil {
 		return nil
 	}
-	if IsUsageError(err) {
+	if IsUsageError(err) || IsDiffError(err) {
 		return err
 	}
 	return NewRuntimeError(err)
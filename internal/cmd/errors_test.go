@@ -38,10 +38,20 @@ func TestRuntimeError(t *testing.T) {
 	a.Equal("foobar", re.Error())
 }
 
+func TestDiffError(t *testing.T) {
+	de := NewDiffError("foobar")
+	a := assert.New(t)
+	a.True(IsDiffError(de))
+	a.False(IsUsageError(de))
+	a.False(IsRuntimeError(de))
+	a.Equal("foobar", de.Error())
+}
+
 func TestWrapError(t *testing.T) {
 	ue := NewUsageError("foobar")
 	a := assert.New(t)
 	a.Nil(WrapError(nil))
 	a.True(IsUsageError(WrapError(ue)))
+	a.True(IsDiffError(WrapError(NewDiffError("foobar"))))
 	a.True(IsRuntimeError(WrapError(errors.New("foobar"))))
 }
@@ -48,6 +48,7 @@ func (c *closers) close() error {
 			lastError = err
 		}
 	}
+	c.closers = nil
 	return lastError // XXX: return a multi-error later
 }
 
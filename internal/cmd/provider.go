@@ -43,6 +43,7 @@ func addForceOptions(cmd *cobra.Command, cfg *remote.Config, prefix string) func
 		remote.ForceInClusterContext, currentMarker)
 	pf := cmd.PersistentFlags()
 	pf.StringVar(&forceOpts.K8sContext, prefix+"k8s-context", envOrDefault("QBEC_FORCE_K8S_CONTEXT", ""), ctxUsage)
+	pf.StringVar(&forceOpts.K8sContext, "kube-context", envOrDefault("QBEC_FORCE_K8S_CONTEXT", ""), "same as --"+prefix+"k8s-context")
 	nsUsage := fmt.Sprintf("override default namespace for environment with supplied value. The special value %s can be used to extract the value in the kube config. Defaulted from QBEC_FORCE_K8S_NAMESPACE", currentMarker)
 	pf.StringVar(&forceOpts.K8sNamespace, prefix+"k8s-namespace", envOrDefault("QBEC_FORCE_K8S_NAMESPACE", ""), nsUsage)
 	return func() (ForceOptions, error) {
@@ -86,6 +87,10 @@ func (s stdClientProvider) connectOpts(env string) (ret remote.ConnectOpts, _ er
 	if s.forceContext != "" {
 		fc = s.forceContext
 	}
+	tls, err := s.app.ClientTLS(env)
+	if err != nil {
+		return ret, err
+	}
 	ns := s.app.DefaultNamespace(env)
 	return remote.ConnectOpts{
 		EnvName:      env,
@@ -93,6 +98,9 @@ func (s stdClientProvider) connectOpts(env string) (ret remote.ConnectOpts, _ er
 		Namespace:    ns,
 		ForceContext: fc,
 		Verbosity:    s.verbosity,
+		CertFile:     tls.CertFile,
+		KeyFile:      tls.KeyFile,
+		CAFile:       tls.CAFile,
 	}, nil
 }
 
@@ -109,6 +117,20 @@ func (s stdClientProvider) Client(env string) (KubeClient, error) {
 	return rem, nil
 }
 
+// ContextClient returns a client for the supplied environment connected to the supplied context.
+func (s stdClientProvider) ContextClient(env string, context string) (KubeClient, error) {
+	opts, err := s.connectOpts(env)
+	if err != nil {
+		return nil, errors.Wrap(err, "get client")
+	}
+	opts.ForceContext = context
+	rem, err := s.config.Client(opts)
+	if err != nil {
+		return nil, errors.Wrapf(err, "context %s", context)
+	}
+	return rem, nil
+}
+
 func (s stdClientProvider) Attrs(env string) (*remote.KubeAttributes, error) {
 	opts, err := s.connectOpts(env)
 	if err != nil {
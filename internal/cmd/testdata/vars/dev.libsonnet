@@ -0,0 +1,4 @@
+{
+  extFoo: 'foo-' + std.extVar('qbec.io/env'),
+  extBar: { bar: 'from-file' },
+}
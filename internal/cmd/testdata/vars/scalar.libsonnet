@@ -0,0 +1 @@
+'not an object'
@@ -0,0 +1,3 @@
+{
+  extBaz: 'baz',
+}
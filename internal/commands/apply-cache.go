@@ -0,0 +1,170 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/remote"
+	"github.com/splunk/qbec/internal/sio"
+	"github.com/splunk/qbec/internal/types"
+)
+
+// applyCacheDir returns the directory where apply caches are stored, overridden in tests.
+var applyCacheDir = func() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "qbec", "apply"), nil
+}
+
+// applyCacheData is the on-disk form of an apply cache.
+type applyCacheData struct {
+	Version string            `json:"version"` // the qbec version and commit that wrote the cache
+	Objects map[string]string `json:"objects"` // content hashes of applied objects keyed by display name
+}
+
+// applyCache records content hashes of the objects that were last applied to a specific cluster for an app,
+// environment and tag. It is safe for concurrent use.
+type applyCache struct {
+	file string
+	l    sync.Mutex
+	data applyCacheData
+}
+
+func cacheVersion() string {
+	return version + "-" + commit
+}
+
+// loadApplyCache loads the apply cache for the supplied key parts. A missing or unreadable cache, or one written by
+// a different version of qbec, results in an empty cache.
+func loadApplyCache(keyParts ...string) (*applyCache, error) {
+	dir, err := applyCacheDir()
+	if err != nil {
+		return nil, errors.Wrap(err, "get cache directory")
+	}
+	sum := sha256.Sum256([]byte(strings.Join(keyParts, "\x00")))
+	c := &applyCache{
+		file: filepath.Join(dir, hex.EncodeToString(sum[:])+".json"),
+		data: applyCacheData{Version: cacheVersion(), Objects: map[string]string{}},
+	}
+	b, err := ioutil.ReadFile(c.file)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			sio.Warnf("ignore apply cache %s, %v\n", c.file, err)
+		}
+		return c, nil
+	}
+	var data applyCacheData
+	if err := json.Unmarshal(b, &data); err != nil {
+		sio.Warnf("ignore apply cache %s, %v\n", c.file, err)
+		return c, nil
+	}
+	if data.Version != cacheVersion() {
+		sio.Debugf("ignore apply cache written by qbec %s\n", data.Version)
+		return c, nil
+	}
+	if data.Objects != nil {
+		c.data.Objects = data.Objects
+	}
+	return c, nil
+}
+
+// objectHash returns the content hash of the supplied object, or an empty string if the object cannot be
+// cached. Objects with generated names and ones that have sensitive information are never cached.
+func objectHash(ob model.K8sLocalObject) string {
+	un := ob.ToUnstructured()
+	if ob.GetName() == "" || types.HasSensitiveInfo(un) {
+		return ""
+	}
+	b, err := json.Marshal(un.Object)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (c *applyCache) unchanged(name string, hash string) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return hash != "" && c.data.Objects[name] == hash
+}
+
+func (c *applyCache) record(name string, hash string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if hash == "" {
+		delete(c.data.Objects, name)
+		return
+	}
+	c.data.Objects[name] = hash
+}
+
+func (c *applyCache) save() error {
+	c.l.Lock()
+	defer c.l.Unlock()
+	b, err := json.MarshalIndent(c.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.file, b, 0644)
+}
+
+// cachedSyncClient is a client that does not sync objects whose rendered form is the same as when they were last
+// applied, according to the apply cache. It records the objects that were synced or deleted in the cache.
+type cachedSyncClient struct {
+	cmd.KubeClient
+	cache *applyCache
+}
+
+// Sync implements the interface method.
+func (c *cachedSyncClient) Sync(ctx context.Context, ob model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+	name, hash := c.DisplayName(ob), objectHash(ob)
+	if c.cache.unchanged(name, hash) {
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical, Details: "unchanged since last apply"}, nil
+	}
+	res, err := c.KubeClient.Sync(ctx, ob, opts)
+	if err == nil && res.Type != remote.SyncSkip {
+		c.cache.record(name, hash)
+	}
+	return res, err
+}
+
+// Delete implements the interface method.
+func (c *cachedSyncClient) Delete(ctx context.Context, ob model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+	res, err := c.KubeClient.Delete(ctx, ob, opts)
+	if err == nil {
+		c.cache.record(c.DisplayName(ob), "")
+	}
+	return res, err
+}
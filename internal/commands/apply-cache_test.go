@@ -0,0 +1,101 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyChangedOnly(t *testing.T) {
+	dir := t.TempDir()
+	origDir, origVersion := applyCacheDir, version
+	applyCacheDir = func() (string, error) { return dir, nil }
+	defer func() { applyCacheDir, version = origDir, origVersion }()
+
+	apply := func(args ...string) []string {
+		s := newScaffold(t)
+		defer s.reset()
+		var l sync.Mutex
+		var synced []string
+		s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+			l.Lock()
+			defer l.Unlock()
+			synced = append(synced, s.client.DisplayName(obj))
+			return &remote.SyncResult{Type: remote.SyncUpdated}, nil
+		}
+		err := s.executeCommand(append([]string{"apply", "dev", "--changed-only", "--gc=false", "--wait-all=false"}, args...)...)
+		require.NoError(t, err)
+		sort.Strings(synced)
+		return synced
+	}
+
+	all := apply()
+	require.True(t, len(all) > 2)
+	// secrets and objects with generated names are always synced
+	uncached := []string{"Job::tj-<xxxxx>", "Secret:bar-system:svc2-secret"}
+	assert.Equal(t, uncached, apply())
+	assert.Equal(t, uncached, apply("-n"))
+
+	// dry-runs do not update the cache
+	version = "other"
+	assert.Equal(t, all, apply("-n"))
+	assert.Equal(t, all, apply())
+	assert.Equal(t, uncached, apply())
+}
+
+func TestApplyChangedOnlyDelete(t *testing.T) {
+	dir := t.TempDir()
+	origDir := applyCacheDir
+	applyCacheDir = func() (string, error) { return dir, nil }
+	defer func() { applyCacheDir = origDir }()
+
+	cache, err := loadApplyCache("key")
+	require.NoError(t, err)
+	cache.record("ConfigMap:ns:cm", "hash")
+	require.NoError(t, cache.save())
+
+	cache, err = loadApplyCache("key")
+	require.NoError(t, err)
+	assert.True(t, cache.unchanged("ConfigMap:ns:cm", "hash"))
+	assert.False(t, cache.unchanged("ConfigMap:ns:cm", "other"))
+	assert.False(t, cache.unchanged("ConfigMap:ns:cm", ""))
+
+	c := &cachedSyncClient{KubeClient: &client{
+		deleteFunc: func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+			return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+		},
+	}, cache: cache}
+	_, err = c.Delete(context.TODO(), model.NewK8sObject(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"namespace": "ns", "name": "cm"},
+	}), remote.DeleteOptions{})
+	require.NoError(t, err)
+	assert.False(t, cache.unchanged("ConfigMap:ns:cm", "hash"))
+
+	other, err := loadApplyCache("other-key")
+	require.NoError(t, err)
+	assert.Empty(t, other.data.Objects)
+}
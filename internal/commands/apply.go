@@ -19,8 +19,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/model"
@@ -28,15 +34,20 @@ import (
 	"github.com/splunk/qbec/internal/remote"
 	"github.com/splunk/qbec/internal/rollout"
 	"github.com/splunk/qbec/internal/sio"
+	"github.com/splunk/qbec/internal/types"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
 type applyStats struct {
-	Created []string `json:"created,omitempty"`
-	Updated []string `json:"updated,omitempty"`
-	Skipped []string `json:"skipped,omitempty"`
-	Deleted []string `json:"deleted,omitempty"`
-	Same    int      `json:"same,omitempty"`
+	Created      []string `json:"created,omitempty"`
+	Updated      []string `json:"updated,omitempty"`
+	Skipped      []string `json:"skipped,omitempty"`
+	Deleted      []string `json:"deleted,omitempty"`
+	Adopted      []string `json:"adopted,omitempty"`
+	Failed       []string `json:"failed,omitempty"`
+	NotAttempted []string `json:"notAttempted,omitempty"` // objects not synced since an object applied before them failed
+	Same         int      `json:"same,omitempty"`
 }
 
 func (a *applyStats) update(name string, s *remote.SyncResult) {
@@ -49,20 +60,179 @@ func (a *applyStats) update(name string, s *remote.SyncResult) {
 		a.Created = append(a.Created, name)
 	case remote.SyncUpdated:
 		a.Updated = append(a.Updated, name)
+		if s.AdoptedFrom != "" {
+			a.Adopted = append(a.Adopted, name)
+		}
 	case remote.SyncDeleted:
 		a.Deleted = append(a.Deleted, name)
 	}
 }
 
+// parseDryRun parses the value of the dry-run flag and returns whether dry-run mode is active and whether requests
+// should be sent to the server. Boolean values are accepted for backwards compatibility.
+func parseDryRun(s string) (dryRun bool, server bool, _ error) {
+	switch s {
+	case "none", "false":
+		return false, false, nil
+	case "client", "true":
+		return true, false, nil
+	case "server":
+		return true, true, nil
+	default:
+		return false, false, cmd.NewUsageError(fmt.Sprintf("invalid dry-run value %q, must be one of none, client or server", s))
+	}
+}
+
 type applyCommandConfig struct {
 	cmd.AppContext
-	syncOptions remote.SyncOptions
-	showDetails bool
-	gc          bool
-	wait        bool
-	waitAll     bool
-	waitTimeout time.Duration
-	filterFunc  func() (model.Filters, error)
+	syncOptions     remote.SyncOptions
+	dryRun          string
+	showDetails     bool
+	gc              bool
+	gcClusterScoped bool
+	pruneOnly       bool
+	pruneOrder      string
+	stamp           bool
+	createNamespace bool
+	changedOnly     bool
+	parallel        int
+	pruneWhitelist  []string
+	wait            bool
+	waitAll         bool
+	waitTimeout     time.Duration
+	pruneGrace      time.Duration
+	pruneThresholds []string
+	pruneLimits     []pruneThreshold
+	yesPrune        bool
+	confirm         bool
+	output          string
+	timings         bool
+	filterFunc      func() (model.Filters, error)
+	nsFunc          func() (namespaceOverride, error)
+}
+
+// pruneFilter restricts garbage collection to a set of kinds. Kinds are matched by group and kind
+// since remote objects are listed using their canonical version.
+type pruneFilter struct {
+	kinds map[schema.GroupKind]bool
+}
+
+// parseGVK parses a string of the form [<group>/]<version>/<kind> into a group version kind.
+func parseGVK(s string) (schema.GroupVersionKind, error) {
+	parts := strings.Split(s, "/")
+	for _, p := range parts {
+		if p == "" {
+			return schema.GroupVersionKind{}, fmt.Errorf("invalid kind %q, must be of the form [<group>/]<version>/<kind>", s)
+		}
+	}
+	switch len(parts) {
+	case 2:
+		return schema.GroupVersionKind{Version: parts[0], Kind: parts[1]}, nil
+	case 3:
+		return schema.GroupVersionKind{Group: parts[0], Version: parts[1], Kind: parts[2]}, nil
+	default:
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid kind %q, must be of the form [<group>/]<version>/<kind>", s)
+	}
+}
+
+// newPruneFilter returns a filter for the supplied kinds verifying that every kind is known to the server.
+// It returns nil when no kinds are specified.
+func newPruneFilter(specs []string, client model.Namespaced) (*pruneFilter, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+	pf := &pruneFilter{kinds: map[schema.GroupKind]bool{}}
+	for _, s := range specs {
+		gvk, err := parseGVK(s)
+		if err != nil {
+			return nil, cmd.NewUsageError(err.Error())
+		}
+		if _, err := client.IsNamespaced(gvk); err != nil {
+			return nil, cmd.NewUsageError(fmt.Sprintf("prune whitelist: unknown kind %q, %v", s, err))
+		}
+		pf.kinds[gvk.GroupKind()] = true
+	}
+	return pf, nil
+}
+
+// filter returns the subset of objects that may be deleted. A nil filter allows all objects.
+func (p *pruneFilter) filter(objs []model.K8sQbecMeta) []model.K8sQbecMeta {
+	if p == nil {
+		return objs
+	}
+	var ret []model.K8sQbecMeta
+	for _, o := range objs {
+		if p.kinds[o.GroupVersionKind().GroupKind()] {
+			ret = append(ret, o)
+		}
+	}
+	return ret
+}
+
+// pruneThreshold is the maximum number of objects, or the maximum percentage of tracked objects, that garbage
+// collection may delete without --yes-prune.
+type pruneThreshold struct {
+	spec    string  // the threshold as specified on the command line
+	value   float64 // the count or percentage
+	percent bool    // true if the value is a percentage
+}
+
+// parsePruneThreshold parses a threshold of the form <count> or <percentage>%.
+func parsePruneThreshold(s string) (pruneThreshold, error) {
+	ret := pruneThreshold{spec: s}
+	if strings.HasSuffix(s, "%") {
+		v, err := strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		if err != nil || v < 0 || v > 100 {
+			return ret, cmd.NewUsageError(fmt.Sprintf("invalid prune threshold %q, must be a percentage between 0 and 100", s))
+		}
+		ret.value, ret.percent = v, true
+		return ret, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < 0 {
+		return ret, cmd.NewUsageError(fmt.Sprintf("invalid prune threshold %q, must be a non-negative number or a percentage", s))
+	}
+	ret.value = float64(v)
+	return ret, nil
+}
+
+// exceeded returns true if deleting the supplied number of objects out of the tracked objects exceeds the threshold.
+func (p pruneThreshold) exceeded(deletions, tracked int) bool {
+	if p.percent {
+		return tracked > 0 && float64(deletions)*100 > p.value*float64(tracked)
+	}
+	return float64(deletions) > p.value
+}
+
+// checkPruneThresholds returns an error for the first threshold that is exceeded by the supplied deletions. Tracked
+// objects are the local objects of the environment along with the deletions.
+func checkPruneThresholds(thresholds []pruneThreshold, deletions, tracked int) error {
+	for _, p := range thresholds {
+		if p.exceeded(deletions, tracked) {
+			return fmt.Errorf("garbage collection would delete %d of %d tracked object(s), more than the prune threshold of %s, use --yes-prune to allow this",
+				deletions, tracked, p.spec)
+		}
+	}
+	return nil
+}
+
+// splitClusterScoped splits the supplied garbage collection candidates into namespaced objects that may be deleted
+// and cluster-scoped objects that must be retained. Objects whose scope cannot be determined are also retained.
+func splitClusterScoped(objs []model.K8sQbecMeta, client model.Namespaced) (namespaced, clusterScoped []model.K8sQbecMeta) {
+	for _, o := range objs {
+		isNamespaced, err := client.IsNamespaced(o.GroupVersionKind())
+		if err != nil {
+			sio.Warnf("unable to determine scope of %s, not deleting it: %v\n", model.NameForDisplay(o), err)
+			clusterScoped = append(clusterScoped, o)
+			continue
+		}
+		if isNamespaced {
+			namespaced = append(namespaced, o)
+		} else {
+			clusterScoped = append(clusterScoped, o)
+		}
+	}
+	return namespaced, clusterScoped
 }
 
 type nameWrap struct {
@@ -93,6 +263,61 @@ func (n nsWrap) GetNamespace() string {
 
 var applyWaitFn = rollout.WaitUntilComplete // allow override in tests
 
+// applyWave is a set of objects that are applied together, before the objects of later waves.
+type applyWave struct {
+	wave    int
+	objects []model.K8sLocalObject
+}
+
+// splitWaves splits the supplied objects into waves ordered by their apply wave directive, retaining the relative
+// order of objects in each wave.
+func splitWaves(objects []model.K8sLocalObject, displayName func(o model.K8sMeta) string) ([]applyWave, error) {
+	byWave := map[int][]model.K8sLocalObject{}
+	for _, ob := range objects {
+		wave, err := waveOf(ob)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", displayName(ob), err)
+		}
+		byWave[wave] = append(byWave[wave], ob)
+	}
+	var ret []applyWave
+	for wave, objs := range byWave {
+		ret = append(ret, applyWave{wave: wave, objects: objs})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].wave < ret[j].wave })
+	return ret, nil
+}
+
+type syncOutcome struct {
+	res *remote.SyncResult
+	err error
+}
+
+// syncGroup syncs the supplied objects using at most parallel concurrent calls and returns
+// outcomes in the same order as the input objects.
+func syncGroup(ctx context.Context, client cmd.KubeClient, objs []model.K8sLocalObject, opts remote.SyncOptions, parallel int) []syncOutcome {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	outcomes := make([]syncOutcome, len(objs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, ob := range objs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ob model.K8sLocalObject) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := client.Sync(ctx, ob, opts)
+			outcomes[i] = syncOutcome{res: res, err: err}
+		}(i, ob)
+	}
+	wg.Wait()
+	return outcomes
+}
+
 func doApply(ctx context.Context, args []string, config applyCommandConfig) error {
 	if len(args) != 1 {
 		return cmd.NewUsageError(fmt.Sprintf("exactly one environment required, but provided: %q", args))
@@ -101,6 +326,55 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 	if env == model.Baseline { // cannot apply for the baseline environment
 		return cmd.NewUsageError("cannot apply baseline environment, use a real environment")
 	}
+	if config.syncOptions.ForceConflicts && !config.syncOptions.ServerSide {
+		return cmd.NewUsageError("--force-conflicts can only be used with --server-side")
+	}
+	if config.pruneOnly && !config.gc {
+		return cmd.NewUsageError("--prune-only cannot be used with --gc=false")
+	}
+	if config.pruneGrace < 0 {
+		return cmd.NewUsageError(fmt.Sprintf("invalid prune grace period: %v, must not be negative", config.pruneGrace))
+	}
+	switch config.pruneOrder {
+	case pruneOrderPost:
+	case pruneOrderPre:
+		if config.pruneGrace > 0 {
+			return cmd.NewUsageError("--prune-grace-period cannot be used with --prune-order=pre")
+		}
+	default:
+		return cmd.NewUsageError(fmt.Sprintf("invalid prune order %q, must be one of %s or %s", config.pruneOrder, pruneOrderPre, pruneOrderPost))
+	}
+	if config.pruneOnly && config.createNamespace {
+		return cmd.NewUsageError("--create-namespace cannot be used with --prune-only")
+	}
+	for _, s := range config.pruneThresholds {
+		p, err := parsePruneThreshold(s)
+		if err != nil {
+			return err
+		}
+		config.pruneLimits = append(config.pruneLimits, p)
+	}
+	if config.confirm && config.pruneOnly {
+		return cmd.NewUsageError("--confirm cannot be used with --prune-only")
+	}
+	switch config.output {
+	case applyOutputText:
+	case applyOutputDiff:
+		if !config.syncOptions.DryRun {
+			return cmd.NewUsageError("--output=diff requires --dry-run")
+		}
+		if config.pruneOnly {
+			return cmd.NewUsageError("--output=diff cannot be used with --prune-only")
+		}
+	default:
+		return cmd.NewUsageError(fmt.Sprintf("invalid output %q, must be one of %s or %s", config.output, applyOutputText, applyOutputDiff))
+	}
+	if config.confirm && !config.syncOptions.DryRun && !config.AutoConfirm() && !config.StdinIsTerminal() {
+		return cmd.NewUsageError("--confirm requires --yes when standard input is not a terminal")
+	}
+	if config.stamp {
+		config.syncOptions.Stamp = stampAnnotations(stampTime())
+	}
 	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
@@ -109,37 +383,407 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 	if err != nil {
 		return err
 	}
-	client, err := envCtx.Client()
+	nso, err := config.nsFunc()
 	if err != nil {
 		return err
 	}
-	objects, err := generateObjects(ctx, envCtx, makeFilterOpts(fp, client))
+	targets, err := envCtx.Clients()
 	if err != nil {
 		return err
 	}
+	// components are evaluated once for all targets, objects of all components are retained for garbage collection
+	rendered, err := renderEnv(envCtx, fp, config.timings, config.gc)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.Context != "" {
+			sio.Noticef("apply context %s\n", t.Context)
+		}
+		var err error
+		if config.output == applyOutputDiff {
+			err = diffApply(ctx, envCtx, t, rendered, fp, nso, config)
+		} else {
+			err = applyTarget(ctx, envCtx, t, rendered, fp, nso, config)
+		}
+		switch {
+		case err == errApplyCanceled:
+			sio.Noticeln("apply canceled")
+			return nil
+		case err != nil && t.Context != "":
+			return errors.Wrapf(err, "context %s", t.Context)
+		case err != nil:
+			return err
+		}
+	}
+	return nil
+}
 
-	opts := config.syncOptions
-	opts.DisableUpdateFn = newUpdatePolicy().disableUpdate
+// errApplyCanceled is returned by applyTarget when the user declines the changes shown by --confirm.
+var errApplyCanceled = errors.New("apply canceled")
 
-	if !opts.DryRun && len(objects) > 0 {
-		msg := fmt.Sprintf("will synchronize %d object(s)", len(objects))
-		if err := config.Confirm(msg); err != nil {
+const (
+	pruneOrderPre  = "pre"  // garbage collect extra objects before other objects are created and updated
+	pruneOrderPost = "post" // garbage collect extra objects after other objects are created and updated
+)
+
+const (
+	applyOutputText = "text" // report every object operation on a line of its own
+	applyOutputDiff = "diff" // print the unified diff of the changes instead, for dry-runs
+)
+
+// applyPlan is the set of changes that an apply makes to the cluster of a single target. The same plan is used to
+// apply the changes and to show them as a diff, such that the diff matches what is applied.
+type applyPlan struct {
+	client        cmd.KubeClient
+	objects       []model.K8sLocalObject // local objects after filtering
+	waves         []applyWave            // local objects by wave
+	createNs      model.K8sLocalObject   // namespace created before all other objects, if any
+	lister        lister
+	retainObjects []model.K8sLocalObject // objects that are never garbage collected
+	pf            *pruneFilter
+	prunePolicy   *deletePolicy       // delete policy for garbage collection
+	listed        bool                // set once deletions are computed
+	deletions     []model.K8sQbecMeta // extra objects to delete, sorted in delete order
+	clusterScoped []model.K8sQbecMeta // cluster scoped extra objects that are not deleted
+	protected     []model.K8sQbecMeta // extra objects that are not deleted due to the no-prune directive
+}
+
+// newApplyPlan returns the plan for applying the supplied rendered objects using the supplied client.
+func newApplyPlan(ctx context.Context, envCtx cmd.EnvContext, client cmd.KubeClient, rendered *renderedEnv, fp model.Filters,
+	nso namespaceOverride, config applyCommandConfig) (*applyPlan, error) {
+	pf, err := newPruneFilter(config.pruneWhitelist, client)
+	if err != nil {
+		return nil, err
+	}
+	fo := makeFilterOpts(fp, client)
+	fo.nsOverride = nso
+	objects, err := filterObjects(envCtx, rendered.selected, fo)
+	if err != nil {
+		return nil, err
+	}
+	if config.wait || config.waitAll {
+		for _, ob := range objects {
+			if _, err := types.WaitCondition(ob); err != nil {
+				return nil, fmt.Errorf("%s: %v", client.DisplayName(ob), err)
+			}
+		}
+	}
+	waves, err := splitWaves(objects, client.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+	p := &applyPlan{
+		client:      client,
+		objects:     objects,
+		waves:       waves,
+		lister:      &stubLister{},
+		pf:          pf,
+		prunePolicy: newPrunePolicy(client.IsNamespaced, envCtx.App().DefaultNamespace(envCtx.Env())),
+	}
+	if config.createNamespace {
+		p.createNs, err = namespaceToCreate(ctx, client, namespaceObject(envCtx, nso), objects)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.gc {
+		p.lister, p.retainObjects, err = startRemoteList(ctx, envCtx, client, rendered.all, fp, nso)
+		if err != nil {
+			return nil, err
+		}
+		if config.createNamespace {
+			p.retainObjects = append(p.retainObjects, namespaceObject(envCtx, nso))
+		}
+	}
+	return p, nil
+}
+
+// syncCount returns the number of objects that are synced.
+func (p *applyPlan) syncCount() int {
+	count := len(p.objects)
+	if p.createNs != nil {
+		count++
+	}
+	return count
+}
+
+// groups returns the groups of objects to sync, in order, and the wave number of the first group of every wave when
+// there are many waves. Objects in the same group are synced concurrently. A synthesized namespace is always
+// created first irrespective of the apply order of kinds. Objects are sorted by kind within each wave.
+func (p *applyPlan) groups(config applyCommandConfig) ([][]model.K8sLocalObject, map[int]int) {
+	var groups [][]model.K8sLocalObject
+	waveStarts := map[int]int{}
+	if config.pruneOnly {
+		return groups, waveStarts
+	}
+	if p.createNs != nil {
+		groups = append(groups, []model.K8sLocalObject{p.createNs})
+	}
+	for _, w := range p.waves {
+		if len(p.waves) > 1 {
+			waveStarts[len(groups)] = w.wave
+		}
+		groups = append(groups, objsort.SortGroups(w.objects, sortConfig(p.client.IsNamespaced, config.App().ApplyOrder()))...)
+	}
+	return groups, waveStarts
+}
+
+// prunable returns the extra objects on the server that are deleted, sorted in delete order, and the cluster scoped
+// extra objects that are skipped unless cluster scoped objects are garbage collected. Extra objects that have the
+// no-prune directive set are never candidates for deletion and are reported as skipped when first computed.
+// The objects are only computed once, using the objects retained at the time of the first call.
+func (p *applyPlan) prunable(fp model.Filters, config applyCommandConfig) (deletions, clusterScoped []model.K8sQbecMeta, _ error) {
+	if p.listed {
+		return p.deletions, p.clusterScoped, nil
+	}
+	deletions, err := p.lister.deletions(p.retainObjects, fp.Match)
+	if err != nil {
+		return nil, nil, err
+	}
+	deletions = p.pf.filter(deletions)
+	deletions, p.protected = p.prunePolicy.splitNoPrune(deletions)
+	dryRun := ""
+	if config.syncOptions.DryRun {
+		dryRun = "[dry-run] "
+	}
+	for _, ob := range p.protected {
+		sio.Noticef("%sskip delete %s, garbage collection is disabled by the %s directive\n", dryRun, p.client.DisplayName(ob), model.QbecNames.Directives.NoPrune)
+	}
+	if !config.gcClusterScoped {
+		deletions, clusterScoped = splitClusterScoped(deletions, p.client)
+	}
+	p.deletions = objsort.SortMeta(deletions, sortConfig(p.client.IsNamespaced, config.App().DeleteOrder()))
+	p.clusterScoped = clusterScoped
+	p.listed = true
+	return p.deletions, p.clusterScoped, nil
+}
+
+// checkPruneLimits returns an error if the supplied deletions exceed the prune thresholds, unless these are
+// overridden. Exceeded thresholds are only reported as a warning for dry-runs.
+func (p *applyPlan) checkPruneLimits(deletions []model.K8sQbecMeta, config applyCommandConfig) error {
+	if config.yesPrune {
+		return nil
+	}
+	if err := checkPruneThresholds(config.pruneLimits, len(deletions), len(p.retainObjects)+len(deletions)); err != nil {
+		if !config.syncOptions.DryRun {
 			return err
 		}
+		sio.Warnf("[dry-run] %v\n", err)
 	}
+	return nil
+}
 
-	// prepare for GC with object list of deletions
-	var lister lister = &stubLister{}
-	var retainObjects []model.K8sLocalObject
-	if config.gc {
-		lister, retainObjects, err = startRemoteList(ctx, envCtx, client, fp)
+// diffApply prints the changes that an apply to the supplied target would make as a unified diff,
+// including the extra objects that would be garbage collected.
+func diffApply(ctx context.Context, envCtx cmd.EnvContext, target cmd.TargetClient, rendered *renderedEnv, fp model.Filters, nso namespaceOverride, config applyCommandConfig) error {
+	plan, err := newApplyPlan(ctx, envCtx, target.Client, rendered, fp, nso, config)
+	if err != nil {
+		return err
+	}
+	stats, err := printApplyDiff(ctx, envCtx, target, plan, fp, config)
+	if err != nil {
+		return err
+	}
+	sio.Noticef("[dry-run] %s\n", stats.Summary)
+	return nil
+}
+
+// confirmApply prints a diff of the changes that an apply to the supplied target would make and asks the user
+// whether to proceed. It does not prompt when there are no changes or prompts are skipped, and returns
+// errApplyCanceled when the user declines.
+func confirmApply(ctx context.Context, envCtx cmd.EnvContext, target cmd.TargetClient, plan *applyPlan, fp model.Filters, config applyCommandConfig) error {
+	stats, err := printApplyDiff(ctx, envCtx, target, plan, fp, config)
+	if err != nil {
+		return err
+	}
+	if len(stats.Additions)+len(stats.Changes)+len(stats.Deletions) == 0 {
+		sio.Noticeln(stats.Summary)
+		return nil
+	}
+	if err := config.Confirm(stats.Summary.String()); err != nil {
+		if err == cmd.ErrConfirmCanceled {
+			return errApplyCanceled
+		}
+		return err
+	}
+	return nil
+}
+
+// printApplyDiff prints the diff of the changes in the supplied plan in the order that these are applied, followed
+// by the stats of the diff, and returns the stats.
+func printApplyDiff(ctx context.Context, envCtx cmd.EnvContext, target cmd.TargetClient, plan *applyPlan, fp model.Filters, config applyCommandConfig) (*diffStats, error) {
+	d := newDiffer(envCtx, cmd.TargetClient{Context: target.Context, Client: plan.client}, &lockWriter{Writer: config.Stdout()}, diffCommandConfig{
+		AppContext:   config.AppContext,
+		showSecrets:  config.syncOptions.ShowSecrets,
+		contextLines: 3,
+		format:       diffFormatUnified,
+		serverSide:   config.syncOptions.ServerSide,
+		fieldManager: config.syncOptions.FieldManager,
+	})
+	deletions, clusterScoped, err := plan.prunable(fp, config)
+	if err != nil {
+		return nil, err
+	}
+	for _, ob := range clusterScoped {
+		d.stats.skippedDeletion(plan.client.DisplayName(ob))
+	}
+	for _, ob := range plan.protected {
+		d.stats.skippedDeletion(plan.client.DisplayName(ob))
+	}
+	if err := plan.checkPruneLimits(deletions, config); err != nil {
+		return nil, err
+	}
+	diffDeletions := func() error {
+		for i := len(deletions) - 1; i >= 0; i-- {
+			if err := d.diff(ctx, deletions[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if config.pruneOrder == pruneOrderPre {
+		if err := diffDeletions(); err != nil {
+			return nil, err
+		}
+	}
+	groups, waveStarts := plan.groups(config)
+	for gi, group := range groups {
+		if wave, ok := waveStarts[gi]; ok {
+			sio.Noticef("changes for wave %d\n", wave)
+		}
+		if err := runInParallel(ctx, group, d.diffLocal, config.parallel); err != nil {
+			return nil, err
+		}
+	}
+	if config.pruneOrder == pruneOrderPost {
+		if err := diffDeletions(); err != nil {
+			return nil, err
+		}
+	}
+	d.stats.done()
+	printStats(d.w, &d.stats)
+	return &d.stats, nil
+}
+
+// namespaceComponent is the component name of the namespace object synthesized by apply.
+const namespaceComponent = "qbec-namespace"
+
+// namespaceObject returns a namespace object for the default namespace of the environment, or the override namespace
+// if one is specified. The object carries the labels of the app such that it is tracked for garbage collection.
+func namespaceObject(envCtx cmd.EnvContext, nso namespaceOverride) model.K8sLocalObject {
+	ns := envCtx.App().DefaultNamespace(envCtx.Env())
+	if nso.namespace != "" {
+		ns = nso.namespace
+	}
+	return envCtx.ObjectProducer()(namespaceComponent, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Namespace",
+		"metadata": map[string]interface{}{
+			"name": ns,
+		},
+	})
+}
+
+// namespaceToCreate returns the supplied namespace object if it needs to be created, or nil if the namespace
+// is already defined by one of the local objects or exists on the server.
+func namespaceToCreate(ctx context.Context, client cmd.KubeClient, ns model.K8sLocalObject, objects []model.K8sLocalObject) (model.K8sLocalObject, error) {
+	for _, o := range objects {
+		gvk := o.GroupVersionKind()
+		if gvk.Group == "" && gvk.Kind == "Namespace" && o.GetName() == ns.GetName() {
+			return nil, nil
+		}
+	}
+	_, err := client.Get(ctx, ns)
+	if err == nil {
+		return nil, nil
+	}
+	if err != remote.ErrNotFound {
+		return nil, errors.Wrapf(err, "get namespace %s", ns.GetName())
+	}
+	return ns, nil
+}
+
+var stampTime = time.Now // allow override in tests
+
+// pruneSleep waits for the grace period before garbage collection, overridden in tests.
+var pruneSleep = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// stampAnnotations returns the annotations that record the user running the apply, taken from the environment,
+// and the supplied time.
+func stampAnnotations(t time.Time) map[string]string {
+	user := os.Getenv("USER")
+	if user == "" {
+		user = os.Getenv("USERNAME")
+	}
+	if user == "" {
+		user = "unknown"
+	}
+	return map[string]string{
+		model.QbecNames.AppliedByAnnotation: user,
+		model.QbecNames.AppliedAtAnnotation: t.UTC().Format(time.RFC3339),
+	}
+}
+
+// applyTarget applies the objects of the environment to the cluster of the supplied target, and garbage collects
+// extra objects from that cluster. Only garbage collection is performed in prune-only mode.
+func applyTarget(ctx context.Context, envCtx cmd.EnvContext, target cmd.TargetClient, rendered *renderedEnv, fp model.Filters, nso namespaceOverride, config applyCommandConfig) error {
+	env := envCtx.Env()
+	client := target.Client
+	inContext := ""
+	if target.Context != "" {
+		inContext = " in context " + target.Context
+	}
+	if config.changedOnly {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		cache, err := loadApplyCache(wd, envCtx.App().Name(), env, envCtx.App().Tag(), target.Context)
 		if err != nil {
 			return err
 		}
+		client = &cachedSyncClient{KubeClient: client, cache: cache}
+		if !config.syncOptions.DryRun {
+			defer func() {
+				if err := cache.save(); err != nil {
+					sio.Warnf("unable to save apply cache, %v\n", err)
+				}
+			}()
+		}
+	}
+	plan, err := newApplyPlan(ctx, envCtx, client, rendered, fp, nso, config)
+	if err != nil {
+		return err
 	}
 
-	// continue with apply
-	objects = objsort.Sort(objects, sortConfig(client.IsNamespaced))
+	opts := config.syncOptions
+	up := newUpdatePolicy()
+	opts.DisableCreateFn = up.disableCreate
+	opts.DisableUpdateFn = up.disableUpdate
+
+	switch {
+	case opts.DryRun || config.pruneOnly:
+	case config.confirm:
+		if err := confirmApply(ctx, envCtx, target, plan, fp, config); err != nil {
+			return err
+		}
+	case plan.syncCount() > 0:
+		msg := fmt.Sprintf("will synchronize %d object(s)%s", plan.syncCount(), inContext)
+		if err := config.Confirm(msg); err != nil {
+			return err
+		}
+	}
+
+	groups, waveStarts := plan.groups(config)
 
 	dryRun := ""
 	if opts.DryRun {
@@ -149,14 +793,16 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 	var stats applyStats
 	var waitObjects []model.K8sMeta
 
-	printSyncStatus := func(name string, res *remote.SyncResult, err error) {
+	printSyncStatus := func(ob model.K8sLocalObject, name string, res *remote.SyncResult, err error) {
+		fields := sio.Fields{Component: ob.Component(), Object: name, Action: "sync"}
 		if err != nil {
-			sio.Errorf("%ssync %s failed\n", dryRun, name)
+			fields.Errorf("%ssync %s failed\n", dryRun, name)
 			return
 		}
 		if res.Type == remote.SyncObjectsIdentical {
 			if config.Verbosity() > 0 {
-				sio.Noticef("%sno changes to %s\n", dryRun, name)
+				fields.Action = "none"
+				fields.Noticef("%sno changes to %s\n", dryRun, name)
 				if res.Details != "" {
 					sio.Println(res.Details)
 				}
@@ -170,7 +816,12 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 		if res.Type == remote.SyncSkip {
 			verb = "skip"
 		}
-		sio.Noticef("%s%s %s\n", dryRun, verb, name)
+		fields.Action = verb
+		fields.Noticef("%s%s %s\n", dryRun, verb, name)
+		if res.AdoptedFrom != "" {
+			fields.Action = "adopt"
+			fields.Noticef("%sadopt %s, previously %s\n", dryRun, name, res.AdoptedFrom)
+		}
 		if config.showDetails || config.Verbosity() > 0 {
 			if res.Details != "" {
 				sio.Println(res.Details)
@@ -178,75 +829,175 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 		}
 	}
 
-	waitPolicy := newWaitPolicy()
-	for _, ob := range objects {
-		name := client.DisplayName(ob)
-		res, err := client.Sync(ctx, ob, opts)
-		if res != nil && res.GeneratedName != "" {
-			ob = nameWrap{name: res.GeneratedName, K8sLocalObject: ob}
-			name = client.DisplayName(ob)
-			retainObjects = append(retainObjects, ob)
-		}
-		printSyncStatus(name, res, err)
+	defaultNs := envCtx.App().DefaultNamespace(env)
+	waitForObjects := func() error {
+		if !config.wait && !config.waitAll {
+			return nil
+		}
+		wl := &waitListener{
+			displayNameFn: client.DisplayName,
+		}
+		return applyWaitFn(waitObjects,
+			func(obj model.K8sMeta) (watch.Interface, error) {
+				return waitWatcher(ctx, client.ResourceInterface, nsWrap{K8sMeta: obj, ns: defaultNs})
+			},
+			rollout.WaitOptions{
+				Listener: wl,
+				Timeout:  config.waitTimeout,
+			},
+		)
+	}
+
+	waited := false
+	// prune deletes the extra objects on the server, before or after the other objects are synced depending on the
+	// prune order.
+	prune := func() error {
+		deletions, clusterScoped, err := plan.prunable(fp, config)
 		if err != nil {
 			return err
 		}
-		shouldWait := config.waitAll || (res.Type == remote.SyncCreated || res.Type == remote.SyncUpdated)
-		if shouldWait {
-			if waitPolicy.disableWait(ob) {
-				sio.Debugf("%s: wait disabled by policy\n", name)
-			} else {
-				waitObjects = append(waitObjects, metaWrap{K8sMeta: ob})
+		for _, ob := range clusterScoped {
+			name := client.DisplayName(ob)
+			sio.Noticef("%sskip delete %s, cluster-scoped objects are only garbage collected with --gc-cluster-scoped\n", dryRun, name)
+			stats.Skipped = append(stats.Skipped, name)
+		}
+		for _, ob := range plan.protected {
+			stats.Skipped = append(stats.Skipped, client.DisplayName(ob))
+		}
+		if err := plan.checkPruneLimits(deletions, config); err != nil {
+			return err
+		}
+
+		if !opts.DryRun && !config.confirm && len(deletions) > 0 { // deletions were already confirmed with the diff otherwise
+			msg := fmt.Sprintf("will delete %d object(s)%s", len(deletions), inContext)
+			if err := config.Confirm(msg); err != nil {
+				return err
 			}
 		}
-		stats.update(name, res)
-	}
 
-	// process deletions
-	deletions, err := lister.deletions(retainObjects, fp.Match)
-	if err != nil {
-		return err
+		// wait for the new set of objects to be ready before the grace period, such that the grace period starts
+		// after the objects that replace the deleted ones are available.
+		if config.pruneGrace > 0 && len(deletions) > 0 {
+			if err := waitForObjects(); err != nil {
+				return err
+			}
+			waited = true
+			if !opts.DryRun {
+				sio.Noticef("waiting %v before deleting %d object(s)%s\n", config.pruneGrace, len(deletions), inContext)
+				if err := pruneSleep(ctx, config.pruneGrace); err != nil {
+					return err
+				}
+			}
+		}
+
+		deleteOpts := remote.DeleteOptions{DryRun: opts.DryRun, ServerDryRun: opts.ServerDryRun, DisableDeleteFn: plan.prunePolicy.disableDelete}
+
+		printDelStatus := func(ob model.K8sQbecMeta, name string, res *remote.SyncResult, err error) {
+			fields := sio.Fields{Component: ob.Component(), Object: name, Action: "delete"}
+			if err != nil {
+				fields.Errorf("%sdelete %s failed\n", dryRun, name)
+				return
+			}
+			verb := "delete"
+			if res.Type == remote.SyncSkip {
+				verb = "skip delete"
+			}
+			fields.Action = verb
+			fields.Noticef("%s%s %s\n", dryRun, verb, name)
+			if config.showDetails || config.Verbosity() > 0 {
+				if res.Details != "" {
+					sio.Println(res.Details)
+				}
+			}
+		}
+
+		for i := len(deletions) - 1; i >= 0; i-- {
+			ob := deletions[i]
+			name := client.DisplayName(ob)
+
+			res, err := client.Delete(ctx, ob, deleteOpts)
+			printDelStatus(ob, name, res, err)
+			if err != nil {
+				return err
+			}
+			stats.update(name, res)
+		}
+		return nil
 	}
 
-	if !opts.DryRun && len(deletions) > 0 {
-		msg := fmt.Sprintf("will delete %d object(s)", len(deletions))
-		if err := config.Confirm(msg); err != nil {
+	if config.pruneOrder == pruneOrderPre {
+		if err := prune(); err != nil {
 			return err
 		}
 	}
 
-	dp := newDeletePolicy(client.IsNamespaced, config.App().DefaultNamespace(env))
-	deleteOpts := remote.DeleteOptions{DryRun: opts.DryRun, DisableDeleteFn: dp.disableDelete}
-
-	deletions = objsort.SortMeta(deletions, sortConfig(client.IsNamespaced))
-
-	printDelStatus := func(name string, res *remote.SyncResult, err error) {
-		if err != nil {
-			sio.Errorf("%sdelete %s failed\n", dryRun, name)
-			return
+	// abort reports what was and was not applied before failing with the supplied error. Objects in groups
+	// starting at the supplied index are never attempted since they may depend on the ones that failed.
+	abort := func(next int, msg string, err error) error {
+		for _, rest := range groups[next:] {
+			for _, ob := range rest {
+				stats.NotAttempted = append(stats.NotAttempted, client.DisplayName(ob))
+			}
 		}
-		verb := "delete"
-		if res.Type == remote.SyncSkip {
-			verb = "skip delete"
+		printStats(config.Stdout(), &stats)
+		sio.Errorf("%s%s%s, %d object(s) not attempted\n", dryRun, msg, inContext, len(stats.NotAttempted))
+		return err
+	}
+
+	waitPolicy := newWaitPolicy()
+	prevWave := 0
+	for gi, group := range groups {
+		if wave, ok := waveStarts[gi]; ok {
+			if wave != plan.waves[0].wave {
+				// objects of earlier waves must be ready before the next wave is applied
+				if err := waitForObjects(); err != nil {
+					return abort(gi, fmt.Sprintf("objects of wave %d not ready", prevWave), err)
+				}
+				waitObjects = nil
+			}
+			prevWave = wave
+			sio.Noticef("%sapply wave %d%s\n", dryRun, wave, inContext)
 		}
-		sio.Noticef("%s%s %s\n", dryRun, verb, name)
-		if config.showDetails || config.Verbosity() > 0 {
-			if res.Details != "" {
-				sio.Println(res.Details)
+		outcomes := syncGroup(ctx, client, group, opts, config.parallel)
+		var firstErr error
+		for i, ob := range group {
+			res, err := outcomes[i].res, outcomes[i].err
+			name := client.DisplayName(ob)
+			if res != nil && res.GeneratedName != "" {
+				ob = nameWrap{name: res.GeneratedName, K8sLocalObject: ob}
+				name = client.DisplayName(ob)
+				plan.retainObjects = append(plan.retainObjects, ob)
 			}
+			printSyncStatus(ob, name, res, err)
+			if err != nil {
+				stats.Failed = append(stats.Failed, name)
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			shouldWait := config.waitAll || (res.Type == remote.SyncCreated || res.Type == remote.SyncUpdated)
+			if res.Type == remote.SyncSkip && up.disableCreate(ob) { // may not exist on the server
+				shouldWait = false
+			}
+			if shouldWait {
+				if waitPolicy.disableWait(ob) {
+					sio.Debugf("%s: wait disabled by policy\n", name)
+				} else {
+					waitObjects = append(waitObjects, metaWrap{K8sMeta: ob})
+				}
+			}
+			stats.update(name, res)
+		}
+		if firstErr != nil {
+			return abort(gi+1, fmt.Sprintf("%d object(s) failed to sync", len(stats.Failed)), firstErr)
 		}
 	}
 
-	for i := len(deletions) - 1; i >= 0; i-- {
-		ob := deletions[i]
-		name := client.DisplayName(ob)
-
-		res, err := client.Delete(ctx, ob, deleteOpts)
-		printDelStatus(name, res, err)
-		if err != nil {
+	if config.pruneOrder == pruneOrderPost {
+		if err := prune(); err != nil {
 			return err
 		}
-		stats.update(name, res)
 	}
 
 	printStats(config.Stdout(), &stats)
@@ -254,23 +1005,10 @@ func doApply(ctx context.Context, args []string, config applyCommandConfig) erro
 		sio.Noticeln("** dry-run mode, nothing was actually changed **")
 	}
 
-	defaultNs := envCtx.App().DefaultNamespace(env)
-	if config.wait || config.waitAll {
-		wl := &waitListener{
-			displayNameFn: client.DisplayName,
-		}
-		return applyWaitFn(waitObjects,
-			func(obj model.K8sMeta) (watch.Interface, error) {
-				return waitWatcher(ctx, client.ResourceInterface, nsWrap{K8sMeta: obj, ns: defaultNs})
-			},
-			rollout.WaitOptions{
-				Listener: wl,
-				Timeout:  config.waitTimeout,
-			},
-		)
+	if waited {
+		return nil
 	}
-
-	return nil
+	return waitForObjects()
 }
 
 func newApplyCommand(cp ctxProvider) *cobra.Command {
@@ -282,13 +1020,39 @@ func newApplyCommand(cp ctxProvider) *cobra.Command {
 
 	config := applyCommandConfig{
 		filterFunc: addFilterParams(c, true),
+		nsFunc:     addNamespaceOverrideParams(c),
 	}
 
 	c.Flags().BoolVar(&config.syncOptions.DisableCreate, "skip-create", false, "set to true to only update existing resources but not create new ones")
-	c.Flags().BoolVarP(&config.syncOptions.DryRun, "dry-run", "n", false, "dry-run, do not create/ update resources but show what would happen")
+	c.Flags().StringVarP(&config.dryRun, "dry-run", "n", "none", "dry-run, do not create/ update resources but show what would happen. "+
+		"One of none, client or server, where server sends requests to the server without persisting them")
+	c.Flags().Lookup("dry-run").NoOptDefVal = "client"
 	c.Flags().BoolVarP(&config.syncOptions.ShowSecrets, "show-secrets", "S", false, "do not obfuscate secret values in the output")
 	c.Flags().BoolVar(&config.showDetails, "show-details", false, "show details for object operations")
+	c.Flags().IntVar(&config.syncOptions.Retries, "retries", 0, "number of times to retry creates and updates that fail with transient server errors")
+	c.Flags().DurationVar(&config.syncOptions.RetryBackoff, "retry-backoff", time.Second, "initial wait between retries, doubled for every subsequent retry")
+	c.Flags().BoolVar(&config.syncOptions.ServerSide, "server-side", false, "use server-side apply instead of client-side patches")
+	c.Flags().StringVar(&config.syncOptions.FieldManager, "field-manager", remote.DefaultFieldManager, "field manager name recorded for fields set by creates, updates and server-side apply")
+	c.Flags().BoolVar(&config.syncOptions.ForceConflicts, "force-conflicts", false, "take ownership of fields managed by others for server-side apply")
+	c.Flags().BoolVar(&config.syncOptions.Adopt, "adopt", false, "take ownership of existing objects that are not managed by this app and environment")
+	c.Flags().BoolVar(&config.syncOptions.CheckOwnership, "check-ownership", false, "fail to update existing objects managed by other apps, environments or tags unless --adopt is specified")
+	c.Flags().BoolVar(&config.timings, "timings", false, "print the evaluation time of every component to stderr")
+	c.Flags().BoolVar(&config.confirm, "confirm", false, "show a diff of the changes and prompt for confirmation before applying them")
+	c.Flags().StringVar(&config.output, "output", applyOutputText, "output of a dry-run, one of text or diff, which prints the changes and deletions as a unified diff")
 	c.Flags().BoolVar(&config.gc, "gc", true, "garbage collect extra objects on the server")
+	var noGC bool
+	c.Flags().BoolVar(&noGC, "no-gc", false, "do not garbage collect extra objects on the server, same as --gc=false")
+	c.Flags().BoolVar(&config.gcClusterScoped, "gc-cluster-scoped", false, "also garbage collect extra cluster-scoped objects like cluster roles and CRDs")
+	c.Flags().DurationVar(&config.pruneGrace, "prune-grace-period", 0, "time to wait after creating and updating objects, and waiting for them to be ready, before garbage collecting extra objects")
+	c.Flags().StringArrayVar(&config.pruneThresholds, "prune-threshold", nil, "abort before garbage collection deletes more than this number of objects, or percentage of tracked objects when suffixed with %")
+	c.Flags().BoolVar(&config.yesPrune, "yes-prune", false, "delete extra objects even when a prune threshold is exceeded")
+	c.Flags().StringVar(&config.pruneOrder, "prune-order", pruneOrderPost, "when to garbage collect extra objects, one of pre, before creating and updating objects, or post")
+	c.Flags().BoolVar(&config.pruneOnly, "prune-only", false, "only garbage collect extra objects on the server, do not create or update any objects")
+	c.Flags().BoolVar(&config.stamp, "stamp", false, "annotate created and updated objects with the user who applied them and the time of the apply")
+	c.Flags().BoolVar(&config.changedOnly, "changed-only", false, "do not sync objects whose rendered form is unchanged since they were last applied from this machine")
+	c.Flags().BoolVar(&config.createNamespace, "create-namespace", false, "create the default namespace of the environment before other objects if it does not exist")
+	c.Flags().IntVar(&config.parallel, "parallel", 5, "number of objects of the same apply order to sync concurrently")
+	c.Flags().StringArrayVar(&config.pruneWhitelist, "prune-whitelist", nil, "restrict garbage collection to objects of this kind, specified as [<group>/]<version>/<kind>")
 	c.Flags().BoolVar(&config.wait, "wait", false, "wait for changed objects to be ready")
 	c.Flags().BoolVar(&config.waitAll, "wait-all", true, "wait for all objects to be ready, not just the ones that have changed")
 	var waitTime string
@@ -301,7 +1065,17 @@ func newApplyCommand(cp ctxProvider) *cobra.Command {
 		if err != nil {
 			return cmd.NewUsageError(fmt.Sprintf("invalid wait timeout: %s, %v", waitTime, err))
 		}
-		if config.syncOptions.DryRun {
+		config.syncOptions.DryRun, config.syncOptions.ServerDryRun, err = parseDryRun(config.dryRun)
+		if err != nil {
+			return cmd.WrapError(err)
+		}
+		if noGC {
+			if c.Flags().Changed("gc") {
+				return cmd.NewUsageError("--no-gc cannot be used together with --gc")
+			}
+			config.gc = false
+		}
+		if config.syncOptions.DryRun || config.pruneOnly {
 			config.wait = false
 			config.waitAll = false
 		}
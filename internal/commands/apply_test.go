@@ -20,7 +20,10 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/model"
@@ -65,13 +68,10 @@ func TestApplyBasic(t *testing.T) {
 		return nil
 	}
 	defer func() { applyWaitFn = origWait }()
-	first := true
+	var once sync.Once
 	var captured remote.SyncOptions
 	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
-		if first {
-			first = false
-			captured = opts
-		}
+		once.Do(func() { captured = opts })
 		switch {
 		case obj.GetName() == "svc2-cm":
 			return &remote.SyncResult{Type: remote.SyncUpdated, Details: "data updated"}, nil
@@ -104,16 +104,97 @@ func TestApplyBasic(t *testing.T) {
 	s.assertErrorLineMatch(regexp.MustCompile(`update ConfigMap:bar-system:svc2-cm`))
 }
 
+func TestApplyNoPrune(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical, Details: "sync skipped"}, nil
+	}
+	s.client.listFunc = func(ctx context.Context, _ remote.ListQueryConfig) (remote.Collection, error) {
+		c := &coll{}
+		c.add(
+			&basicObject{
+				objectKey: objectKey{
+					gvk:       schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					namespace: "bar-system",
+					name:      "svc2-previous-deploy",
+				},
+				component: "service2",
+				app:       "app",
+				env:       "dev",
+				anns:      map[string]string{"directives.qbec.io/no-prune": "true"},
+			},
+			&basicObject{
+				objectKey: objectKey{
+					gvk:       schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					namespace: "bar-system",
+					name:      "svc2-old-deploy",
+				},
+				component: "service2",
+				app:       "app",
+				env:       "dev",
+			},
+		)
+		return c, nil
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		if opts.DisableDeleteFn(obj) {
+			return &remote.SyncResult{Type: remote.SyncSkip, Details: "deletion disabled due to user request"}, nil
+		}
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--wait-all=false")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-old-deploy"}, stats["deleted"])
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["skipped"])
+	s.assertErrorLineMatch(regexp.MustCompile(`skip delete Deployment:bar-system:svc2-previous-deploy`))
+}
+
+func TestApplyNoPruneThreshold(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical, Details: "sync skipped"}, nil
+	}
+	s.client.listFunc = func(ctx context.Context, _ remote.ListQueryConfig) (remote.Collection, error) {
+		c := &coll{}
+		c.add(
+			&basicObject{
+				objectKey: objectKey{
+					gvk:       schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+					namespace: "bar-system",
+					name:      "svc2-previous-deploy",
+				},
+				component: "service2",
+				app:       "app",
+				env:       "dev",
+				anns:      map[string]string{"directives.qbec.io/no-prune": "true"},
+			},
+		)
+		return c, nil
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("no deletes expected")
+	}
+	err := s.executeCommand("apply", "dev", "--wait-all=false", "--prune-threshold=0")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.Nil(stats["deleted"])
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["skipped"])
+	s.assertErrorLineMatch(regexp.MustCompile(`skip delete Deployment:bar-system:svc2-previous-deploy, garbage collection is disabled`))
+	a.NotContains(s.stderr(), "will delete")
+}
+
 func TestApplyFlags(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
-	first := true
+	var once sync.Once
 	var captured remote.SyncOptions
 	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
-		if first {
-			first = false
-			captured = opts
-		}
+		once.Do(func() { captured = opts })
 		switch {
 		case obj.GetName() == "svc2-cm":
 			return &remote.SyncResult{Type: remote.SyncUpdated, Details: "data updated"}, nil
@@ -123,10 +204,14 @@ func TestApplyFlags(t *testing.T) {
 			return &remote.SyncResult{Type: remote.SyncObjectsIdentical, Details: "sync skipped"}, nil
 		}
 	}
-	err := s.executeCommand("apply", "dev", "-S", "-n", "--skip-create", "--gc=false", "--wait-all=false")
+	err := s.executeCommand("apply", "dev", "-S", "-n", "--skip-create", "--gc=false", "--wait-all=false", "--retries=3", "--retry-backoff=2s")
 	require.NoError(t, err)
 	stats := s.outputStats()
 	a := assert.New(t)
+	a.Equal(3, captured.Retries)
+	a.Equal(2*time.Second, captured.RetryBackoff)
+	a.False(captured.ServerSide)
+	a.Equal(remote.DefaultFieldManager, captured.FieldManager)
 	a.True(captured.ShowSecrets)
 	a.True(captured.DryRun)
 	a.True(captured.DisableCreate)
@@ -137,6 +222,205 @@ func TestApplyFlags(t *testing.T) {
 	s.assertErrorLineMatch(regexp.MustCompile(`\*\* dry-run mode, nothing was actually changed \*\*`))
 }
 
+func TestApplyServerSideFlags(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	var once sync.Once
+	var captured remote.SyncOptions
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		once.Do(func() { captured = opts })
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--gc=false", "--wait-all=false", "--server-side", "--field-manager=ci", "--force-conflicts")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.True(captured.ServerSide)
+	a.Equal("ci", captured.FieldManager)
+	a.True(captured.ForceConflicts)
+}
+
+func TestApplyFieldManager(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{name: "default", expected: remote.DefaultFieldManager},
+		{name: "client-side", args: []string{"--field-manager=ci"}, expected: "ci"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var once sync.Once
+			var captured remote.SyncOptions
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				once.Do(func() { captured = opts })
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--gc=false", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.False(captured.ServerSide)
+			a.Equal(test.expected, captured.FieldManager)
+		})
+	}
+}
+
+func TestApplyAdopt(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	var l sync.Mutex
+	var adopt bool
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		l.Lock()
+		defer l.Unlock()
+		adopt = opts.Adopt
+		if obj.GetName() == "svc2-cm" {
+			return &remote.SyncResult{Type: remote.SyncUpdated, Details: "labels updated", AdoptedFrom: "not managed by qbec"}, nil
+		}
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical, Details: "sync skipped"}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--adopt", "--gc=false", "--wait-all=false")
+	require.NoError(t, err)
+	assert.True(t, adopt)
+	stats := s.outputStats()
+	assert.EqualValues(t, []interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["updated"])
+	assert.EqualValues(t, []interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["adopted"])
+	s.assertErrorLineMatch(regexp.MustCompile(`adopt ConfigMap:bar-system:svc2-cm, previously not managed by qbec`))
+}
+
+func TestApplyCheckOwnership(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected bool
+	}{
+		{name: "default"},
+		{name: "enabled", args: []string{"--check-ownership"}, expected: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var l sync.Mutex
+			var captured remote.SyncOptions
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				captured = opts
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--gc=false", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.Equal(test.expected, captured.CheckOwnership)
+			a.False(captured.Adopt)
+		})
+	}
+}
+
+func TestApplyNamespaceOverride(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		namespaces []string
+		created    []interface{}
+	}{
+		{
+			name:       "default namespace objects",
+			args:       []string{"--namespace", "ad-hoc"},
+			namespaces: []string{"ad-hoc", "bar-system"},
+			created: []interface{}{
+				"Secret:bar-system:svc2-secret",
+				"ConfigMap:bar-system:svc2-cm",
+				"Deployment:bar-system:svc2-deploy",
+				"Job:ad-hoc:tj-<xxxxx>",
+			},
+		},
+		{
+			name:       "forced",
+			args:       []string{"--namespace", "ad-hoc", "--force-namespace"},
+			namespaces: []string{"ad-hoc"},
+			created: []interface{}{
+				"Secret:ad-hoc:svc2-secret",
+				"ConfigMap:ad-hoc:svc2-cm",
+				"Deployment:ad-hoc:svc2-deploy",
+				"Job:ad-hoc:tj-<xxxxx>",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.nsFunc = func(kind schema.GroupVersionKind) (bool, error) {
+				return kind.Group != "rbac.authorization.k8s.io" && kind.Kind != "PodSecurityPolicy" && kind.Kind != "Namespace", nil
+			}
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				if obj.GetNamespace() == "" {
+					return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+				}
+				return &remote.SyncResult{Type: remote.SyncCreated}, nil
+			}
+			var scope remote.ListQueryConfig
+			s.client.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+				scope = q
+				return &coll{}, nil
+			}
+			args := append([]string{"apply", "dev", "-n", "--wait-all=false"}, test.args...)
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			stats := s.outputStats()
+			a.ElementsMatch(test.created, stats["created"])
+			a.ElementsMatch(test.namespaces, scope.Namespaces)
+			a.True(scope.ClusterObjects)
+		})
+	}
+}
+
+func TestApplyJSONLog(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		if obj.GetName() == "svc2-cm" {
+			return &remote.SyncResult{Type: remote.SyncUpdated}, nil
+		}
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	err := s.executeCommand("apply", "dev", "-n", "--gc=false", "--wait-all=false", "--log-format=json")
+	require.NoError(t, err)
+	s.assertErrorLineMatch(regexp.MustCompile(`^{"level":"notice","component":"service2","object":"ConfigMap:bar-system:svc2-cm","action":"update","message":"\[dry-run\] update ConfigMap:bar-system:svc2-cm"}$`))
+	s.assertErrorLineMatch(regexp.MustCompile(`^{"level":"notice","message":"\*\* dry-run mode, nothing was actually changed \*\*"}$`))
+}
+
+func TestApplyParallel(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	var l sync.Mutex
+	nsDone := false
+	var outOfOrder []string
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		l.Lock()
+		defer l.Unlock()
+		switch {
+		case obj.GetKind() == "Namespace":
+			nsDone = true
+		case obj.GetNamespace() != "" && !nsDone:
+			outOfOrder = append(outOfOrder, obj.GetName())
+		}
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--parallel=10", "--gc=false", "--wait-all=false")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.EqualValues(11, stats["same"])
+	a.Nil(outOfOrder)
+	a.True(nsDone)
+}
+
 func TestApplyNamespaceClusterFilters(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -199,6 +483,70 @@ func TestApplyNamespaceClusterFilters(t *testing.T) {
 	}
 }
 
+func TestApplyOnlyNamespace(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-ns")
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncCreated}, nil
+	}
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		return nil, nil
+	}
+	var scope remote.ListQueryConfig
+	s.client.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		scope = q
+		c := &coll{}
+		for _, ns := range []string{"first", "second"} {
+			c.add(&basicObject{
+				objectKey: objectKey{
+					gvk:       schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+					namespace: ns,
+					name:      ns + "-old-cm",
+				},
+				component: ns,
+				app:       "multi-ns",
+				env:       "local",
+			})
+		}
+		return c, nil
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("apply", "local", "-n", "--only-namespace", "first")
+	require.NoError(t, err)
+	a := assert.New(t)
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"ConfigMap:first:first-cm"}, stats["created"])
+	a.EqualValues([]interface{}{"ConfigMap:first:first-old-cm"}, stats["deleted"])
+	a.EqualValues([]string{"first"}, scope.Namespaces)
+	a.False(scope.ClusterObjects)
+	a.False(scope.ClusterScopedLists)
+}
+
+func TestApplyOnlyNamespaceNegative(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{name: "include", args: []string{"-p", "second"}, message: "--only-namespace cannot be used together with namespace include or exclude filters"},
+		{name: "exclude", args: []string{"-P", "second"}, message: "--only-namespace cannot be used together with namespace include or exclude filters"},
+		{name: "cluster", args: []string{"--include-cluster-objects"}, message: "--only-namespace cannot be used together with --include-cluster-objects"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newCustomScaffold(t, "testdata/projects/multi-ns")
+			defer s.reset()
+			err := s.executeCommand(append([]string{"apply", "local", "-n", "--only-namespace", "first"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.message, err.Error())
+		})
+	}
+}
+
 func TestApplyNamespaceFilterMetadataError(t *testing.T) {
 	s := newCustomScaffold(t, "testdata/projects/multi-ns")
 	defer s.reset()
@@ -210,6 +558,47 @@ func TestApplyNamespaceFilterMetadataError(t *testing.T) {
 	assert.Equal(t, "namespace filter: no metadata found", err.Error())
 }
 
+func TestApplyPruneWhitelist(t *testing.T) {
+	tests := []struct {
+		name     string
+		kinds    []string
+		expected interface{}
+	}{
+		{name: "other kind", kinds: []string{"v1/ConfigMap"}, expected: nil},
+		{
+			name:     "matching kind",
+			kinds:    []string{"v1/ConfigMap", "apps/v1/Deployment"},
+			expected: []interface{}{"Deployment:bar-system:svc2-previous-deploy"},
+		},
+		{
+			name:     "matching kind different version",
+			kinds:    []string{"apps/v1beta1/Deployment"},
+			expected: []interface{}{"Deployment:bar-system:svc2-previous-deploy"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = stdLister
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			args := []string{"apply", "dev", "--wait-all=false"}
+			for _, k := range test.kinds {
+				args = append(args, "--prune-whitelist", k)
+			}
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			stats := s.outputStats()
+			assert.EqualValues(t, test.expected, stats["deleted"])
+		})
+	}
+}
+
 func TestApplyNegative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -261,6 +650,24 @@ func TestApplyNegative(t *testing.T) {
 				a.Equal("cannot apply baseline environment, use a real environment", err.Error())
 			},
 		},
+		{
+			name: "force conflicts without server side",
+			args: []string{"apply", "dev", "--force-conflicts"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--force-conflicts can only be used with --server-side", err.Error())
+			},
+		},
+		{
+			name: "force namespace without namespace",
+			args: []string{"apply", "dev", "--force-namespace"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--force-namespace can only be used with --namespace", err.Error())
+			},
+		},
 		{
 			name: "c and C",
 			args: []string{"apply", "dev", "-c", "cluster-objects", "-C", "service2"},
@@ -288,11 +695,35 @@ func TestApplyNegative(t *testing.T) {
 				a.Equal(`cannot include as well as exclude namespaces, specify one or the other`, err.Error())
 			},
 		},
+		{
+			name: "bad prune whitelist",
+			args: []string{"apply", "dev", "--prune-whitelist", "Deployment"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid kind "Deployment", must be of the form [<group>/]<version>/<kind>`, err.Error())
+			},
+		},
+		{
+			name: "unknown prune whitelist",
+			args: []string{"apply", "dev", "--prune-whitelist", "foo.io/v1/Bar"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`prune whitelist: unknown kind "foo.io/v1/Bar", server does not recognize foo.io/v1, Kind=Bar`, err.Error())
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			s := newScaffold(t)
 			defer s.reset()
+			s.client.nsFunc = func(kind schema.GroupVersionKind) (bool, error) {
+				if kind.Group == "foo.io" {
+					return false, fmt.Errorf("server does not recognize %v", kind)
+				}
+				return true, nil
+			}
 			err := s.executeCommand(test.args...)
 			require.NotNil(t, err)
 			test.asserter(s, err)
@@ -300,3 +731,998 @@ func TestApplyNegative(t *testing.T) {
 	}
 
 }
+
+func TestApplyMultiContext(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	synced := map[string][]string{}
+	deleted := map[string][]string{}
+	var l sync.Mutex
+	for _, name := range []string{"primary", "dr"} {
+		name := name
+		c := s.contextClient(name)
+		c.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+			l.Lock()
+			defer l.Unlock()
+			synced[name] = append(synced[name], obj.GetName())
+			return &remote.SyncResult{Type: remote.SyncCreated}, nil
+		}
+		c.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+			c := &coll{}
+			if name == "dr" {
+				c.add(&basicObject{
+					objectKey: objectKey{
+						gvk:       schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+						namespace: "apps",
+						name:      "dr-only-cm",
+					},
+					component: "cm",
+					app:       "multi-context",
+					env:       "prod",
+				})
+			}
+			return c, nil
+		}
+		c.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+			l.Lock()
+			defer l.Unlock()
+			deleted[name] = append(deleted[name], obj.GetName())
+			return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+		}
+	}
+	err := s.executeCommand("apply", "prod")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.EqualValues(map[string][]string{"primary": {"app-cm"}, "dr": {"app-cm"}}, synced)
+	a.EqualValues(map[string][]string{"dr": {"dr-only-cm"}}, deleted)
+	s.assertErrorLineMatch(regexp.MustCompile(`apply context primary`))
+	s.assertErrorLineMatch(regexp.MustCompile(`apply context dr`))
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"ConfigMap:apps:dr-only-cm"}, stats["deleted"])
+}
+
+func TestApplyMultiContextEvalOnce(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	for _, name := range []string{"primary", "dr"} {
+		c := s.contextClient(name)
+		c.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+			return &remote.SyncResult{Type: remote.SyncCreated}, nil
+		}
+		c.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+			return &coll{}, nil
+		}
+	}
+	err := s.executeCommand("apply", "prod", "--timings")
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(s.stderr(), "COMPONENT  TIME"))
+}
+
+func TestApplyMultiContextForced(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncCreated}, nil
+	}
+	err := s.executeCommand("apply", "prod", "--gc=false", "--force:k8s-context=other")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Empty(s.contextClients)
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"ConfigMap::app-cm"}, stats["created"])
+}
+
+func TestApplyPartialFailure(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		switch obj.GetName() {
+		case "svc2-cm":
+			return nil, fmt.Errorf("config map rejected")
+		case "svc2-secret":
+			return &remote.SyncResult{Type: remote.SyncCreated}, nil
+		default:
+			return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+		}
+	}
+	s.client.listFunc = stdLister
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("unexpected delete of %s", s.client.DisplayName(obj))
+	}
+	err := s.executeCommand("apply", "dev", "--wait-all=false")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("config map rejected", err.Error())
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["failed"])
+	a.EqualValues([]interface{}{"Secret:bar-system:svc2-secret"}, stats["created"])
+	a.EqualValues([]interface{}{
+		"ClusterRole::allow-root-psp-policy",
+		"ClusterRoleBinding::allow-root-psp-policy",
+		"ClusterRoleBinding::default-psp-policy",
+		"Deployment:bar-system:svc2-deploy",
+		"Job::tj-<xxxxx>",
+	}, stats["notAttempted"])
+	a.Nil(stats["deleted"])
+	s.assertErrorLineMatch(regexp.MustCompile(`1 object\(s\) failed to sync, 5 object\(s\) not attempted`))
+}
+
+func TestApplyMultiContextError(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	s.contextClient("primary").syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncCreated}, nil
+	}
+	s.contextClient("dr").syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("cluster unavailable")
+	}
+	err := s.executeCommand("apply", "prod", "--gc=false")
+	require.Error(t, err)
+	assert.Equal(t, "context dr: cluster unavailable", err.Error())
+}
+
+func TestApplyGCClusterScoped(t *testing.T) {
+	lister := func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		c, _ := stdLister(ctx, q)
+		c.(*coll).add(&basicObject{
+			objectKey: objectKey{
+				gvk:  schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+				name: "old-psp",
+			},
+			component: "cluster-objects",
+			app:       "app",
+			env:       "dev",
+		})
+		return c, nil
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		deleted []interface{}
+		skipped []interface{}
+	}{
+		{
+			name:    "default",
+			deleted: []interface{}{"Deployment:bar-system:svc2-previous-deploy"},
+			skipped: []interface{}{"PodSecurityPolicy::old-psp"},
+		},
+		{
+			name:    "enabled",
+			args:    []string{"--gc-cluster-scoped"},
+			deleted: []interface{}{"Deployment:bar-system:svc2-previous-deploy", "PodSecurityPolicy::old-psp"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = lister
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			stats := s.outputStats()
+			a := assert.New(t)
+			a.ElementsMatch(test.deleted, stats["deleted"])
+			if test.skipped == nil {
+				a.Nil(stats["skipped"])
+				return
+			}
+			a.EqualValues(test.skipped, stats["skipped"])
+			s.assertErrorLineMatch(regexp.MustCompile(`skip delete PodSecurityPolicy::old-psp, cluster-scoped objects are only garbage collected with --gc-cluster-scoped`))
+		})
+	}
+}
+
+func TestApplyPruneOnly(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		dryRun bool
+	}{
+		{name: "basic"},
+		{name: "dry-run", args: []string{"-n"}, dryRun: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return nil, fmt.Errorf("unexpected sync of %s", s.client.DisplayName(obj))
+			}
+			s.client.listFunc = stdLister
+			var deleteOpts remote.DeleteOptions
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				deleteOpts = opts
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--prune-only"}, test.args...)...)
+			require.NoError(t, err)
+			stats := s.outputStats()
+			a := assert.New(t)
+			a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deleted"])
+			a.Nil(stats["created"])
+			a.Nil(stats["updated"])
+			a.Equal(test.dryRun, deleteOpts.DryRun)
+		})
+	}
+}
+
+func TestApplyDryRunModes(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		dryRun bool
+		server bool
+	}{
+		{name: "none"},
+		{name: "short", args: []string{"-n"}, dryRun: true},
+		{name: "client", args: []string{"--dry-run=client"}, dryRun: true},
+		{name: "server", args: []string{"--dry-run=server"}, dryRun: true, server: true},
+		{name: "bool-true", args: []string{"--dry-run=true"}, dryRun: true},
+		{name: "bool-false", args: []string{"--dry-run=false"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var l sync.Mutex
+			var syncOpts remote.SyncOptions
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				syncOpts = opts
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = stdLister
+			var deleteOpts remote.DeleteOptions
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				deleteOpts = opts
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.Equal(test.dryRun, syncOpts.DryRun)
+			a.Equal(test.server, syncOpts.ServerDryRun)
+			a.Equal(test.dryRun, deleteOpts.DryRun)
+			a.Equal(test.server, deleteOpts.ServerDryRun)
+		})
+	}
+}
+
+func TestApplyDryRunBadMode(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--dry-run=remote")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal(`invalid dry-run value "remote", must be one of none, client or server`, err.Error())
+}
+
+func TestApplyPruneOnlyNoGC(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--prune-only", "--gc=false")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("--prune-only cannot be used with --gc=false", err.Error())
+}
+
+func TestApplyNoGC(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	s.client.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		return nil, fmt.Errorf("unexpected list of objects")
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("unexpected delete of %s", s.client.DisplayName(obj))
+	}
+	err := s.executeCommand("apply", "dev", "--no-gc", "--wait-all=false")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	assert.Nil(t, stats["deleted"])
+}
+
+func TestApplyNoGCNegative(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--no-gc", "--gc")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("--no-gc cannot be used together with --gc", err.Error())
+}
+
+func TestApplyGCTag(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	var l sync.Mutex
+	gcTags := map[string]string{}
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		l.Lock()
+		defer l.Unlock()
+		gcTags[obj.GetName()] = obj.ToUnstructured().GetLabels()[model.QbecNames.GCTagLabel]
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	var query remote.ListQueryConfig
+	s.client.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		query = q
+		return stdLister(ctx, q)
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--gc-tag=shared", "--wait-all=false")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal("shared", query.GCTag)
+	a.Equal("shared", gcTags["svc2-cm"])
+}
+
+func TestApplyPruneThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		aborted bool
+	}{
+		{name: "count-exceeded", args: []string{"--prune-threshold=0"}, aborted: true},
+		{name: "count-ok", args: []string{"--prune-threshold=1"}},
+		{name: "percent-exceeded", args: []string{"--prune-threshold=5%"}, aborted: true},
+		{name: "percent-ok", args: []string{"--prune-threshold=50%"}},
+		{name: "any-exceeded", args: []string{"--prune-threshold=1", "--prune-threshold=5%"}, aborted: true},
+		{name: "override", args: []string{"--prune-threshold=0", "--yes-prune"}},
+		{name: "dry-run", args: []string{"--prune-threshold=0", "-n"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = stdLister
+			var deleted []string
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				deleted = append(deleted, s.client.DisplayName(obj))
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--wait-all=false"}, test.args...)...)
+			a := assert.New(t)
+			if test.aborted {
+				require.Error(t, err)
+				a.Regexp(`^garbage collection would delete 1 of \d+ tracked object\(s\), more than the prune threshold of \S+, use --yes-prune to allow this$`, err.Error())
+				a.Nil(deleted)
+				return
+			}
+			require.NoError(t, err)
+			a.EqualValues([]string{"Deployment:bar-system:svc2-previous-deploy"}, deleted)
+		})
+	}
+}
+
+func TestApplyPruneThresholdNegative(t *testing.T) {
+	tests := []struct {
+		threshold string
+		msg       string
+	}{
+		{threshold: "abc", msg: `invalid prune threshold "abc", must be a non-negative number or a percentage`},
+		{threshold: "-1", msg: `invalid prune threshold "-1", must be a non-negative number or a percentage`},
+		{threshold: "120%", msg: `invalid prune threshold "120%", must be a percentage between 0 and 100`},
+		{threshold: "x%", msg: `invalid prune threshold "x%", must be a percentage between 0 and 100`},
+	}
+	for _, test := range tests {
+		t.Run(test.threshold, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand("apply", "dev", "--prune-threshold="+test.threshold)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
+
+func TestApplyPruneGracePeriod(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "basic", expected: []string{"wait", "sleep 30s", "delete"}},
+		{name: "dry-run", args: []string{"-n"}, expected: []string{"delete"}},
+		{name: "no-wait", args: []string{"--wait-all=false"}, expected: []string{"sleep 30s", "delete"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var events []string
+			origWait := applyWaitFn
+			applyWaitFn = func(objects []model.K8sMeta, wp rollout.WatchProvider, opts rollout.WaitOptions) (finalErr error) {
+				events = append(events, "wait")
+				return nil
+			}
+			defer func() { applyWaitFn = origWait }()
+			origSleep := pruneSleep
+			pruneSleep = func(ctx context.Context, d time.Duration) error {
+				events = append(events, "sleep "+d.String())
+				return nil
+			}
+			defer func() { pruneSleep = origSleep }()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = stdLister
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				events = append(events, "delete")
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--prune-grace-period=30s"}, test.args...)...)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, events)
+		})
+	}
+}
+
+func TestApplyWaves(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "wait",
+			expected: []string{"sync config", "wait config", "sync db", "wait db", "sync app", "wait app"},
+		},
+		{
+			name:     "no-wait",
+			args:     []string{"--wait-all=false"},
+			expected: []string{"sync config", "sync db", "sync app"},
+		},
+		{
+			name:     "dry-run",
+			args:     []string{"-n"},
+			expected: []string{"sync config", "sync db", "sync app"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newCustomScaffold(t, "testdata/projects/waves")
+			defer s.reset()
+			var l sync.Mutex
+			var events []string
+			origWait := applyWaitFn
+			applyWaitFn = func(objects []model.K8sMeta, wp rollout.WatchProvider, opts rollout.WaitOptions) (finalErr error) {
+				l.Lock()
+				defer l.Unlock()
+				for _, o := range objects {
+					events = append(events, "wait "+o.GetName())
+				}
+				return nil
+			}
+			defer func() { applyWaitFn = origWait }()
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				events = append(events, "sync "+obj.GetName())
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--gc=false"}, test.args...)...)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, events)
+			s.assertErrorLineMatch(regexp.MustCompile(`apply wave 1`))
+			s.assertErrorLineMatch(regexp.MustCompile(`apply wave 2`))
+		})
+	}
+}
+
+func TestApplyWavesFailure(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/waves")
+	defer s.reset()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		if obj.GetName() == "db" {
+			return nil, fmt.Errorf("db failed")
+		}
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--gc=false", "--wait-all=false")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("db failed", err.Error())
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"Deployment::db"}, stats["failed"])
+	a.EqualValues([]interface{}{"Deployment::app"}, stats["notAttempted"])
+}
+
+func TestApplyWavesWaitFailure(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/waves")
+	defer s.reset()
+	origWait := applyWaitFn
+	applyWaitFn = func(objects []model.K8sMeta, wp rollout.WatchProvider, opts rollout.WaitOptions) (finalErr error) {
+		for _, o := range objects {
+			if o.GetName() == "db" {
+				return fmt.Errorf("db not ready")
+			}
+		}
+		return nil
+	}
+	defer func() { applyWaitFn = origWait }()
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+	}
+	err := s.executeCommand("apply", "dev", "--gc=false")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("db not ready", err.Error())
+	stats := s.outputStats()
+	a.Nil(stats["failed"])
+	a.EqualValues([]interface{}{"Deployment::app"}, stats["notAttempted"])
+	s.assertErrorLineMatch(regexp.MustCompile(`objects of wave 1 not ready, 1 object\(s\) not attempted`))
+}
+
+func TestApplyWavesNegative(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/bad-waves")
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--gc=false")
+	require.Error(t, err)
+	assert.Equal(t, "ConfigMap::config: invalid apply wave directive 'first', must be an integer", err.Error())
+}
+
+func TestApplyPruneGracePeriodNegative(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--prune-grace-period=-1s")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("invalid prune grace period: -1s, must not be negative", err.Error())
+}
+
+func TestApplyPruneOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{name: "default", expected: []string{"sync", "delete"}},
+		{name: "post", args: []string{"--prune-order=post"}, expected: []string{"sync", "delete"}},
+		{name: "pre", args: []string{"--prune-order=pre"}, expected: []string{"delete", "sync"}},
+		{name: "pre dry-run", args: []string{"--prune-order=pre", "-n"}, expected: []string{"delete", "sync"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var l sync.Mutex
+			var events []string
+			record := func(e string) {
+				l.Lock()
+				defer l.Unlock()
+				if len(events) == 0 || events[len(events)-1] != e {
+					events = append(events, e)
+				}
+			}
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				record("sync")
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = stdLister
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				record("delete")
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, events)
+			stats := s.outputStats()
+			assert.EqualValues(t, []interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deleted"])
+		})
+	}
+}
+
+func TestApplyPruneOrderNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "bad", args: []string{"--prune-order=before"}, msg: `invalid prune order "before", must be one of pre or post`},
+		{name: "grace", args: []string{"--prune-order=pre", "--prune-grace-period=10s"}, msg: "--prune-grace-period cannot be used with --prune-order=pre"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"apply", "dev"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
+
+func TestApplyStamp(t *testing.T) {
+	oldStampTime := stampTime
+	defer func() { stampTime = oldStampTime }()
+	stampTime = func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("X", 3600)) }
+	t.Setenv("USER", "jdoe")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected map[string]string
+	}{
+		{name: "default"},
+		{
+			name: "stamp",
+			args: []string{"--stamp"},
+			expected: map[string]string{
+				"qbec.io/last-applied-by":   "jdoe",
+				"qbec.io/last-applied-time": "2021-03-04T04:06:07Z",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var l sync.Mutex
+			var stamps []map[string]string
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				stamps = append(stamps, opts.Stamp)
+				_, ok := obj.GetAnnotations()[model.QbecNames.AppliedByAnnotation]
+				assert.False(t, ok)
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "--gc=false", "--wait-all=false"}, test.args...)...)
+			require.NoError(t, err)
+			require.NotEmpty(t, stamps)
+			for _, st := range stamps {
+				assert.Equal(t, test.expected, st)
+			}
+		})
+	}
+}
+
+func TestApplyCreateNamespace(t *testing.T) {
+	lister := func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		c, _ := stdLister(ctx, q)
+		c.(*coll).add(&basicObject{
+			objectKey: objectKey{
+				gvk:  schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+				name: "new-ns",
+			},
+			component: namespaceComponent,
+			app:       "app",
+			env:       "dev",
+		})
+		return c, nil
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		exists  bool
+		created bool
+	}{
+		{name: "absent", args: []string{"--namespace", "new-ns"}, created: true},
+		{name: "exists", args: []string{"--namespace", "new-ns"}, exists: true},
+		{name: "local", args: []string{"--namespace", "bar-system"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			var l sync.Mutex
+			var gets []string
+			s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+				l.Lock()
+				defer l.Unlock()
+				gets = append(gets, s.client.DisplayName(obj))
+				if test.exists {
+					return &unstructured.Unstructured{}, nil
+				}
+				return nil, remote.ErrNotFound
+			}
+			var synced []model.K8sLocalObject
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				synced = append(synced, obj)
+				if obj.GetKind() == "Namespace" && obj.GetName() == "new-ns" {
+					return &remote.SyncResult{Type: remote.SyncCreated}, nil
+				}
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.listFunc = lister
+			var deleted []string
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				deleted = append(deleted, s.client.DisplayName(obj))
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			args := append([]string{"apply", "dev", "--wait-all=false", "--create-namespace", "--gc-cluster-scoped"}, test.args...)
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			if test.name == "local" {
+				a.Empty(gets)
+				return
+			}
+			a.NotContains(deleted, "Namespace::new-ns")
+			a.Equal([]string{"Namespace::new-ns"}, gets)
+			stats := s.outputStats()
+			if !test.created {
+				a.Nil(stats["created"])
+				for _, o := range synced {
+					a.False(o.GetKind() == "Namespace" && o.GetName() == "new-ns")
+				}
+				return
+			}
+			a.EqualValues([]interface{}{"Namespace::new-ns"}, stats["created"])
+			require.NotEmpty(t, synced)
+			ns := synced[0]
+			a.Equal("Namespace", ns.GetKind())
+			a.Equal("new-ns", ns.GetName())
+			a.Equal(namespaceComponent, ns.Component())
+			a.Equal("example1", ns.ToUnstructured().GetLabels()[model.QbecNames.ApplicationLabel])
+			a.Equal("dev", ns.ToUnstructured().GetLabels()[model.QbecNames.EnvironmentLabel])
+		})
+	}
+}
+
+func TestApplyCreateNamespacePruneOnly(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("apply", "dev", "--prune-only", "--create-namespace")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("--create-namespace cannot be used with --prune-only", err.Error())
+}
+
+func TestApplyConfirm(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		input   string
+		synced  bool
+		message string
+	}{
+		{name: "yes", input: "y\n", synced: true},
+		{name: "no", input: "n\n", message: "apply canceled"},
+		{name: "auto-confirm", args: []string{"--yes"}, synced: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.stdin.WriteString(test.input)
+			d := &dg{cmValue: "baz", secretValue: "baz"}
+			s.client.getFunc = d.get
+			s.client.listFunc = stdLister
+			var l sync.Mutex
+			synced := false
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				defer l.Unlock()
+				synced = true
+				return &remote.SyncResult{Type: remote.SyncObjectsIdentical}, nil
+			}
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			args := append([]string{"apply", "dev", "--confirm", "--wait-all=false", "--yes=false"}, test.args...)
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.Equal(test.synced, synced)
+			s.assertOutputLineMatch(regexp.MustCompile(`^\+\+\+ config ConfigMap:bar-system:svc2-cm`))
+			s.assertErrorLineMatch(regexp.MustCompile(`object\(s\) to create, 2 to update, 1 to delete$`))
+			if test.message != "" {
+				s.assertErrorLineMatch(regexp.MustCompile(test.message))
+			}
+		})
+	}
+}
+
+func TestApplyDryRunDiff(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("no syncs expected")
+	}
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("no deletes expected")
+	}
+	err := s.executeCommand("apply", "dev", "-n", "--output=diff")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`^\+\+\+ config ConfigMap:bar-system:svc2-cm`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^--- live Deployment:bar-system:svc2-previous-deploy`))
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deletions"])
+	s.assertErrorLineMatch(regexp.MustCompile(`^\[dry-run\] \d+ object\(s\) to create, 2 to update, 1 to delete$`))
+}
+
+func TestApplyDryRunDiffPlan(t *testing.T) {
+	lister := func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+		c, _ := stdLister(ctx, q)
+		c.(*coll).add(&basicObject{
+			objectKey: objectKey{
+				gvk:  schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"},
+				name: "old-psp",
+			},
+			component: "cluster-objects",
+			app:       "app",
+			env:       "dev",
+		})
+		return c, nil
+	}
+	tests := []struct {
+		name      string
+		args      []string
+		additions string
+		deletions []interface{}
+		skipped   interface{}
+		warning   string
+	}{
+		{
+			name:      "default",
+			deletions: []interface{}{"Deployment:bar-system:svc2-previous-deploy"},
+			skipped:   map[string]interface{}{"deletions": []interface{}{"PodSecurityPolicy::old-psp"}},
+		},
+		{
+			name:      "gc-cluster-scoped",
+			args:      []string{"--gc-cluster-scoped"},
+			deletions: []interface{}{"Deployment:bar-system:svc2-previous-deploy", "PodSecurityPolicy::old-psp"},
+		},
+		{
+			name: "prune-whitelist",
+			args: []string{"--prune-whitelist", "v1/ConfigMap"},
+		},
+		{
+			name:      "create-namespace",
+			args:      []string{"--create-namespace", "--namespace", "new-ns", "--gc=false"},
+			additions: "Namespace::new-ns",
+		},
+		{
+			name:      "prune-threshold",
+			args:      []string{"--prune-threshold=0"},
+			deletions: []interface{}{"Deployment:bar-system:svc2-previous-deploy"},
+			skipped:   map[string]interface{}{"deletions": []interface{}{"PodSecurityPolicy::old-psp"}},
+			warning:   `\[dry-run\] garbage collection would delete 1 of \d+ tracked object\(s\)`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			d := &dg{cmValue: "baz", secretValue: "baz"}
+			s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+				if obj.GetName() == "old-psp" {
+					return &unstructured.Unstructured{Object: map[string]interface{}{
+						"apiVersion": "policy/v1beta1",
+						"kind":       "PodSecurityPolicy",
+						"metadata":   map[string]interface{}{"name": "old-psp"},
+					}}, nil
+				}
+				return d.get(ctx, obj)
+			}
+			s.client.listFunc = lister
+			s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+				return nil, fmt.Errorf("no syncs expected")
+			}
+			err := s.executeCommand(append([]string{"apply", "dev", "-n", "--output=diff"}, test.args...)...)
+			require.NoError(t, err)
+			stats := s.outputStats()
+			a := assert.New(t)
+			a.ElementsMatch(test.deletions, stats["deletions"])
+			a.EqualValues(test.skipped, stats["skipped"])
+			if test.additions != "" {
+				a.Contains(stats["additions"], test.additions)
+			}
+			if test.warning != "" {
+				s.assertErrorLineMatch(regexp.MustCompile(test.warning))
+			}
+		})
+	}
+}
+
+func TestApplyDryRunDiffWaves(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/waves")
+	defer s.reset()
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		return nil, remote.ErrNotFound
+	}
+	err := s.executeCommand("apply", "dev", "-n", "--output=diff", "--gc=false", "--parallel=1")
+	require.NoError(t, err)
+	var order []string
+	for _, line := range strings.Split(s.stdout(), "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			order = append(order, strings.Fields(line)[2])
+		}
+	}
+	assert.Equal(t, []string{"ConfigMap::config", "Deployment::db", "Deployment::app"}, order)
+	s.assertErrorLineMatch(regexp.MustCompile(`changes for wave 1`))
+	s.assertErrorLineMatch(regexp.MustCompile(`changes for wave 2`))
+}
+
+func TestApplyConfirmPruneThreshold(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	s.client.syncFunc = func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error) {
+		return nil, fmt.Errorf("no syncs expected")
+	}
+	err := s.executeCommand("apply", "dev", "--confirm", "--yes=false", "--prune-threshold=0")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than the prune threshold of 0")
+	assert.NotContains(t, s.stderr(), "Do you want to continue")
+}
+
+func TestApplyDryRunDiffNegative(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		message string
+	}{
+		{name: "no-dry-run", args: []string{"--output=diff"}, message: "--output=diff requires --dry-run"},
+		{name: "prune-only", args: []string{"-n", "--output=diff", "--prune-only"}, message: "--output=diff cannot be used with --prune-only"},
+		{name: "bad-output", args: []string{"-n", "--output=json"}, message: `invalid output "json", must be one of text or diff`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"apply", "dev"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.message, err.Error())
+		})
+	}
+}
+
+func TestApplyConfirmNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		terminal bool
+		message  string
+	}{
+		{name: "not-terminal", args: []string{"--yes=false"}, message: "--confirm requires --yes when standard input is not a terminal"},
+		{name: "prune-only", args: []string{"--prune-only"}, terminal: true, message: "--confirm cannot be used with --prune-only"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			*s.terminal = test.terminal
+			err := s.executeCommand(append([]string{"apply", "dev", "--confirm"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.message, err.Error())
+		})
+	}
+}
@@ -44,9 +44,11 @@ func setupCommands(root *cobra.Command, cp ctxProvider) {
 	root.AddCommand(newApplyCommand(cp))
 	root.AddCommand(newValidateCommand(cp))
 	root.AddCommand(newShowCommand(cp))
+	root.AddCommand(newStatsCommand(cp))
 	root.AddCommand(newEvalCommand(cp))
 	root.AddCommand(newDiffCommand(cp))
 	root.AddCommand(newDeleteCommand(cp))
+	root.AddCommand(newExplainCommand(cp))
 	root.AddCommand(newComponentCommand(cp))
 	root.AddCommand(newParamCommand(cp))
 	root.AddCommand(newEnvCommand(cp))
@@ -56,6 +58,9 @@ func setupCommands(root *cobra.Command, cp ctxProvider) {
 	alplhaCmd := newAlphaCommand()
 	alplhaCmd.AddCommand(newFmtCommand(cp))
 	alplhaCmd.AddCommand(newLintCommand(cp))
+	alplhaCmd.AddCommand(newEnvMapCommand(cp))
+	alplhaCmd.AddCommand(newAlphaEvalCommand(cp))
+	alplhaCmd.AddCommand(newAlphaSchemaCommand(cp))
 	root.AddCommand(alplhaCmd)
 }
 
@@ -124,8 +129,10 @@ func (lw *lockWriter) Write(buf []byte) (int, error) {
 	return n, err
 }
 
-func startRemoteList(ctx context.Context, envCtx cmd.EnvContext, client cmd.KubeClient, fp model.Filters) (_ lister, retainObjects []model.K8sLocalObject, _ error) {
-	all, err := generateObjects(ctx, envCtx, emptyFilterOpts())
+// startRemoteList starts listing the objects on the server that are tracked for garbage collection, given the
+// evaluated objects of all components of the environment. It returns the objects that must be retained.
+func startRemoteList(ctx context.Context, envCtx cmd.EnvContext, client cmd.KubeClient, objects []model.K8sLocalObject, fp model.Filters, nso namespaceOverride) (_ lister, retainObjects []model.K8sLocalObject, _ error) {
+	all, err := filterObjects(envCtx, objects, filterOpts{client: client, nsOverride: nso})
 	if err != nil {
 		return nil, nil, err
 	}
@@ -135,10 +142,17 @@ func startRemoteList(ctx context.Context, envCtx cmd.EnvContext, client cmd.Kube
 		}
 	}
 	var scope remote.ListQueryScope
-	lister, scope, err := newRemoteLister(client, all, envCtx.App().DefaultNamespace(envCtx.Env()))
+	defaultNs := envCtx.App().DefaultNamespace(envCtx.Env())
+	if nso.namespace != "" {
+		defaultNs = nso.namespace
+	}
+	lister, scope, err := newRemoteLister(client, all, defaultNs)
 	if err != nil {
 		return nil, nil, err
 	}
+	if ns := fp.OnlyNamespace(); ns != "" { // do not look for extra objects in other namespaces or at cluster scope
+		scope = remote.ListQueryScope{Namespaces: []string{ns}}
+	}
 	clusterScopedLists := false
 	if len(scope.Namespaces) > 1 && envCtx.App().ClusterScopedLists() {
 		clusterScopedLists = true
@@ -146,6 +160,7 @@ func startRemoteList(ctx context.Context, envCtx cmd.EnvContext, client cmd.Kube
 	lister.start(ctx, remote.ListQueryConfig{
 		Application:        envCtx.App().Name(),
 		Tag:                envCtx.App().Tag(),
+		GCTag:              envCtx.App().GCTag(),
 		Environment:        envCtx.Env(),
 		KindFilter:         fp.GVKFilter,
 		ListQueryScope:     scope,
@@ -172,8 +187,8 @@ func ordering(item model.K8sQbecMeta) int {
 	return val
 }
 
-// sortConfig returns the sort configuration.
-func sortConfig(provider objsort.Namespaced) objsort.Config {
+// sortConfig returns the sort configuration using the supplied kind orders from the app, if any.
+func sortConfig(provider objsort.Namespaced, kindOrder map[schema.GroupKind]int) objsort.Config {
 	return objsort.Config{
 		NamespacedIndicator: func(gvk schema.GroupVersionKind) (bool, error) {
 			ret, err := provider(gvk)
@@ -183,5 +198,6 @@ func sortConfig(provider objsort.Namespaced) objsort.Config {
 			return ret, nil
 		},
 		OrderingProvider: ordering,
+		KindOrdering:     kindOrder,
 	}
 }
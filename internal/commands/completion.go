@@ -15,15 +15,22 @@
 package commands
 
 import (
-	"os"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/filematcher"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/sio"
 )
 
 const (
 	completionLongDesc = `
-Output shell completion code for bash.
+Output shell completion code for bash (the default), zsh, fish or powershell.
 The shell code must be evaluated to provide interactive
 completion of qbec commands.  This can be done by sourcing it from
 the .bash_profile.`
@@ -43,6 +50,12 @@ bash_completion.d/ directory,
 	source '~/qbec.bash'
 	" >> ~/.bash_profile
 	source ~/.bash_profile
+
+# To load the fish completion into your current shell
+	qbec completion fish | source
+
+# To load the powershell completion into your current shell
+	qbec completion powershell | Out-String | Invoke-Expression
 `
 	// BashCompletionFunc contains all the custom bash functions that are
 	// used to generate dynamic completion lists to extend the completion
@@ -101,23 +114,194 @@ var (
 	customFlagCompletions = map[string]string{}
 )
 
+var completionShells = []string{"bash", "zsh", "fish", "powershell"}
+
+func doCompletion(root *cobra.Command, args []string, w io.Writer) error {
+	if len(args) > 1 {
+		return cmd.NewUsageError(fmt.Sprintf("at most one shell may be specified, but provided: %q", args))
+	}
+	shell := "bash"
+	if len(args) == 1 {
+		shell = args[0]
+	}
+	switch shell {
+	case "bash":
+		if len(customFlagCompletions) > 0 {
+			addCustomFlagCompletions(root)
+		}
+		return root.GenBashCompletion(w)
+	case "zsh":
+		return root.GenZshCompletion(w)
+	case "fish":
+		return root.GenFishCompletion(w, true)
+	case "powershell":
+		return root.GenPowerShellCompletionWithDesc(w)
+	default:
+		return cmd.NewUsageError(fmt.Sprintf("unsupported shell %q, must be one of %s", shell, strings.Join(completionShells, ", ")))
+	}
+}
+
 func newCompletionCommand(root *cobra.Command) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:                   "completion",
+		Use:                   "completion [bash|zsh|fish|powershell]",
 		DisableFlagsInUseLine: true,
-		Short:                 "Output shell completion for bash",
+		Short:                 "Output shell completion for bash, zsh, fish or powershell",
 		Long:                  completionLongDesc,
 		Example:               completionExample,
-		RunE: func(_ *cobra.Command, _ []string) error {
-			if len(customFlagCompletions) > 0 {
-				addCustomFlagCompletions(root)
-			}
-			return cmd.WrapError(root.GenBashCompletion(os.Stdout))
+		ValidArgs:             completionShells,
+		RunE: func(_ *cobra.Command, args []string) error {
+			return cmd.WrapError(doCompletion(root, args, root.OutOrStdout()))
 		},
 	}
 	return cmd
 }
 
+// envArgs describes the environment arguments accepted by a command.
+type envArgs struct {
+	max      int  // maximum number of environment arguments
+	baseline bool // whether the baseline environment may be specified
+	files    bool // whether the arguments after the environments are files
+}
+
+// envArgCommands are the commands that accept environment names as arguments, keyed by command path without the
+// name of the executable.
+var envArgCommands = map[string]envArgs{
+	"alpha eval":     {max: 1, files: true},
+	"apply":          {max: 1},
+	"component diff": {max: 2, baseline: true},
+	"component list": {max: 1},
+	"component show": {max: 1},
+	"delete":         {max: 1},
+	"diff":           {max: 1},
+	"env props":      {max: 1},
+	"env vars":       {max: 1},
+	"explain":        {max: 1},
+	"param diff":     {max: 2, baseline: true},
+	"param list":     {max: 1, baseline: true},
+	"show":           {max: 1},
+	"stats":          {max: 1},
+	"validate":       {max: 1},
+}
+
+// completionApp loads the app for the command being completed, honoring the root and env file options. It returns
+// nil if the app cannot be loaded. Messages printed while loading the app are discarded.
+func completionApp(c *cobra.Command) *model.App {
+	out := sio.Output
+	sio.Output = ioutil.Discard
+	defer func() { sio.Output = out }()
+
+	flagValue := func(name string) string {
+		if f := c.Flag(name); f != nil {
+			return f.Value.String()
+		}
+		return ""
+	}
+	var envFiles []string
+	if envFile := flagValue("env-file"); envFile != "" {
+		files, err := filematcher.Match(envFile)
+		if err != nil {
+			return nil
+		}
+		envFiles = files
+	}
+	if err := setWorkDir(flagValue("root")); err != nil {
+		return nil
+	}
+	app, err := model.NewApp("qbec.yaml", envFiles, "")
+	if err != nil {
+		return nil
+	}
+	return app
+}
+
+// withPrefix returns the sorted subset of the supplied names that start with the supplied prefix.
+func withPrefix(names []string, prefix string) []string {
+	var ret []string
+	for _, n := range names {
+		if strings.HasPrefix(n, prefix) {
+			ret = append(ret, n)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func envNames(app *model.App, baseline bool) []string {
+	var ret []string
+	for name := range app.Environments() {
+		ret = append(ret, name)
+	}
+	if baseline {
+		ret = append(ret, model.Baseline)
+	}
+	return ret
+}
+
+// completeEnvArgs returns a completion function that suggests environment names for positional arguments.
+func completeEnvArgs(ea envArgs) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= ea.max {
+			if ea.files {
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		app := completionApp(c)
+		if app == nil {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return withPrefix(envNames(app, ea.baseline), toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeEnvFlag suggests environment names for flag values.
+func completeEnvFlag(c *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	app := completionApp(c)
+	if app == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return withPrefix(envNames(app, false), toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeComponentFlag suggests component names for flag values. When an environment has already been specified
+// as an argument, only the components for that environment are suggested.
+func completeComponentFlag(c *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	app := completionApp(c)
+	if app == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names := app.ComponentNames()
+	if len(args) > 0 {
+		if comps, err := app.ComponentsForEnvironment(args[0], nil, nil); err == nil {
+			names = names[:0]
+			for _, comp := range comps {
+				names = append(names, comp.Name)
+			}
+		}
+	}
+	return withPrefix(names, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// addDynamicCompletions registers functions that complete environment and component names read from qbec.yaml
+// for the supplied command and all its sub-commands.
+func addDynamicCompletions(root *cobra.Command, c *cobra.Command) {
+	path := strings.TrimPrefix(c.CommandPath(), root.Name()+" ")
+	if ea, ok := envArgCommands[path]; ok && c.ValidArgsFunction == nil {
+		c.ValidArgsFunction = completeEnvArgs(ea)
+	}
+	for _, name := range []string{"component", "exclude-component"} {
+		if c.Flags().Lookup(name) != nil {
+			_ = c.RegisterFlagCompletionFunc(name, completeComponentFlag)
+		}
+	}
+	if c.Name() == "eval" && c.Flags().Lookup("env") != nil {
+		_ = c.RegisterFlagCompletionFunc("env", completeEnvFlag)
+	}
+	for _, sub := range c.Commands() {
+		addDynamicCompletions(root, sub)
+	}
+}
+
 func addCustomFlagCompletions(c *cobra.Command) {
 	for name, completion := range customFlagCompletions {
 		if f := c.Flags().Lookup(name); f != nil {
@@ -0,0 +1,124 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func completions(s *scaffold) []string {
+	var ret []string
+	for _, l := range strings.Split(strings.TrimSpace(s.stdout()), "\n") {
+		if !strings.HasPrefix(l, ":") && !strings.HasPrefix(l, "Completion ended") {
+			ret = append(ret, l)
+		}
+	}
+	return ret
+}
+
+func TestCompletionEnvironments(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "apply", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev", "local", "prod", "stage"}, completions(s))
+	s.assertOutputLineMatch(regexp.MustCompile(`^:4$`))
+}
+
+func TestCompletionEnvironmentsPrefix(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "param", "diff", "dev", "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_", "dev", "local", "prod", "stage"}, completions(s))
+}
+
+func TestCompletionEnvironmentsMaxArgs(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "show", "dev", "")
+	require.NoError(t, err)
+	assert.Nil(t, completions(s))
+}
+
+func TestCompletionEnvironmentsSubcommands(t *testing.T) {
+	for _, args := range [][]string{{"component", "show"}, {"alpha", "eval"}} {
+		t.Run(strings.Join(args, " "), func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append(append([]string{"__complete"}, args...), "")...)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"dev", "local", "prod", "stage"}, completions(s))
+		})
+	}
+}
+
+func TestCompletionEnvironmentsFileArgs(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "alpha", "eval", "dev", "")
+	require.NoError(t, err)
+	assert.Nil(t, completions(s))
+	s.assertOutputLineMatch(regexp.MustCompile(`^:0$`))
+}
+
+func TestCompletionComponents(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "show", "dev", "--component", "serv")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service2"}, completions(s))
+}
+
+func TestCompletionComponentsNoEnv(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("__complete", "show", "--component", "serv")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"service1", "service2"}, completions(s))
+}
+
+func TestCompletionShells(t *testing.T) {
+	tests := map[string]string{
+		"bash":       "# bash completion for",
+		"zsh":        "#compdef",
+		"fish":       "# fish completion for",
+		"powershell": "# powershell completion for",
+	}
+	for shell, prefix := range tests {
+		t.Run(shell, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand("completion", shell)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(s.stdout(), prefix), s.stdout()[:40])
+		})
+	}
+}
+
+func TestCompletionBadShell(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("completion", "tcsh")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `unsupported shell "tcsh"`)
+}
@@ -29,14 +29,16 @@ import (
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/diff"
 	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/types"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func newComponentCommand(cp ctxProvider) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "component <subcommand>",
-		Short: "component lists and diffs",
+		Short: "component lists, diffs and renders",
 	}
-	cmd.AddCommand(newComponentListCommand(cp), newComponentDiffCommand(cp))
+	cmd.AddCommand(newComponentListCommand(cp), newComponentDiffCommand(cp), newComponentShowCommand(cp))
 	return cmd
 }
 
@@ -68,8 +70,27 @@ func listComponents(components []model.Component, formatSpecified bool, format s
 
 type componentListCommandConfig struct {
 	cmd.AppContext
-	format  string
-	objects bool
+	format   string
+	objects  bool
+	selector string
+}
+
+// selectComponents returns the components whose labels match the supplied selector.
+func selectComponents(components []model.Component, selector string) ([]model.Component, error) {
+	if selector == "" {
+		return components, nil
+	}
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, cmd.NewUsageError(fmt.Sprintf("invalid component selector %q: %v", selector, err))
+	}
+	var ret []model.Component
+	for _, c := range components {
+		if sel.Matches(labels.Set(c.Labels)) {
+			ret = append(ret, c)
+		}
+	}
+	return ret, nil
 }
 
 func doComponentList(ctx context.Context, args []string, config componentListCommandConfig) error {
@@ -77,6 +98,14 @@ func doComponentList(ctx context.Context, args []string, config componentListCom
 		return cmd.NewUsageError(fmt.Sprintf("exactly one environment required, but provided: %q", args))
 	}
 	env := args[0]
+	components, err := config.App().ComponentsForEnvironment(env, nil, nil)
+	if err != nil {
+		return err
+	}
+	components, err = selectComponents(components, config.selector)
+	if err != nil {
+		return err
+	}
 	if config.objects {
 		envCtx, err := config.EnvContext(env)
 		if err != nil {
@@ -86,12 +115,21 @@ func doComponentList(ctx context.Context, args []string, config componentListCom
 		if err != nil {
 			return err
 		}
+		if config.selector != "" {
+			selected := map[string]bool{}
+			for _, c := range components {
+				selected[c.Name] = true
+			}
+			var filtered []model.K8sLocalObject
+			for _, o := range objects {
+				if selected[o.Component()] {
+					filtered = append(filtered, o)
+				}
+			}
+			objects = filtered
+		}
 		return showNames(objects, config.format != "", config.format, config.Stdout())
 	}
-	components, err := config.App().ComponentsForEnvironment(env, nil, nil)
-	if err != nil {
-		return err
-	}
 	return listComponents(components, config.format != "", config.format, config.Stdout())
 }
 
@@ -105,6 +143,7 @@ func newComponentListCommand(cp ctxProvider) *cobra.Command {
 	config := componentListCommandConfig{}
 	c.Flags().BoolVarP(&config.objects, "objects", "O", false, "set to true to also list objects in each component")
 	c.Flags().StringVarP(&config.format, "format", "o", "", "use json|yaml to display machine readable input")
+	c.Flags().StringVarP(&config.selector, "selector", "l", "", "label selector to filter components by their metadata labels")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -115,7 +154,8 @@ func newComponentListCommand(cp ctxProvider) *cobra.Command {
 
 type componentDiffCommandConfig struct {
 	cmd.AppContext
-	objects bool
+	objects    bool
+	filterFunc func() (model.Filters, error)
 }
 
 func doComponentDiff(ctx context.Context, args []string, config componentDiffCommandConfig) error {
@@ -143,6 +183,11 @@ func doComponentDiff(ctx context.Context, args []string, config componentDiffCom
 	default:
 		return cmd.NewUsageError("one or two environments required")
 	}
+	fp, err := config.filterFunc()
+	if err != nil {
+		return err
+	}
+	renderContents := !config.objects && (len(fp.ComponentIncludes()) > 0 || len(fp.ComponentExcludes()) > 0)
 
 	getComponents := func(envCtx cmd.EnvContext) (str string, name string, err error) {
 		env := envCtx.Env()
@@ -163,7 +208,7 @@ func doComponentDiff(ctx context.Context, args []string, config componentDiffCom
 	}
 
 	getObjects := func(envCtx cmd.EnvContext) (str string, name string, err error) {
-		objs, err := generateObjects(ctx, envCtx, emptyFilterOpts())
+		objs, err := generateObjects(ctx, envCtx, filterOpts{filters: fp})
 		if err != nil {
 			return
 		}
@@ -178,9 +223,45 @@ func doComponentDiff(ctx context.Context, args []string, config componentDiffCom
 		}
 		return
 	}
+	// getContents renders the objects of the selected components as YAML without qbec metadata, which
+	// always differs across environments.
+	getContents := func(envCtx cmd.EnvContext) (str string, name string, err error) {
+		objs, err := generateObjects(ctx, envCtx, filterOpts{filters: fp, keyFunc: localObjectKey})
+		if err != nil {
+			return
+		}
+		var buf bytes.Buffer
+		for _, o := range objs {
+			o, _ = types.HideSensitiveLocalInfo(o)
+			var b []byte
+			b, err = yaml.Marshal(cleanMeta(o))
+			if err != nil {
+				return
+			}
+			fmt.Fprintln(&buf, "---")
+			fmt.Fprintf(&buf, "%s\n", b)
+		}
+		str = buf.String()
+		name = "environment: " + envCtx.Env()
+		if envCtx.Env() == model.Baseline {
+			name = "baseline"
+		}
+		return
+	}
 	var left, right, leftName, rightName string
+	contextLines := -1
 
-	if config.objects {
+	if renderContents {
+		left, leftName, err = getContents(leftEnv)
+		if err != nil {
+			return err
+		}
+		right, rightName, err = getContents(rightEnv)
+		if err != nil {
+			return err
+		}
+		contextLines = 0
+	} else if config.objects {
 		left, leftName, err = getObjects(leftEnv)
 		if err != nil {
 			return err
@@ -200,7 +281,7 @@ func doComponentDiff(ctx context.Context, args []string, config componentDiffCom
 		}
 	}
 
-	opts := diff.Options{Context: -1, LeftName: leftName, RightName: rightName, Colorize: config.Colorize()}
+	opts := diff.Options{Context: contextLines, LeftName: leftName, RightName: rightName, Colorize: config.Colorize()}
 	d, err := diff.Strings(left, right, opts)
 	if err != nil {
 		return err
@@ -212,11 +293,13 @@ func doComponentDiff(ctx context.Context, args []string, config componentDiffCom
 func newComponentDiffCommand(cp ctxProvider) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "diff [-objects] <environment>|_ [<environment>|_]",
-		Short:   "diff component lists across two environments or between the baseline (use _ for baseline) and an environment",
+		Short:   "diff component lists or rendered components across two environments or between the baseline (use _ for baseline) and an environment",
 		Example: componentDiffExamples(),
 	}
 
-	config := componentDiffCommandConfig{}
+	config := componentDiffCommandConfig{
+		filterFunc: addFilterParams(c, false),
+	}
 	c.Flags().BoolVarP(&config.objects, "objects", "O", false, "set to true to also list objects in each component")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
@@ -225,3 +308,54 @@ func newComponentDiffCommand(cp ctxProvider) *cobra.Command {
 	}
 	return c
 }
+
+// doComponentShow renders the selected components for an environment. Only the selected components are evaluated.
+func doComponentShow(ctx context.Context, args []string, config showCommandConfig) error {
+	if len(args) != 1 {
+		return cmd.NewUsageError(fmt.Sprintf("exactly one environment required, but provided: %q", args))
+	}
+	fp, err := config.filterFunc()
+	if err != nil {
+		return err
+	}
+	if len(fp.ComponentIncludes()) == 0 {
+		return cmd.NewUsageError("at least one component must be specified using -c")
+	}
+	env := args[0]
+	components, err := config.App().ComponentsForEnvironment(env, fp.ComponentIncludes(), fp.ComponentExcludes())
+	if err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		return fmt.Errorf("none of the specified components are enabled for environment %s", env)
+	}
+	return doShow(ctx, args, config)
+}
+
+func newComponentShowCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "show <environment> -c <component>",
+		Short:   "show output in YAML or JSON format for the specified components without evaluating others",
+		Example: componentShowExamples(),
+	}
+
+	config := showCommandConfig{
+		filterFunc: addFilterParams(c, false),
+	}
+
+	var clean bool
+	c.Flags().StringVarP(&config.format, "format", "o", "yaml", "Output format. Supported values are: json, yaml")
+	c.Flags().BoolVar(&clean, "clean", false, "do not display qbec-generated labels and annotations")
+	c.Flags().BoolVarP(&config.showSecrets, "show-secrets", "S", false, "do not obfuscate secret values in the output")
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		config.AppContext = cp()
+		if config.format == "kustomize" {
+			return cmd.NewUsageError(fmt.Sprintf("invalid output format: %q", config.format))
+		}
+		config.formatSpecified = c.Flags().Changed("format")
+		cleanEvalMode = clean
+		return cmd.WrapError(doComponentShow(c.Context(), args, config))
+	}
+	return c
+}
@@ -17,12 +17,14 @@
 package commands
 
 import (
+	"encoding/json"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -71,6 +73,52 @@ func TestComponentListObjects(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(`service2\s+ConfigMap\s+svc2-cm\s+bar-system`))
 }
 
+func TestComponentListSelector(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "list", "dev", "-l", "tier=backend")
+	require.NoError(t, err)
+	lines := strings.Split(strings.Trim(s.stdout(), "\n"), "\n")
+	a := assert.New(t)
+	a.Equal(2, len(lines))
+	s.assertOutputLineMatch(regexp.MustCompile(`service2\s+` + regexp.QuoteMeta(filepath.FromSlash("components/service2.jsonnet"))))
+}
+
+func TestComponentListSelectorObjects(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "list", "dev", "-O", "-l", "tier in (batch)")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`test-job\s+Job\s+tj-`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`service2`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`cluster-objects`))
+}
+
+func TestComponentListSelectorJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "list", "dev", "-l", "tier", "-o", "json")
+	require.NoError(t, err)
+	var data []model.Component
+	err = s.jsonOutput(&data)
+	require.NoError(t, err)
+	a := assert.New(t)
+	require.Equal(t, 2, len(data))
+	a.Equal("service2", data[0].Name)
+	a.EqualValues(map[string]string{"tier": "backend"}, data[0].Labels)
+	a.Equal("test-job", data[1].Name)
+}
+
+func TestComponentListBadSelector(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "list", "dev", "-l", "tier=(")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Contains(err.Error(), `invalid component selector "tier=("`)
+}
+
 func TestComponentDiffBasic(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -112,6 +160,18 @@ func TestComponentDiffObjects(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(`-service1\s+ConfigMap\s+svc1-cm\s+foo-system`))
 }
 
+func TestComponentDiffContents(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "diff", "_", "dev", "-c", "service1")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`--- baseline`))
+	s.assertOutputLineMatch(regexp.MustCompile(`\+\+\+ environment: dev`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^-\s+name: svc1-cm`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^-\s+foo: bar`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`qbec\.io/`))
+}
+
 func TestComponentNegative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -202,3 +262,56 @@ func TestComponentNegative(t *testing.T) {
 		})
 	}
 }
+
+func TestComponentShow(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "show", "dev", "-c", "service2")
+	require.NoError(t, err)
+	out, err := s.yamlOutput()
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.True(len(out) > 0)
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+name: svc2-cm`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+qbec\.io/component: service2`))
+	a.NotContains(s.stdout(), "qbec.io/component: cluster-objects")
+	a.NotContains(s.stdout(), "qbec.io/component: test-job")
+}
+
+func TestComponentShowJSONClean(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("component", "show", "dev", "-c", "service2", "-o", "json", "--clean")
+	require.NoError(t, err)
+	var data []interface{}
+	require.NoError(t, json.Unmarshal([]byte(s.stdout()), &data))
+	a := assert.New(t)
+	a.True(len(data) > 0)
+	a.NotContains(s.stdout(), "qbec.io/component")
+}
+
+func TestComponentShowNegative(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		usage   bool
+		message string
+	}{
+		{name: "no-env", args: []string{"-c", "service2"}, usage: true, message: `exactly one environment required, but provided: []`},
+		{name: "no-component", args: []string{"dev"}, usage: true, message: "at least one component must be specified using -c"},
+		{name: "bad-format", args: []string{"dev", "-c", "service2", "-o", "kustomize"}, usage: true, message: `invalid output format: "kustomize"`},
+		{name: "unknown-component", args: []string{"dev", "-c", "foo"}, message: "specified components: bad component reference(s): foo"},
+		{name: "not-enabled", args: []string{"dev", "-c", "service1"}, message: "none of the specified components are enabled for environment dev"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"component", "show"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.Equal(test.usage, cmd.IsUsageError(err))
+			a.Equal(test.message, err.Error())
+		})
+	}
+}
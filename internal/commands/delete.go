@@ -19,6 +19,10 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
@@ -30,9 +34,106 @@ import (
 
 type deleteCommandConfig struct {
 	cmd.AppContext
-	dryRun     bool
-	useLocal   bool
-	filterFunc func() (model.Filters, error)
+	dryRun      bool
+	useLocal    bool
+	wait        bool
+	waitTimeout time.Duration
+	parallel    int
+	filterFunc  func() (model.Filters, error)
+	nsFunc      func() (namespaceOverride, error)
+}
+
+var deletePollInterval = 2 * time.Second // allow override in tests
+
+// deleteGroup deletes the supplied objects using at most parallel concurrent calls and returns
+// outcomes in the same order as the input objects.
+func deleteGroup(ctx context.Context, client cmd.KubeClient, objs []model.K8sQbecMeta, opts remote.DeleteOptions, parallel int) []syncOutcome {
+	if parallel <= 0 {
+		parallel = 1
+	}
+	outcomes := make([]syncOutcome, len(objs))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, ob := range objs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, ob model.K8sQbecMeta) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			res, err := client.Delete(ctx, ob, opts)
+			outcomes[i] = syncOutcome{res: res, err: err}
+		}(i, ob)
+	}
+	wg.Wait()
+	return outcomes
+}
+
+// waitForDeletions polls the supplied objects using at most parallel concurrent routines until all of them are
+// gone from the server or the timeout elapses. It returns an error listing the objects that are still terminating.
+func waitForDeletions(ctx context.Context, client cmd.KubeClient, objs []model.K8sMeta, parallel int, timeout time.Duration) error {
+	if len(objs) == 0 {
+		return nil
+	}
+	if parallel <= 0 {
+		parallel = 1
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sio.Noticef("waiting for deletion of %d object(s)\n", len(objs))
+	var l sync.Mutex
+	var pending, failed []string
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for _, ob := range objs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(ob model.K8sMeta) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			name := client.DisplayName(ob)
+			for {
+				_, err := client.Get(ctx, ob)
+				if err == remote.ErrNotFound {
+					sio.Noticef("%s deleted\n", name)
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					sio.Errorf("get %s: %v\n", name, err)
+					l.Lock()
+					failed = append(failed, name)
+					l.Unlock()
+					return
+				}
+				select {
+				case <-ctx.Done():
+					l.Lock()
+					pending = append(pending, name)
+					l.Unlock()
+					return
+				case <-time.After(deletePollInterval):
+				}
+			}
+		}(ob)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("unable to wait for deletion of %s", strings.Join(failed, ", "))
+	}
+	if len(pending) > 0 {
+		sort.Strings(pending)
+		for _, name := range pending {
+			sio.Warnf("%s is still terminating\n", name)
+		}
+		return fmt.Errorf("timed out after %v waiting for deletion of %d object(s): %s", timeout, len(pending), strings.Join(pending, ", "))
+	}
+	return nil
 }
 
 func doDelete(ctx context.Context, args []string, config deleteCommandConfig) error {
@@ -47,6 +148,10 @@ func doDelete(ctx context.Context, args []string, config deleteCommandConfig) er
 	if err != nil {
 		return err
 	}
+	nso, err := config.nsFunc()
+	if err != nil {
+		return err
+	}
 	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
@@ -59,7 +164,9 @@ func doDelete(ctx context.Context, args []string, config deleteCommandConfig) er
 
 	var deletions []model.K8sQbecMeta
 	if config.useLocal {
-		objects, err := generateObjects(ctx, envCtx, makeFilterOpts(fp, client))
+		fo := makeFilterOpts(fp, client)
+		fo.nsOverride = nso
+		objects, err := generateObjects(ctx, envCtx, fo)
 		if err != nil {
 			return err
 		}
@@ -69,7 +176,11 @@ func doDelete(ctx context.Context, args []string, config deleteCommandConfig) er
 			}
 		}
 	} else {
-		lister, _, err := startRemoteList(ctx, envCtx, client, fp)
+		rendered, err := renderEnv(envCtx, fp, false, true)
+		if err != nil {
+			return err
+		}
+		lister, _, err := startRemoteList(ctx, envCtx, client, rendered.all, fp, nso)
 		if err != nil {
 			return err
 		}
@@ -84,8 +195,8 @@ func doDelete(ctx context.Context, args []string, config deleteCommandConfig) er
 		dryRun = "[dry-run] "
 	}
 
-	// process deletions
-	deletions = objsort.SortMeta(deletions, sortConfig(client.IsNamespaced))
+	// process deletions in reverse order, objects of the same order are deleted concurrently
+	groups := objsort.SortMetaGroups(deletions, sortConfig(client.IsNamespaced, config.App().DeleteOrder()))
 
 	if !config.dryRun && len(deletions) > 0 {
 		msg := fmt.Sprintf("will delete %d object(s)", len(deletions))
@@ -94,41 +205,64 @@ func doDelete(ctx context.Context, args []string, config deleteCommandConfig) er
 		}
 	}
 
-	printDelStatus := func(name string, res *remote.SyncResult, err error) {
+	printDelStatus := func(ob model.K8sQbecMeta, name string, res *remote.SyncResult, err error) {
+		fields := sio.Fields{Component: ob.Component(), Object: name, Action: "delete"}
 		if err != nil {
-			sio.Errorf("%sdelete %s failed\n", dryRun, name)
+			fields.Errorf("%sdelete %s failed\n", dryRun, name)
 			return
 		}
 		verb := "delete"
 		if res.Type == remote.SyncSkip {
 			verb = "skip delete"
 		}
-		sio.Noticef("%s%s %s\n", dryRun, verb, name)
+		fields.Action = verb
+		fields.Noticef("%s%s %s\n", dryRun, verb, name)
 		if res.Details != "" {
 			sio.Println(res.Details)
 		}
 	}
 
 	var stats applyStats
+	var deleted []model.K8sMeta
 	dp := newDeletePolicy(client.IsNamespaced, config.App().DefaultNamespace(env))
 	delOpts := remote.DeleteOptions{
 		DryRun:          config.dryRun,
 		DisableDeleteFn: dp.disableDelete,
 	}
-	for i := len(deletions) - 1; i >= 0; i-- {
-		ob := deletions[i]
-		name := client.DisplayName(ob)
-		res, err := client.Delete(ctx, ob, delOpts)
-		printDelStatus(name, res, err)
-		if err != nil {
-			return err
+	for gi := len(groups) - 1; gi >= 0; gi-- {
+		var group []model.K8sQbecMeta
+		for i := len(groups[gi]) - 1; i >= 0; i-- {
+			group = append(group, groups[gi][i])
+		}
+		outcomes := deleteGroup(ctx, client, group, delOpts, config.parallel)
+		var firstErr error
+		for i, ob := range group {
+			res, err := outcomes[i].res, outcomes[i].err
+			name := client.DisplayName(ob)
+			printDelStatus(ob, name, res, err)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			stats.update(name, res)
+			if res.Type == remote.SyncDeleted {
+				deleted = append(deleted, ob)
+			}
+		}
+		if firstErr != nil {
+			return firstErr
 		}
-		stats.update(name, res)
 	}
 
 	printStats(config.Stdout(), &stats)
 	if config.dryRun {
 		sio.Noticeln("** dry-run mode, nothing was actually changed **")
+		return nil
+	}
+	if config.wait {
+		return waitForDeletions(ctx, client, deleted, config.parallel, config.waitTimeout)
 	}
 	return nil
 }
@@ -142,10 +276,14 @@ func newDeleteCommand(cp ctxProvider) *cobra.Command {
 
 	config := deleteCommandConfig{
 		filterFunc: addFilterParams(c, true),
+		nsFunc:     addNamespaceOverrideParams(c),
 	}
 
 	c.Flags().BoolVarP(&config.dryRun, "dry-run", "n", false, "dry-run, do not delete resources but show what would happen")
 	c.Flags().BoolVar(&config.useLocal, "local", false, "use local object names to delete, do not derive list from server")
+	c.Flags().BoolVar(&config.wait, "wait", false, "wait until deleted objects are gone from the server, useful for objects with finalizers")
+	c.Flags().DurationVar(&config.waitTimeout, "wait-timeout", 5*time.Minute, "wait timeout")
+	c.Flags().IntVar(&config.parallel, "parallel", 5, "number of objects of the same delete order to delete concurrently and to poll when waiting for deletions")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
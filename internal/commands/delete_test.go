@@ -2,13 +2,18 @@ package commands
 
 import (
 	"context"
+	"fmt"
+	"regexp"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/model"
 	"github.com/splunk/qbec/internal/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 /*
@@ -74,6 +79,105 @@ func TestDeleteLocal(t *testing.T) {
 	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-deploy", "Secret:bar-system:svc2-secret", "ConfigMap:bar-system:svc2-cm"}, stats["deleted"])
 }
 
+func TestDeleteParallel(t *testing.T) {
+	for _, parallel := range []int{1, 2} {
+		t.Run(fmt.Sprint(parallel), func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			d := &dg{cmValue: "baz", secretValue: "baz"}
+			s.client.getFunc = d.get
+			s.client.listFunc = stdLister
+			var l sync.Mutex
+			inFlight, maxInFlight := 0, 0
+			s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+				l.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				l.Unlock()
+				time.Sleep(20 * time.Millisecond)
+				l.Lock()
+				inFlight--
+				l.Unlock()
+				return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+			}
+			err := s.executeCommand("delete", "dev", fmt.Sprintf("--parallel=%d", parallel))
+			require.NoError(t, err)
+			stats := s.outputStats()
+			a := assert.New(t)
+			a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy", "Deployment:bar-system:svc2-deploy"}, stats["deleted"])
+			a.Equal(parallel, maxInFlight)
+		})
+	}
+}
+
+func TestDeleteWait(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	origInterval := deletePollInterval
+	deletePollInterval = time.Millisecond
+	defer func() { deletePollInterval = origInterval }()
+	var l sync.Mutex
+	polls := map[string]int{}
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		l.Lock()
+		defer l.Unlock()
+		polls[obj.GetName()]++
+		if polls[obj.GetName()] < 3 {
+			return &unstructured.Unstructured{}, nil
+		}
+		return nil, remote.ErrNotFound
+	}
+	s.client.listFunc = stdLister
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("delete", "dev", "--wait", "--parallel=1")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal(map[string]int{"svc2-deploy": 3, "svc2-previous-deploy": 3}, polls)
+	s.assertErrorLineMatch(regexp.MustCompile(`waiting for deletion of 2 object\(s\)`))
+	s.assertErrorLineMatch(regexp.MustCompile(`Deployment:bar-system:svc2-deploy deleted`))
+}
+
+func TestDeleteWaitTimeout(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	origInterval := deletePollInterval
+	deletePollInterval = time.Millisecond
+	defer func() { deletePollInterval = origInterval }()
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		if obj.GetName() == "svc2-deploy" {
+			return &unstructured.Unstructured{}, nil
+		}
+		return nil, remote.ErrNotFound
+	}
+	s.client.listFunc = stdLister
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("delete", "dev", "--wait", "--wait-timeout=50ms")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("timed out after 50ms waiting for deletion of 1 object(s): Deployment:bar-system:svc2-deploy", err.Error())
+	s.assertErrorLineMatch(regexp.MustCompile(`Deployment:bar-system:svc2-deploy is still terminating`))
+}
+
+func TestDeleteWaitDryRun(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		return nil, fmt.Errorf("unexpected get")
+	}
+	s.client.listFunc = stdLister
+	s.client.deleteFunc = func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error) {
+		return &remote.SyncResult{Type: remote.SyncDeleted}, nil
+	}
+	err := s.executeCommand("delete", "dev", "-n", "--wait")
+	require.NoError(t, err)
+}
+
 func TestDeleteNegative(t *testing.T) {
 	tests := []struct {
 		name     string
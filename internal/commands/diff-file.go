@@ -0,0 +1,178 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/diff"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/vm/vmutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// manifestObject is an object loaded from a saved manifest. Its qbec metadata is taken from the labels and
+// annotations that qbec sets on local objects.
+type manifestObject struct {
+	*unstructured.Unstructured
+}
+
+func (m manifestObject) Application() string { return m.GetLabels()[model.QbecNames.ApplicationLabel] }
+func (m manifestObject) Component() string {
+	return m.GetAnnotations()[model.QbecNames.ComponentAnnotation]
+}
+func (m manifestObject) Environment() string { return m.GetLabels()[model.QbecNames.EnvironmentLabel] }
+func (m manifestObject) Tag() string         { return m.GetLabels()[model.QbecNames.TagLabel] }
+
+// appendManifestObjects appends the objects in the supplied document to the list, flattening arrays and lists.
+func appendManifestObjects(list []manifestObject, doc interface{}, index int) ([]manifestObject, error) {
+	switch d := doc.(type) {
+	case nil:
+		return list, nil
+	case []interface{}:
+		var err error
+		for _, item := range d {
+			list, err = appendManifestObjects(list, item, index)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case map[string]interface{}:
+		u := &unstructured.Unstructured{Object: d}
+		if u.IsList() {
+			items, _, _ := unstructured.NestedSlice(d, "items")
+			return appendManifestObjects(list, items, index)
+		}
+		if u.GetKind() == "" || u.GetAPIVersion() == "" {
+			return nil, fmt.Errorf("document at index %d: object without a kind and apiVersion", index)
+		}
+		if err := model.AssertMetadataValid(d); err != nil {
+			return nil, errors.Wrapf(err, "document at index %d", index)
+		}
+		return append(list, manifestObject{Unstructured: u}), nil
+	default:
+		return nil, fmt.Errorf("document at index %d: unexpected type %T, not a Kubernetes object", index, doc)
+	}
+}
+
+// readManifest returns the objects in the supplied file, which can have any number of YAML documents or a JSON
+// array of objects, such as the output of the show command. List objects are replaced by their items.
+func readManifest(r io.Reader) ([]manifestObject, error) {
+	docs, err := vmutil.ParseYAMLDocuments(r)
+	if err != nil {
+		return nil, err
+	}
+	var ret []manifestObject
+	for i, doc := range docs {
+		ret, err = appendManifestObjects(ret, doc, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// loadManifest reads the objects in the supplied manifest file keyed by their local object key. Objects with
+// generated names are ignored since they cannot be matched with local objects.
+func loadManifest(file string) (objects []manifestObject, byKey map[string]*unstructured.Unstructured, _ error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	all, err := readManifest(f)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "read manifest %s", file)
+	}
+	byKey = map[string]*unstructured.Unstructured{}
+	for _, o := range all {
+		if o.GetName() == "" {
+			continue
+		}
+		key := localObjectKey(o)
+		if _, ok := byKey[key]; ok {
+			return nil, nil, fmt.Errorf("read manifest %s: duplicate object %s", file, localDisplayName(o))
+		}
+		byKey[key] = o.Unstructured
+		objects = append(objects, o)
+	}
+	return objects, byKey, nil
+}
+
+// diffFile diffs the objects of the environment against the objects in the manifest file of the diff configuration
+// without connecting to a cluster. Objects in the file that are not produced locally are reported as deletions.
+func diffFile(ctx context.Context, envCtx cmd.EnvContext, fp model.Filters, nso namespaceOverride, w io.Writer, config diffCommandConfig) (*diffOutcome, error) {
+	saved, byKey, err := loadManifest(config.againstFile)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := generateObjects(ctx, envCtx, filterOpts{filters: fp, keyFunc: localObjectKey, timings: config.timings, nsOverride: nso})
+	if err != nil {
+		return nil, err
+	}
+	if config.contextLines == 0 {
+		config.contextLines = -1
+	}
+	never := func(gvk schema.GroupVersionKind) (bool, error) { return false, nil }
+	d := &differ{
+		w:           w,
+		opts:        diff.Options{Context: config.contextLines, Colorize: config.Colorize()},
+		ignores:     config.di,
+		showSecrets: config.showSecrets,
+		verbose:     config.Verbosity(),
+		upPolicy:    newUpdatePolicy(),
+		delPolicy:   newPrunePolicy(never, config.App().DefaultNamespace(envCtx.Env())),
+		jsonPatch:   config.format == diffFormatJSONPatch,
+		namesOnly:   config.liveMissing,
+		summaryOnly: config.summaryOnly,
+		baseline:    byKey,
+	}
+	out := &diffOutcome{d: d}
+	out.diffErr = runInParallel(ctx, objects, d.diffLocal, config.parallel)
+	if out.diffErr != nil || !config.showDeletions {
+		return out, nil
+	}
+	local := map[string]bool{}
+	for _, o := range objects {
+		local[localObjectKey(o)] = true
+	}
+	defaultNs := config.App().DefaultNamespace(envCtx.Env())
+	for _, o := range saved {
+		if local[localObjectKey(o)] {
+			continue
+		}
+		ok, err := fp.Match(o, nil, defaultNs)
+		if err != nil {
+			out.listErr = err
+			return out, nil
+		}
+		if !ok {
+			continue
+		}
+		if err := d.diff(ctx, o); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,110 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"github.com/splunk/qbec/internal/cmd"
+)
+
+const (
+	reportFormatDiff = "diff" // the diff output, exactly as written to stdout but without colors
+	reportFormatJSON = "json" // the stats of the diff as a JSON document
+)
+
+// diffReport writes the results of a diff to a file, in addition to or instead of standard output.
+type diffReport struct {
+	file   string
+	format string
+	only   bool
+	f      *os.File
+	stats  map[string]*diffStats
+}
+
+// newDiffReport returns a report for the supplied options, or nil if no report file is specified.
+func newDiffReport(file, format string, only bool) (*diffReport, error) {
+	if file == "" {
+		if only {
+			return nil, cmd.NewUsageError("--report-only requires --report-file")
+		}
+		return nil, nil
+	}
+	if format != reportFormatDiff && format != reportFormatJSON {
+		return nil, cmd.NewUsageError(fmt.Sprintf("invalid report format %q, must be one of %s or %s", format, reportFormatDiff, reportFormatJSON))
+	}
+	return &diffReport{file: file, format: format, only: only, stats: map[string]*diffStats{}}, nil
+}
+
+// open creates the report file and returns the writer to which diff output should be written.
+func (r *diffReport) open(stdout io.Writer) (io.Writer, error) {
+	f, err := os.Create(r.file)
+	if err != nil {
+		return nil, err
+	}
+	r.f = f
+	if r.only {
+		stdout = ioutil.Discard
+	}
+	if r.format == reportFormatDiff {
+		return io.MultiWriter(stdout, &escapeStripper{w: f}), nil
+	}
+	return stdout, nil
+}
+
+// add records the stats of the diff for the supplied context.
+func (r *diffReport) add(context string, stats *diffStats) {
+	r.stats[context] = stats
+}
+
+// close writes the stats of the diff for JSON reports and closes the report file. The stats are keyed by context
+// for environments that target multiple contexts.
+func (r *diffReport) close(single bool) error {
+	if r.format == reportFormatJSON {
+		var doc interface{} = r.stats
+		if single {
+			for _, s := range r.stats {
+				doc = s
+			}
+		}
+		enc := json.NewEncoder(r.f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return r.f.Close()
+}
+
+var reEscape = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// escapeStripper removes the escape sequences for colors from the output written to a writer.
+type escapeStripper struct {
+	w io.Writer
+}
+
+func (e *escapeStripper) Write(b []byte) (int, error) {
+	if _, err := e.w.Write(reEscape.ReplaceAll(b, nil)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
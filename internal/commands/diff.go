@@ -18,6 +18,7 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"sort"
@@ -40,6 +41,7 @@ type diffIgnores struct {
 	allLabels       bool
 	annotationNames []string
 	labelNames      []string
+	paths           []diff.Path
 }
 
 func (di diffIgnores) preprocess(obj *unstructured.Unstructured) {
@@ -71,21 +73,37 @@ func (di diffIgnores) preprocess(obj *unstructured.Unstructured) {
 		}
 		obj.SetAnnotations(annotations)
 	}
+	for _, p := range di.paths {
+		p.Remove(obj.Object)
+	}
 }
 
 type skipStats struct {
+	Creates   []string `json:"creates,omitempty"`
 	Updates   []string `json:"updates,omitempty"`
 	Deletions []string `json:"deletions,omitempty"`
 }
 
+// diffSummary has the number of objects that would be created, updated and deleted by an apply.
+type diffSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+func (s diffSummary) String() string {
+	return fmt.Sprintf("%d object(s) to create, %d to update, %d to delete", s.Create, s.Update, s.Delete)
+}
+
 type diffStats struct {
 	l         sync.Mutex
-	Additions []string   `json:"additions,omitempty"`
-	Changes   []string   `json:"changes,omitempty"`
-	Deletions []string   `json:"deletions,omitempty"`
-	SameCount int        `json:"same,omitempty"`
-	Errors    []string   `json:"errors,omitempty"`
-	Skipped   *skipStats `json:"skipped,omitempty"`
+	Additions []string    `json:"additions,omitempty"`
+	Changes   []string    `json:"changes,omitempty"`
+	Deletions []string    `json:"deletions,omitempty"`
+	SameCount int         `json:"same,omitempty"`
+	Errors    []string    `json:"errors,omitempty"`
+	Skipped   *skipStats  `json:"skipped,omitempty"`
+	Summary   diffSummary `json:"summary"`
 }
 
 func (d *diffStats) added(s string) {
@@ -106,6 +124,15 @@ func (d *diffStats) deleted(s string) {
 	d.Deletions = append(d.Deletions, s)
 }
 
+func (d *diffStats) skippedCreate(s string) {
+	d.l.Lock()
+	defer d.l.Unlock()
+	if d.Skipped == nil {
+		d.Skipped = &skipStats{}
+	}
+	d.Skipped.Creates = append(d.Skipped.Creates, s)
+}
+
 func (d *diffStats) skippedUpdated(s string) {
 	d.l.Lock()
 	defer d.l.Unlock()
@@ -137,30 +164,77 @@ func (d *diffStats) errors(s string) {
 }
 
 func (d *diffStats) done() {
+	d.Summary = diffSummary{Create: len(d.Additions), Update: len(d.Changes), Delete: len(d.Deletions)}
 	sort.Strings(d.Additions)
 	sort.Strings(d.Changes)
 	sort.Strings(d.Errors)
 	if d.Skipped != nil {
+		sort.Strings(d.Skipped.Creates)
 		sort.Strings(d.Skipped.Deletions)
 		sort.Strings(d.Skipped.Updates)
 	}
 }
 
+const (
+	diffFormatUnified   = "unified"
+	diffFormatJSONPatch = "jsonpatch"
+)
+
 type differ struct {
-	w           io.Writer
-	client      cmd.KubeClient
-	opts        diff.Options
-	stats       diffStats
-	ignores     diffIgnores
-	showSecrets bool
-	verbose     int
-	upPolicy    *updatePolicy
-	delPolicy   *deletePolicy
+	w            io.Writer
+	client       cmd.KubeClient
+	opts         diff.Options
+	stats        diffStats
+	ignores      diffIgnores
+	showSecrets  bool
+	verbose      int
+	upPolicy     *updatePolicy
+	delPolicy    *deletePolicy
+	jsonPatch    bool
+	serverSide   bool
+	fieldManager string
+	kubeContext  string // set when the environment targets multiple contexts
+	namesOnly    bool   // only record objects missing on either side, without diffing contents
+	summaryOnly  bool   // print a single line per changed object instead of its diff
+	pl           sync.Mutex
+	patches      map[string][]diff.PatchOperation
+
+	// saved objects keyed by local object key, diffed instead of cluster objects when set
+	baseline map[string]*unstructured.Unstructured
+}
+
+// addPatch computes and records the JSON patch for the supplied object. Either object may be nil.
+func (d *differ) addPatch(name string, left, right *unstructured.Unstructured) error {
+	var l, r interface{}
+	if left != nil {
+		l = left.Object
+	}
+	if right != nil {
+		r = right.Object
+	}
+	ops, err := diff.JSONPatch(l, r)
+	if err != nil {
+		return err
+	}
+	d.pl.Lock()
+	defer d.pl.Unlock()
+	if d.patches == nil {
+		d.patches = map[string][]diff.PatchOperation{}
+	}
+	d.patches[name] = ops
+	return nil
 }
 
 func (d *differ) names(ob model.K8sMeta) (name, leftName, rightName string) {
+	if d.baseline != nil {
+		name = localDisplayName(ob)
+		return name, "file " + name, "config " + name
+	}
 	name = d.client.DisplayName(ob)
 	leftName = "live " + name
+	if d.kubeContext != "" {
+		leftName += " [" + d.kubeContext + "]"
+	}
 	rightName = "config " + name
 	return
 }
@@ -210,14 +284,39 @@ func (d *differ) writeDiff(name string, left, right namedUn) (finalErr error) {
 			}
 			d.stats.same(name)
 		} else {
-			if d.upPolicy.disableUpdate(left.obj) {
+			if d.upPolicy.disableUpdate(left.obj) || d.upPolicy.disableUpdate(right.obj) {
 				d.stats.skippedUpdated(name)
 			} else {
-				fmt.Fprintln(d.w, string(b))
+				switch {
+				case d.jsonPatch:
+					if err := d.addPatch(name, left.obj, right.obj); err != nil {
+						return err
+					}
+				case d.summaryOnly:
+					fmt.Fprintf(d.w, "%s modified\n", name)
+				default:
+					fmt.Fprintln(d.w, string(b))
+				}
 				d.stats.changed(name)
 			}
 		}
 	case left.obj == nil:
+		if d.upPolicy.disableCreate(right.obj) {
+			d.stats.skippedCreate(name)
+			break
+		}
+		if d.jsonPatch {
+			if err := d.addPatch(name, nil, right.obj); err != nil {
+				return err
+			}
+			d.stats.added(name)
+			break
+		}
+		if d.summaryOnly {
+			fmt.Fprintf(d.w, "%s created\n", name)
+			d.stats.added(name)
+			break
+		}
 		rightContent, err := asYaml(right.obj)
 		if err != nil {
 			return err
@@ -238,6 +337,18 @@ func (d *differ) writeDiff(name string, left, right namedUn) (finalErr error) {
 			d.stats.skippedDeletion(name)
 			break
 		}
+		if d.jsonPatch {
+			if err := d.addPatch(name, left.obj, nil); err != nil {
+				return err
+			}
+			d.stats.deleted(name)
+			break
+		}
+		if d.summaryOnly {
+			fmt.Fprintf(d.w, "%s deleted\n", name)
+			d.stats.deleted(name)
+			break
+		}
 		leftContent, err := asYaml(left.obj)
 		if err != nil {
 			return err
@@ -262,7 +373,12 @@ func (d *differ) diff(ctx context.Context, ob model.K8sMeta) error {
 	var remoteObject *unstructured.Unstructured
 	var err error
 
-	if ob.GetName() != "" {
+	switch {
+	case d.baseline != nil:
+		if saved := d.baseline[localObjectKey(ob)]; saved != nil && ob.GetName() != "" {
+			remoteObject = saved.DeepCopy()
+		}
+	case ob.GetName() != "":
 		remoteObject, err = d.client.Get(ctx, ob)
 		if err != nil && err != remote.ErrNotFound && err.Error() != "server type not found" { // *sigh*
 			d.stats.errors(name)
@@ -271,6 +387,11 @@ func (d *differ) diff(ctx context.Context, ob model.K8sMeta) error {
 		}
 	}
 
+	if d.namesOnly {
+		d.recordPresence(name, ob, remoteObject)
+		return nil
+	}
+
 	fixup := func(u *unstructured.Unstructured) *unstructured.Unstructured {
 		if u == nil {
 			return u
@@ -283,6 +404,44 @@ func (d *differ) diff(ctx context.Context, ob model.K8sMeta) error {
 	}
 
 	var left, right *unstructured.Unstructured
+	if d.baseline != nil {
+		// secrets saved without showing their values are redacted differently on every run, so only their
+		// keys can be compared.
+		redacted := types.IsRedacted(remoteObject)
+		if redacted {
+			sio.Warnf("%s has redacted values in the file, comparing keys only. Save the file using --show-secrets to compare values\n", name)
+			remoteObject = types.MaskSensitiveInfo(remoteObject)
+		}
+		left = fixup(remoteObject)
+		if r, ok := ob.(model.K8sObject); ok {
+			right = r.ToUnstructured()
+			if redacted {
+				right = types.MaskSensitiveInfo(right)
+			}
+			right = fixup(right)
+		}
+		return d.writeDiff(name, namedUn{name: leftName, obj: left}, namedUn{name: rightName, obj: right})
+	}
+	if local, ok := ob.(model.K8sLocalObject); ok && d.serverSide && remoteObject != nil {
+		applied, err := d.client.ServerSideDryRun(ctx, local, d.fieldManager, false)
+		if err != nil {
+			d.stats.errors(name)
+			sio.Errorf("error in server-side dry-run for %s, %v\n", name, err)
+			return err
+		}
+		left = fixup(serverSideView(remoteObject))
+		right = fixup(serverSideView(applied))
+		return d.writeDiff(name, namedUn{name: leftName + " (source: live)", obj: left}, namedUn{name: rightName + " (source: server-side dry-run)", obj: right})
+	}
+	if local, ok := ob.(model.K8sLocalObject); ok && remoteObject != nil {
+		merged, err := remote.GetMergedVersionForDiff(remoteObject, local)
+		if err == nil {
+			left = fixup(liveView(remoteObject))
+			right = fixup(liveView(merged))
+			return d.writeDiff(name, namedUn{name: leftName + " (source: live)", obj: left}, namedUn{name: rightName + " (source: merged with live)", obj: right})
+		}
+		sio.Warnf("unable to merge %s with its live version, diffing against last applied configuration, %v\n", name, err)
+	}
 	if remoteObject != nil {
 		var source string
 		left, source = remote.GetPristineVersionForDiff(remoteObject)
@@ -296,6 +455,51 @@ func (d *differ) diff(ctx context.Context, ob model.K8sMeta) error {
 	return d.writeDiff(name, namedUn{name: leftName, obj: left}, namedUn{name: rightName, obj: right})
 }
 
+// recordPresence records local objects missing on the server as additions and remote objects missing locally as
+// deletions, without comparing the contents of objects that exist on both sides.
+func (d *differ) recordPresence(name string, ob model.K8sMeta, remoteObject *unstructured.Unstructured) {
+	if _, ok := ob.(model.K8sLocalObject); ok {
+		if remoteObject == nil {
+			d.stats.added(name)
+		} else {
+			d.stats.same(name)
+		}
+		return
+	}
+	if remoteObject == nil {
+		return
+	}
+	if d.delPolicy.disableDelete(model.NewK8sObject(remoteObject.Object)) {
+		d.stats.skippedDeletion(name)
+		return
+	}
+	d.stats.deleted(name)
+}
+
+// serverSideView returns a copy of the supplied server object without metadata that is updated by the server on
+// every write and without the pristine annotation, which is irrelevant for server-side apply.
+func serverSideView(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	ret := remote.WithoutServerFields(obj)
+	unstructured.RemoveNestedField(ret.Object, "metadata", "annotations", model.QbecNames.PristineAnnotation)
+	return ret
+}
+
+// liveView returns a copy of the supplied server object without runtime information and status, such that
+// it can be diffed against the result of merging a local object into it.
+func liveView(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	ret := serverSideView(obj)
+	unstructured.RemoveNestedField(ret.Object, "metadata", "creationTimestamp")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "deletionTimestamp")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "selfLink")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "uid")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "annotations", "deployment.kubernetes.io/revision")
+	unstructured.RemoveNestedField(ret.Object, "status")
+	if len(ret.GetAnnotations()) == 0 {
+		unstructured.RemoveNestedField(ret.Object, "metadata", "annotations")
+	}
+	return ret
+}
+
 // diffLocal adapts the diff method to run as a parallel worker.
 func (d *differ) diffLocal(ctx context.Context, ob model.K8sLocalObject) error {
 	return d.diff(ctx, ob)
@@ -308,8 +512,21 @@ type diffCommandConfig struct {
 	parallel      int
 	contextLines  int
 	di            diffIgnores
+	ignorePaths   []string
 	filterFunc    func() (model.Filters, error)
+	nsFunc        func() (namespaceOverride, error)
 	exitNonZero   bool
+	exitCode      bool
+	format        string
+	serverSide    bool
+	fieldManager  string
+	timings       bool
+	liveMissing   bool
+	summaryOnly   bool
+	againstFile   string
+	reportFile    string
+	reportFormat  string
+	reportOnly    bool
 }
 
 func doDiff(ctx context.Context, args []string, config diffCommandConfig) error {
@@ -321,81 +538,155 @@ func doDiff(ctx context.Context, args []string, config diffCommandConfig) error
 	if env == model.Baseline {
 		return cmd.NewUsageError("cannot diff baseline environment, use a real environment")
 	}
-	fp, err := config.filterFunc()
+	switch config.format {
+	case diffFormatUnified, diffFormatJSONPatch:
+	default:
+		return cmd.NewUsageError(fmt.Sprintf("invalid diff format %q, must be one of %s or %s", config.format, diffFormatUnified, diffFormatJSONPatch))
+	}
+	if config.liveMissing && config.format != diffFormatUnified {
+		return cmd.NewUsageError("--only-live-missing cannot be used with --format")
+	}
+	if config.liveMissing && config.serverSide {
+		return cmd.NewUsageError("--only-live-missing cannot be used with --server-side")
+	}
+	if config.summaryOnly && config.format != diffFormatUnified {
+		return cmd.NewUsageError("--summary-only cannot be used with --format")
+	}
+	if config.summaryOnly && config.liveMissing {
+		return cmd.NewUsageError("--summary-only cannot be used with --only-live-missing")
+	}
+	if config.againstFile != "" && config.serverSide {
+		return cmd.NewUsageError("--against-file cannot be used with --server-side")
+	}
+	if config.contextLines < 0 {
+		return cmd.NewUsageError(fmt.Sprintf("invalid context lines %d, must not be negative", config.contextLines))
+	}
+	report, err := newDiffReport(config.reportFile, config.reportFormat, config.reportOnly)
 	if err != nil {
 		return err
 	}
-
-	envCtx, err := config.EnvContext(env)
+	for _, expr := range config.App().DiffIgnorePaths() {
+		p, err := diff.ParsePath(expr)
+		if err != nil {
+			return fmt.Errorf("qbec.yaml: diffIgnorePaths: %v", err)
+		}
+		config.di.paths = append(config.di.paths, p)
+	}
+	for _, expr := range config.ignorePaths {
+		p, err := diff.ParsePath(expr)
+		if err != nil {
+			return cmd.NewUsageError(err.Error())
+		}
+		config.di.paths = append(config.di.paths, p)
+	}
+	fp, err := config.filterFunc()
 	if err != nil {
 		return err
 	}
-
-	client, err := envCtx.Client()
+	nso, err := config.nsFunc()
 	if err != nil {
 		return err
 	}
 
-	objects, err := generateObjects(ctx, envCtx, makeFilterOpts(fp, client))
+	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
 	}
-
-	var lister lister = &stubLister{}
-	var retainObjects []model.K8sLocalObject
-	if config.showDeletions {
-		lister, retainObjects, err = startRemoteList(ctx, envCtx, client, fp)
+	// a saved manifest is diffed as if it were the only target, without connecting to any cluster
+	targets := []cmd.TargetClient{{}}
+	if config.againstFile == "" {
+		targets, err = envCtx.Clients()
 		if err != nil {
 			return err
 		}
 	}
 
-	objects = objsort.Sort(objects, sortConfig(client.IsNamespaced))
-
-	// since the 0 value of context is turned to 3 by the diff library,
-	// special case to turn 0 into a negative number so that zero means zero.
-	if config.contextLines == 0 {
-		config.contextLines = -1
+	// components are evaluated once for all targets, objects of all components are needed to find deletions
+	var rendered *renderedEnv
+	if config.againstFile == "" {
+		rendered, err = renderEnv(envCtx, fp, config.timings, config.showDeletions)
+		if err != nil {
+			return err
+		}
 	}
-	opts := diff.Options{Context: config.contextLines, Colorize: config.Colorize()}
 
-	w := &lockWriter{Writer: config.Stdout()}
-	d := &differ{
-		w:           w,
-		client:      client,
-		opts:        opts,
-		ignores:     config.di,
-		showSecrets: config.showSecrets,
-		verbose:     config.Verbosity(),
-		upPolicy:    newUpdatePolicy(),
-		delPolicy:   newDeletePolicy(client.IsNamespaced, config.App().DefaultNamespace(env)),
-	}
-	dErr := runInParallel(ctx, objects, d.diffLocal, config.parallel)
-
-	var listErr error
-	if dErr == nil {
-		extra, err := lister.deletions(retainObjects, fp.Match)
+	var stdout io.Writer = config.Stdout()
+	if report != nil {
+		stdout, err = report.open(stdout)
 		if err != nil {
-			listErr = err
+			return err
+		}
+		defer report.f.Close()
+	}
+	w := &lockWriter{Writer: stdout}
+	numDiffs := 0
+	var firstErr error
+	patches := map[string]map[string][]diff.PatchOperation{}
+	for _, t := range targets {
+		if t.Context != "" {
+			sio.Noticef("diff context %s\n", t.Context)
+		}
+		var out *diffOutcome
+		if config.againstFile != "" {
+			out, err = diffFile(ctx, envCtx, fp, nso, w, config)
 		} else {
-			for _, ob := range extra {
-				if err := d.diff(ctx, ob); err != nil {
-					return err
-				}
+			out, err = diffTarget(ctx, envCtx, t, rendered, fp, nso, w, config)
+		}
+		if err != nil {
+			return err
+		}
+		d, dErr, listErr := out.d, out.diffErr, out.listErr
+		d.stats.done()
+		if report != nil {
+			report.add(t.Context, &d.stats)
+		}
+		if d.jsonPatch {
+			p := d.patches
+			if p == nil {
+				p = map[string][]diff.PatchOperation{}
+			}
+			patches[t.Context] = p
+		} else {
+			printStats(d.w, &d.stats)
+		}
+		numDiffs += len(d.stats.Additions) + len(d.stats.Changes) + len(d.stats.Deletions)
+		if dErr == nil && listErr == nil {
+			sio.Noticeln(d.stats.Summary)
+		}
+		if firstErr == nil {
+			if dErr != nil {
+				firstErr = dErr
+			} else {
+				firstErr = listErr
 			}
 		}
 	}
-
-	d.stats.done()
-	printStats(d.w, &d.stats)
-	numDiffs := len(d.stats.Additions) + len(d.stats.Changes) + len(d.stats.Deletions)
+	if config.format == diffFormatJSONPatch {
+		// the patch document is the only output on stdout so that it can be consumed by other tools.
+		// Environments that target multiple contexts produce a document keyed by context.
+		var doc interface{} = patches
+		if len(targets) == 1 {
+			doc = patches[targets[0].Context]
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+	}
+	if report != nil {
+		if err := report.close(len(targets) == 1); err != nil {
+			return err
+		}
+	}
 
 	switch {
-	case dErr != nil:
-		return dErr
-	case listErr != nil:
-		return listErr
+	case firstErr != nil:
+		return firstErr
 	case numDiffs > 0:
+		if config.exitCode {
+			return cmd.NewDiffError(fmt.Sprintf("%d object(s) different", numDiffs))
+		}
 		if config.exitNonZero {
 			return fmt.Errorf("%d object(s) different", numDiffs)
 		}
@@ -406,6 +697,82 @@ func doDiff(ctx context.Context, args []string, config diffCommandConfig) error
 	}
 }
 
+// diffOutcome is the outcome of diffing an environment against the cluster of a single target.
+type diffOutcome struct {
+	d       *differ // the differ holding stats and patches
+	diffErr error   // error diffing local objects
+	listErr error   // error listing remote objects for deletions
+}
+
+// diffTarget diffs the objects of the environment against the cluster of the supplied target. It returns an error
+// when it fails before any diffs are computed.
+func diffTarget(ctx context.Context, envCtx cmd.EnvContext, target cmd.TargetClient, rendered *renderedEnv, fp model.Filters,
+	nso namespaceOverride, w io.Writer, config diffCommandConfig) (*diffOutcome, error) {
+	client := target.Client
+	fo := makeFilterOpts(fp, client)
+	fo.nsOverride = nso
+	objects, err := filterObjects(envCtx, rendered.selected, fo)
+	if err != nil {
+		return nil, err
+	}
+
+	var lister lister = &stubLister{}
+	var retainObjects []model.K8sLocalObject
+	if config.showDeletions {
+		lister, retainObjects, err = startRemoteList(ctx, envCtx, client, rendered.all, fp, nso)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	objects = objsort.Sort(objects, sortConfig(client.IsNamespaced, config.App().ApplyOrder()))
+
+	d := newDiffer(envCtx, target, w, config)
+	out := &diffOutcome{d: d}
+	out.diffErr = runInParallel(ctx, objects, d.diffLocal, config.parallel)
+	if out.diffErr == nil {
+		extra, err := lister.deletions(retainObjects, fp.Match)
+		if err != nil {
+			out.listErr = err
+		} else {
+			for _, ob := range extra {
+				if err := d.diff(ctx, ob); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// newDiffer returns a differ that diffs local objects of the environment against the cluster of the supplied target.
+func newDiffer(envCtx cmd.EnvContext, target cmd.TargetClient, w io.Writer, config diffCommandConfig) *differ {
+	client := target.Client
+	// since the 0 value of context is turned to 3 by the diff library,
+	// special case to turn 0 into a negative number so that zero means zero.
+	if config.contextLines == 0 {
+		config.contextLines = -1
+	}
+	opts := diff.Options{Context: config.contextLines, Colorize: config.Colorize()}
+
+	return &differ{
+		w:            w,
+		client:       client,
+		opts:         opts,
+		ignores:      config.di,
+		showSecrets:  config.showSecrets,
+		verbose:      config.Verbosity(),
+		upPolicy:     newUpdatePolicy(),
+		delPolicy:    newPrunePolicy(client.IsNamespaced, config.App().DefaultNamespace(envCtx.Env())),
+		jsonPatch:    config.format == diffFormatJSONPatch,
+		serverSide:   config.serverSide,
+		fieldManager: config.fieldManager,
+		kubeContext:  target.Context,
+		namesOnly:    config.liveMissing,
+		summaryOnly:  config.summaryOnly,
+	}
+}
+
 func newDiffCommand(cp ctxProvider) *cobra.Command {
 	c := &cobra.Command{
 		Use:     "diff <environment>",
@@ -415,17 +782,32 @@ func newDiffCommand(cp ctxProvider) *cobra.Command {
 
 	config := diffCommandConfig{
 		filterFunc: addFilterParams(c, true),
+		nsFunc:     addNamespaceOverrideParams(c),
 	}
 
 	c.Flags().BoolVar(&config.showDeletions, "show-deletes", true, "include deletions in diff")
+	c.Flags().IntVar(&config.contextLines, "context-lines", 3, "number of unchanged lines to show around changes, 0 to only show changes")
 	c.Flags().IntVar(&config.contextLines, "context", 3, "context lines for diff")
+	_ = c.Flags().MarkDeprecated("context", "use --context-lines instead")
 	c.Flags().IntVar(&config.parallel, "parallel", 5, "number of parallel routines to run")
 	c.Flags().BoolVarP(&config.showSecrets, "show-secrets", "S", false, "do not obfuscate secret values in the diff")
 	c.Flags().BoolVar(&config.di.allAnnotations, "ignore-all-annotations", false, "remove all annotations from objects before diff")
 	c.Flags().StringArrayVar(&config.di.annotationNames, "ignore-annotation", nil, "remove specific annotation from objects before diff")
 	c.Flags().BoolVar(&config.di.allLabels, "ignore-all-labels", false, "remove all labels from objects before diff")
 	c.Flags().StringArrayVar(&config.di.labelNames, "ignore-label", nil, "remove specific label from objects before diff")
+	c.Flags().StringArrayVar(&config.ignorePaths, "ignore-paths", nil, "remove fields at this JSONPath-like path, e.g. status or metadata.annotations['a.b/c'], from objects before diff")
 	c.Flags().BoolVar(&config.exitNonZero, "error-exit", false, "exit with non-zero status code when diffs present")
+	c.Flags().BoolVar(&config.exitCode, "exit-code", false, fmt.Sprintf("exit with status code %d when diffs present, distinct from the status code for failures", cmd.DiffExitCode))
+	c.Flags().StringVar(&config.format, "format", diffFormatUnified, "diff output format, one of unified or jsonpatch")
+	c.Flags().BoolVar(&config.timings, "timings", false, "print the evaluation time of every component to stderr")
+	c.Flags().BoolVar(&config.serverSide, "server-side", false, "diff live objects against the result of a server-side apply dry-run")
+	c.Flags().StringVar(&config.fieldManager, "field-manager", remote.DefaultFieldManager, "field manager name to use for server-side dry-runs")
+	c.Flags().BoolVar(&config.summaryOnly, "summary-only", false, "print one line per created, modified or deleted object instead of content diffs")
+	c.Flags().StringVar(&config.againstFile, "against-file", "", "diff against the objects in this YAML or JSON manifest, such as saved show output, instead of the cluster")
+	c.Flags().StringVar(&config.reportFile, "report-file", "", "also write the diff to this file, for example to archive it as a build artifact")
+	c.Flags().StringVar(&config.reportFormat, "report-format", reportFormatDiff, "format of the report file, one of diff, for the output without colors, or json, for the stats")
+	c.Flags().BoolVar(&config.reportOnly, "report-only", false, "only write the diff to the report file, not to standard output")
+	c.Flags().BoolVar(&config.liveMissing, "only-live-missing", false, "only list objects that do not exist on the server, and extra server objects when deletes are shown, without diffing contents")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -17,14 +17,23 @@
 package commands
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/remote"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestDiffBasicNoDiffs(t *testing.T) {
@@ -34,6 +43,240 @@ func TestDiffBasicNoDiffs(t *testing.T) {
 	s.client.getFunc = d.get
 	err := s.executeCommand("diff", "dev", "-k", "configmaps", "--ignore-all-annotations", "--ignore-all-labels", "--show-deletes=false")
 	require.NoError(t, err)
+	s.assertErrorLineMatch(regexp.MustCompile(`^0 object\(s\) to create, 0 to update, 0 to delete$`))
+}
+
+func TestDiffExitCode(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	err := s.executeCommand("diff", "dev", "--exit-code")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsDiffError(err))
+	a.Regexp(`^\d+ object\(s\) different$`, err.Error())
+}
+
+func TestDiffExitCodeNoDiffs(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "bar"}
+	s.client.getFunc = d.get
+	err := s.executeCommand("diff", "dev", "-k", "configmaps", "--ignore-all-annotations", "--ignore-all-labels", "--show-deletes=false", "--exit-code")
+	require.NoError(t, err)
+}
+
+func TestDiffContextLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		present  []string
+		excluded []string
+	}{
+		{name: "default", present: []string{`^ data:`, `^ apiVersion: v1`, `^ metadata:`}},
+		{name: "one", args: []string{"--context-lines=1"}, present: []string{`^ data:`, `^ kind: ConfigMap`}, excluded: []string{`^ apiVersion: v1`, `^ metadata:`}},
+		{name: "none", args: []string{"--context-lines=0"}, excluded: []string{`^ data:`, `^ kind: ConfigMap`}},
+		{name: "deprecated", args: []string{"--context=0"}, excluded: []string{`^ data:`, `^ kind: ConfigMap`}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			d := &dg{cmValue: "baz"}
+			s.client.getFunc = d.get
+			args := append([]string{"diff", "dev", "-k", "configmaps", "-c", "service2", "--ignore-all-annotations", "--ignore-all-labels", "--show-deletes=false"}, test.args...)
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			s.assertOutputLineMatch(regexp.MustCompile(`^\+\s+foo: bar`))
+			for _, p := range test.present {
+				s.assertOutputLineMatch(regexp.MustCompile(p))
+			}
+			for _, p := range test.excluded {
+				s.assertOutputLineNoMatch(regexp.MustCompile(p))
+			}
+		})
+	}
+}
+
+func TestDiffServerSide(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "bar"}
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		u, err := d.get(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		_ = unstructured.SetNestedField(u.Object, "100", "metadata", "resourceVersion")
+		return u, nil
+	}
+	var fieldManager string
+	s.client.ssaFunc = func(ctx context.Context, obj model.K8sLocalObject, fm string, force bool) (*unstructured.Unstructured, error) {
+		fieldManager = fm
+		u, err := d.get(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		_ = unstructured.SetNestedField(u.Object, "101", "metadata", "resourceVersion")
+		_ = unstructured.SetNestedField(u.Object, "defaulted", "data", "extra")
+		return u, nil
+	}
+	err := s.executeCommand("diff", "dev", "-k", "configmaps", "--show-deletes=false", "--server-side", "--field-manager=ci")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal("ci", fieldManager)
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["changes"])
+	s.assertOutputLineMatch(regexp.MustCompile(`\+\+\+ config ConfigMap:bar-system:svc2-cm \(source: server-side dry-run\)`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\+\s+extra: defaulted`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`resourceVersion`))
+}
+
+func TestDiffServerDefaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		image   string
+		changes []interface{}
+	}{
+		{name: "same", image: "nginx:latest"},
+		{name: "different", image: "nginx:1.19", changes: []interface{}{"Deployment:bar-system:svc2-deploy"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+				if obj.GetName() != "svc2-deploy" {
+					return nil, remote.ErrNotFound
+				}
+				return &unstructured.Unstructured{
+					Object: map[string]interface{}{
+						"apiVersion": "apps/v1",
+						"kind":       "Deployment",
+						"metadata": map[string]interface{}{
+							"creationTimestamp": "xxx",
+							"namespace":         "bar-system",
+							"name":              "svc2-deploy",
+							"uid":               "1234",
+							"resourceVersion":   "100",
+						},
+						"spec": map[string]interface{}{
+							"progressDeadlineSeconds": int64(600),
+							"replicas":                int64(1),
+							"revisionHistoryLimit":    int64(10),
+							"selector": map[string]interface{}{
+								"matchLabels": map[string]interface{}{"app": "svc2-deploy"},
+							},
+							"template": map[string]interface{}{
+								"metadata": map[string]interface{}{
+									"labels": map[string]interface{}{"app": "svc2-deploy"},
+								},
+								"spec": map[string]interface{}{
+									"containers": []interface{}{
+										map[string]interface{}{
+											"name":                     "main",
+											"image":                    test.image,
+											"imagePullPolicy":          "Always",
+											"terminationMessagePath":   "/dev/termination-log",
+											"terminationMessagePolicy": "File",
+										},
+									},
+									"dnsPolicy":     "ClusterFirst",
+									"restartPolicy": "Always",
+								},
+							},
+						},
+						"status": map[string]interface{}{
+							"replicas": int64(1),
+						},
+					},
+				}, nil
+			}
+			err := s.executeCommand("diff", "dev", "-k", "deployments", "-c", "service2", "--ignore-all-annotations", "--ignore-all-labels", "--show-deletes=false")
+			a := assert.New(t)
+			stats := s.outputStats()
+			require.NoError(t, err)
+			if test.changes == nil {
+				a.Nil(stats["changes"])
+				return
+			}
+			a.EqualValues(test.changes, stats["changes"])
+			s.assertOutputLineMatch(regexp.MustCompile(`^\+\+\+ config Deployment:bar-system:svc2-deploy \(source: merged with live\)`))
+			s.assertOutputLineMatch(regexp.MustCompile(`^-\s+- image: nginx:1.19`))
+			s.assertOutputLineMatch(regexp.MustCompile(`^\+\s+- image: nginx:latest`))
+			s.assertOutputLineMatch(regexp.MustCompile(`^\s+imagePullPolicy: Always`))
+			s.assertOutputLineNoMatch(regexp.MustCompile(`^[-+]\s+(progressDeadlineSeconds|replicas|status)`))
+		})
+	}
+}
+
+func TestDiffUpdatePolicy(t *testing.T) {
+	secret := func(policy string, value string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata": map[string]interface{}{
+				"namespace": "bar-system",
+				"name":      "password",
+				"annotations": map[string]interface{}{
+					"directives.qbec.io/update-policy": policy,
+				},
+			},
+			"data": map[string]interface{}{"password": value},
+		}}
+	}
+	tests := []struct {
+		name     string
+		left     *unstructured.Unstructured
+		right    *unstructured.Unstructured
+		asserter func(t *testing.T, stats *diffStats)
+	}{
+		{
+			name:  "create-only-create",
+			right: secret("create-only", "Zm9v"),
+			asserter: func(t *testing.T, stats *diffStats) {
+				assert.Equal(t, []string{"password"}, stats.Additions)
+				assert.Nil(t, stats.Skipped)
+			},
+		},
+		{
+			name:  "create-only-update",
+			left:  secret("default", "YmFy"),
+			right: secret("create-only", "Zm9v"),
+			asserter: func(t *testing.T, stats *diffStats) {
+				assert.Nil(t, stats.Changes)
+				assert.Equal(t, []string{"password"}, stats.Skipped.Updates)
+			},
+		},
+		{
+			name:  "never-create",
+			right: secret("never", "Zm9v"),
+			asserter: func(t *testing.T, stats *diffStats) {
+				assert.Nil(t, stats.Additions)
+				assert.Equal(t, []string{"password"}, stats.Skipped.Creates)
+			},
+		},
+		{
+			name:  "default-update",
+			left:  secret("default", "YmFy"),
+			right: secret("default", "Zm9v"),
+			asserter: func(t *testing.T, stats *diffStats) {
+				assert.Equal(t, []string{"password"}, stats.Changes)
+				assert.Nil(t, stats.Skipped)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var b bytes.Buffer
+			d := &differ{w: &b, upPolicy: newUpdatePolicy()}
+			err := d.writeDiff("password", namedUn{name: "live", obj: test.left}, namedUn{name: "config", obj: test.right})
+			require.NoError(t, err)
+			test.asserter(t, &d.stats)
+		})
+	}
 }
 
 func testDiffBasic(t *testing.T, errorExit bool) {
@@ -58,6 +301,8 @@ func testDiffBasic(t *testing.T, errorExit bool) {
 	adds, ok := stats["additions"].([]interface{})
 	require.True(t, ok)
 	a.Contains(adds, "Job::tj-<xxxxx>")
+	a.EqualValues(map[string]interface{}{"create": float64(len(adds)), "update": float64(2), "delete": float64(1)}, stats["summary"])
+	s.assertErrorLineMatch(regexp.MustCompile(fmt.Sprintf(`^%d object\(s\) to create, 2 to update, 1 to delete$`, len(adds))))
 	secretValue := base64.StdEncoding.EncodeToString([]byte("baz"))
 	redactedValue := base64.RawStdEncoding.EncodeToString([]byte("redacted."))
 	a.Contains(s.stdout(), redactedValue)
@@ -73,6 +318,289 @@ func TestDiffBasicNoErrorExit(t *testing.T) {
 	testDiffBasic(t, false)
 }
 
+func TestDiffNoPrune(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		ret, err := d.get(ctx, obj)
+		if err == nil && obj.GetName() == "svc2-previous-deploy" {
+			ret.SetAnnotations(map[string]string{"directives.qbec.io/no-prune": "true"})
+		}
+		return ret, err
+	}
+	s.client.listFunc = stdLister
+	err := s.executeCommand("diff", "dev", "--error-exit=false")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.Nil(stats["deletions"])
+	a.EqualValues(map[string]interface{}{"deletions": []interface{}{"Deployment:bar-system:svc2-previous-deploy"}}, stats["skipped"])
+}
+
+func TestDiffOnlyLiveMissing(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	err := s.executeCommand("diff", "dev", "--only-live-missing")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.Nil(stats["changes"])
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deletions"])
+	adds, ok := stats["additions"].([]interface{})
+	require.True(t, ok)
+	a.Contains(adds, "Job::tj-<xxxxx>")
+	a.NotContains(adds, "ConfigMap:bar-system:svc2-cm")
+	a.EqualValues(2, stats["same"])
+	a.NotContains(s.stdout(), "object doesn't exist")
+	a.NotContains(s.stdout(), "foo: baz")
+}
+
+func TestDiffSummaryOnly(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	err := s.executeCommand("diff", "dev", "--summary-only")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm", "Secret:bar-system:svc2-secret"}, stats["changes"])
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deletions"])
+	s.assertOutputLineMatch(regexp.MustCompile(`^ConfigMap:bar-system:svc2-cm modified$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^Secret:bar-system:svc2-secret modified$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^Job::tj-<xxxxx> created$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^Deployment:bar-system:svc2-previous-deploy deleted$`))
+	a.NotContains(s.stdout(), "object doesn't exist")
+	a.NotContains(s.stdout(), "foo: baz")
+	a.NotContains(s.stdout(), "@@")
+}
+
+func TestDiffSummaryOnlyNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "format", args: []string{"--format=jsonpatch"}, msg: "--summary-only cannot be used with --format"},
+		{name: "live-missing", args: []string{"--only-live-missing"}, msg: "--summary-only cannot be used with --only-live-missing"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"diff", "dev", "--summary-only"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
+
+func TestDiffReportFile(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	file := filepath.Join(t.TempDir(), "diff.txt")
+	err := s.executeCommand("diff", "dev", "--report-file", file)
+	require.NoError(t, err)
+	b, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal(s.stdout(), string(b))
+	a.Contains(string(b), "svc2-cm")
+}
+
+func TestDiffReportFileJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	file := filepath.Join(t.TempDir(), "diff.json")
+	err := s.executeCommand("diff", "dev", "--report-file", file, "--report-format=json", "--report-only")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal("", s.stdout())
+	b, err := ioutil.ReadFile(file)
+	require.NoError(t, err)
+	var stats map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &stats))
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm", "Secret:bar-system:svc2-secret"}, stats["changes"])
+	a.EqualValues([]interface{}{"Deployment:bar-system:svc2-previous-deploy"}, stats["deletions"])
+}
+
+func TestDiffReportFileNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "format", args: []string{"--report-file=diff.txt", "--report-format=yaml"}, msg: `invalid report format "yaml", must be one of diff or json`},
+		{name: "only", args: []string{"--report-only"}, msg: "--report-only requires --report-file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"diff", "dev"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
+
+func TestEscapeStripper(t *testing.T) {
+	var buf bytes.Buffer
+	e := &escapeStripper{w: &buf}
+	n, err := e.Write([]byte("\x1b[31m- foo\x1b[0m\n\x1b[1;32m+ bar\x1b[0m\n"))
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal(32, n)
+	a.Equal("- foo\n+ bar\n", buf.String())
+}
+
+func TestDiffAgainstFile(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/diff-file")
+	defer s.reset()
+	s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+		t.Errorf("unexpected get of %s", obj.GetName())
+		return nil, remote.ErrNotFound
+	}
+	err := s.executeCommand("diff", "dev", "--against-file=baseline.yaml", "--error-exit")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("3 object(s) different", err.Error())
+	stats := s.outputStats()
+	a.EqualValues([]interface{}{"configmap added (source config)"}, stats["additions"])
+	a.EqualValues([]interface{}{"configmap changed (source config)"}, stats["changes"])
+	a.EqualValues([]interface{}{"configmap removed (source config)"}, stats["deletions"])
+	a.EqualValues(2, stats["same"])
+	s.assertOutputLineMatch(regexp.MustCompile(`--- file configmap changed \(source config\)`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^-\s+foo: old`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\+\s+foo: new`))
+	s.assertErrorLineMatch(regexp.MustCompile(`secret creds \(source config\) has redacted values in the file, comparing keys only`))
+}
+
+func TestDiffAgainstFileFilters(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/diff-file")
+	defer s.reset()
+	err := s.executeCommand("diff", "dev", "--against-file=baseline.yaml", "--show-deletes=false", "--summary-only")
+	require.NoError(t, err)
+	stats := s.outputStats()
+	a := assert.New(t)
+	a.Nil(stats["deletions"])
+	s.assertOutputLineMatch(regexp.MustCompile(`configmap changed \(source config\) modified`))
+	s.assertOutputLineMatch(regexp.MustCompile(`configmap added \(source config\) created`))
+}
+
+func TestDiffAgainstFileNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		asserter func(a *assert.Assertions, err error)
+	}{
+		{
+			name: "server-side",
+			args: []string{"--against-file=baseline.yaml", "--server-side"},
+			asserter: func(a *assert.Assertions, err error) {
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--against-file cannot be used with --server-side", err.Error())
+			},
+		},
+		{
+			name: "missing",
+			args: []string{"--against-file=missing.yaml"},
+			asserter: func(a *assert.Assertions, err error) {
+				a.Contains(err.Error(), "open missing.yaml")
+			},
+		},
+		{
+			name: "duplicate",
+			args: []string{"--against-file=bad.yaml"},
+			asserter: func(a *assert.Assertions, err error) {
+				a.Equal("read manifest bad.yaml: duplicate object configmap dup", err.Error())
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newCustomScaffold(t, "testdata/projects/diff-file")
+			defer s.reset()
+			err := s.executeCommand(append([]string{"diff", "dev"}, test.args...)...)
+			require.Error(t, err)
+			test.asserter(assert.New(t), err)
+		})
+	}
+}
+
+func TestReadManifest(t *testing.T) {
+	objs, err := readManifest(strings.NewReader(`[{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a"}}, {"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "b"}}]`))
+	require.NoError(t, err)
+	require.Len(t, objs, 2)
+	assert.Equal(t, "Secret", objs[1].GetKind())
+
+	_, err = readManifest(strings.NewReader("---\nfoo: bar\n"))
+	require.Error(t, err)
+	assert.Equal(t, "document at index 0: object without a kind and apiVersion", err.Error())
+
+	_, err = readManifest(strings.NewReader("---\n- 1\n"))
+	require.Error(t, err)
+	assert.Equal(t, "document at index 0: unexpected type float64, not a Kubernetes object", err.Error())
+}
+
+func TestDiffOnlyLiveMissingNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "format", args: []string{"--format=jsonpatch"}, msg: "--only-live-missing cannot be used with --format"},
+		{name: "server-side", args: []string{"--server-side"}, msg: "--only-live-missing cannot be used with --server-side"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"diff", "dev", "--only-live-missing"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
+
+func TestDiffJSONPatch(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	d := &dg{cmValue: "baz", secretValue: "baz"}
+	s.client.getFunc = d.get
+	s.client.listFunc = stdLister
+	err := s.executeCommand("diff", "dev", "--format=jsonpatch")
+	require.NoError(t, err)
+	var out map[string][]map[string]interface{}
+	err = s.jsonOutput(&out)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Contains(out, "ConfigMap:bar-system:svc2-cm")
+	a.Contains(out, "Secret:bar-system:svc2-secret")
+	a.Contains(out, "Job::tj-<xxxxx>")
+	require.Contains(t, out, "Deployment:bar-system:svc2-previous-deploy")
+	a.EqualValues([]map[string]interface{}{{"op": "remove", "path": ""}}, out["Deployment:bar-system:svc2-previous-deploy"])
+	a.Equal("add", out["Job::tj-<xxxxx>"][0]["op"])
+	a.NotContains(s.stdout(), "stats:")
+}
+
 func TestDiffGetFail(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -213,6 +741,24 @@ func TestDiffNegative(t *testing.T) {
 				a.Equal(`cannot include as well as exclude kinds, specify one or the other`, err.Error())
 			},
 		},
+		{
+			name: "bad format",
+			args: []string{"diff", "dev", "--format", "xml"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid diff format "xml", must be one of unified or jsonpatch`, err.Error())
+			},
+		},
+		{
+			name: "negative context lines",
+			args: []string{"diff", "dev", "--context-lines=-1"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid context lines -1, must not be negative`, err.Error())
+			},
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -225,3 +771,119 @@ func TestDiffNegative(t *testing.T) {
 	}
 
 }
+
+func TestDiffMultiContext(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	for _, name := range []string{"primary", "dr"} {
+		c := s.contextClient(name)
+		c.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+			return nil, remote.ErrNotFound
+		}
+		c.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+			return &coll{}, nil
+		}
+	}
+	err := s.executeCommand("diff", "prod", "--exit-code")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsDiffError(err))
+	a.Equal("2 object(s) different", err.Error())
+	s.assertOutputLineMatch(regexp.MustCompile(`^--- live ConfigMap::app-cm \[primary\]`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^--- live ConfigMap::app-cm \[dr\]`))
+	s.assertErrorLineMatch(regexp.MustCompile(`diff context primary`))
+	s.assertErrorLineMatch(regexp.MustCompile(`diff context dr`))
+}
+
+func TestDiffMultiContextEvalOnce(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	for _, name := range []string{"primary", "dr"} {
+		c := s.contextClient(name)
+		c.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+			return nil, remote.ErrNotFound
+		}
+		c.listFunc = func(ctx context.Context, q remote.ListQueryConfig) (remote.Collection, error) {
+			return &coll{}, nil
+		}
+	}
+	err := s.executeCommand("diff", "prod", "--timings")
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(s.stderr(), "COMPONENT  TIME"))
+}
+
+func TestDiffMultiContextJSONPatch(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/multi-context")
+	defer s.reset()
+	for _, name := range []string{"primary", "dr"} {
+		c := s.contextClient(name)
+		c.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+			return nil, remote.ErrNotFound
+		}
+	}
+	err := s.executeCommand("diff", "prod", "--format=jsonpatch", "--show-deletes=false")
+	require.NoError(t, err)
+	var out map[string]map[string][]map[string]interface{}
+	err = s.jsonOutput(&out)
+	require.NoError(t, err)
+	a := assert.New(t)
+	require.Contains(t, out, "primary")
+	require.Contains(t, out, "dr")
+	a.Equal("add", out["primary"]["ConfigMap::app-cm"][0]["op"])
+	a.Equal("add", out["dr"]["ConfigMap::app-cm"][0]["op"])
+}
+
+func TestDiffIgnorePaths(t *testing.T) {
+	remoteCM := func(foo string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name": "app-cm",
+			},
+			"data": map[string]interface{}{
+				"foo":       foo,
+				"generated": "by-controller",
+			},
+		}}
+	}
+	tests := []struct {
+		name    string
+		foo     string
+		args    []string
+		changed bool
+	}{
+		{name: "qbec-yaml", foo: "bar"},
+		{name: "changed", foo: "baz", changed: true},
+		{name: "flag", foo: "baz", args: []string{"--ignore-paths", "$.data.foo"}},
+		{name: "flag-bracket", foo: "baz", args: []string{"--ignore-paths", "data['foo']"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newCustomScaffold(t, "testdata/projects/diff-ignores")
+			defer s.reset()
+			s.client.getFunc = func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error) {
+				return remoteCM(test.foo), nil
+			}
+			args := append([]string{"diff", "dev", "--ignore-all-annotations", "--ignore-all-labels", "--show-deletes=false"}, test.args...)
+			err := s.executeCommand(args...)
+			require.NoError(t, err)
+			if test.changed {
+				s.assertOutputLineMatch(regexp.MustCompile(`^\+\s+foo: bar`))
+				s.assertOutputLineNoMatch(regexp.MustCompile(`generated`))
+				return
+			}
+			s.assertErrorLineMatch(regexp.MustCompile(`^0 object\(s\) to create, 0 to update, 0 to delete$`))
+		})
+	}
+}
+
+func TestDiffIgnorePathsBad(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("diff", "dev", "--ignore-paths", "data[foo]")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal(`invalid path "data[foo]": invalid index "foo", must be a non-negative number, '*' or a quoted field name`, err.Error())
+}
@@ -17,7 +17,9 @@
 package commands
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/splunk/qbec/internal/model"
@@ -26,8 +28,9 @@ import (
 )
 
 const (
-	policyNever   = "never"
-	policyDefault = "default"
+	policyNever      = "never"
+	policyDefault    = "default"
+	policyCreateOnly = "create-only"
 )
 
 // isSet return true if the annotation name specified as directive is equal to the supplied value.
@@ -58,10 +61,35 @@ func isSet(ob model.K8sMeta, directive, value string, otherAllowedValues []strin
 	return false
 }
 
+// policyValue returns the value of the annotation name specified as directive if it is one of the allowed values,
+// and the default policy otherwise. A warning is printed if the annotation exists but has some other value.
+func policyValue(ob model.K8sMeta, directive string, allowedValues []string) string {
+	v := ob.GetAnnotations()[directive]
+	if v == "" || v == policyDefault {
+		return policyDefault
+	}
+	for _, allowed := range allowedValues {
+		if v == allowed {
+			return v
+		}
+	}
+	allVals := append([]string{policyDefault}, allowedValues...)
+	sort.Strings(allVals)
+	sio.Warnf("ignored annotation %s=%s does not have one of the allowed values: %s\n", directive, v, strings.Join(allVals, ", "))
+	return policyDefault
+}
+
 type updatePolicy struct{}
 
+// disableUpdate returns true if the update policy of the supplied object is never or create-only.
 func (u *updatePolicy) disableUpdate(ob model.K8sMeta) bool {
-	return isSet(ob, model.QbecNames.Directives.UpdatePolicy, policyNever, []string{policyDefault})
+	p := policyValue(ob, model.QbecNames.Directives.UpdatePolicy, []string{policyNever, policyCreateOnly})
+	return p == policyNever || p == policyCreateOnly
+}
+
+// disableCreate returns true if the update policy of the supplied object is never.
+func (u *updatePolicy) disableCreate(ob model.K8sMeta) bool {
+	return policyValue(ob, model.QbecNames.Directives.UpdatePolicy, []string{policyNever, policyCreateOnly}) == policyNever
 }
 
 func newUpdatePolicy() *updatePolicy {
@@ -72,6 +100,7 @@ type deletePolicy struct {
 	nsFunc         func(kind schema.GroupVersionKind) (bool, error)
 	defaultNS      string
 	keepNamespaces map[string]bool
+	prune          bool // deletions are for garbage collection, honor the no-prune directive
 }
 
 func newDeletePolicy(nsFunc func(kind schema.GroupVersionKind) (bool, error), defaultNS string) *deletePolicy {
@@ -84,17 +113,52 @@ func newDeletePolicy(nsFunc func(kind schema.GroupVersionKind) (bool, error), de
 		}}
 }
 
+// newPrunePolicy returns a delete policy for garbage collection, which also retains objects that have the
+// no-prune directive set.
+func newPrunePolicy(nsFunc func(kind schema.GroupVersionKind) (bool, error), defaultNS string) *deletePolicy {
+	d := newDeletePolicy(nsFunc, defaultNS)
+	d.prune = true
+	return d
+}
+
+// noPrune returns true if the supplied object has the no-prune directive set.
+func noPrune(ob model.K8sMeta) bool {
+	return isSet(ob, model.QbecNames.Directives.NoPrune, "true", []string{"false"})
+}
+
+// keepNamespace ensures that the namespace of the supplied object, if any, is not deleted.
+func (d *deletePolicy) keepNamespace(ob model.K8sMeta) {
+	isNamespaced, _ := d.nsFunc(ob.GroupVersionKind())
+	if isNamespaced {
+		ns := ob.GetNamespace()
+		if ns == "" {
+			ns = d.defaultNS
+		}
+		d.keepNamespaces[ns] = true
+	}
+}
+
+// splitNoPrune returns the supplied objects that may be garbage collected and the ones that have the no-prune
+// directive set. The namespaces of the latter are never deleted.
+func (d *deletePolicy) splitNoPrune(objs []model.K8sQbecMeta) (prunable, protected []model.K8sQbecMeta) {
+	for _, o := range objs {
+		if noPrune(o) {
+			d.keepNamespace(o)
+			protected = append(protected, o)
+			continue
+		}
+		prunable = append(prunable, o)
+	}
+	return prunable, protected
+}
+
 func (d *deletePolicy) disableDelete(ob model.K8sMeta) bool {
 	ret := isSet(ob, model.QbecNames.Directives.DeletePolicy, policyNever, []string{policyDefault})
+	if !ret && d.prune {
+		ret = noPrune(ob)
+	}
 	if ret {
-		isNamespaced, _ := d.nsFunc(ob.GroupVersionKind())
-		if isNamespaced {
-			ns := ob.GetNamespace()
-			if ns == "" {
-				ns = d.defaultNS
-			}
-			d.keepNamespaces[ns] = true
-		}
+		d.keepNamespace(ob)
 		return true
 	}
 	if ob.GroupVersionKind().Group == "" && ob.GetKind() == "Namespace" {
@@ -114,3 +178,16 @@ func newWaitPolicy() *waitPolicy {
 func (d *waitPolicy) disableWait(ob model.K8sMeta) bool {
 	return isSet(ob, model.QbecNames.Directives.WaitPolicy, policyNever, []string{policyDefault})
 }
+
+// waveOf returns the apply wave of the supplied object, 0 when the object does not declare one.
+func waveOf(ob model.K8sMeta) (int, error) {
+	v := ob.GetAnnotations()[model.QbecNames.Directives.ApplyWave]
+	if v == "" {
+		return 0, nil
+	}
+	wave, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid apply wave directive '%s', must be an integer", v)
+	}
+	return wave, nil
+}
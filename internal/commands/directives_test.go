@@ -90,6 +90,39 @@ func TestDirectivesUpdatePolicy(t *testing.T) {
 		"directives.qbec.io/update-policy": "never",
 	}))
 	a.True(ret)
+
+	tests := []struct {
+		policy        string
+		createDisable bool
+		updateDisable bool
+		warning       string
+	}{
+		{policy: "default"},
+		{policy: "never", createDisable: true, updateDisable: true},
+		{policy: "create-only", updateDisable: true},
+		{policy: "sometimes", warning: "ignored annotation directives.qbec.io/update-policy=sometimes does not have one of the allowed values: create-only, default, never"},
+	}
+	for _, test := range tests {
+		t.Run(test.policy, func(t *testing.T) {
+			var b bytes.Buffer
+			orig := sio.Output
+			defer func() {
+				sio.Output = orig
+			}()
+			sio.Output = &b
+			ob := k8sMetaWithAnnotations("Secret", "foo", "bar", map[string]interface{}{
+				"directives.qbec.io/update-policy": test.policy,
+			})
+			a := assert.New(t)
+			a.Equal(test.createDisable, up.disableCreate(ob))
+			a.Equal(test.updateDisable, up.disableUpdate(ob))
+			if test.warning != "" {
+				a.Contains(b.String(), test.warning)
+			} else {
+				a.Empty(b.String())
+			}
+		})
+	}
 }
 
 func TestDirectivesDeletePolicy(t *testing.T) {
@@ -118,6 +151,33 @@ func TestDirectivesDeletePolicy(t *testing.T) {
 	a.False(dp.disableDelete(k8sMetaWithAnnotations("Namespace", "", "yyy", nil)))
 }
 
+func TestDirectivesPrunePolicy(t *testing.T) {
+	nsFunc := func(gvk schema.GroupVersionKind) (bool, error) {
+		return gvk.Kind == "ConfigMap", nil
+	}
+	noPruneAnns := map[string]interface{}{
+		"directives.qbec.io/no-prune": "true",
+	}
+	a := assert.New(t)
+
+	dp := newDeletePolicy(nsFunc, "foobar")
+	a.False(dp.disableDelete(k8sMetaWithAnnotations("ConfigMap", "xxx", "cm1", noPruneAnns)))
+	a.False(dp.disableDelete(k8sMetaWithAnnotations("Namespace", "", "xxx", nil)))
+
+	pp := newPrunePolicy(nsFunc, "foobar")
+	a.False(pp.disableDelete(k8sMetaWithAnnotations("ConfigMap", "xxx", "cm1", nil)))
+	a.False(pp.disableDelete(k8sMetaWithAnnotations("ConfigMap", "xxx", "cm1", map[string]interface{}{
+		"directives.qbec.io/no-prune": "false",
+	})))
+	a.False(pp.disableDelete(k8sMetaWithAnnotations("Namespace", "", "xxx", nil)))
+	a.True(pp.disableDelete(k8sMetaWithAnnotations("ConfigMap", "xxx", "cm1", noPruneAnns)))
+	a.True(pp.disableDelete(k8sMetaWithAnnotations("Namespace", "", "xxx", nil)))
+	a.True(pp.disableDelete(k8sMetaWithAnnotations("ConfigMap", "", "cm2", map[string]interface{}{
+		"directives.qbec.io/delete-policy": "never",
+	})))
+	a.True(pp.disableDelete(k8sMetaWithAnnotations("Namespace", "", "foobar", nil)))
+}
+
 func TestDirectivesWaitPolicy(t *testing.T) {
 	wp := newWaitPolicy()
 	a := assert.New(t)
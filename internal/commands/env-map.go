@@ -0,0 +1,129 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/splunk/qbec/internal/cmd"
+)
+
+type envMapCommandConfig struct {
+	cmd.AppContext
+	format string
+}
+
+func newEnvMapCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "env-map [-o <format>]",
+		Short:   "list all environments with their server, contexts and default namespace",
+		Example: envMapExamples(),
+	}
+
+	config := envMapCommandConfig{}
+	c.Flags().StringVarP(&config.format, "format", "o", "", "use json|yaml to display machine readable output")
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		config.AppContext = cp()
+		return cmd.WrapError(doEnvMap(args, config))
+	}
+	return c
+}
+
+// envMapEntry is the resolved connection information of an environment.
+type envMapEntry struct {
+	Name             string   `json:"name"`
+	Server           string   `json:"server,omitempty"`
+	Contexts         []string `json:"contexts,omitempty"`
+	DefaultNamespace string   `json:"defaultNamespace"`
+}
+
+type envMap struct {
+	Environments []envMapEntry `json:"environments"`
+}
+
+func environmentMap(config envMapCommandConfig) (envMap, error) {
+	app := config.App()
+	var list []envMapEntry
+	for name := range app.Environments() {
+		server, err := app.ServerURL(name)
+		if err != nil {
+			return envMap{}, err
+		}
+		contexts, err := app.Contexts(name)
+		if err != nil {
+			return envMap{}, err
+		}
+		if len(contexts) == 0 {
+			ctx, err := app.Context(name)
+			if err != nil {
+				return envMap{}, err
+			}
+			if ctx != "" {
+				contexts = []string{ctx}
+			}
+		}
+		list = append(list, envMapEntry{
+			Name:             name,
+			Server:           server,
+			Contexts:         contexts,
+			DefaultNamespace: app.DefaultNamespace(name),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return envMap{Environments: list}, nil
+}
+
+func doEnvMap(args []string, config envMapCommandConfig) error {
+	if len(args) != 0 {
+		return cmd.NewUsageError("extra arguments specified")
+	}
+	if config.format != "" && config.format != "json" && config.format != "yaml" {
+		return cmd.NewUsageError(fmt.Sprintf("invalid output format: %q", config.format))
+	}
+	m, err := environmentMap(config)
+	if err != nil {
+		return err
+	}
+	w := config.Stdout()
+	switch config.format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	case "yaml":
+		b, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		fmt.Fprintf(w, "%-20s %-40s %-30s %s\n", "NAME", "SERVER", "CONTEXTS", "DEFAULT NAMESPACE")
+		for _, e := range m.Environments {
+			fmt.Fprintf(w, "%-20s %-40s %-30s %s\n", e.Name, e.Server, strings.Join(e.Contexts, ","), e.DefaultNamespace)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,98 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvMapTable(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("alpha", "env-map")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`^NAME\s+SERVER\s+CONTEXTS\s+DEFAULT NAMESPACE$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^dev\s+https://dev-server\s+default$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^local\s+minikube\s+default$`))
+}
+
+func TestEnvMapJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("alpha", "env-map", "-o", "json")
+	require.NoError(t, err)
+	var data envMap
+	err = s.jsonOutput(&data)
+	require.NoError(t, err)
+	a := assert.New(t)
+	require.Equal(t, 4, len(data.Environments))
+	a.Equal(envMapEntry{Name: "dev", Server: "https://dev-server", DefaultNamespace: "default"}, data.Environments[0])
+	a.Equal(envMapEntry{Name: "local", Contexts: []string{"minikube"}, DefaultNamespace: "default"}, data.Environments[1])
+	a.Equal("prod", data.Environments[2].Name)
+	a.Equal("stage", data.Environments[3].Name)
+}
+
+func TestEnvMapYAML(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("alpha", "env-map", "-o", "yaml")
+	require.NoError(t, err)
+	out, err := s.yamlOutput()
+	require.NoError(t, err)
+	assert.True(t, len(out) > 0)
+}
+
+func TestEnvMapNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		asserter func(t *testing.T, err error)
+	}{
+		{
+			name: "extra args",
+			args: []string{"alpha", "env-map", "dev"},
+			asserter: func(t *testing.T, err error) {
+				a := assert.New(t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("extra arguments specified", err.Error())
+			},
+		},
+		{
+			name: "bad format",
+			args: []string{"alpha", "env-map", "-o", "table"},
+			asserter: func(t *testing.T, err error) {
+				a := assert.New(t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid output format: "table"`, err.Error())
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(test.args...)
+			require.Error(t, err)
+			test.asserter(t, err)
+		})
+	}
+}
@@ -25,6 +25,8 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/sio"
 )
 
 func newEnvCommand(cp ctxProvider) *cobra.Command {
@@ -32,7 +34,7 @@ func newEnvCommand(cp ctxProvider) *cobra.Command {
 		Use:   "env <subcommand>",
 		Short: "environment lists and details",
 	}
-	cmd.AddCommand(newEnvListCommand(cp), newEnvVarsCommand(cp), newEnvPropsCommand(cp))
+	cmd.AddCommand(newEnvListCommand(cp), newEnvVarsCommand(cp), newEnvPropsCommand(cp), newEnvValidateCommand(cp))
 	return cmd
 }
 
@@ -62,6 +64,7 @@ type displayEnv struct {
 	Name             string `json:"name"`
 	Server           string `json:"server"`
 	DefaultNamespace string `json:"defaultNamespace"`
+	Extends          string `json:"extends,omitempty"`
 }
 
 type displayEnvList struct {
@@ -80,6 +83,7 @@ func listEnvironments(config envListCommandConfig) error {
 			Name:             name,
 			Server:           obj.Server,
 			DefaultNamespace: defNs,
+			Extends:          obj.Extends,
 		})
 	}
 	sort.Slice(list, func(i, j int) bool {
@@ -123,6 +127,7 @@ func newEnvVarsCommand(cp ctxProvider) *cobra.Command {
 
 	config := envVarsCommandConfig{}
 	c.Flags().StringVarP(&config.format, "format", "o", "", "use json|yaml to display machine readable output")
+	c.Flags().BoolVar(&config.json, "json", false, "print the resolved server, context, default namespace and computed variables as JSON")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -134,6 +139,48 @@ func newEnvVarsCommand(cp ctxProvider) *cobra.Command {
 type envVarsCommandConfig struct {
 	cmd.AppContext
 	format string
+	json   bool
+}
+
+// envConfig is the resolved configuration of an environment exported by env vars --json.
+type envConfig struct {
+	Env              string                 `json:"env"`
+	Server           string                 `json:"server,omitempty"`
+	Context          string                 `json:"context"`
+	Cluster          string                 `json:"cluster"`
+	DefaultNamespace string                 `json:"defaultNamespace"`
+	ConfigFile       string                 `json:"configFile"`
+	ComputedVars     map[string]interface{} `json:"computedVars"`
+}
+
+func environmentConfig(name string, config envVarsCommandConfig) error {
+	envCtx, err := config.EnvContext(name)
+	if err != nil {
+		return err
+	}
+	attrs, err := envCtx.KubeAttributes()
+	if err != nil {
+		return err
+	}
+	server, err := config.App().ServerURL(name)
+	if err != nil {
+		return err
+	}
+	vars, err := envCtx.ComputedVars()
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(config.Stdout())
+	enc.SetIndent("", "  ")
+	return enc.Encode(envConfig{
+		Env:              name,
+		Server:           server,
+		Context:          attrs.Context,
+		Cluster:          attrs.Cluster,
+		DefaultNamespace: attrs.Namespace,
+		ConfigFile:       attrs.ConfigFile,
+		ComputedVars:     vars,
+	})
 }
 
 func doEnvVars(args []string, config envVarsCommandConfig) error {
@@ -143,6 +190,12 @@ func doEnvVars(args []string, config envVarsCommandConfig) error {
 	if _, ok := config.App().Environments()[args[0]]; !ok {
 		return fmt.Errorf("invalid environment: %q", args[0])
 	}
+	if config.json {
+		if config.format != "" {
+			return cmd.NewUsageError("--json cannot be used together with --format")
+		}
+		return environmentConfig(args[0], config)
+	}
 	return environmentVars(args[0], config)
 }
 
@@ -247,3 +300,39 @@ func environmentProps(name string, config envPropsCommandConfig) error {
 	}
 	return nil
 }
+
+func newEnvValidateCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "validate",
+		Short:   "check qbec.yaml and environment files for problems, reporting all of them",
+		Example: envValidateExamples(),
+	}
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		return cmd.WrapError(doEnvValidate(args, cp()))
+	}
+	return c
+}
+
+func doEnvValidate(args []string, ac cmd.AppContext) error {
+	if len(args) != 0 {
+		return cmd.NewUsageError("extra arguments specified")
+	}
+	envFiles, err := matchEnvFiles(ac.Context)
+	if err != nil {
+		return err
+	}
+	name, b, root, err := readApp(ac.Context)
+	if err != nil {
+		return err
+	}
+	errs := model.ValidateAppContent(name, b, root, envFiles)
+	for _, err := range errs {
+		sio.Errorln(err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problem(s) found in %s", len(errs), name)
+	}
+	sio.Noticeln("no problems found in", name)
+	return nil
+}
@@ -60,6 +60,21 @@ func TestEnvListJSON(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestEnvListExtends(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/extends")
+	defer s.reset()
+	err := s.executeCommand("env", "list", "-o", "json")
+	require.NoError(t, err)
+	var data displayEnvList
+	err = s.jsonOutput(&data)
+	require.NoError(t, err)
+	assert.EqualValues(t, []displayEnv{
+		{Name: "prod", Server: "https://prod-server", DefaultNamespace: "apps"},
+		{Name: "prod-eu", Server: "https://prod-server", DefaultNamespace: "apps-eu", Extends: "prod"},
+		{Name: "prod-us", Server: "https://prod-server", DefaultNamespace: "apps", Extends: "prod"},
+	}, data.Environments)
+}
+
 func TestEnvVarsBasic(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -92,6 +107,38 @@ func TestEnvVarsJSON(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestEnvVarsFullJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("env", "vars", "dev", "--json", "--k8s:kubeconfig=kubeconfig.yaml")
+	require.NoError(t, err)
+	var data map[string]interface{}
+	err = s.jsonOutput(&data)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal("dev", data["env"])
+	a.Equal("https://dev-server", data["server"])
+	a.Equal("dev", data["context"])
+	a.Equal("dev", data["cluster"])
+	a.Equal("default", data["defaultNamespace"])
+	a.Equal("kubeconfig.yaml", data["configFile"])
+	vars, ok := data["computedVars"].(map[string]interface{})
+	require.True(t, ok)
+	a.EqualValues(map[string]interface{}{"env": "dev"}, vars["c1"])
+	a.EqualValues(map[string]interface{}{"vars": map[string]interface{}{"env": "dev"}}, vars["c2"])
+	a.EqualValues(map[string]interface{}{"foo": "bar"}, vars["c3"])
+}
+
+func TestEnvVarsFullJSONWithFormat(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("env", "vars", "dev", "--json", "-o", "yaml")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("--json cannot be used together with --format", err.Error())
+}
+
 func TestEnvPropsYAML(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -238,3 +285,38 @@ func TestEnvNegative(t *testing.T) {
 		})
 	}
 }
+
+func TestEnvValidate(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("env", "validate")
+	require.NoError(t, err)
+	s.assertErrorLineMatch(regexp.MustCompile(`no problems found in qbec.yaml`))
+}
+
+func TestEnvValidateProblems(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/bad-config")
+	defer s.reset()
+	err := s.executeCommand("env", "validate")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.Equal("4 problem(s) found in qbec.yaml", err.Error())
+	s.assertErrorLineMatch(regexp.MustCompile(`qbec.yaml: duplicate definition for environment 'dev'`))
+	s.assertErrorLineMatch(regexp.MustCompile(`verify environment prod: neither server nor context was set`))
+	s.assertErrorLineMatch(regexp.MustCompile(`environment dev: invalid default namespace 'dev_ns'`))
+	s.assertErrorLineMatch(regexp.MustCompile(`default exclusions: bad component reference\(s\): missing`))
+
+	err = s.executeCommand("env", "list")
+	require.Error(t, err)
+	a.Equal("verify environment prod: neither server nor context was set", err.Error())
+}
+
+func TestEnvValidateNegative(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("env", "validate", "dev")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("extra arguments specified", err.Error())
+}
@@ -19,10 +19,12 @@ package commands
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/eval"
+	"github.com/splunk/qbec/vm"
 	"github.com/splunk/qbec/vm/vmutil"
 )
 
@@ -57,15 +59,20 @@ func doEval(args []string, config evalCommandConfig) error {
 	if err != nil {
 		return err
 	}
+	return printEvalOutput(output, config.format, config.Stdout())
+}
+
+// printEvalOutput prints the supplied JSON output of an evaluation in the specified format.
+func printEvalOutput(output string, format string, w io.Writer) error {
 	var data interface{}
-	err = json.Unmarshal([]byte(output), &data)
+	err := json.Unmarshal([]byte(output), &data)
 	if err != nil {
 		return err
 	}
 	var b []byte
-	switch config.format {
+	switch format {
 	case "yaml":
-		err = vmutil.RenderYAMLDocuments([]interface{}{data}, config.Stdout())
+		err = vmutil.RenderYAMLDocuments([]interface{}{data}, w)
 		if err != nil {
 			return err
 		}
@@ -74,7 +81,7 @@ func doEval(args []string, config evalCommandConfig) error {
 		if err != nil {
 			return err
 		}
-		_, err = fmt.Fprintf(config.Stdout(), "%s\n", b)
+		_, err = fmt.Fprintf(w, "%s\n", b)
 		if err != nil {
 			return err
 		}
@@ -97,3 +104,60 @@ func newEvalCommand(cp ctxProvider) *cobra.Command {
 	}
 	return c
 }
+
+type alphaEvalCommandConfig struct {
+	cmd.AppContext
+	format string
+	expr   string
+}
+
+// doAlphaEval evaluates a jsonnet expression or file with the external variables, native functions and library
+// paths that are set up for components of the supplied environment.
+func doAlphaEval(args []string, config alphaEvalCommandConfig) error {
+	var file string
+	switch {
+	case len(args) == 0:
+		return cmd.NewUsageError("an environment is required")
+	case len(args) > 2:
+		return cmd.NewUsageError(fmt.Sprintf("at most an environment and a file can be specified, but provided: %q", args))
+	case len(args) == 2:
+		file = args[1]
+	}
+	if (file == "") == (config.expr == "") {
+		return cmd.NewUsageError("exactly one of an expression using -e or a file must be specified")
+	}
+	if config.format != "json" && config.format != "yaml" {
+		return cmd.NewUsageError(fmt.Sprintf("invalid output format: %q", config.format))
+	}
+	envCtx, err := config.EnvContext(args[0])
+	if err != nil {
+		return err
+	}
+	ctx := envCtx.EvalContext(cleanEvalMode)
+	var output string
+	if file != "" {
+		output, err = eval.File(file, ctx.BaseContext)
+	} else {
+		output, err = eval.Code("<expression>", vm.MakeCode(config.expr), ctx.BaseContext)
+	}
+	if err != nil {
+		return err
+	}
+	return printEvalOutput(output, config.format, config.Stdout())
+}
+
+func newAlphaEvalCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "eval <environment> [-e <expression>] [path/to/file.jsonnet]",
+		Short:   "evaluate a jsonnet expression or file with the VM set up for components of an environment",
+		Example: alphaEvalExamples(),
+	}
+	cfg := alphaEvalCommandConfig{}
+	c.Flags().StringVarP(&cfg.expr, "expr", "e", "", "jsonnet expression to evaluate instead of a file")
+	c.Flags().StringVarP(&cfg.format, "format", "o", "json", "Output format. Supported values are: json, yaml")
+	c.RunE = func(c *cobra.Command, args []string) error {
+		cfg.AppContext = cp()
+		return cmd.WrapError(doAlphaEval(args, cfg))
+	}
+	return c
+}
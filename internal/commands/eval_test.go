@@ -17,9 +17,11 @@
 package commands
 
 import (
+	"regexp"
 	"runtime"
 	"testing"
 
+	"github.com/splunk/qbec/internal/cmd"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -109,6 +111,7 @@ func TestEvalInQBECContext(t *testing.T) {
 	a := assert.New(t)
 	a.Equal("dev", data["foo"])
 	a.Equal("development", data["bar"])
+	a.Equal("dev", data["env"])
 }
 
 func TestEvalBadArgs(t *testing.T) {
@@ -134,3 +137,55 @@ func TestEvalBadFileQbecCtx(t *testing.T) {
 	require.Error(t, err)
 	assert.Equal(t, "non-existent.jsonnet: file not found", err.Error())
 }
+
+func TestAlphaEvalExpression(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("alpha", "eval", "dev", "-e",
+		"{ env: std.native('qbecEnvName')(), envType: std.extVar('qbec.io/envProperties').envType, params: std.objectHas(import 'params.libsonnet', 'components') }")
+	require.NoError(t, err)
+	var data map[string]interface{}
+	err = s.jsonOutput(&data)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("dev", data["env"])
+	a.Equal("development", data["envType"])
+	a.Equal(true, data["params"])
+}
+
+func TestAlphaEvalFile(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("alpha", "eval", "dev", "misc/qbec.jsonnet", "-o", "yaml")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`^env: dev$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^foo: dev$`))
+}
+
+func TestAlphaEvalNegative(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		usage   bool
+		message string
+	}{
+		{name: "no-env", usage: true, message: "an environment is required"},
+		{name: "too-many", args: []string{"dev", "a.jsonnet", "b.jsonnet"}, usage: true, message: `at most an environment and a file can be specified, but provided: ["dev" "a.jsonnet" "b.jsonnet"]`},
+		{name: "no-input", args: []string{"dev"}, usage: true, message: "exactly one of an expression using -e or a file must be specified"},
+		{name: "both-inputs", args: []string{"dev", "misc/qbec.jsonnet", "-e", "1"}, usage: true, message: "exactly one of an expression using -e or a file must be specified"},
+		{name: "bad-format", args: []string{"dev", "-e", "1", "-o", "xml"}, usage: true, message: `invalid output format: "xml"`},
+		{name: "bad-env", args: []string{"foo", "-e", "1"}, message: "invalid environment \"foo\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"alpha", "eval"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.Equal(test.usage, cmd.IsUsageError(err))
+			a.Equal(test.message, err.Error())
+		})
+	}
+}
@@ -51,8 +51,27 @@ func applyExamples() string {
 		newExample("apply dev --yes --wait", "create/ update all dev components and delete extra objects on the server",
 			"do not ask for confirmation, wait until all objects have a ready status"),
 		newExample("apply -n dev", "show what apply would do for the dev environment"),
+		newExample("apply dev --confirm", "show a diff of the changes for the dev environment and ask before applying them"),
+		newExample("apply dev --only-namespace team-a", "apply only the objects in the team-a namespace and only garbage collect extra objects in it"),
+		newExample("apply dev --dry-run --output=diff", "show what an apply to the dev environment would do as a diff, including objects that would be deleted"),
+		newExample("apply dev --dry-run=server", "show what apply would do, running server validation and admission without persisting changes"),
 		newExample("apply dev -c redis -K secret", "update all objects except secrets just for the redis component"),
-		newExample("apply dev --gc=false", "only create/ update, do not delete extra objects from the server"),
+		newExample("apply dev --no-gc", "only create/ update, do not delete extra objects from the server"),
+		newExample("apply dev --gc-tag=shared", "garbage collect extra objects labeled with the shared GC tag instead of the app name"),
+		newExample("apply dev --prune-threshold=10 --prune-threshold=20%",
+			"fail before deleting more than 10 extra objects or more than 20% of the tracked objects"),
+		newExample("apply dev --gc-cluster-scoped", "also delete extra cluster-scoped objects like cluster roles and CRDs from the server"),
+		newExample("apply dev --prune-only", "only delete extra objects from the server, do not create/ update anything"),
+		newExample("apply dev --wait-all --prune-grace-period=30s", "wait for objects to be ready and another 30 seconds before deleting extra objects"),
+		newExample("apply dev --prune-order=pre", "delete extra objects before creating and updating objects, for example to replace immutable jobs"),
+		newExample("apply dev --stamp", "annotate created/ updated objects with the user who applied them and when"),
+		newExample("apply dev --prune-whitelist apps/v1/Deployment --prune-whitelist v1/ConfigMap",
+			"only delete extra deployments and config maps from the server"),
+		newExample("apply dev --server-side --force-conflicts", "use server-side apply, taking ownership of fields managed by others"),
+		newExample("apply dev --namespace test-1 --force-namespace", "apply all namespaced objects for the dev environment to the test-1 namespace"),
+		newExample("apply dev --create-namespace", "create the default namespace of the dev environment first if it does not exist"),
+		newExample("apply dev --changed-only", "only sync objects that have changed since they were last applied from this machine"),
+		newExample("apply dev --adopt", "take ownership of existing objects that are managed by another app or environment"),
 	)
 }
 
@@ -61,9 +80,23 @@ func showExamples() string {
 		newExample("show dev", "show all components for the 'dev' environment in YAML"),
 		newExample("show dev -c postgres -c redis -o json", "expand just 2 components and output JSON"),
 		newExample("show dev -C postgres -C redis", "expand all but 2 components"),
+		newExample("show dev -c 'frontend-*'", "expand all components whose names start with frontend-"),
 		newExample("show dev -k deployment -k configmap", "show only deployments and config maps"),
 		newExample("show dev -K secret", "show all objects except secrets"),
+		newExample("show dev -c service2 -k configmap", "show only the config maps of the service2 component"),
 		newExample("show dev -O", "list all objects for the dev environment"),
+		newExample("show dev --output-dir=out/dev --clean-output-dir", "write every object to its own file under out/dev, removing files of objects that no longer exist"),
+		newExample("show dev -o kustomize --output-dir=out/dev", "write every object to its own file under out/dev along with a kustomization.yaml listing them"),
+		newExample("show dev --timings", "show all components and print the evaluation time of each component, slowest first"),
+		newExample("show dev --sort-by kind,namespace,name", "show all objects sorted by kind, namespace and name, for output that stays stable across runs"),
+		newExample("show dev --seed=release-42 --freeze-time=2021-01-01T00:00:00Z", "show all components with fixed output from the random, randomString and now native functions"),
+	)
+}
+
+func statsExamples() string {
+	return exampleHelp(
+		newExample("stats dev", "print counts of objects by kind, namespace and component for the dev environment"),
+		newExample("stats dev -c redis --json", "print counts for just the redis component in JSON format"),
 	)
 }
 
@@ -74,6 +107,14 @@ func evalExamples() string {
 	)
 }
 
+func alphaEvalExamples() string {
+	return exampleHelp(
+		newExample("alpha eval dev -e \"std.extVar('qbec.io/defaultNs')\"", "print the default namespace of the dev environment"),
+		newExample("alpha eval dev -e \"(import 'params.libsonnet').components.redis\" -o yaml", "print the parameters of the redis component for the dev environment in YAML"),
+		newExample("alpha eval prod queries/replicas.jsonnet", "evaluate the supplied file with the external variables, native functions and library paths of the prod environment"),
+	)
+}
+
 func fmtExamples() string {
 	return exampleHelp(
 		newExample("fmt -w", "format all jsonnet and libsonnet files in-place"),
@@ -83,6 +124,7 @@ func fmtExamples() string {
 		newExample("fmt -t=yaml", "format all yaml files to stdout"),
 		newExample("fmt --type=json,yaml somefolder file1.yaml file2.yml file3.json", "format all json and yaml files in the somefolder, file1.yaml, file2.yml and file3.json files to stdout"),
 		newExample("fmt -w -x '**/testdata'", "format jsonnet and libsonnet files in-place excluding files that appear under directories called testdata"),
+		newExample("fmt --check components", "check that jsonnet files under components are formatted using options from .jsonnetfmt, if present"),
 	)
 }
 
@@ -101,6 +143,8 @@ func deleteExamples() string {
 		newExample("delete dev -c redis -k secret", "delete all secrets for the redis component"),
 		newExample("delete dev --local", "use object names from local component files for deletion list",
 			"by default, the list is produced using server queries"),
+		newExample("delete dev --wait --wait-timeout 10m", "delete all objects and wait until they are gone from the server",
+			"useful for namespaces and custom resources with finalizers"),
 	)
 }
 
@@ -110,12 +154,28 @@ func diffExamples() string {
 		newExample("diff dev -c redis --show-deletes=false", "show differences for the redis component for the dev environment",
 			"ignore extra remote objects"),
 		newExample("diff dev -ignore-all-labels", "do not take labels into account when calculating the diff"),
+		newExample("diff dev --ignore-paths status --ignore-paths \"metadata.annotations['deployment.kubernetes.io/revision']\"",
+			"do not take the status and the revision annotation into account when calculating the diff"),
+		newExample("diff dev --format=jsonpatch", "show differences as JSON patches keyed by object name"),
+		newExample("diff dev --server-side", "diff live objects against the result of a server-side apply dry-run"),
+		newExample("diff dev --summary-only", "only list the objects that would be created, modified or deleted"),
+		newExample("diff dev --only-live-missing", "only list objects that do not exist on the server and extra objects on the server"),
+		newExample("diff dev --context-lines=0", "only show changed lines without any surrounding context"),
+		newExample("diff dev --exit-code", "exit with status 2 when differences are found, suitable for CI checks"),
+		newExample("diff dev --against-file=last.yaml", "show differences between local objects and a manifest saved using qbec show, without cluster access"),
+		newExample("diff dev --report-file=diff.json --report-format=json", "also write the stats of the diff to a JSON file, for example as a CI artifact"),
 	)
 }
 
 func validateExamples() string {
 	return exampleHelp(
 		newExample("validate dev", "validate all objects for all components against the dev environment"),
+		newExample("validate dev -o json", "validate all objects and print a JSON summary of the results"),
+		newExample("validate dev --strict", "validate all objects and fail if the schema of any object cannot be found"),
+		newExample("validate dev --list-unknown", "validate all objects and list the kinds without a schema, typically custom resources whose CRDs are not installed"),
+		newExample("validate dev --schema-file swagger.json", "validate objects using a local OpenAPI document without connecting to a cluster"),
+		newExample("validate dev --schema-version v3", "validate objects using the OpenAPI v3 schemas of the cluster, which are more accurate for custom resources"),
+		newExample("validate dev --admission", "also report objects that would be denied by admission webhooks and policies of the cluster"),
 	)
 }
 
@@ -124,6 +184,14 @@ func componentListExamples() string {
 		newExample("component list dev", "list all components for the dev environment"),
 		newExample("component list dev -O", "list all objects for the dev environment"),
 		newExample("component list _", "list all baseline components"),
+		newExample("component list dev -l tier=backend", "list components for the dev environment whose metadata labels match the selector"),
+	)
+}
+
+func componentShowExamples() string {
+	return exampleHelp(
+		newExample("component show staging -c frontend", "show the objects of just the frontend component for the staging environment in YAML"),
+		newExample("component show staging -c frontend -c backend -o json --clean", "show the objects of 2 components in JSON without qbec metadata"),
 	)
 }
 
@@ -131,6 +199,16 @@ func componentDiffExamples() string {
 	return exampleHelp(
 		newExample("component diff dev", "show differences in component lists between baseline and dev"),
 		newExample("component diff dev prod -O", "show differences in object lists between dev and prod"),
+		newExample("component diff staging prod -c redis", "show differences in the rendered objects of the redis component between staging and prod"),
+	)
+}
+
+func initExamples() string {
+	return exampleHelp(
+		newExample("init my-app", "create a qbec app in the my-app directory using the current kube context for the default environment"),
+		newExample("init my-app --with-example", "also create a hello world component"),
+		newExample("init my-app --server https://dev-server --namespace my-ns", "use the supplied server and namespace for the default environment"),
+		newExample("init my-app --force", "overwrite files in an existing my-app directory"),
 	)
 }
 
@@ -146,6 +224,14 @@ func paramDiffExamples() string {
 	return exampleHelp(
 		newExample("param diff dev", "show differences in parameter values between baseline and dev"),
 		newExample("param diff dev prod", "show differences in parameter values  between dev and prod"),
+		newExample("param diff dev prod -o json", "list added, removed and changed parameters between dev and prod as JSON"),
+	)
+}
+
+func explainExamples() string {
+	return exampleHelp(
+		newExample("explain dev --name svc2-cm", "show the component, files and parameters that produced objects named svc2-cm"),
+		newExample("explain dev --name svc2-cm -k configmap", "only consider config maps with the supplied name"),
 	)
 }
 
@@ -156,9 +242,31 @@ func envListExamples() string {
 	)
 }
 
+func envMapExamples() string {
+	return exampleHelp(
+		newExample("alpha env-map", "list the server, contexts and default namespace of every environment as a table"),
+		newExample("alpha env-map -o json", "list the same information in JSON format, (use -o yaml for YAML)"),
+	)
+}
+
+func schemaExamples() string {
+	return exampleHelp(
+		newExample("alpha schema > qbec.schema.json", "save the JSON schema of qbec.yaml, for example to configure an editor to validate it"),
+		newExample("alpha schema --kind EnvironmentMap", "print the JSON schema of environment files"),
+	)
+}
+
+func envValidateExamples() string {
+	return exampleHelp(
+		newExample("env validate", "report all problems with qbec.yaml and environment files, such as duplicate environments and invalid namespaces"),
+		newExample("env validate --env-file=extra-envs.yaml", "also check the environments in an additional file"),
+	)
+}
+
 func envVarsExamples() string {
 	return exampleHelp(
 		newExample("env vars <env>", "print kubernetes variables for env in eval format, run as `eval $(qbec env vars env)`"),
 		newExample("env vars -o json", "print kubernetes variables for env in JSON format, (use -o yaml for YAML)"),
+		newExample("env vars <env> --json", "print the resolved server, context, default namespace and computed variables for env as a JSON object"),
 	)
 }
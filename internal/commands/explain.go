@@ -0,0 +1,158 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/eval"
+	"github.com/splunk/qbec/internal/model"
+)
+
+// paramOverlay is the set of parameter values for a component as seen by a specific environment.
+type paramOverlay struct {
+	Source string      `json:"source"`
+	Values interface{} `json:"values"`
+}
+
+// explanation describes the origin of an object.
+type explanation struct {
+	Object      string         `json:"object"`
+	Component   string         `json:"component"`
+	Environment string         `json:"environment"`
+	Files       []string       `json:"files"`
+	Params      []paramOverlay `json:"params"`
+}
+
+type explainCommandConfig struct {
+	cmd.AppContext
+	name       string
+	filterFunc func() (model.Filters, error)
+}
+
+// explainParams returns the parameter values of the supplied components for the environment.
+func explainParams(config cmd.AppContext, env string, components map[string]bool) (map[string]interface{}, error) {
+	envCtx, err := config.EnvContext(env)
+	if err != nil {
+		return nil, err
+	}
+	include := func(component string) bool { return components[component] }
+	return eval.ComponentParams(config.App().ParamsFile(), envCtx.EvalContext(cleanEvalMode), include)
+}
+
+func doExplain(ctx context.Context, args []string, config explainCommandConfig) error {
+	if len(args) != 1 {
+		return cmd.NewUsageError(fmt.Sprintf("exactly one environment required, but provided: %q", args))
+	}
+	env := args[0]
+	if env == model.Baseline {
+		return cmd.NewUsageError("cannot explain baseline environment, use a real environment")
+	}
+	if config.name == "" {
+		return cmd.NewUsageError("object name must be specified using --name")
+	}
+	fp, err := config.filterFunc()
+	if err != nil {
+		return err
+	}
+	envCtx, err := config.EnvContext(env)
+	if err != nil {
+		return err
+	}
+	client, err := envCtx.Client()
+	if err != nil {
+		return err
+	}
+	objects, err := generateObjects(ctx, envCtx, makeFilterOpts(fp, client))
+	if err != nil {
+		return err
+	}
+	var matches []model.K8sLocalObject
+	for _, o := range objects {
+		if client.DisplayName(o) == config.name || model.NameForDisplay(o) == config.name {
+			matches = append(matches, o)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Errorf("no objects found with name %q", config.name)
+	}
+
+	components, err := config.App().ComponentsForEnvironment(env, nil, nil)
+	if err != nil {
+		return err
+	}
+	filesByComponent := map[string][]string{}
+	for _, c := range components {
+		filesByComponent[c.Name] = c.Files
+	}
+	matched := map[string]bool{}
+	for _, o := range matches {
+		matched[o.Component()] = true
+	}
+	baseParams, err := explainParams(config.AppContext, model.Baseline, matched)
+	if err != nil {
+		return err
+	}
+	envParams, err := explainParams(config.AppContext, env, matched)
+	if err != nil {
+		return err
+	}
+
+	w := config.Stdout()
+	for _, o := range matches {
+		ex := explanation{
+			Object:      client.DisplayName(o),
+			Component:   o.Component(),
+			Environment: o.Environment(),
+			Files:       filesByComponent[o.Component()],
+			Params: []paramOverlay{
+				{Source: "baseline", Values: baseParams[o.Component()]},
+				{Source: "environment: " + env, Values: envParams[o.Component()]},
+			},
+		}
+		b, err := yaml.Marshal(ex)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "%s\n", b)
+	}
+	return nil
+}
+
+func newExplainCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "explain --name <object-name> <environment>",
+		Short:   "show the component, source files and parameters that produced an object",
+		Example: explainExamples(),
+	}
+
+	config := explainCommandConfig{
+		filterFunc: addFilterParams(c, true),
+	}
+	c.Flags().StringVar(&config.name, "name", "", "the name of the object, either the object name or its display name")
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		config.AppContext = cp()
+		return cmd.WrapError(doExplain(c.Context(), args, config))
+	}
+	return c
+}
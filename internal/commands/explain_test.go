@@ -0,0 +1,101 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainBasic(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("explain", "dev", "--name", "svc2-cm")
+	require.NoError(t, err)
+	out, err := s.yamlOutput()
+	require.NoError(t, err)
+	require.Len(t, out, 1)
+	ex, ok := out[0].(map[string]interface{})
+	require.True(t, ok)
+	a := assert.New(t)
+	a.Equal("ConfigMap:bar-system:svc2-cm", ex["object"])
+	a.Equal("service2", ex["component"])
+	a.Equal("dev", ex["environment"])
+	a.EqualValues([]interface{}{"components/service2.jsonnet"}, ex["files"])
+	params, ok := ex["params"].([]interface{})
+	require.True(t, ok)
+	require.Len(t, params, 2)
+	a.Equal("baseline", params[0].(map[string]interface{})["source"])
+	a.Equal("environment: dev", params[1].(map[string]interface{})["source"])
+}
+
+func TestExplainNegative(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		asserter func(s *scaffold, err error)
+	}{
+		{
+			name: "no env",
+			args: []string{"explain", "--name", "foo"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("exactly one environment required, but provided: []", err.Error())
+			},
+		},
+		{
+			name: "baseline env",
+			args: []string{"explain", "_", "--name", "foo"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("cannot explain baseline environment, use a real environment", err.Error())
+			},
+		},
+		{
+			name: "no name",
+			args: []string{"explain", "dev"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("object name must be specified using --name", err.Error())
+			},
+		},
+		{
+			name: "not found",
+			args: []string{"explain", "dev", "--name", "foo"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.False(cmd.IsUsageError(err))
+				a.Equal(`no objects found with name "foo"`, err.Error())
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(test.args...)
+			require.NotNil(t, err)
+			test.asserter(s, err)
+		})
+	}
+}
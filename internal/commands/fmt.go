@@ -22,6 +22,7 @@ import (
 	"io/ioutil"
 	"os"
 
+	"github.com/google/go-jsonnet/formatter"
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/fswalk"
@@ -40,6 +41,7 @@ type fmtCommandConfig struct {
 	formatTypes    map[string]bool
 	specifiedTypes []string
 	files          []string
+	jsonnetOpts    formatter.Options
 }
 
 type processor struct {
@@ -78,6 +80,11 @@ func doFmt(args []string, config *fmtCommandConfig, failFast *bool) error {
 		}
 		config.formatTypes[s] = true
 	}
+	opts, err := loadJsonnetOptions(jsonnetOptionsFile)
+	if err != nil {
+		return err
+	}
+	config.jsonnetOpts = opts
 	config.opts.ContinueOnError = config.check
 	if failFast != nil {
 		config.opts.ContinueOnError = !*failFast
@@ -96,6 +103,7 @@ func newFmtCommand(cp ctxProvider) *cobra.Command {
 
 	config := fmtCommandConfig{}
 	c.Flags().BoolVarP(&config.check, "check-errors", "e", false, "check for unformatted files")
+	c.Flags().BoolVar(&config.check, "check", false, "check for unformatted files, same as --check-errors")
 	c.Flags().BoolVarP(&config.write, "write", "w", false, "write result to (source) file instead of stdout")
 	c.Flags().StringSliceVarP(&config.specifiedTypes, "type", "t", []string{"jsonnet"}, "file types that should be formatted")
 	excludeFn := fswalk.AddExclusions(c.Flags())
@@ -168,7 +176,7 @@ func processFile(config *fmtCommandConfig, filename string, in io.Reader, out io
 		return err
 	}
 
-	res, err := format(src, filename)
+	res, err := format(src, filename, config.jsonnetOpts)
 	if err != nil {
 		return fmt.Errorf("%s: error formatting file %w", filename, err)
 	}
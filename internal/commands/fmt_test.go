@@ -24,6 +24,7 @@ import (
 	"runtime"
 	"testing"
 
+	"github.com/google/go-jsonnet/formatter"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/fswalk"
 	"github.com/splunk/qbec/internal/testutil"
@@ -216,17 +217,50 @@ func TestFormatYaml(t *testing.T) {
 func TestFormatJsonnet(t *testing.T) {
 	var testfile, err = ioutil.ReadFile("testdata/test.libsonnet")
 	require.Nil(t, err)
-	o, err := formatJsonnet(testfile)
+	o, err := formatJsonnet(testfile, formatter.DefaultOptions())
 	require.Nil(t, err)
 	e, err := ioutil.ReadFile("testdata/test.libsonnet.formatted")
 	require.Nil(t, err)
 	if !bytes.Equal(o, e) {
 		t.Errorf("Expected %q, got %q", string(e), string(o))
 	}
-	_, err = formatJsonnet([]byte("---"))
+	_, err = formatJsonnet([]byte("---"), formatter.DefaultOptions())
 	require.NotNil(t, err)
 }
 
+func TestLoadJsonnetOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qbecfmt_opts")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	opts, err := loadJsonnetOptions(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, formatter.DefaultOptions(), opts)
+
+	file := filepath.Join(dir, ".jsonnetfmt")
+	err = ioutil.WriteFile(file, []byte("indent: 4\nstringStyle: double\npadObjects: false\n"), 0644)
+	require.NoError(t, err)
+	opts, err = loadJsonnetOptions(file)
+	require.NoError(t, err)
+	expected := formatter.DefaultOptions()
+	expected.Indent = 4
+	expected.StringStyle = formatter.StringStyleDouble
+	expected.PadObjects = false
+	assert.Equal(t, expected, opts)
+
+	o, err := formatJsonnet([]byte("{\n  a: 'b',\n  c: { d: 1 },\n}\n"), opts)
+	require.NoError(t, err)
+	assert.Equal(t, "{\n    a: \"b\",\n    c: {d: 1},\n}\n", string(o))
+
+	for _, contents := range []string{"indentation: 4\n", "stringStyle: fancy\n", "commentStyle: fancy\n", "indent: [\n"} {
+		err = ioutil.WriteFile(file, []byte(contents), 0644)
+		require.NoError(t, err)
+		_, err = loadJsonnetOptions(file)
+		require.Error(t, err, contents)
+		assert.Contains(t, err.Error(), file)
+	}
+}
+
 func TestFormatJSON(t *testing.T) {
 	var testfile, err = ioutil.ReadFile("testdata/test.json/test.json")
 	require.Nil(t, err)
@@ -270,7 +304,7 @@ func TestProcessFile(t *testing.T) {
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
 			var b bytes.Buffer
-			var config = fmtCommandConfig{}
+			var config = fmtCommandConfig{jsonnetOpts: formatter.DefaultOptions()}
 			var err = processFile(&config, test.input, nil, &b)
 			require.Nil(t, err)
 			e, err := ioutil.ReadFile(test.output)
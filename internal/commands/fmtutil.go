@@ -26,17 +26,98 @@ import (
 	"runtime"
 	"strings"
 
+	ghyaml "github.com/ghodss/yaml"
 	"github.com/google/go-jsonnet/formatter"
 	"github.com/tidwall/pretty"
 	"gopkg.in/yaml.v3"
 )
 
-func format(in []byte, filename string) ([]byte, error) {
+// jsonnetOptionsFile is the name of the file in the current directory from which jsonnet formatting options are read.
+const jsonnetOptionsFile = ".jsonnetfmt"
+
+// jsonnetFmtOptions are the user-specified overrides for the default jsonnet formatting options.
+type jsonnetFmtOptions struct {
+	Indent           *int    `json:"indent,omitempty"`
+	MaxBlankLines    *int    `json:"maxBlankLines,omitempty"`
+	StringStyle      *string `json:"stringStyle,omitempty"`  // one of double, single or leave
+	CommentStyle     *string `json:"commentStyle,omitempty"` // one of hash, slash or leave
+	PrettyFieldNames *bool   `json:"prettyFieldNames,omitempty"`
+	PadArrays        *bool   `json:"padArrays,omitempty"`
+	PadObjects       *bool   `json:"padObjects,omitempty"`
+	SortImports      *bool   `json:"sortImports,omitempty"`
+	UseImplicitPlus  *bool   `json:"useImplicitPlus,omitempty"`
+}
+
+// loadJsonnetOptions returns the jsonnet formatting options from the supplied file in YAML or JSON format,
+// applied on top of the default options. Default options are returned if the file does not exist.
+func loadJsonnetOptions(file string) (formatter.Options, error) {
+	opts := formatter.DefaultOptions()
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return opts, nil
+		}
+		return opts, err
+	}
+	j, err := ghyaml.YAMLToJSON(b)
+	if err != nil {
+		return opts, fmt.Errorf("%s: %v", file, err)
+	}
+	var fo jsonnetFmtOptions
+	dec := json.NewDecoder(bytes.NewReader(j))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fo); err != nil {
+		return opts, fmt.Errorf("%s: %v", file, err)
+	}
+	if fo.Indent != nil {
+		opts.Indent = *fo.Indent
+	}
+	if fo.MaxBlankLines != nil {
+		opts.MaxBlankLines = *fo.MaxBlankLines
+	}
+	if fo.StringStyle != nil {
+		switch *fo.StringStyle {
+		case "double":
+			opts.StringStyle = formatter.StringStyleDouble
+		case "single":
+			opts.StringStyle = formatter.StringStyleSingle
+		case "leave":
+			opts.StringStyle = formatter.StringStyleLeave
+		default:
+			return opts, fmt.Errorf("%s: invalid string style %q, must be one of double, single or leave", file, *fo.StringStyle)
+		}
+	}
+	if fo.CommentStyle != nil {
+		switch *fo.CommentStyle {
+		case "hash":
+			opts.CommentStyle = formatter.CommentStyleHash
+		case "slash":
+			opts.CommentStyle = formatter.CommentStyleSlash
+		case "leave":
+			opts.CommentStyle = formatter.CommentStyleLeave
+		default:
+			return opts, fmt.Errorf("%s: invalid comment style %q, must be one of hash, slash or leave", file, *fo.CommentStyle)
+		}
+	}
+	setBool := func(target *bool, v *bool) {
+		if v != nil {
+			*target = *v
+		}
+	}
+	setBool(&opts.PrettyFieldNames, fo.PrettyFieldNames)
+	setBool(&opts.PadArrays, fo.PadArrays)
+	setBool(&opts.PadObjects, fo.PadObjects)
+	setBool(&opts.SortImports, fo.SortImports)
+	setBool(&opts.UseImplicitPlus, fo.UseImplicitPlus)
+	return opts, nil
+}
+
+func format(in []byte, filename string, jsonnetOpts formatter.Options) ([]byte, error) {
 	if getFileType(filename) == "yaml" {
 		return formatYaml(in)
 	}
 	if getFileType(filename) == "jsonnet" {
-		return formatJsonnet(in)
+		return formatJsonnet(in, jsonnetOpts)
 	}
 	if getFileType(filename) == "json" {
 		return formatJSON(in)
@@ -72,8 +153,8 @@ func getFileType(filename string) string {
 	return ""
 }
 
-func formatJsonnet(in []byte) ([]byte, error) {
-	var ret, err = formatter.Format("", string(in), formatter.DefaultOptions())
+func formatJsonnet(in []byte, opts formatter.Options) ([]byte, error) {
+	var ret, err = formatter.Format("", string(in), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +34,10 @@ import (
 
 type initCommandConfig struct {
 	cmd.AppContext
-	withExample bool // create a hello world example
+	withExample bool   // create a hello world example
+	server      string // server URL for the default environment
+	namespace   string // default namespace for the default environment
+	force       bool   // overwrite files in an existing app directory
 }
 
 var baseParamsTemplate = template.Must(template.New("base").Parse(`
@@ -154,7 +157,11 @@ func writeTemplateFile(file string, t *template.Template, data interface{}) erro
 }
 
 func writeFiles(dir string, app model.QbecApp, config initCommandConfig) error {
-	if err := os.Mkdir(app.Metadata.Name, 0755); err != nil {
+	mkdir := os.Mkdir
+	if config.force {
+		mkdir = os.MkdirAll
+	}
+	if err := mkdir(dir, 0755); err != nil {
 		return err
 	}
 
@@ -216,21 +223,34 @@ func doInit(args []string, config initCommandConfig) error {
 		return fmt.Errorf("a single app name argument must be supplied")
 	}
 	name := args[0]
-	_, err := os.Stat(name)
-	if err == nil {
-		return fmt.Errorf("directory %s already exists", name)
-	}
-	if !os.IsNotExist(err) {
+	stat, err := os.Stat(name)
+	switch {
+	case err == nil && !stat.IsDir():
+		return fmt.Errorf("%s already exists and is not a directory", name)
+	case err == nil && !config.force:
+		return fmt.Errorf("directory %s already exists, use --force to overwrite files in it", name)
+	case err != nil && !os.IsNotExist(err):
 		return err
 	}
 
-	ctx, err := config.KubeContextInfo()
-	if err != nil {
-		sio.Warnf("could not get current K8s context info, %v\n", err)
-		sio.Warnln("using fake parameters for the default environment")
+	var ctx *remote.ContextInfo
+	if config.server != "" {
 		ctx = &remote.ContextInfo{
-			ServerURL: "https://minikube",
+			ServerURL: config.server,
+			Namespace: "default",
 		}
+	} else {
+		ctx, err = config.KubeContextInfo()
+		if err != nil {
+			sio.Warnf("could not get current K8s context info, %v\n", err)
+			sio.Warnln("using fake parameters for the default environment")
+			ctx = &remote.ContextInfo{
+				ServerURL: "https://minikube",
+			}
+		}
+	}
+	if config.namespace != "" {
+		ctx.Namespace = config.namespace
 	}
 	sio.Noticef("using server URL %q and default namespace %q for the default environment\n", ctx.ServerURL, ctx.Namespace)
 	app := model.QbecApp{
@@ -253,12 +273,16 @@ func doInit(args []string, config initCommandConfig) error {
 
 func newInitCommand(cp ctxProvider) *cobra.Command {
 	c := &cobra.Command{
-		Use:   "init <app-name>",
-		Short: "initialize a qbec app",
+		Use:     "init <app-name>",
+		Short:   "initialize a qbec app",
+		Example: initExamples(),
 	}
 
 	config := initCommandConfig{}
 	c.Flags().BoolVar(&config.withExample, "with-example", false, "create a hello world sample component")
+	c.Flags().StringVar(&config.server, "server", "", "server URL for the default environment, instead of the one from the current kube context")
+	c.Flags().StringVar(&config.namespace, "namespace", "", "default namespace for the default environment, instead of the one from the current kube context")
+	c.Flags().BoolVar(&config.force, "force", false, "overwrite files if the app directory already exists")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -0,0 +1,98 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func inTempDir(t *testing.T) func() {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(t.TempDir()))
+	return func() {
+		require.NoError(t, os.Chdir(wd))
+	}
+}
+
+// loadInitApp loads the app in the supplied directory, from within that directory.
+func loadInitApp(t *testing.T, dir string) *model.App {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer func() {
+		require.NoError(t, os.Chdir(wd))
+	}()
+	app, err := model.NewApp("qbec.yaml", nil, "")
+	require.NoError(t, err)
+	return app
+}
+
+func TestInitBasic(t *testing.T) {
+	defer inTempDir(t)()
+	err := doInit([]string{"my-app"}, initCommandConfig{withExample: true, server: "https://dev-server", namespace: "my-ns"})
+	require.NoError(t, err)
+	for _, f := range []string{"params.libsonnet", "environments/base.libsonnet", "environments/default.libsonnet", "components/hello.jsonnet"} {
+		_, err := os.Stat(filepath.Join("my-app", f))
+		require.NoError(t, err)
+	}
+	app := loadInitApp(t, "my-app")
+	a := assert.New(t)
+	a.Equal("my-app", app.Name())
+	env := app.Environments()["default"]
+	a.Equal("https://dev-server", env.Server)
+	a.Equal("my-ns", env.DefaultNamespace)
+}
+
+func TestInitExisting(t *testing.T) {
+	defer inTempDir(t)()
+	config := initCommandConfig{server: "https://dev-server"}
+	require.NoError(t, doInit([]string{"my-app"}, config))
+	params := filepath.Join("my-app", "params.libsonnet")
+	require.NoError(t, ioutil.WriteFile(params, []byte("{}"), 0644))
+
+	err := doInit([]string{"my-app"}, config)
+	require.Error(t, err)
+	assert.Equal(t, "directory my-app already exists, use --force to overwrite files in it", err.Error())
+
+	config.force = true
+	require.NoError(t, doInit([]string{"my-app"}, config))
+	b, err := ioutil.ReadFile(params)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), "returns the params for the current qbec environment")
+	app := loadInitApp(t, "my-app")
+	assert.Equal(t, "default", app.Environments()["default"].DefaultNamespace)
+}
+
+func TestInitNegative(t *testing.T) {
+	defer inTempDir(t)()
+	require.NoError(t, ioutil.WriteFile("my-app", []byte("foo"), 0644))
+	err := doInit([]string{"my-app"}, initCommandConfig{server: "https://dev-server", force: true})
+	require.Error(t, err)
+	assert.Equal(t, "my-app already exists and is not a directory", err.Error())
+
+	err = doInit(nil, initCommandConfig{})
+	require.Error(t, err)
+	assert.Equal(t, "a single app name argument must be supplied", err.Error())
+}
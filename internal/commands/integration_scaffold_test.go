@@ -79,7 +79,7 @@ func newNamespace(t *testing.T) (name string, reset func()) {
 
 func newIntegrationScaffold(t *testing.T, ns string, dir string) *integrationScaffold {
 	once.Do(func() { initialize(t) })
-	b := newBaseScaffold(t, dir, nil)
+	b := newBaseScaffold(t, dir, nil, nil)
 	return &integrationScaffold{
 		baseScaffold: b,
 		ns:           ns,
@@ -143,9 +143,11 @@ func doLint(args []string, config *lintCommandConfig, ac cmd.AppContext) error {
 		config.files = []string{"."}
 	}
 	var libPaths []string
+	var remoteLibs []vm.RemoteLib
 	var dataSources []datasource.DataSource
 	if ac.App() != nil {
 		libPaths = ac.App().LibPaths()
+		remoteLibs = ac.RemoteLibs()
 		examples := ac.App().DataSourceExamples()
 		for _, dsStr := range ac.App().DataSources() {
 			ds, err := createMockDatasource(dsStr, examples)
@@ -158,6 +160,10 @@ func doLint(args []string, config *lintCommandConfig, ac cmd.AppContext) error {
 	cfg := vm.Config{
 		LibPaths:    libPaths,
 		DataSources: dataSources,
+		RemoteLibs:  remoteLibs,
+		LibCacheDir: ac.LibCacheDir(),
+		Seed:        ac.EvalSeed(),
+		Now:         ac.EvalTime(),
 	}
 	config.vm = vm.New(cfg)
 	config.opts.VerboseWalk = ac.Context.Verbosity() > 0
@@ -19,7 +19,11 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/eval"
@@ -44,6 +48,13 @@ func addFilterParams(c *cobra.Command, includeAllFilters bool) func() (model.Fil
 	}
 }
 
+// localObjectKey returns a key for a shallow duplicate check of objects when no cluster metadata is available.
+func localObjectKey(obj model.K8sMeta) string {
+	gvk := obj.GroupVersionKind()
+	ns := obj.GetNamespace()
+	return fmt.Sprintf("%s:%s:%s:%s", gvk.Group, gvk.Kind, ns, obj.GetName())
+}
+
 func displayName(obj model.K8sLocalObject) string {
 	group := obj.GroupVersionKind().Group
 	if group != "" {
@@ -75,9 +86,103 @@ func checkDuplicates(objects []model.K8sLocalObject, kf keyFunc) error {
 }
 
 type filterOpts struct {
-	filters model.Filters
-	client  model.Namespaced
-	keyFunc keyFunc
+	filters    model.Filters
+	client     model.Namespaced
+	keyFunc    keyFunc
+	timings    bool              // print component evaluation times
+	nsOverride namespaceOverride // namespace to move namespaced objects to
+}
+
+// namespaceOverride moves namespaced objects to a different namespace after evaluation.
+type namespaceOverride struct {
+	namespace string // the target namespace, no override when empty
+	force     bool   // also move objects that explicitly set a namespace other than the default namespace
+}
+
+func addNamespaceOverrideParams(c *cobra.Command) func() (namespaceOverride, error) {
+	var nso namespaceOverride
+	c.Flags().StringVar(&nso.namespace, "namespace", "", "move all namespaced objects that use the default namespace to this namespace")
+	c.Flags().BoolVar(&nso.force, "force-namespace", false, "with --namespace, also move objects that explicitly set a different namespace")
+	return func() (namespaceOverride, error) {
+		if nso.force && nso.namespace == "" {
+			return nso, cmd.NewUsageError("--force-namespace can only be used with --namespace")
+		}
+		return nso, nil
+	}
+}
+
+// apply sets the override namespace on the supplied namespaced objects. Objects that explicitly set a namespace
+// different from the default namespace are left alone unless the override is forced. Cluster scoped objects are
+// never changed.
+func (n namespaceOverride) apply(objects []model.K8sLocalObject, client model.Namespaced, defaultNs string) ([]model.K8sLocalObject, error) {
+	if n.namespace == "" {
+		return objects, nil
+	}
+	ret := make([]model.K8sLocalObject, 0, len(objects))
+	for _, o := range objects {
+		ns := o.GetNamespace()
+		if ns != "" && ns != defaultNs && !n.force {
+			ret = append(ret, o)
+			continue
+		}
+		isNamespaced, err := client.IsNamespaced(o.GroupVersionKind())
+		if err != nil {
+			return nil, errors.Wrapf(err, "namespace override for %s", displayName(o))
+		}
+		if !isNamespaced {
+			ret = append(ret, o)
+			continue
+		}
+		un := o.ToUnstructured().DeepCopy()
+		un.SetNamespace(n.namespace)
+		ret = append(ret, model.NewK8sLocalObject(un.Object, model.LocalAttrs{
+			App:       o.Application(),
+			Tag:       o.Tag(),
+			Component: o.Component(),
+			Env:       o.Environment(),
+		}))
+	}
+	return ret, nil
+}
+
+// componentTimings collects the evaluation times of components.
+type componentTimings struct {
+	l     sync.Mutex
+	times map[string]time.Duration
+}
+
+func (ct *componentTimings) record(component string, elapsed time.Duration) {
+	ct.l.Lock()
+	defer ct.l.Unlock()
+	if ct.times == nil {
+		ct.times = map[string]time.Duration{}
+	}
+	ct.times[component] = elapsed
+}
+
+// print prints evaluation times of components to the console, slowest first.
+func (ct *componentTimings) print() {
+	ct.l.Lock()
+	defer ct.l.Unlock()
+	var names []string
+	width := len("COMPONENT")
+	for name := range ct.times {
+		names = append(names, name)
+		if len(name) > width {
+			width = len(name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		left, right := ct.times[names[i]], ct.times[names[j]]
+		if left == right {
+			return names[i] < names[j]
+		}
+		return left > right
+	})
+	sio.Printf("%-*s  %s\n", width, "COMPONENT", "TIME")
+	for _, name := range names {
+		sio.Printf("%-*s  %v\n", width, name, ct.times[name].Round(time.Millisecond))
+	}
 }
 
 func emptyFilterOpts() filterOpts {
@@ -90,15 +195,102 @@ func makeFilterOpts(filters model.Filters, client cmd.KubeClient) filterOpts {
 
 func generateObjects(_ context.Context, envCtx cmd.EnvContext, opts filterOpts) ([]model.K8sLocalObject, error) {
 	fp := opts.filters
-	client := opts.client
 	components, err := envCtx.App().ComponentsForEnvironment(envCtx.Env(), fp.ComponentIncludes(), fp.ComponentExcludes())
 	if err != nil {
 		return nil, err
 	}
-	output, err := eval.Components(components, envCtx.EvalContext(cleanEvalMode), envCtx.ObjectProducer())
+	output, err := evalComponents(envCtx, components, opts.timings)
+	if err != nil {
+		return nil, err
+	}
+	return filterObjects(envCtx, output, opts)
+}
+
+// LocalObjects returns the objects of the components selected by the supplied filters in the same way as the show
+// command, for programs that use qbec as a library. The filters must not require cluster access.
+func LocalObjects(ctx context.Context, envCtx cmd.EnvContext, fp model.Filters) ([]model.K8sLocalObject, error) {
+	return generateObjects(ctx, envCtx, filterOpts{keyFunc: localObjectKey, filters: fp})
+}
+
+// evalComponents evaluates the supplied components of the environment, printing evaluation times if requested.
+func evalComponents(envCtx cmd.EnvContext, components []model.Component, timings bool) ([]model.K8sLocalObject, error) {
+	evalCtx := envCtx.EvalContext(cleanEvalMode)
+	var ct componentTimings
+	if timings {
+		evalCtx.OnComponentEval = ct.record
+	}
+	output, err := eval.Components(components, evalCtx, envCtx.ObjectProducer())
+	if err != nil {
+		return nil, err
+	}
+	if timings {
+		ct.print()
+	}
+	return output, nil
+}
+
+// renderedEnv holds the objects of an environment evaluated once, such that they can be processed for every target
+// of the environment without evaluating components again.
+type renderedEnv struct {
+	selected []model.K8sLocalObject // objects of the components selected by the filters
+	all      []model.K8sLocalObject // objects of all components, only set when requested
+}
+
+// renderEnv evaluates the components of the environment that are selected by the supplied filters. When all is set,
+// every component is evaluated and the objects of all components are also returned, for garbage collection.
+func renderEnv(envCtx cmd.EnvContext, fp model.Filters, timings bool, all bool) (*renderedEnv, error) {
+	components, err := envCtx.App().ComponentsForEnvironment(envCtx.Env(), fp.ComponentIncludes(), fp.ComponentExcludes())
+	if err != nil {
+		return nil, err
+	}
+	if !all {
+		output, err := evalComponents(envCtx, components, timings)
+		if err != nil {
+			return nil, err
+		}
+		return &renderedEnv{selected: output}, nil
+	}
+	allComponents, err := envCtx.App().ComponentsForEnvironment(envCtx.Env(), nil, nil)
 	if err != nil {
 		return nil, err
 	}
+	output, err := evalComponents(envCtx, allComponents, timings)
+	if err != nil {
+		return nil, err
+	}
+	names := map[string]bool{}
+	for _, c := range components {
+		names[c.Name] = true
+	}
+	ret := &renderedEnv{all: output}
+	for _, o := range output {
+		if names[o.Component()] {
+			ret.selected = append(ret.selected, o)
+		}
+	}
+	return ret, nil
+}
+
+// filterObjects moves the supplied evaluated objects to the override namespace, if any, checks them for duplicates
+// and returns the ones that match the filters.
+func filterObjects(envCtx cmd.EnvContext, output []model.K8sLocalObject, opts filterOpts) ([]model.K8sLocalObject, error) {
+	fp := opts.filters
+	client := opts.client
+	var err error
+	defaultNs := envCtx.App().DefaultNamespace(envCtx.Env())
+	if opts.nsOverride.namespace != "" {
+		if client == nil {
+			client, err = envCtx.Client()
+			if err != nil {
+				return nil, err
+			}
+		}
+		output, err = opts.nsOverride.apply(output, client, defaultNs)
+		if err != nil {
+			return nil, err
+		}
+		defaultNs = opts.nsOverride.namespace
+	}
 	if err := checkDuplicates(output, opts.keyFunc); err != nil {
 		return nil, err
 	}
@@ -112,7 +304,6 @@ func generateObjects(_ context.Context, envCtx cmd.EnvContext, opts filterOpts)
 			return nil, err
 		}
 	}
-	defaultNs := envCtx.App().DefaultNamespace(envCtx.Env())
 
 	var ret []model.K8sLocalObject
 	for _, o := range output {
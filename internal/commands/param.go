@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
 	"sort"
 
 	"github.com/ghodss/yaml"
@@ -98,27 +99,14 @@ type param struct {
 	Value     interface{} `json:"value"`
 }
 
-func extractComponentParams(paramsObject map[string]interface{}, fp model.Filters) (map[string]interface{}, error) {
+// componentParams returns the parameters of the components selected by the supplied filters for the environment.
+// The parameters of other components are not evaluated.
+func componentParams(envCtx cmd.EnvContext, fp model.Filters) (map[string]interface{}, error) {
 	cf, err := model.NewComponentFilter(fp.ComponentIncludes(), fp.ComponentExcludes())
 	if err != nil {
 		return nil, err
 	}
-	baseComponents, ok := paramsObject["components"].(map[string]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unable to find 'components' key in the parameter object")
-	}
-	var components map[string]interface{}
-	if !cf.HasFilters() {
-		components = baseComponents
-	} else {
-		components = map[string]interface{}{}
-		for k, v := range baseComponents {
-			if cf.ShouldInclude(k) {
-				components[k] = v
-			}
-		}
-	}
-	return components, nil
+	return eval.ComponentParams(envCtx.App().ParamsFile(), envCtx.EvalContext(cleanEvalMode), cf.ShouldInclude)
 }
 
 type paramListCommandConfig struct {
@@ -138,20 +126,15 @@ func doParamList(args []string, config paramListCommandConfig) error {
 			return err
 		}
 	}
-	paramsFile := config.App().ParamsFile()
 	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
 	}
-	paramsObject, err := eval.Params(paramsFile, envCtx.EvalContext(cleanEvalMode))
-	if err != nil {
-		return err
-	}
 	fp, err := config.filterFunc()
 	if err != nil {
 		return err
 	}
-	components, err := extractComponentParams(paramsObject, fp)
+	components, err := componentParams(envCtx, fp)
 	if err != nil {
 		return err
 	}
@@ -177,9 +160,60 @@ func newParamListCommand(cp ctxProvider) *cobra.Command {
 
 type paramDiffCommandConfig struct {
 	cmd.AppContext
+	format     string
 	filterFunc func() (model.Filters, error)
 }
 
+// paramChange is a single difference between the parameters of two environments.
+type paramChange struct {
+	Component string      `json:"component"`
+	Name      string      `json:"name"`
+	Change    string      `json:"change"`          // one of added, removed or changed
+	Left      interface{} `json:"left,omitempty"`  // value in the left environment, not set for additions
+	Right     interface{} `json:"right,omitempty"` // value in the right environment, not set for removals
+}
+
+// paramChanges returns the differences between the left and right component parameters sorted by component and
+// parameter name.
+func paramChanges(left, right map[string]interface{}) []paramChange {
+	flatten := func(components map[string]interface{}) map[[2]string]interface{} {
+		ret := map[[2]string]interface{}{}
+		for c, v := range components {
+			val, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			for n, v := range val {
+				ret[[2]string{c, n}] = v
+			}
+		}
+		return ret
+	}
+	l, r := flatten(left), flatten(right)
+	ret := []paramChange{}
+	for k, lv := range l {
+		rv, ok := r[k]
+		switch {
+		case !ok:
+			ret = append(ret, paramChange{Component: k[0], Name: k[1], Change: "removed", Left: lv})
+		case !reflect.DeepEqual(lv, rv):
+			ret = append(ret, paramChange{Component: k[0], Name: k[1], Change: "changed", Left: lv, Right: rv})
+		}
+	}
+	for k, rv := range r {
+		if _, ok := l[k]; !ok {
+			ret = append(ret, paramChange{Component: k[0], Name: k[1], Change: "added", Right: rv})
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Component != ret[j].Component {
+			return ret[i].Component < ret[j].Component
+		}
+		return ret[i].Name < ret[j].Name
+	})
+	return ret
+}
+
 func doParamDiff(args []string, config paramDiffCommandConfig) error {
 	var leftEnv, rightEnv string
 	switch len(args) {
@@ -192,53 +226,71 @@ func doParamDiff(args []string, config paramDiffCommandConfig) error {
 	default:
 		return cmd.NewUsageError("one or two environments required")
 	}
+	switch config.format {
+	case "", "json", "yaml":
+	default:
+		return cmd.NewUsageError(fmt.Sprintf("invalid output format %q, must be one of json or yaml", config.format))
+	}
 
 	fp, err := config.filterFunc()
 	if err != nil {
 		return err
 	}
-	getParams := func(env string) (str string, name string, err error) {
+	getParams := func(env string) (components map[string]interface{}, name string, err error) {
 		if env != model.Baseline {
 			_, err := config.App().ServerURL(env)
 			if err != nil {
-				return "", "", err
+				return nil, "", err
 			}
 		}
-		paramsFile := config.App().ParamsFile()
 		envCtx, err := config.AppContext.EnvContext(env)
 		if err != nil {
-			return "", "", err
-		}
-		paramsObject, err := eval.Params(paramsFile, envCtx.EvalContext(cleanEvalMode))
-		if err != nil {
-			return "", "", err
+			return nil, "", err
 		}
-		components, err := extractComponentParams(paramsObject, fp)
+		components, err = componentParams(envCtx, fp)
 		if err != nil {
-			return "", "", err
-		}
-		var buf bytes.Buffer
-		if err := listParams(components, false, "", &buf); err != nil {
-			return "", "", err
+			return nil, "", err
 		}
 		name = "environment: " + env
 		if env == model.Baseline {
 			name = "baseline"
 		}
-		return buf.String(), name, nil
+		return components, name, nil
 	}
 
-	var left, right, leftName, rightName string
-
-	left, leftName, err = getParams(leftEnv)
+	leftParams, leftName, err := getParams(leftEnv)
 	if err != nil {
 		return err
 	}
-	right, rightName, err = getParams(rightEnv)
+	rightParams, rightName, err := getParams(rightEnv)
 	if err != nil {
 		return err
 	}
 
+	switch config.format {
+	case "json":
+		encoder := json.NewEncoder(config.Stdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(paramChanges(leftParams, rightParams))
+	case "yaml":
+		b, err := yaml.Marshal(paramChanges(leftParams, rightParams))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(config.Stdout(), "---")
+		fmt.Fprintf(config.Stdout(), "%s\n", b)
+		return nil
+	}
+
+	var lb, rb bytes.Buffer
+	if err := listParams(leftParams, false, "", &lb); err != nil {
+		return err
+	}
+	if err := listParams(rightParams, false, "", &rb); err != nil {
+		return err
+	}
+	left, right := lb.String(), rb.String()
+
 	opts := diff.Options{Context: -1, LeftName: leftName, RightName: rightName, Colorize: config.Colorize()}
 	d, err := diff.Strings(left, right, opts)
 	if err != nil {
@@ -259,6 +311,7 @@ func newParamDiffCommand(cp ctxProvider) *cobra.Command {
 	config := paramDiffCommandConfig{
 		filterFunc: addFilterParams(c, false),
 	}
+	c.Flags().StringVarP(&config.format, "format", "o", "", "use json|yaml to display a machine readable list of added, removed and changed parameters")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -90,6 +90,52 @@ func TestParamDiff2Envs(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(`\+service1\s+cpu\s+"1"`))
 }
 
+func TestParamDiffJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("param", "diff", "dev", "prod", "-o", "json")
+	require.NoError(t, err)
+	var changes []paramChange
+	err = s.jsonOutput(&changes)
+	require.NoError(t, err)
+	assert.Equal(t, []paramChange{
+		{Component: "service1", Name: "cpu", Change: "changed", Left: "10m", Right: "1"},
+		{Component: "service2", Name: "cpu", Change: "changed", Left: "50m", Right: "100m"},
+		{Component: "service2", Name: "memory", Change: "changed", Left: "8Gi", Right: "16Gi"},
+	}, changes)
+}
+
+func TestParamDiffYAML(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("param", "diff", "dev", "-o", "yaml", "-c", "service2")
+	require.NoError(t, err)
+	docs, err := s.yamlOutput()
+	require.NoError(t, err)
+	require.Equal(t, 1, len(docs))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"component": "service2", "name": "cpu", "change": "changed", "left": "100m", "right": "50m"},
+	}, docs[0])
+}
+
+func TestParamChanges(t *testing.T) {
+	left := map[string]interface{}{
+		"a": map[string]interface{}{"same": 1, "removed": "x", "changed": map[string]interface{}{"foo": "bar"}},
+		"b": map[string]interface{}{"gone": true},
+	}
+	right := map[string]interface{}{
+		"a": map[string]interface{}{"same": 1, "changed": map[string]interface{}{"foo": "baz"}, "added": []interface{}{"y"}},
+		"c": map[string]interface{}{"new": 10},
+	}
+	assert.Equal(t, []paramChange{
+		{Component: "a", Name: "added", Change: "added", Right: []interface{}{"y"}},
+		{Component: "a", Name: "changed", Change: "changed", Left: map[string]interface{}{"foo": "bar"}, Right: map[string]interface{}{"foo": "baz"}},
+		{Component: "a", Name: "removed", Change: "removed", Left: "x"},
+		{Component: "b", Name: "gone", Change: "removed", Left: true},
+		{Component: "c", Name: "new", Change: "added", Right: 10},
+	}, paramChanges(left, right))
+}
+
 func TestParamNegative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -150,6 +196,15 @@ func TestParamNegative(t *testing.T) {
 				a.Equal(`one or two environments required`, err.Error())
 			},
 		},
+		{
+			name: "diff bad format",
+			args: []string{"param", "diff", "dev", "-o", "table"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid output format "table", must be one of json or yaml`, err.Error())
+			},
+		},
 		{
 			name: "diff 3 envs",
 			args: []string{"param", "diff", "dev", "prod", "_"},
@@ -0,0 +1,62 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+)
+
+type schemaCommandConfig struct {
+	cmd.AppContext
+	kind string
+}
+
+func newAlphaSchemaCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "schema [--kind <kind>]",
+		Short:   "print the JSON schema of qbec.yaml or environment files for editor validation",
+		Example: schemaExamples(),
+	}
+
+	config := schemaCommandConfig{}
+	c.Flags().StringVar(&config.kind, "kind", "App", "kind of document, one of App for qbec.yaml or EnvironmentMap for environment files")
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		config.AppContext = cp()
+		return cmd.WrapError(doSchema(args, config))
+	}
+	return c
+}
+
+func doSchema(args []string, config schemaCommandConfig) error {
+	if len(args) != 0 {
+		return cmd.NewUsageError("extra arguments specified")
+	}
+	if config.kind != "App" && config.kind != "EnvironmentMap" {
+		return cmd.NewUsageError(fmt.Sprintf("invalid kind %q, must be one of App or EnvironmentMap", config.kind))
+	}
+	b, err := model.JSONSchema(config.kind)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(config.Stdout(), "%s\n", b)
+	return err
+}
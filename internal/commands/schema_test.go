@@ -0,0 +1,73 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"testing"
+
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		ref  string
+	}{
+		{name: "default", ref: "#/definitions/qbec.io.v1alpha1.App"},
+		{name: "env map", args: []string{"--kind", "EnvironmentMap"}, ref: "#/definitions/qbec.io.v1alpha1.EnvironmentMap"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"alpha", "schema"}, test.args...)...)
+			require.NoError(t, err)
+			var doc map[string]interface{}
+			err = s.jsonOutput(&doc)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.Equal("http://json-schema.org/draft-07/schema#", doc["$schema"])
+			a.Equal(test.ref, doc["$ref"])
+			a.Contains(doc["definitions"], "qbec.io.v1alpha1.Environment")
+		})
+	}
+}
+
+func TestSchemaNegative(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		msg  string
+	}{
+		{name: "bad kind", args: []string{"--kind", "Environment"}, msg: `invalid kind "Environment", must be one of App or EnvironmentMap`},
+		{name: "extra args", args: []string{"App"}, msg: "extra arguments specified"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"alpha", "schema"}, test.args...)...)
+			require.Error(t, err)
+			a := assert.New(t)
+			a.True(cmd.IsUsageError(err))
+			a.Equal(test.msg, err.Error())
+		})
+	}
+}
@@ -17,8 +17,11 @@
 package commands
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
@@ -173,12 +176,88 @@ func setWorkDir(specified string) error {
 	}
 }
 
+// readApp sets the working directory to the qbec root and reads the qbec.yaml file in it, or the app file specified
+// on the command line. The root of an app file defaults to the directory that contains it, and to the current
+// directory for URLs and standard input. The root need not contain a qbec.yaml file in this case. It returns the
+// name of the file for use in messages, its contents and the root directory.
+func readApp(ctx cmd.Context) (name string, b []byte, root string, err error) {
+	file := ctx.AppFile()
+	if file == "" {
+		if err := setWorkDir(ctx.RootDir()); err != nil {
+			return "", nil, "", err
+		}
+		b, err := ioutil.ReadFile("qbec.yaml")
+		if err != nil {
+			return "", nil, "", err
+		}
+		return "qbec.yaml", b, ".", nil
+	}
+	root = ctx.RootDir()
+	if file != model.StdinAppFile && !filematcher.IsRemoteFile(file) {
+		abs, err := filepath.Abs(file)
+		if err != nil {
+			return "", nil, "", err
+		}
+		file = abs
+		if root == "" {
+			root = filepath.Dir(file)
+		}
+	}
+	if root == "" {
+		root = "."
+	}
+	root, err = filepath.Abs(root)
+	if err != nil {
+		return "", nil, "", err
+	}
+	b, err = model.ReadAppFile(file, ctx.Stdin())
+	if err != nil {
+		return "", nil, "", err
+	}
+	sio.Debugln(fmt.Sprintf("cd %s", root))
+	if err := os.Chdir(root); err != nil {
+		return "", nil, "", err
+	}
+	name = file
+	if file == model.StdinAppFile {
+		name = "<stdin>"
+	}
+	return name, b, root, nil
+}
+
+// loadApp reads the app as described for readApp and loads it.
+func loadApp(ctx cmd.Context, envFiles []string) (*model.App, error) {
+	name, b, root, err := readApp(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return model.NewAppFromContent(name, b, root, envFiles, ctx.AppTag())
+}
+
+// matchEnvFiles returns the env files specified on the command line, expanding glob patterns. This must be called
+// before the working directory is changed, so that the files are resolved with respect to the current directory.
+func matchEnvFiles(ctx cmd.Context) ([]string, error) {
+	var envFiles []string
+	for _, envFile := range ctx.EnvFiles() {
+		files, err := filematcher.Match(envFile)
+		if err != nil {
+			return nil, err
+		}
+		envFiles = append(envFiles, files...)
+	}
+	return envFiles, nil
+}
+
 var noQbecContext = map[string]bool{
 	"version":    true,
 	"init":       true,
 	"completion": true,
 	"options":    true,
 	"fmt":        true,
+	"schema":     true,
+
+	cobra.ShellCompRequestCmd:       true,
+	cobra.ShellCompNoDescRequestCmd: true,
 }
 
 func doSetup(root *cobra.Command, opts cmd.Options) {
@@ -198,11 +277,15 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 			return err
 		}
 		sio.EnableColors(ctx.Colorize())
-		cmd.RegisterSignalHandlers()
+		sio.EnableJSON(ctx.LogFormat() == "json")
+		if !opts.SkipSignalHandlers {
+			cmd.RegisterSignalHandlers()
+		}
 
 		skipApp := noQbecContext[c.Name()]
-		// for the eval command, require qbec machinery only if the env option is specified
-		if c.Name() == "eval" {
+		// for the eval command, require qbec machinery only if the env option is specified. The alpha eval command
+		// always requires an environment.
+		if c.Name() == "eval" && c.Parent().Name() != "alpha" {
 			e, err := c.Flags().GetString("env")
 			if err != nil {
 				return err
@@ -221,6 +304,11 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 			skipApp = !e
 		}
 
+		// env validate loads the app itself in order to report all problems with it
+		if c.Name() == "validate" && c.Parent().Name() == "env" {
+			skipApp = true
+		}
+
 		// retain backwards compatibility for the alpha fmt command
 		if c.Name() == "fmt" && c.Parent().Name() == "alpha" {
 			skipApp = false
@@ -232,18 +320,11 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 		}
 		// if env file has been specified on the command line, ensure it is resolved w.r.t to the current working
 		// directory before we change it
-		var envFiles []string
-		for _, envFile := range ctx.EnvFiles() {
-			files, err := filematcher.Match(envFile)
-			if err != nil {
-				return err
-			}
-			envFiles = append(envFiles, files...)
-		}
-		if err := setWorkDir(ctx.RootDir()); err != nil {
+		envFiles, err := matchEnvFiles(ctx)
+		if err != nil {
 			return err
 		}
-		app, err := model.NewApp("qbec.yaml", envFiles, ctx.AppTag())
+		app, err := loadApp(ctx, envFiles)
 		if err != nil {
 			return err
 		}
@@ -252,6 +333,9 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 			return err
 		}
 		app.SetOverrideNamespace(forceOpts.K8sNamespace)
+		if err := app.SetGCTag(ctx.GCTag()); err != nil {
+			return err
+		}
 		appCtx, err = ctx.AppContext(app)
 		return err
 	}
@@ -263,6 +347,7 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 	setupCommands(root, func() cmd.AppContext {
 		return appCtx
 	})
+	addDynamicCompletions(root, root)
 }
 
 // Setup sets up all sub-commands for the supplied root command and adds facilities for commands
@@ -270,3 +355,35 @@ func doSetup(root *cobra.Command, opts cmd.Options) {
 func Setup(root *cobra.Command) {
 	doSetup(root, cmd.Options{})
 }
+
+// Execute runs the supplied qbec command line in-process, for programs that embed qbec. Command output is written
+// to stdout and progress, warning and error messages to stderr. The working directory of the process is changed
+// to the qbec root while the command runs and is restored on return, so Execute must not be called concurrently.
+func Execute(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return errors.Wrap(err, "os.Getwd")
+	}
+	oldOut := sio.Output
+	sio.Output = stderr
+	defer func() {
+		_ = cmd.Close() // data sources are not closed by the post-run hook when the command fails
+		sio.Output = oldOut
+		_ = os.Chdir(wd)
+	}()
+	root := &cobra.Command{
+		Use:           Executable,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	doSetup(root, cmd.Options{
+		Stdout:             stdout,
+		Stderr:             stderr,
+		SkipConfirm:        true,
+		SkipSignalHandlers: true,
+	})
+	root.SetOut(stdout)
+	root.SetErr(stderr)
+	root.SetArgs(args)
+	return root.ExecuteContext(ctx)
+}
@@ -18,6 +18,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -65,6 +66,7 @@ func TestOptionsCommand(t *testing.T) {
 	require.NoError(t, err)
 	a := assert.New(t)
 	expectedStrings := []string{
+		"--app-root",
 		"--app-tag",
 		"--colors",
 		"--env-file",
@@ -147,6 +149,16 @@ func TestSetupEnvironments(t *testing.T) {
 				assert.Contains(t, out, "minikube")
 			},
 		},
+		{
+			name: "app root",
+			fn: func(t *testing.T, s *scaffold) {
+				err := s.executeCommand("--app-root", "testdata", "env", "list")
+				require.NoError(t, err)
+				out := s.stdout()
+				assert.Contains(t, out, "dev")
+				assert.Contains(t, out, "minikube")
+			},
+		},
 		{
 			name:   "env root",
 			envMap: map[string]string{"QBEC_ROOT": "testdata"},
@@ -199,6 +211,50 @@ func TestSetupEnvironments(t *testing.T) {
 				assert.True(t, errors.Is(err, fs.ErrNotExist))
 			},
 		},
+		{
+			name: "app file",
+			fn: func(t *testing.T, s *scaffold) {
+				err := s.executeCommand("--app-file", "testdata/qbec.yaml", "env", "list")
+				require.NoError(t, err)
+				out := s.stdout()
+				assert.Contains(t, out, "dev")
+				assert.Contains(t, out, "minikube")
+			},
+		},
+		{
+			name: "app file from URL",
+			fn: func(t *testing.T, s *scaffold) {
+				b, err := ioutil.ReadFile("testdata/qbec.yaml")
+				require.NoError(t, err)
+				ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write(b)
+				}))
+				defer ts.Close()
+				err = s.executeCommand("--root", "testdata", "--app-file", ts.URL, "env", "list")
+				require.NoError(t, err)
+				out := s.stdout()
+				assert.Contains(t, out, "dev")
+				assert.Contains(t, out, "minikube")
+			},
+		},
+		{
+			name: "app file from bad URL",
+			fn: func(t *testing.T, s *scaffold) {
+				ts := httptest.NewServer(http.NotFoundHandler())
+				defer ts.Close()
+				err := s.executeCommand("--root", "testdata", "--app-file", ts.URL, "env", "list")
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), "download app from "+ts.URL)
+			},
+		},
+		{
+			name: "bad app file",
+			fn: func(t *testing.T, s *scaffold) {
+				err := s.executeCommand("--app-file", "testdata/qbec-missing.yaml", "env", "list")
+				require.Error(t, err)
+				assert.True(t, errors.Is(err, fs.ErrNotExist))
+			},
+		},
 		{
 			name: "force current context",
 			envMap: map[string]string{
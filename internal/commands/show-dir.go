@@ -0,0 +1,258 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/sio"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// checkPathSegment returns an error if the supplied name cannot be used as a single element of a file path.
+func checkPathSegment(what, name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid %s %q, cannot be used in a file path", what, name)
+	}
+	return nil
+}
+
+// objectFilePath returns the path of the file for the supplied object relative to the output directory. The path
+// is of the form <kind>[.<group>]/[<namespace>/]<name>.<format> with a lower-case kind. Objects with generated names
+// use their name prefix followed by "generated" as the name. Kinds, namespaces and names that would place the file
+// elsewhere are an error.
+func objectFilePath(obj *unstructured.Unstructured, format string) (string, error) {
+	gvk := obj.GroupVersionKind()
+	kind := strings.ToLower(gvk.Kind)
+	if gvk.Group != "" {
+		kind += "." + gvk.Group
+	}
+	name := obj.GetName()
+	if name == "" {
+		name = obj.GetGenerateName() + "generated"
+	}
+	if err := checkPathSegment("kind", kind); err != nil {
+		return "", err
+	}
+	parts := []string{kind}
+	if ns := obj.GetNamespace(); ns != "" {
+		if err := checkPathSegment("namespace", ns); err != nil {
+			return "", err
+		}
+		parts = append(parts, ns)
+	}
+	if err := checkPathSegment("name", name); err != nil {
+		return "", err
+	}
+	parts = append(parts, name+"."+format)
+	return filepath.Join(parts...), nil
+}
+
+// filesManifest is the name of the file that lists the files written to the output directory, such that files
+// written by an earlier run can be removed when they are no longer produced.
+const filesManifest = ".qbec-files"
+
+// kustomizationFile is the name of the index file that is written for the kustomize format.
+const kustomizationFile = "kustomization.yaml"
+
+// kustomization is the subset of a kustomize index written for the kustomize format.
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+}
+
+// writeObjectFiles writes every object to its own file under the output directory, creating directories as needed
+// and overwriting existing files. For the kustomize format, objects are written as YAML and a kustomization file
+// listing them is written at the root of the directory. The files that were written are recorded in a manifest
+// in the directory. When clean is set, files recorded by the previous run that were not written by this one are
+// removed, along with directories that become empty as a result. Other files are never removed.
+func writeObjectFiles(dir string, objects []model.K8sLocalObject, displayObjects []*unstructured.Unstructured, format string, clean bool) error {
+	ext := format
+	if format == "kustomize" {
+		ext = "yaml"
+	}
+	previous, err := readFilesManifest(dir)
+	if err != nil {
+		return err
+	}
+	written := map[string]string{}
+	var resources []string
+	for i, o := range displayObjects {
+		name := model.NameForDisplay(objects[i])
+		rel, err := objectFilePath(o, ext)
+		if err != nil {
+			return errors.Wrapf(err, "write %s", name)
+		}
+		file := filepath.Join(dir, rel)
+		if prev, ok := written[file]; ok {
+			return fmt.Errorf("objects %s and %s map to the same file %s", prev, name, file)
+		}
+		written[file] = name
+		resources = append(resources, filepath.ToSlash(rel))
+		var b []byte
+		switch format {
+		case "json":
+			b, err = json.MarshalIndent(o, "", "  ")
+			b = append(b, '\n')
+		default:
+			b, err = yaml.Marshal(o)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "serialize %s", name)
+		}
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(file, b, 0644); err != nil {
+			return err
+		}
+	}
+	sio.Noticef("wrote %d object(s) to %s\n", len(written), dir)
+	if format == "kustomize" {
+		file := filepath.Join(dir, kustomizationFile)
+		if err := writeKustomization(file, resources); err != nil {
+			return err
+		}
+		written[file] = kustomizationFile
+	}
+	if clean {
+		if err := removeStaleFiles(dir, previous, written); err != nil {
+			return err
+		}
+	} else {
+		// files from the previous run that were not removed are still owned by qbec
+		for _, rel := range previous {
+			file := filepath.Join(dir, rel)
+			if _, ok := written[file]; !ok {
+				written[file] = rel
+			}
+		}
+	}
+	return writeFilesManifest(dir, written)
+}
+
+// readFilesManifest returns the paths relative to the supplied directory of the files recorded by the previous run,
+// if any. Paths that are outside the directory are an error.
+func readFilesManifest(dir string) ([]string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, filesManifest))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ret []string
+	for _, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		rel := filepath.Clean(filepath.FromSlash(line))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return nil, fmt.Errorf("%s: invalid path %s outside %s", filepath.Join(dir, filesManifest), line, dir)
+		}
+		ret = append(ret, rel)
+	}
+	return ret, nil
+}
+
+// writeFilesManifest records the supplied files in the manifest of the directory.
+func writeFilesManifest(dir string, files map[string]string) error {
+	var lines []string
+	for file := range files {
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, filepath.ToSlash(rel))
+	}
+	sort.Strings(lines)
+	return ioutil.WriteFile(filepath.Join(dir, filesManifest), []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// writeKustomization writes a kustomization file to the supplied path that lists the supplied resources.
+func writeKustomization(file string, resources []string) error {
+	b, err := yaml.Marshal(kustomization{
+		APIVersion: "kustomize.config.k8s.io/v1beta1",
+		Kind:       "Kustomization",
+		Resources:  resources,
+	})
+	if err != nil {
+		return errors.Wrap(err, "serialize kustomization")
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, b, 0644)
+}
+
+// removeStaleFiles removes the previously written files, relative to the directory, that are not in the set of
+// current files, and the directories containing them that are empty after the removal.
+func removeStaleFiles(dir string, previous []string, current map[string]string) error {
+	var stale []string
+	dirs := map[string]bool{}
+	for _, rel := range previous {
+		file := filepath.Join(dir, rel)
+		if _, ok := current[file]; ok {
+			continue
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		}
+		stale = append(stale, file)
+		for d := filepath.Dir(file); d != dir && d != "."; d = filepath.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	for _, file := range stale {
+		sio.Debugln("remove stale file", file)
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	// remove nested directories before their parents
+	var sorted []string
+	for d := range dirs {
+		sorted = append(sorted, d)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(sorted)))
+	for _, d := range sorted {
+		entries, err := ioutil.ReadDir(d)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			if err := os.Remove(d); err != nil {
+				return err
+			}
+		}
+	}
+	if len(stale) > 0 {
+		sio.Noticef("removed %d stale file(s) from %s\n", len(stale), dir)
+	}
+	return nil
+}
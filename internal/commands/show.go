@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -90,10 +91,49 @@ type showCommandConfig struct {
 	format          string
 	formatSpecified bool
 	sortAsApply     bool
+	sortBy          string
 	namesOnly       bool
+	timings         bool
+	outputDir       string
+	cleanOutputDir  bool
 	filterFunc      func() (model.Filters, error)
 }
 
+// sortKeys are the attributes by which show output can be sorted, mapped to functions that extract them.
+var sortKeys = map[string]func(o model.K8sLocalObject) string{
+	"component": func(o model.K8sLocalObject) string { return o.Component() },
+	"kind":      func(o model.K8sLocalObject) string { return o.GetKind() },
+	"namespace": func(o model.K8sLocalObject) string { return o.GetNamespace() },
+	"name":      func(o model.K8sLocalObject) string { return o.GetName() },
+}
+
+// parseSortKeys parses a comma-separated list of sort keys.
+func parseSortKeys(s string) ([]string, error) {
+	var ret []string
+	for _, k := range strings.Split(s, ",") {
+		k = strings.TrimSpace(k)
+		if _, ok := sortKeys[k]; !ok {
+			return nil, cmd.NewUsageError(fmt.Sprintf("invalid sort key %q, must be one of component, kind, namespace or name", k))
+		}
+		ret = append(ret, k)
+	}
+	return ret, nil
+}
+
+// sortObjectsBy sorts objects lexicographically using the supplied keys in order. Objects with identical
+// keys retain their relative order.
+func sortObjectsBy(objects []model.K8sLocalObject, keys []string) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		for _, k := range keys {
+			left, right := sortKeys[k](objects[i]), sortKeys[k](objects[j])
+			if left != right {
+				return left < right
+			}
+		}
+		return false
+	})
+}
+
 func removeMetadataKey(un *unstructured.Unstructured, name string) {
 	meta := un.Object["metadata"]
 	if m, ok := meta.(map[string]interface{}); ok {
@@ -133,27 +173,40 @@ func doShow(ctx context.Context, args []string, config showCommandConfig) error
 	}
 	env := args[0]
 	format := config.format
-	if format != "json" && format != "yaml" {
+	if format != "json" && format != "yaml" && format != "kustomize" {
 		return cmd.NewUsageError(fmt.Sprintf("invalid output format: %q", format))
 	}
+	if config.outputDir != "" && config.namesOnly {
+		return cmd.NewUsageError("--output-dir cannot be used together with --objects")
+	}
+	if format == "kustomize" && config.outputDir == "" {
+		return cmd.NewUsageError("--format=kustomize requires --output-dir")
+	}
+	if config.cleanOutputDir && config.outputDir == "" {
+		return cmd.NewUsageError("--clean-output-dir requires --output-dir")
+	}
+	var sortBy []string
+	if config.sortBy != "" {
+		if config.sortAsApply {
+			return cmd.NewUsageError("--sort-by cannot be used together with --sort-apply")
+		}
+		keys, err := parseSortKeys(config.sortBy)
+		if err != nil {
+			return err
+		}
+		sortBy = keys
+	}
 	fp, err := config.filterFunc()
 	if err != nil {
 		return err
 	}
 
-	// shallow duplicate check
-	keyFunc := func(obj model.K8sMeta) string {
-		gvk := obj.GroupVersionKind()
-		ns := obj.GetNamespace()
-		return fmt.Sprintf("%s:%s:%s:%s", gvk.Group, gvk.Kind, ns, obj.GetName())
-	}
-
 	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
 	}
 
-	objects, err := generateObjects(ctx, envCtx, filterOpts{keyFunc: keyFunc, filters: fp})
+	objects, err := generateObjects(ctx, envCtx, filterOpts{keyFunc: localObjectKey, filters: fp, timings: config.timings})
 	if err != nil {
 		return err
 	}
@@ -172,9 +225,12 @@ func doShow(ctx context.Context, args []string, config showCommandConfig) error
 			if err != nil {
 				return err
 			}
-			objects = objsort.Sort(objects, sortConfig(client.IsNamespaced))
+			objects = objsort.Sort(objects, sortConfig(client.IsNamespaced, config.App().ApplyOrder()))
 		}
 	}
+	if sortBy != nil {
+		sortObjectsBy(objects, sortBy)
+	}
 
 	if config.namesOnly {
 		return showNames(objects, config.formatSpecified, format, config.Stdout())
@@ -191,6 +247,10 @@ func doShow(ctx context.Context, args []string, config showCommandConfig) error
 		displayObjects = append(displayObjects, mapper(o))
 	}
 
+	if config.outputDir != "" {
+		return writeObjectFiles(config.outputDir, objects, displayObjects, format, config.cleanOutputDir)
+	}
+
 	switch format {
 	case "json":
 		encoder := json.NewEncoder(config.Stdout())
@@ -221,11 +281,15 @@ func newShowCommand(cp ctxProvider) *cobra.Command {
 	}
 
 	var clean bool
-	c.Flags().StringVarP(&config.format, "format", "o", "yaml", "Output format. Supported values are: json, yaml")
+	c.Flags().StringVarP(&config.format, "format", "o", "yaml", "Output format. Supported values are: json, yaml and kustomize, which requires --output-dir")
 	c.Flags().BoolVarP(&config.namesOnly, "objects", "O", false, "Only print names of objects instead of their contents")
 	c.Flags().BoolVar(&config.sortAsApply, "sort-apply", false, "sort output in apply order (requires cluster access)")
+	c.Flags().StringVar(&config.sortBy, "sort-by", "", "sort output lexicographically by a comma-separated list of component, kind, namespace and name")
 	c.Flags().BoolVar(&clean, "clean", false, "do not display qbec-generated labels and annotations")
 	c.Flags().BoolVarP(&config.showSecrets, "show-secrets", "S", false, "do not obfuscate secret values in the output")
+	c.Flags().BoolVar(&config.timings, "timings", false, "print the evaluation time of every component to stderr")
+	c.Flags().StringVar(&config.outputDir, "output-dir", "", "write every object to its own file under this directory instead of stdout")
+	c.Flags().BoolVar(&config.cleanOutputDir, "clean-output-dir", false, "remove files written to the output directory by an earlier run that are not written by this run")
 
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
@@ -17,14 +17,24 @@
 package commands
 
 import (
+	"bytes"
 	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ghodss/yaml"
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/sio"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func TestShowBasic(t *testing.T) {
@@ -47,6 +57,30 @@ func TestShowBasic(t *testing.T) {
 	a.True(pos1 < pos2) // namespace before psp in std sort
 }
 
+func TestShowTimings(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("show", "dev", "--timings")
+	require.NoError(t, err)
+	s.assertErrorLineMatch(regexp.MustCompile(`^COMPONENT\s+TIME$`))
+	for _, c := range []string{"cluster-objects", "service2", "test-job"} {
+		s.assertErrorLineMatch(regexp.MustCompile(`^` + c + `\s+\d`))
+	}
+}
+
+func TestComponentTimingsOrder(t *testing.T) {
+	o := sio.Output
+	defer func() { sio.Output = o }()
+	var buf bytes.Buffer
+	sio.Output = &buf
+	var ct componentTimings
+	ct.record("fast", time.Millisecond)
+	ct.record("slow", time.Second)
+	ct.record("medium", 100*time.Millisecond)
+	ct.print()
+	assert.Equal(t, "COMPONENT  TIME\nslow       1s\nmedium     100ms\nfast       1ms\n", buf.String())
+}
+
 func TestShowBasicClean(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -95,6 +129,75 @@ func TestShowApplySortBaseline(t *testing.T) {
 	a.Contains(s.stderr(), "[warn] cannot sort in apply order for baseline environment")
 }
 
+func TestShowSortBy(t *testing.T) {
+	tests := []struct {
+		sortBy   string
+		expected []string
+	}{
+		{
+			sortBy: "name",
+			expected: []string{
+				"Job:<nil>", // generated name
+				"PodSecurityPolicy:100-default",
+				"PodSecurityPolicy:200-allow-root",
+				"ClusterRole:allow-root-psp-policy",
+				"ClusterRoleBinding:allow-root-psp-policy",
+				"Namespace:bar-system",
+				"ClusterRoleBinding:default-psp-policy",
+				"Namespace:foo-system",
+				"ConfigMap:svc2-cm",
+				"Deployment:svc2-deploy",
+				"Secret:svc2-secret",
+			},
+		},
+		{
+			sortBy: "kind,namespace,name",
+			expected: []string{
+				"ClusterRole:allow-root-psp-policy",
+				"ClusterRoleBinding:allow-root-psp-policy",
+				"ClusterRoleBinding:default-psp-policy",
+				"ConfigMap:svc2-cm",
+				"Deployment:svc2-deploy",
+				"Job:<nil>",
+				"Namespace:bar-system",
+				"Namespace:foo-system",
+				"PodSecurityPolicy:100-default",
+				"PodSecurityPolicy:200-allow-root",
+				"Secret:svc2-secret",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.sortBy, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand("show", "dev", "--sort-by", test.sortBy, "-o", "json")
+			require.NoError(t, err)
+			var data []map[string]interface{}
+			err = s.jsonOutput(&data)
+			require.NoError(t, err)
+			var names []string
+			for _, o := range data {
+				meta := o["metadata"].(map[string]interface{})
+				names = append(names, fmt.Sprintf("%s:%v", o["kind"], meta["name"]))
+			}
+			assert.EqualValues(t, test.expected, names)
+		})
+	}
+}
+
+func TestShowHelmChart(t *testing.T) {
+	s := newCustomScaffold(t, "testdata/projects/helm-chart")
+	defer s.reset()
+	err := s.executeCommand("show", "local", "-o", "json")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+"name": "rendered"`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+"qbec\.io/component": "cache"`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+"qbec\.io/environment": "local"`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+"args": "template redis --name-template cache --namespace cache-ns --repo https://charts.example.com --version 1.2.3 --values -"`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\s+"values": "env: local\\n"`))
+}
+
 func TestShowBasicJSON(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -161,6 +264,15 @@ func TestShowObjectsComponentFilter2(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(`\s+name: svc2`))
 }
 
+func TestShowObjectsAnnotationFilter(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("show", "dev", "-O", "--annotation-selector", "qbec.io/component=service2")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`service2\s+ConfigMap\s+svc2-cm\s+bar-system`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`cluster-objects\s+Namespace\s+bar-system`))
+}
+
 func TestShowObjectsKindFilter(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -196,6 +308,52 @@ func TestShowObjectsKindFilter3(t *testing.T) {
 	assert.Contains(t, s.stderr(), "check for typos and kind abbreviations")
 }
 
+func TestShowKindAndComponentFilters(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "include both",
+			args:     []string{"-c", "service2", "-k", "configmap", "-k", "secrets"},
+			expected: []string{"ConfigMap:svc2-cm", "Secret:svc2-secret"},
+		},
+		{
+			name:     "include component exclude kind",
+			args:     []string{"-c", "service2", "-K", "configmap"},
+			expected: []string{"Deployment:svc2-deploy", "Secret:svc2-secret"},
+		},
+		{
+			name:     "exclude component include kind",
+			args:     []string{"-C", "service2", "-k", "namespace"},
+			expected: []string{"Namespace:bar-system", "Namespace:foo-system"},
+		},
+		{
+			name: "no overlap",
+			args: []string{"-c", "service2", "-k", "namespace"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			err := s.executeCommand(append([]string{"show", "dev", "-o", "json"}, test.args...)...)
+			require.NoError(t, err)
+			var objects []map[string]interface{}
+			err = s.jsonOutput(&objects)
+			require.NoError(t, err)
+			var names []string
+			for _, o := range objects {
+				meta := o["metadata"].(map[string]interface{})
+				names = append(names, fmt.Sprintf("%s:%s", o["kind"], meta["name"]))
+			}
+			sort.Strings(names)
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
 func TestShowHiddenSecrets(t *testing.T) {
 	s := newScaffold(t)
 	defer s.reset()
@@ -240,6 +398,120 @@ func TestShowOpenStringSecrets(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(secretValue))
 }
 
+func TestShowOutputDir(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		ext       string
+		cleaned   bool
+		kustomize bool
+	}{
+		{name: "yaml", ext: "yaml"},
+		{name: "json", args: []string{"-o", "json"}, ext: "json"},
+		{name: "clean", args: []string{"--clean-output-dir"}, ext: "yaml", cleaned: true},
+		{name: "kustomize", args: []string{"-o", "kustomize", "--clean-output-dir"}, ext: "yaml", cleaned: true, kustomize: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newScaffold(t)
+			defer s.reset()
+			dir := t.TempDir()
+			writeFile := func(name string) {
+				file := filepath.Join(dir, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+				require.NoError(t, ioutil.WriteFile(file, []byte("stale"), 0644))
+			}
+			writeFile("configmap/bar-system/svc2-cm." + test.ext)
+			writeFile("configmap/bar-system/old-cm." + test.ext)
+			writeFile("old/obj." + test.ext)
+			writeFile("unowned/obj." + test.ext)
+			writeFile("README.md")
+			// files written by a previous run
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filesManifest),
+				[]byte(fmt.Sprintf("configmap/bar-system/old-cm.%s\nconfigmap/bar-system/svc2-cm.%s\nold/obj.%s\n", test.ext, test.ext, test.ext)), 0644))
+			err := s.executeCommand(append([]string{"show", "dev", "--output-dir", dir}, test.args...)...)
+			require.NoError(t, err)
+			a := assert.New(t)
+			a.Equal("", s.stdout())
+			exists := func(name string) bool {
+				_, err := os.Stat(filepath.Join(dir, name))
+				return err == nil
+			}
+			for _, f := range []string{
+				"configmap/bar-system/svc2-cm",
+				"secret/bar-system/svc2-secret",
+				"deployment.apps/bar-system/svc2-deploy",
+				"job.batch/tj-generated",
+				"namespace/foo-system",
+			} {
+				a.True(exists(f+"."+test.ext), f)
+			}
+			b, err := ioutil.ReadFile(filepath.Join(dir, "configmap/bar-system/svc2-cm."+test.ext))
+			require.NoError(t, err)
+			a.Contains(string(b), "svc2-cm")
+			b, err = ioutil.ReadFile(filepath.Join(dir, "secret/bar-system/svc2-secret."+test.ext))
+			require.NoError(t, err)
+			a.NotContains(string(b), base64.StdEncoding.EncodeToString([]byte("bar")))
+			a.True(exists("README.md"))
+			a.True(exists("unowned/obj." + test.ext))
+			a.Equal(!test.cleaned, exists("configmap/bar-system/old-cm."+test.ext))
+			a.Equal(!test.cleaned, exists("old"))
+			b, err = ioutil.ReadFile(filepath.Join(dir, filesManifest))
+			require.NoError(t, err)
+			a.Contains(string(b), "configmap/bar-system/svc2-cm."+test.ext+"\n")
+			a.NotContains(string(b), "unowned")
+			a.Equal(!test.cleaned, strings.Contains(string(b), "old/obj."+test.ext))
+			a.Equal(test.kustomize, strings.Contains(string(b), "kustomization.yaml"))
+			a.Equal(test.kustomize, exists("kustomization.yaml"))
+			if test.kustomize {
+				b, err := ioutil.ReadFile(filepath.Join(dir, "kustomization.yaml"))
+				require.NoError(t, err)
+				var k kustomization
+				require.NoError(t, yaml.Unmarshal(b, &k))
+				a.Equal("kustomize.config.k8s.io/v1beta1", k.APIVersion)
+				a.Equal("Kustomization", k.Kind)
+				a.Contains(k.Resources, "configmap/bar-system/svc2-cm.yaml")
+				a.Contains(k.Resources, "job.batch/tj-generated.yaml")
+				for _, r := range k.Resources {
+					a.True(exists(r), r)
+				}
+			}
+			s.assertErrorLineMatch(regexp.MustCompile(`^wrote \d+ object\(s\) to `))
+		})
+	}
+}
+
+func TestObjectFilePathNegative(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		message   string
+	}{
+		{name: "../cm", message: `invalid name "../cm", cannot be used in a file path`},
+		{name: "..", message: `invalid name "..", cannot be used in a file path`},
+		{name: "cm", namespace: "..", message: `invalid namespace "..", cannot be used in a file path`},
+		{name: `a\b`, message: `invalid name "a\\b", cannot be used in a file path`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			obj := &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap"}}
+			obj.SetName(test.name)
+			obj.SetNamespace(test.namespace)
+			_, err := objectFilePath(obj, "yaml")
+			require.Error(t, err)
+			assert.Equal(t, test.message, err.Error())
+		})
+	}
+}
+
+func TestReadFilesManifestNegative(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, filesManifest), []byte("ok.yaml\n../qbec.yaml\n"), 0644))
+	_, err := readFilesManifest(dir)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid path ../qbec.yaml outside")
+}
+
 func TestShowNegative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -283,6 +555,51 @@ func TestShowNegative(t *testing.T) {
 				a.Equal("invalid environment \"foo\"", err.Error())
 			},
 		},
+		{
+			name: "bad sort key",
+			args: []string{"show", "dev", "--sort-by", "kind,age"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid sort key "age", must be one of component, kind, namespace or name`, err.Error())
+			},
+		},
+		{
+			name: "sort by with sort apply",
+			args: []string{"show", "dev", "--sort-by", "name", "--sort-apply"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--sort-by cannot be used together with --sort-apply", err.Error())
+			},
+		},
+		{
+			name: "output dir with objects",
+			args: []string{"show", "dev", "--output-dir", "out", "-O"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--output-dir cannot be used together with --objects", err.Error())
+			},
+		},
+		{
+			name: "kustomize without output dir",
+			args: []string{"show", "dev", "-o", "kustomize"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--format=kustomize requires --output-dir", err.Error())
+			},
+		},
+		{
+			name: "clean without output dir",
+			args: []string{"show", "dev", "--clean-output-dir"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal("--clean-output-dir requires --output-dir", err.Error())
+			},
+		},
 		{
 			name: "bad format",
 			args: []string{"show", "dev", "-o", "table"},
@@ -340,3 +657,20 @@ func TestShowNegative(t *testing.T) {
 		})
 	}
 }
+
+func TestShowReproducible(t *testing.T) {
+	render := func(args ...string) string {
+		s := newCustomScaffold(t, "testdata/projects/reproducible")
+		defer s.reset()
+		err := s.executeCommand(append([]string{"show", "local"}, args...)...)
+		require.NoError(t, err)
+		return s.stdout()
+	}
+	a := assert.New(t)
+	args := []string{"--seed=s1", "--freeze-time=2021-01-01T10:00:00+05:00"}
+	out := render(args...)
+	a.Contains(out, "createdAt: \"2021-01-01T05:00:00Z\"")
+	a.Equal(out, render(args...))
+	a.NotEqual(out, render("--seed=s2", "--freeze-time=2021-01-01T10:00:00+05:00"))
+	a.NotEqual(out, render())
+}
@@ -0,0 +1,131 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+)
+
+// noNamespace is the namespace displayed for objects that do not have one.
+const noNamespace = "(none)"
+
+// objectStats has counts of rendered objects by kind, namespace and component.
+type objectStats struct {
+	Total      int            `json:"total"`
+	Kinds      map[string]int `json:"kinds"`
+	Namespaces map[string]int `json:"namespaces"`
+	Components map[string]int `json:"components"`
+}
+
+// newObjectStats aggregates the metadata of the supplied objects.
+func newObjectStats(objects []model.K8sLocalObject) objectStats {
+	ret := objectStats{
+		Total:      len(objects),
+		Kinds:      map[string]int{},
+		Namespaces: map[string]int{},
+		Components: map[string]int{},
+	}
+	for _, o := range objects {
+		ns := o.GetNamespace()
+		if ns == "" {
+			ns = noNamespace
+		}
+		ret.Kinds[o.GroupVersionKind().Kind]++
+		ret.Namespaces[ns]++
+		ret.Components[o.Component()]++
+	}
+	return ret
+}
+
+func printCounts(w io.Writer, title string, counts map[string]int) {
+	var keys []string
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "%-40s %s\n", title, "COUNT")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%-40s %d\n", k, counts[k])
+	}
+	fmt.Fprintln(w)
+}
+
+func (s objectStats) print(w io.Writer) {
+	printCounts(w, "KIND", s.Kinds)
+	printCounts(w, "NAMESPACE", s.Namespaces)
+	printCounts(w, "COMPONENT", s.Components)
+	fmt.Fprintf(w, "%-40s %d\n", "TOTAL", s.Total)
+}
+
+type statsCommandConfig struct {
+	cmd.AppContext
+	json       bool
+	filterFunc func() (model.Filters, error)
+}
+
+func doStats(ctx context.Context, args []string, config statsCommandConfig) error {
+	if len(args) != 1 {
+		return cmd.NewUsageError(fmt.Sprintf("exactly one environment required, but provided: %q", args))
+	}
+	fp, err := config.filterFunc()
+	if err != nil {
+		return err
+	}
+	envCtx, err := config.EnvContext(args[0])
+	if err != nil {
+		return err
+	}
+	objects, err := generateObjects(ctx, envCtx, filterOpts{keyFunc: localObjectKey, filters: fp})
+	if err != nil {
+		return err
+	}
+	stats := newObjectStats(objects)
+	if config.json {
+		encoder := json.NewEncoder(config.Stdout())
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+	stats.print(config.Stdout())
+	return nil
+}
+
+func newStatsCommand(cp ctxProvider) *cobra.Command {
+	c := &cobra.Command{
+		Use:     "stats <environment>",
+		Short:   "print counts of rendered objects by kind, namespace and component",
+		Example: statsExamples(),
+	}
+
+	config := statsCommandConfig{
+		filterFunc: addFilterParams(c, true),
+	}
+	c.Flags().BoolVar(&config.json, "json", false, "print counts in JSON format")
+
+	c.RunE = func(c *cobra.Command, args []string) error {
+		config.AppContext = cp()
+		return cmd.WrapError(doStats(c.Context(), args, config))
+	}
+	return c
+}
@@ -0,0 +1,64 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package commands
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatsBasic(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("stats", "dev")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`^KIND\s+COUNT$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^ClusterRoleBinding\s+2$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^bar-system\s+3$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^\(none\)\s+8$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^cluster-objects\s+7$`))
+	s.assertOutputLineMatch(regexp.MustCompile(`^TOTAL\s+11$`))
+}
+
+func TestStatsJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("stats", "dev", "-c", "service2", "--json")
+	require.NoError(t, err)
+	var stats objectStats
+	err = s.jsonOutput(&stats)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal(3, stats.Total)
+	a.Equal(map[string]int{"ConfigMap": 1, "Deployment": 1, "Secret": 1}, stats.Kinds)
+	a.Equal(map[string]int{"bar-system": 3}, stats.Namespaces)
+	a.Equal(map[string]int{"service2": 3}, stats.Components)
+}
+
+func TestStatsNegative(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	err := s.executeCommand("stats")
+	require.Error(t, err)
+	a := assert.New(t)
+	a.True(cmd.IsUsageError(err))
+	a.Equal("exactly one environment required, but provided: []", err.Error())
+}
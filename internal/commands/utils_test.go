@@ -113,9 +113,12 @@ type client struct {
 	getFunc       func(ctx context.Context, obj model.K8sMeta) (*unstructured.Unstructured, error)
 	syncFunc      func(ctx context.Context, obj model.K8sLocalObject, opts remote.SyncOptions) (*remote.SyncResult, error)
 	validatorFunc func(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error)
+	v3Func        func(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error)
 	listFunc      func(ctx context.Context, scope remote.ListQueryConfig) (remote.Collection, error)
 	deleteFunc    func(ctx context.Context, obj model.K8sMeta, opts remote.DeleteOptions) (*remote.SyncResult, error)
 	objectKeyFunc func(obj model.K8sMeta) string
+	ssaFunc       func(ctx context.Context, obj model.K8sLocalObject, fieldManager string, force bool) (*unstructured.Unstructured, error)
+	admitFunc     func(ctx context.Context, obj model.K8sLocalObject) error
 }
 
 func (c *client) DisplayName(o model.K8sMeta) string {
@@ -153,6 +156,13 @@ func (c *client) ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind)
 	return nil, errors.New("validator: not implemented")
 }
 
+func (c *client) ValidatorV3For(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error) {
+	if c.v3Func != nil {
+		return c.v3Func(ctx, gvk)
+	}
+	return nil, errors.New("v3 validator: not implemented")
+}
+
 func (c *client) ListObjects(ctx context.Context, scope remote.ListQueryConfig) (remote.Collection, error) {
 	if c.listFunc != nil {
 		return c.listFunc(ctx, scope)
@@ -174,6 +184,20 @@ func (c *client) ObjectKey(obj model.K8sMeta) string {
 	return fmt.Sprintf("%s:%s:%s:%s", obj.GroupVersionKind().Group, obj.GetKind(), obj.GetNamespace(), obj.GetName())
 }
 
+func (c *client) ServerSideDryRun(ctx context.Context, obj model.K8sLocalObject, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	if c.ssaFunc != nil {
+		return c.ssaFunc(ctx, obj, fieldManager, force)
+	}
+	return nil, errors.New("server-side dry-run: not implemented")
+}
+
+func (c *client) DryRunCreate(ctx context.Context, obj model.K8sLocalObject) error {
+	if c.admitFunc != nil {
+		return c.admitFunc(ctx, obj)
+	}
+	return errors.New("dry-run create: not implemented")
+}
+
 func (c *client) ResourceInterface(gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
 	return nil, fmt.Errorf("resource-interface: not implemented")
 }
@@ -194,6 +218,8 @@ func setPwd(t *testing.T, dir string) func() {
 type baseScaffold struct {
 	t          *testing.T
 	cp         cmd.ClientProvider
+	stdin      *bytes.Buffer // answers to prompts
+	terminal   *bool         // whether stdin is treated as a terminal, true by default
 	outCapture *bytes.Buffer
 	errCapture *bytes.Buffer
 	reset      func()
@@ -287,17 +313,25 @@ func (s *baseScaffold) outputStats() map[string]interface{} {
 	return data.Stats
 }
 
-func newBaseScaffold(t *testing.T, dir string, clientProvider cmd.ClientProvider) baseScaffold {
+func newBaseScaffold(t *testing.T, dir string, clientProvider cmd.ClientProvider, contextClientProvider cmd.ContextClientProvider) baseScaffold {
 	reset := setPwd(t, dir)
+	in := bytes.NewBuffer(nil)
+	terminal := true
 	out := bytes.NewBuffer(nil)
+	errOut := bytes.NewBuffer(nil)
+	errW := &lockWriter{Writer: errOut} // shared with sio output
 
 	c := &cobra.Command{
 		Use: "qbec-test",
 	}
 	doSetup(c, cmd.Options{
-		SkipConfirm:    true,
-		Stdout:         &lockWriter{Writer: out},
-		ClientProvider: clientProvider,
+		SkipConfirm:           true,
+		Stdin:                 in,
+		StdinIsTerminal:       func() bool { return terminal },
+		Stdout:                &lockWriter{Writer: out},
+		Stderr:                errW,
+		ClientProvider:        clientProvider,
+		ContextClientProvider: contextClientProvider,
 	})
 	c.SetOut(out)
 	c.SetErr(out)
@@ -306,13 +340,15 @@ func newBaseScaffold(t *testing.T, dir string, clientProvider cmd.ClientProvider
 	s := baseScaffold{
 		t:          t,
 		cp:         clientProvider,
+		stdin:      in,
+		terminal:   &terminal,
 		outCapture: out,
-		errCapture: bytes.NewBuffer(nil),
+		errCapture: errOut,
 		cmd:        c,
 	}
 	oldOut := sio.Output
 	oldColors := sio.ColorsEnabled()
-	sio.Output = &lockWriter{Writer: s.errCapture}
+	sio.Output = errW
 	sio.EnableColors(false)
 	s.reset = func() {
 		reset()
@@ -349,7 +385,16 @@ func (s *baseScaffold) sub() baseScaffold {
 
 type scaffold struct {
 	baseScaffold
-	client *client
+	client         *client
+	contextClients map[string]*client // clients for environments that target multiple contexts, keyed by context
+}
+
+// contextClient returns the client used for the supplied context, creating it if needed.
+func (s *scaffold) contextClient(name string) *client {
+	if s.contextClients[name] == nil {
+		s.contextClients[name] = &client{}
+	}
+	return s.contextClients[name]
 }
 
 func newCustomScaffold(t *testing.T, dir string) *scaffold {
@@ -357,12 +402,13 @@ func newCustomScaffold(t *testing.T, dir string) *scaffold {
 		dir = "../../examples/test-app"
 	}
 	c := &client{}
-	clientProvider := func(env string) (cmd.KubeClient, error) { return c, nil }
-	base := newBaseScaffold(t, dir, clientProvider)
 	s := &scaffold{
-		baseScaffold: base,
-		client:       c,
+		client:         c,
+		contextClients: map[string]*client{},
 	}
+	clientProvider := func(env string) (cmd.KubeClient, error) { return c, nil }
+	contextClientProvider := func(env string, context string) (cmd.KubeClient, error) { return s.contextClient(context), nil }
+	s.baseScaffold = newBaseScaffold(t, dir, clientProvider, contextClientProvider)
 	return s
 }
 
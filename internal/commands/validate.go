@@ -18,15 +18,21 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"sort"
 	"strings"
 	"sync"
 
 	"github.com/spf13/cobra"
 	"github.com/splunk/qbec/internal/cmd"
 	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/remote"
 	"github.com/splunk/qbec/internal/remote/k8smeta"
+	"github.com/splunk/qbec/internal/sio"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
@@ -41,11 +47,14 @@ const (
 )
 
 type validatorStats struct {
-	l          sync.Mutex
-	ValidCount int      `json:"valid,omitempty"`
-	Unknown    []string `json:"unknown,omitempty"`
-	Invalid    []string `json:"invalid,omitempty"`
-	Errors     []string `json:"errors,omitempty"`
+	l            sync.Mutex
+	ValidCount   int      `json:"valid,omitempty"`
+	Unknown      []string `json:"unknown,omitempty"`
+	Invalid      []string `json:"invalid,omitempty"`
+	Denied       []string `json:"denied,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+	UnknownKinds []string `json:"unknownKinds,omitempty"`
+	unknownGVKs  map[schema.GroupVersionKind]bool
 }
 
 func (v *validatorStats) valid(s string) {
@@ -60,10 +69,29 @@ func (v *validatorStats) invalid(s string) {
 	v.Invalid = append(v.Invalid, s)
 }
 
-func (v *validatorStats) unknown(s string) {
+func (v *validatorStats) denied(s string) {
+	v.l.Lock()
+	defer v.l.Unlock()
+	v.Denied = append(v.Denied, s)
+}
+
+func (v *validatorStats) unknown(s string, gvk schema.GroupVersionKind) {
 	v.l.Lock()
 	defer v.l.Unlock()
 	v.Unknown = append(v.Unknown, s)
+	if v.unknownGVKs == nil {
+		v.unknownGVKs = map[schema.GroupVersionKind]bool{}
+	}
+	v.unknownGVKs[gvk] = true
+}
+
+// setUnknownKinds sets the sorted, deduplicated list of group version kinds for which no schema was found,
+// formatted as group/version/Kind.
+func (v *validatorStats) setUnknownKinds() {
+	for gvk := range v.unknownGVKs {
+		v.UnknownKinds = append(v.UnknownKinds, gvk.GroupVersion().String()+"/"+gvk.Kind)
+	}
+	sort.Strings(v.UnknownKinds)
 }
 
 func (v *validatorStats) errors(s string) {
@@ -72,36 +100,138 @@ func (v *validatorStats) errors(s string) {
 	v.Errors = append(v.Errors, s)
 }
 
+// validateClient is the subset of client functionality needed for validation.
+type validateClient interface {
+	DisplayName(o model.K8sMeta) string
+	ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error)
+	DryRunCreate(ctx context.Context, obj model.K8sLocalObject) error
+}
+
+// v3ValidateClient is implemented by clients that can supply validators using OpenAPI v3 schemas.
+type v3ValidateClient interface {
+	ValidatorV3For(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error)
+}
+
+// v3Client is a validateClient that uses OpenAPI v3 schemas for validation.
+type v3Client struct {
+	validateClient
+	v3 v3ValidateClient
+}
+
+func (c v3Client) ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error) {
+	return c.v3.ValidatorV3For(ctx, gvk)
+}
+
+// fileValidateClient is a validateClient that uses schemas from a local OpenAPI document
+// and does not require a connection to a Kubernetes cluster.
+type fileValidateClient struct {
+	schema *k8smeta.ServerSchema
+}
+
+func newFileValidateClient(file string) *fileValidateClient {
+	return &fileValidateClient{schema: k8smeta.NewFileSchema(file)}
+}
+
+// DisplayName returns a display name for the object in the same form as the remote client except that
+// the default namespace is not applied since scope information is not available locally.
+func (f *fileValidateClient) DisplayName(o model.K8sMeta) string {
+	return localDisplayName(o)
+}
+
+// localDisplayName returns a display name for objects that are not associated with cluster metadata.
+func localDisplayName(o model.K8sMeta) string {
+	name := strings.ToLower(o.GroupVersionKind().Kind) + " " + model.NameForDisplay(o)
+	if ns := o.GetNamespace(); ns != "" {
+		name += " -n " + ns
+	}
+	if l, ok := o.(model.QbecMeta); ok && l.Component() != "" {
+		name += fmt.Sprintf(" (source %s)", l.Component())
+	}
+	return name
+}
+
+func (f *fileValidateClient) ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error) {
+	return f.schema.ValidatorFor(ctx, gvk)
+}
+
+// DryRunCreate always fails since admission checks require a connection to a Kubernetes cluster.
+func (f *fileValidateClient) DryRunCreate(ctx context.Context, obj model.K8sLocalObject) error {
+	return fmt.Errorf("admission checks are not supported when validating using a schema file")
+}
+
 type validator struct {
 	w                      io.Writer
-	client                 cmd.KubeClient
+	client                 validateClient
 	stats                  validatorStats
 	red, green, dim, reset string
 	silent                 bool
+	strict                 bool
+	admission              bool
 }
 
+// schema validation results
+const (
+	schemaValid = iota
+	schemaInvalid
+	schemaUnknown
+)
+
 func (v *validator) validate(ctx context.Context, obj model.K8sLocalObject) error {
 	name := v.client.DisplayName(obj)
-	schema, err := v.client.ValidatorFor(ctx, obj.GroupVersionKind())
+	result, err := v.validateSchema(ctx, name, obj)
+	if err != nil || result == schemaInvalid {
+		return err
+	}
+	// objects without a schema are still checked for admission since policies often apply to custom resources
+	if v.admission {
+		err := v.client.DryRunCreate(ctx, obj)
+		switch {
+		case err == remote.ErrNamespaceNotFound:
+			if !v.silent {
+				fmt.Fprintf(v.w, "%s%s %s: namespace does not exist, cannot check admission%s\n", v.dim, unicodeQuestion, name, v.reset)
+			}
+		case err != nil:
+			if denial, ok := err.(*remote.AdmissionDenial); ok {
+				fmt.Fprintf(v.w, "%s%s %s was denied admission\n\t- %s%s\n", v.red, unicodeX, name, denial.Message, v.reset)
+				v.stats.denied(name)
+				return nil
+			}
+			fmt.Fprintf(v.w, "%s%s %s: admission check error %v%s\n", v.red, unicodeX, name, err, v.reset)
+			v.stats.errors(name)
+			return err
+		}
+	}
+	if result == schemaUnknown {
+		return nil
+	}
+	if !v.silent {
+		fmt.Fprintf(v.w, "%s%s %s is valid%s\n", v.green, unicodeCheck, name, v.reset)
+	}
+	v.stats.valid(name)
+	return nil
+}
+
+// validateSchema validates the supplied object against the schema for its kind, reporting objects that
+// are invalid or do not have a schema.
+func (v *validator) validateSchema(ctx context.Context, name string, obj model.K8sLocalObject) (int, error) {
+	valSchema, err := v.client.ValidatorFor(ctx, obj.GroupVersionKind())
 	if err != nil {
 		if err == k8smeta.ErrSchemaNotFound {
-			if !v.silent {
+			if v.strict {
+				fmt.Fprintf(v.w, "%s%s %s: no schema found, cannot validate%s\n", v.red, unicodeX, name, v.reset)
+			} else if !v.silent {
 				fmt.Fprintf(v.w, "%s%s %s: no schema found, cannot validate%s\n", v.dim, unicodeQuestion, name, v.reset)
 			}
-			v.stats.unknown(name)
-			return nil
+			v.stats.unknown(name, obj.GroupVersionKind())
+			return schemaUnknown, nil
 		}
 		fmt.Fprintf(v.w, "%s%s %s: schema fetch error %v%s\n", v.red, unicodeX, name, err, v.reset)
 		v.stats.errors(name)
-		return err
+		return schemaInvalid, err
 	}
-	errs := schema.Validate(obj.ToUnstructured())
+	errs := valSchema.Validate(obj.ToUnstructured())
 	if len(errs) == 0 {
-		if !v.silent {
-			fmt.Fprintf(v.w, "%s%s %s is valid%s\n", v.green, unicodeCheck, name, v.reset)
-		}
-		v.stats.valid(name)
-		return nil
+		return schemaValid, nil
 	}
 	var lines []string
 	for _, e := range errs {
@@ -109,14 +239,35 @@ func (v *validator) validate(ctx context.Context, obj model.K8sLocalObject) erro
 	}
 	fmt.Fprintf(v.w, "%s%s %s is invalid\n\t- %s%s\n", v.red, unicodeX, name, strings.Join(lines, "\n\t- "), v.reset)
 	v.stats.invalid(name)
-	return nil
+	return schemaInvalid, nil
+}
+
+// validateOpts are the options for validating objects.
+type validateOpts struct {
+	parallel    int       // number of parallel validations
+	colors      bool      // colorize output
+	out         io.Writer // the writer for results
+	silent      bool      // do not print success messages for every object
+	strict      bool      // fail for objects without a schema
+	jsonOutput  bool      // write a JSON summary instead of per-object results
+	listUnknown bool      // list the distinct group version kinds without a schema in the summary
+	admission   bool      // send server-side dry-run creates for objects that pass schema validation
 }
 
-func validateObjects(ctx context.Context, objs []model.K8sLocalObject, client cmd.KubeClient, parallel int, colors bool, out io.Writer, silent bool) error {
+func validateObjects(ctx context.Context, objs []model.K8sLocalObject, client validateClient, opts validateOpts) error {
+	w := opts.out
+	colors := opts.colors
+	if opts.jsonOutput {
+		// only the final summary is written in JSON mode
+		w = ioutil.Discard
+		colors = false
+	}
 	v := &validator{
-		w:      &lockWriter{Writer: out},
-		client: client,
-		silent: silent,
+		w:         &lockWriter{Writer: w},
+		client:    client,
+		silent:    opts.silent,
+		strict:    opts.strict,
+		admission: opts.admission,
 	}
 	if colors {
 		v.green = escGreen
@@ -125,24 +276,59 @@ func validateObjects(ctx context.Context, objs []model.K8sLocalObject, client cm
 		v.reset = escReset
 	}
 
-	vErr := runInParallel(ctx, objs, v.validate, parallel)
-	printStats(v.w, &v.stats)
+	vErr := runInParallel(ctx, objs, v.validate, opts.parallel)
+	if opts.listUnknown {
+		v.stats.setUnknownKinds()
+	}
+	if opts.jsonOutput {
+		sort.Strings(v.stats.Unknown)
+		sort.Strings(v.stats.Invalid)
+		sort.Strings(v.stats.Denied)
+		sort.Strings(v.stats.Errors)
+		enc := json.NewEncoder(opts.out)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(&v.stats); err != nil {
+			return err
+		}
+	} else {
+		printStats(v.w, &v.stats)
+	}
 
-	switch {
-	case vErr != nil:
+	unknownFailures := 0
+	if opts.strict {
+		unknownFailures = len(v.stats.Unknown)
+	}
+	if vErr != nil {
 		return vErr
-	case len(v.stats.Invalid) > 0:
-		return fmt.Errorf("%d invalid objects found", len(v.stats.Invalid))
-	default:
-		return nil
 	}
+	var failures []string
+	if len(v.stats.Invalid) > 0 {
+		failures = append(failures, fmt.Sprintf("%d invalid objects", len(v.stats.Invalid)))
+	}
+	if len(v.stats.Denied) > 0 {
+		failures = append(failures, fmt.Sprintf("%d objects denied admission", len(v.stats.Denied)))
+	}
+	if unknownFailures > 0 {
+		failures = append(failures, fmt.Sprintf("%d objects without a schema", unknownFailures))
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%s found", strings.Join(failures, " and "))
+	}
+	return nil
 }
 
 type validateCommandConfig struct {
 	cmd.AppContext
-	parallel   int
-	silent     bool
-	filterFunc func() (model.Filters, error)
+	parallel      int
+	silent        bool
+	strict        bool
+	schemaFile    string
+	schemaVersion string
+	format        string
+	listUnknown   bool
+	admission     bool
+	filterFunc    func() (model.Filters, error)
+	nsFunc        func() (namespaceOverride, error)
 }
 
 func doValidate(ctx context.Context, args []string, config validateCommandConfig) error {
@@ -153,23 +339,67 @@ func doValidate(ctx context.Context, args []string, config validateCommandConfig
 	if env == model.Baseline {
 		return cmd.NewUsageError("cannot validate baseline environment, use a real environment")
 	}
+	if config.format != "" && config.format != "json" {
+		return cmd.NewUsageError(fmt.Sprintf("invalid output format: %q", config.format))
+	}
+	if config.schemaVersion != "v2" && config.schemaVersion != "v3" {
+		return cmd.NewUsageError(fmt.Sprintf("invalid schema version %q, must be one of v2 or v3", config.schemaVersion))
+	}
+	if config.schemaVersion == "v3" && config.schemaFile != "" {
+		return cmd.NewUsageError("--schema-version=v3 cannot be used with --schema-file")
+	}
+	if config.admission && config.schemaFile != "" {
+		return cmd.NewUsageError("--admission cannot be used with --schema-file")
+	}
 	fp, err := config.filterFunc()
 	if err != nil {
 		return err
 	}
+	nso, err := config.nsFunc()
+	if err != nil {
+		return err
+	}
 	envCtx, err := config.EnvContext(env)
 	if err != nil {
 		return err
 	}
+	opts := validateOpts{
+		parallel:    config.parallel,
+		colors:      config.Colorize(),
+		out:         config.Stdout(),
+		silent:      config.silent,
+		strict:      config.strict,
+		jsonOutput:  config.format == "json",
+		listUnknown: config.listUnknown,
+		admission:   config.admission,
+	}
+	if config.schemaFile != "" {
+		objects, err := generateObjects(ctx, envCtx, filterOpts{filters: fp, keyFunc: localObjectKey, nsOverride: nso})
+		if err != nil {
+			return err
+		}
+		client := newFileValidateClient(config.schemaFile)
+		return validateObjects(ctx, objects, client, opts)
+	}
 	client, err := envCtx.Client()
 	if err != nil {
 		return err
 	}
-	objects, err := generateObjects(ctx, envCtx, makeFilterOpts(fp, client))
+	fo := makeFilterOpts(fp, client)
+	fo.nsOverride = nso
+	objects, err := generateObjects(ctx, envCtx, fo)
 	if err != nil {
 		return err
 	}
-	return validateObjects(ctx, objects, client, config.parallel, config.Colorize(), config.Stdout(), config.silent)
+	var vc validateClient = client
+	if config.schemaVersion == "v3" {
+		if v3c, ok := client.(v3ValidateClient); ok {
+			vc = v3Client{validateClient: client, v3: v3c}
+		} else {
+			sio.Warnln("client does not support OpenAPI v3 schemas, using v2")
+		}
+	}
+	return validateObjects(ctx, objects, vc, opts)
 
 }
 
@@ -182,10 +412,17 @@ func newValidateCommand(cp ctxProvider) *cobra.Command {
 
 	config := validateCommandConfig{
 		filterFunc: addFilterParams(c, true),
+		nsFunc:     addNamespaceOverrideParams(c),
 	}
 
 	c.Flags().IntVar(&config.parallel, "parallel", 5, "number of parallel routines to run")
 	c.Flags().BoolVar(&config.silent, "silent", false, "do not print success messages for every object")
+	c.Flags().BoolVar(&config.strict, "strict", false, "treat objects for which no schema is found as validation failures")
+	c.Flags().StringVarP(&config.format, "output", "o", "", "use json to display a machine readable summary instead of per-object results")
+	c.Flags().StringVar(&config.schemaFile, "schema-file", "", "validate using the OpenAPI document in the supplied JSON or YAML file instead of the cluster")
+	c.Flags().BoolVar(&config.listUnknown, "list-unknown", false, "list the distinct group version kinds of objects for which no schema is found in the summary")
+	c.Flags().StringVar(&config.schemaVersion, "schema-version", "v2", "OpenAPI version of the cluster schemas used for validation, one of v2 or v3. v3 falls back to v2 when not supported by the cluster")
+	c.Flags().BoolVar(&config.admission, "admission", false, "also send a server-side dry-run create for every object and report objects denied by admission controllers")
 	c.RunE = func(c *cobra.Command, args []string) error {
 		config.AppContext = cp()
 		return cmd.WrapError(doValidate(c.Context(), args, config))
@@ -19,10 +19,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/remote"
 	"github.com/splunk/qbec/internal/remote/k8smeta"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -70,6 +74,179 @@ func TestValidateSilent(t *testing.T) {
 	s.assertOutputLineMatch(regexp.MustCompile(`- bad config map`))
 }
 
+func TestValidateStrict(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "--strict", "--silent")
+	require.NotNil(t, err)
+	a := assert.New(t)
+	a.Equal("1 invalid objects and 2 objects without a schema found", err.Error())
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ PodSecurityPolicy::100-default: no schema found, cannot validate`))
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ ConfigMap:bar-system:svc2-cm is invalid`))
+}
+
+func TestValidateStrictUnknownOnly(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "--strict", "-K", "configmap")
+	require.NotNil(t, err)
+	assert.Equal(t, "2 objects without a schema found", err.Error())
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ PodSecurityPolicy::200-allow-root: no schema found, cannot validate`))
+}
+
+func TestValidateUnknownNotStrict(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "-K", "configmap")
+	require.NoError(t, err)
+}
+
+func TestValidateJSONOutput(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "--output=json")
+	require.NotNil(t, err)
+	a := assert.New(t)
+	a.Equal("1 invalid objects found", err.Error())
+	var stats map[string]interface{}
+	err = s.jsonOutput(&stats)
+	require.NoError(t, err)
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["invalid"])
+	a.EqualValues([]interface{}{"PodSecurityPolicy::100-default", "PodSecurityPolicy::200-allow-root"}, stats["unknown"])
+	a.True(stats["valid"].(float64) > 0)
+	a.Nil(stats["unknownKinds"])
+	a.NotContains(s.stdout(), "is valid")
+}
+
+func TestValidateListUnknown(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "--silent", "--list-unknown")
+	require.NotNil(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`unknownKinds:`))
+	s.assertOutputLineMatch(regexp.MustCompile(`- policy/v1beta1/PodSecurityPolicy`))
+	assert.Equal(t, 1, strings.Count(s.stdout(), "policy/v1beta1/PodSecurityPolicy"))
+}
+
+func TestValidateListUnknownJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	err := s.executeCommand("validate", "dev", "--output=json", "--list-unknown")
+	require.NotNil(t, err)
+	var stats map[string]interface{}
+	err = s.jsonOutput(&stats)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"PodSecurityPolicy::100-default", "PodSecurityPolicy::200-allow-root"}, stats["unknown"])
+	a.EqualValues([]interface{}{"policy/v1beta1/PodSecurityPolicy"}, stats["unknownKinds"])
+}
+
+func TestValidateSchemaFile(t *testing.T) {
+	file, err := filepath.Abs(filepath.Join("..", "remote", "k8smeta", "testdata", "swagger.json"))
+	require.NoError(t, err)
+	s := newScaffold(t)
+	defer s.reset()
+	err = s.executeCommand("validate", "dev", "--schema-file", file)
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`✔ namespace bar-system \(source cluster-objects\) is valid`))
+	s.assertOutputLineMatch(regexp.MustCompile(`\? configmap svc2-cm -n bar-system \(source service2\): no schema found, cannot validate`))
+}
+
+func TestValidateSchemaVersion(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	s.client.v3Func = func(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error) {
+		if gvk.Kind == "ConfigMap" {
+			return nil, k8smeta.ErrSchemaNotFound
+		}
+		return factory(ctx, gvk)
+	}
+	err := s.executeCommand("validate", "dev", "--schema-version=v3")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`\? ConfigMap:bar-system:svc2-cm: no schema found, cannot validate`))
+	s.assertOutputLineMatch(regexp.MustCompile(`\? PodSecurityPolicy::100-default: no schema found, cannot validate`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`is invalid`))
+}
+
+func admitter(ctx context.Context, obj model.K8sLocalObject) error {
+	switch obj.GetName() {
+	case "svc2-secret", "100-default":
+		return &remote.AdmissionDenial{Message: fmt.Sprintf(`admission webhook "policy.example.com" denied the request: %s is not allowed`, obj.GetName())}
+	case "svc2-cm":
+		return fmt.Errorf("admission should not be checked for invalid objects")
+	default:
+		return nil
+	}
+}
+
+func TestValidateAdmission(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	s.client.admitFunc = admitter
+	err := s.executeCommand("validate", "dev", "--admission")
+	require.NotNil(t, err)
+	a := assert.New(t)
+	a.Equal("1 invalid objects and 2 objects denied admission found", err.Error())
+	s.assertOutputLineMatch(regexp.MustCompile(`✔ ClusterRole::allow-root-psp-policy is valid`))
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ ConfigMap:bar-system:svc2-cm is invalid`))
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ Secret:bar-system:svc2-secret was denied admission`))
+	s.assertOutputLineMatch(regexp.MustCompile(`- admission webhook "policy.example.com" denied the request: svc2-secret is not allowed`))
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ PodSecurityPolicy::100-default was denied admission`))
+	s.assertOutputLineNoMatch(regexp.MustCompile(`Secret:bar-system:svc2-secret is valid`))
+}
+
+func TestValidateAdmissionJSON(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	s.client.admitFunc = admitter
+	err := s.executeCommand("validate", "dev", "--admission", "-o", "json")
+	require.NotNil(t, err)
+	var stats map[string]interface{}
+	err = s.jsonOutput(&stats)
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"PodSecurityPolicy::100-default", "Secret:bar-system:svc2-secret"}, stats["denied"])
+	a.EqualValues([]interface{}{"ConfigMap:bar-system:svc2-cm"}, stats["invalid"])
+}
+
+func TestValidateAdmissionNamespaceNotFound(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	s.client.admitFunc = func(ctx context.Context, obj model.K8sLocalObject) error {
+		if obj.GetNamespace() == "bar-system" {
+			return remote.ErrNamespaceNotFound
+		}
+		return nil
+	}
+	err := s.executeCommand("validate", "dev", "--admission", "-k", "secret")
+	require.NoError(t, err)
+	s.assertOutputLineMatch(regexp.MustCompile(`\? Secret:bar-system:svc2-secret: namespace does not exist, cannot check admission`))
+	s.assertOutputLineMatch(regexp.MustCompile(`✔ Secret:bar-system:svc2-secret is valid`))
+}
+
+func TestValidateAdmissionErrors(t *testing.T) {
+	s := newScaffold(t)
+	defer s.reset()
+	s.client.validatorFunc = factory
+	s.client.admitFunc = func(ctx context.Context, obj model.K8sLocalObject) error {
+		return fmt.Errorf("dry-run create: server unavailable")
+	}
+	err := s.executeCommand("validate", "dev", "--admission", "-K", "configmap")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "server unavailable")
+	s.assertOutputLineMatch(regexp.MustCompile(`✘ .*: admission check error dry-run create: server unavailable`))
+}
+
 func TestValidateNegative(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -123,6 +300,33 @@ func TestValidateNegative(t *testing.T) {
 				a.Equal(`specified components: bad component reference(s): foo`, err.Error())
 			},
 		},
+		{
+			name: "bad schema version",
+			args: []string{"validate", "dev", "--schema-version=v4"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`invalid schema version "v4", must be one of v2 or v3`, err.Error())
+			},
+		},
+		{
+			name: "v3 with schema file",
+			args: []string{"validate", "dev", "--schema-version=v3", "--schema-file=swagger.json"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`--schema-version=v3 cannot be used with --schema-file`, err.Error())
+			},
+		},
+		{
+			name: "admission with schema file",
+			args: []string{"validate", "dev", "--admission", "--schema-file=swagger.json"},
+			asserter: func(s *scaffold, err error) {
+				a := assert.New(s.t)
+				a.True(cmd.IsUsageError(err))
+				a.Equal(`--admission cannot be used with --schema-file`, err.Error())
+			},
+		},
 		{
 			name: "bad filters",
 			args: []string{"validate", "dev", "-c", "svc1-cm", "-C", "svc2-cm"},
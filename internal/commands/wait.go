@@ -19,6 +19,7 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -32,61 +33,145 @@ import (
 	"k8s.io/client-go/dynamic"
 )
 
+// default bounds of the interval at which the progress of pending objects is displayed.
+const (
+	defaultMinProgressInterval = time.Second
+	defaultMaxProgressInterval = 16 * time.Second
+)
+
 // waitListener listens to rollout status updates and provides feedback to the user.
 type waitListener struct {
 	start         time.Time                       // start time using which relative progress times are printed
 	displayNameFn func(meta model.K8sMeta) string // MUST produce distinct strings for each object, name used as internal key
+	minInterval   time.Duration                   // initial interval between progress displays, defaults to 1s
+	maxInterval   time.Duration                   // maximum interval between progress displays, defaults to 16s
+	progress      sio.Progress                    // the progress display of pending objects
+	changed       chan struct{}                   // signals a status change to the progress display
+	stop          chan struct{}                   // closed to stop the progress display
+	stopped       chan struct{}                   // closed when the progress display has stopped
 	l             sync.Mutex                      // locks concurrent access to field below
-	remaining     map[string]bool                 // objects not yet marked "done"
+	remaining     map[string]string               // latest status description of objects not yet marked "done"
 }
 
 func (w *waitListener) since() time.Duration {
 	return time.Since(w.start).Round(time.Second)
 }
 
-// OnInit implements the interface method and prints the name of all objects on which we ware waiting
+// showProgress displays the latest status of all pending objects, sorted by name. It must be called with the lock held.
+func (w *waitListener) showProgress() {
+	if len(w.remaining) == 0 {
+		return
+	}
+	var names []string
+	for name := range w.remaining {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := []string{fmt.Sprintf("%s: waiting for %d objects", w.since(), len(names))}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  - %s :: %s", name, w.remaining[name]))
+	}
+	w.progress.Update(lines)
+}
+
+// refresh displays progress with an interval that starts at the minimum interval and doubles after every
+// display up to the maximum interval, such that long waits do not flood the output. The interval is reset
+// to the minimum when the status of an object changes.
+func (w *waitListener) refresh() {
+	defer close(w.stopped)
+	interval := w.minInterval
+	next := time.After(interval)
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.changed:
+			interval = w.minInterval
+		case <-next:
+			w.l.Lock()
+			w.showProgress()
+			w.l.Unlock()
+			interval *= 2
+			if interval > w.maxInterval {
+				interval = w.maxInterval
+			}
+			next = time.After(interval)
+		}
+	}
+}
+
+// OnInit implements the interface method, displays all objects on which we are waiting and starts
+// the periodic progress display.
 func (w *waitListener) OnInit(objects []model.K8sMeta) {
+	w.l.Lock()
+	defer w.l.Unlock()
 	w.start = time.Now()
-	w.remaining = map[string]bool{}
+	if w.minInterval == 0 {
+		w.minInterval = defaultMinProgressInterval
+	}
+	if w.maxInterval == 0 {
+		w.maxInterval = defaultMaxProgressInterval
+	}
+	if w.maxInterval < w.minInterval {
+		w.maxInterval = w.minInterval
+	}
+	w.remaining = map[string]string{}
 	sio.Noticef("waiting for readiness of %d objects\n", len(objects))
 	for _, o := range objects {
-		name := w.displayNameFn(o)
-		w.remaining[name] = true
-		sio.Printf("  - %s\n", w.displayNameFn(o))
+		w.remaining[w.displayNameFn(o)] = "waiting for status"
 	}
-	sio.Println()
+	w.showProgress()
+	w.changed = make(chan struct{}, 1)
+	w.stop = make(chan struct{})
+	w.stopped = make(chan struct{})
+	go w.refresh()
 }
 
-// OnStatusChange prints the updated status of the object and removes it from the internal list of remaining items
-// if the status is marked done.
+// OnStatusChange records the updated status of the object for the next progress display and removes it from the
+// internal list of remaining items if the status is marked done.
 func (w *waitListener) OnStatusChange(object model.K8sMeta, rs types.RolloutStatus) {
 	w.l.Lock()
 	defer w.l.Unlock()
+	name := w.displayNameFn(object)
 	if rs.Done {
-		name := w.displayNameFn(object)
 		delete(w.remaining, name)
-		sio.Noticef("✓ %-6s: %s :: %s (%d remaining)\n", w.since(), w.displayNameFn(object), rs.Description, len(w.remaining))
-		return
+		w.progress.Clear()
+		sio.Noticef("✓ %-6s: %s :: %s (%d remaining)\n", w.since(), name, rs.Description, len(w.remaining))
+	} else {
+		w.remaining[name] = rs.Description
+	}
+	select {
+	case w.changed <- struct{}{}:
+	default:
 	}
-	sio.Debugf("  %-6s: %s :: %s\n", w.since(), w.displayNameFn(object), rs.Description)
 }
 
 // OnError prints the error for the object to console.
 func (w *waitListener) OnError(object model.K8sMeta, err error) {
 	w.l.Lock()
 	defer w.l.Unlock()
+	w.progress.Clear()
 	sio.Errorf("%-6s: %s :: %v\n", w.since(), w.displayNameFn(object), err)
 }
 
-// OnEnd prints a list of objects that are not marked complete.
+// OnEnd stops the progress display and prints a list of objects that are not marked complete.
 func (w *waitListener) OnEnd(err error) {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.stopped
+	}
 	w.l.Lock()
 	defer w.l.Unlock()
-	sio.Println()
+	w.progress.Clear()
 	if len(w.remaining) > 0 {
-		sio.Printf("%s: rollout not complete for the following %d objects\n", w.since(), len(w.remaining))
+		var names []string
 		for name := range w.remaining {
-			sio.Printf("  - %s\n", name)
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sio.Printf("%s: rollout not complete for the following %d objects\n", w.since(), len(w.remaining))
+		for _, name := range names {
+			sio.Printf("  - %s :: %s\n", name, w.remaining[name])
 		}
 	}
 	if err == nil {
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/splunk/qbec/internal/model"
 	"github.com/splunk/qbec/internal/sio"
@@ -67,8 +68,9 @@ func TestWaitListener(t *testing.T) {
 	output := buf.String()
 	a := assert.New(t)
 	a.Contains(output, "waiting for readiness of 3 objects")
-	a.Contains(output, "- apps/Deployment test-ns/d1")
-	a.Contains(output, "0s    : apps/Deployment test-ns/d1 :: starting d1 rollout")
+	a.Contains(output, "0s: waiting for 3 objects")
+	a.Contains(output, "  - apps/Deployment test-ns/d1 :: waiting for status")
+	a.NotContains(output, "starting d1 rollout")
 	a.Contains(output, "✓ 0s    : apps/Deployment test-ns/d1 :: successful rollout (2 remaining)")
 	a.Contains(output, "rollout complete")
 }
@@ -98,11 +100,39 @@ func TestWaitListenerTimeout(t *testing.T) {
 	output := buf.String()
 	a := assert.New(t)
 	a.Contains(output, "waiting for readiness of 3 objects")
-	a.Contains(output, "- apps/Deployment test-ns/d1")
-	a.Contains(output, "0s    : apps/Deployment test-ns/d1 :: starting d1 rollout")
+	a.Contains(output, "  - apps/Deployment test-ns/d1 :: waiting for status")
 	a.Contains(output, "✓ 0s    : apps/Deployment test-ns/d1 :: successful rollout (2 remaining)")
 	a.Contains(output, "✘ 0s    : apps/Deployment test-ns/d3 :: d3 missing")
 	a.Contains(output, "rollout not complete for the following 1 object")
+	a.Contains(output, "  - apps/Deployment test-ns/d3 :: waiting for version")
+	a.NotContains(output, "rollout complete\n")
+}
+
+func TestWaitListenerProgress(t *testing.T) {
+	var buf bytes.Buffer
+	oldOutput, oldColors := sio.Output, sio.ColorsEnabled()
+	defer func() {
+		sio.Output = oldOutput
+		sio.EnableColors(oldColors)
+	}()
+	sio.Output = &buf
+	sio.EnableColors(false)
+
+	d1, d2 := testDeployment("d1"), testDeployment("d2")
+	wl := &waitListener{displayNameFn: testDisplayName, minInterval: 10 * time.Millisecond, maxInterval: 20 * time.Millisecond}
+	wl.OnInit([]model.K8sMeta{d1, d2})
+	wl.OnStatusChange(d1, types.RolloutStatus{Description: "1 of 2 updated replicas are available"})
+	wl.OnStatusChange(d2, types.RolloutStatus{Description: "successful rollout", Done: true})
+	time.Sleep(100 * time.Millisecond)
+	wl.OnStatusChange(d1, types.RolloutStatus{Description: "successful rollout", Done: true})
+	wl.OnEnd(nil)
+
+	output := buf.String()
+	a := assert.New(t)
+	a.Contains(output, "0s: waiting for 2 objects")
+	a.Contains(output, "0s: waiting for 1 objects\n  - apps/Deployment test-ns/d1 :: 1 of 2 updated replicas are available\n")
+	a.NotContains(output, "apps/Deployment test-ns/d2 :: 1 of 2")
+	a.Contains(output, "✓ 0s: rollout complete")
 }
 
 func TestWaitWatcher(t *testing.T) {
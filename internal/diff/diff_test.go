@@ -17,6 +17,7 @@
 package diff
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -127,3 +128,44 @@ func TestColorize(t *testing.T) {
 	a.Contains(outStr, escRed+"-  line: 1st st\n"+escReset)
 	a.Contains(outStr, escGreen+"+  line: 2nd st\n"+escReset)
 }
+
+func TestJSONPatch(t *testing.T) {
+	left := map[string]interface{}{
+		"name":   "foo",
+		"a/b":    "x",
+		"remove": true,
+		"spec": map[string]interface{}{
+			"replicas": 1,
+			"ports":    []interface{}{80, 443, 8080},
+		},
+	}
+	right := map[string]interface{}{
+		"name":  "foo",
+		"a/b":   "y",
+		"added": nil,
+		"spec": map[string]interface{}{
+			"replicas": 2,
+			"ports":    []interface{}{80},
+		},
+	}
+	ops, err := JSONPatch(left, right)
+	require.NoError(t, err)
+	b, err := json.Marshal(ops)
+	require.NoError(t, err)
+	expected := `[{"op":"replace","path":"/a~1b","value":"y"},{"op":"add","path":"/added","value":null},` +
+		`{"op":"remove","path":"/remove"},{"op":"remove","path":"/spec/ports/2"},{"op":"remove","path":"/spec/ports/1"},` +
+		`{"op":"replace","path":"/spec/replicas","value":2}]`
+	assert.Equal(t, expected, string(b))
+
+	ops, err = JSONPatch(left, left)
+	require.NoError(t, err)
+	assert.Len(t, ops, 0)
+
+	ops, err = JSONPatch(nil, map[string]interface{}{"a": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []PatchOperation{{Op: "add", Path: "", Value: map[string]interface{}{"a": "b"}}}, ops)
+
+	ops, err = JSONPatch(left, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []PatchOperation{{Op: "remove", Path: ""}}, ops)
+}
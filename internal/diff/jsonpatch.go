@@ -0,0 +1,157 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diff
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// PatchOperation is a single RFC 6902 JSON patch operation.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON implements custom marshaling such that null values are retained for add and replace operations.
+func (p PatchOperation) MarshalJSON() ([]byte, error) {
+	if p.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{p.Op, p.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{p.Op, p.Path, p.Value})
+}
+
+// JSONPatch returns the list of RFC 6902 operations that transform the left object into the right object.
+// Either object may be nil. A nil left object produces a single add operation for the document root and
+// a nil right object produces a remove operation for the document root. An empty list is returned when
+// the objects are the same.
+func JSONPatch(left, right interface{}) ([]PatchOperation, error) {
+	normalize := func(data interface{}) (interface{}, error) {
+		if data == nil {
+			return nil, nil
+		}
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		var out interface{}
+		if err := json.Unmarshal(b, &out); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	l, err := normalize(left)
+	if err != nil {
+		return nil, errors.Wrap(err, "normalize left")
+	}
+	r, err := normalize(right)
+	if err != nil {
+		return nil, errors.Wrap(err, "normalize right")
+	}
+	ops := []PatchOperation{}
+	switch {
+	case l == nil && r == nil:
+		return ops, nil
+	case l == nil:
+		return append(ops, PatchOperation{Op: "add", Path: "", Value: r}), nil
+	case r == nil:
+		return append(ops, PatchOperation{Op: "remove", Path: ""}), nil
+	default:
+		return patchValue(ops, "", l, r), nil
+	}
+}
+
+// escapePathToken escapes a reference token as described in RFC 6901.
+func escapePathToken(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func patchValue(ops []PatchOperation, path string, left, right interface{}) []PatchOperation {
+	switch l := left.(type) {
+	case map[string]interface{}:
+		if r, ok := right.(map[string]interface{}); ok {
+			return patchMap(ops, path, l, r)
+		}
+	case []interface{}:
+		if r, ok := right.([]interface{}); ok {
+			return patchArray(ops, path, l, r)
+		}
+	}
+	if reflect.DeepEqual(left, right) {
+		return ops
+	}
+	return append(ops, PatchOperation{Op: "replace", Path: path, Value: right})
+}
+
+func patchMap(ops []PatchOperation, path string, left, right map[string]interface{}) []PatchOperation {
+	var keys []string
+	for k := range left {
+		keys = append(keys, k)
+	}
+	for k := range right {
+		if _, ok := left[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		p := path + "/" + escapePathToken(k)
+		lv, inLeft := left[k]
+		rv, inRight := right[k]
+		switch {
+		case !inRight:
+			ops = append(ops, PatchOperation{Op: "remove", Path: p})
+		case !inLeft:
+			ops = append(ops, PatchOperation{Op: "add", Path: p, Value: rv})
+		default:
+			ops = patchValue(ops, p, lv, rv)
+		}
+	}
+	return ops
+}
+
+// patchArray diffs arrays position by position. Extra elements on the left are removed in
+// reverse order such that indices of previous operations remain valid.
+func patchArray(ops []PatchOperation, path string, left, right []interface{}) []PatchOperation {
+	common := len(left)
+	if len(right) < common {
+		common = len(right)
+	}
+	for i := 0; i < common; i++ {
+		ops = patchValue(ops, path+"/"+strconv.Itoa(i), left[i], right[i])
+	}
+	for i := common; i < len(right); i++ {
+		ops = append(ops, PatchOperation{Op: "add", Path: path + "/-", Value: right[i]})
+	}
+	for i := len(left) - 1; i >= common; i-- {
+		ops = append(ops, PatchOperation{Op: "remove", Path: path + "/" + strconv.Itoa(i)})
+	}
+	return ops
+}
@@ -0,0 +1,166 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diff
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is a single step of a path, either a field name, a list index or a wildcard.
+type pathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// Path is a parsed JSONPath-like expression that addresses fields of an object.
+type Path struct {
+	expr     string
+	segments []pathSegment
+}
+
+// String returns the expression from which the path was parsed.
+func (p Path) String() string {
+	return p.expr
+}
+
+// ParsePath parses a JSONPath-like expression into a path. The expression is a list of field names separated by dots,
+// optionally starting with "$" or ".". Field names that contain dots or other special characters must be specified
+// in brackets using single or double quotes, for example `metadata.annotations['deployment.kubernetes.io/revision']`.
+// A bracketed number addresses a list element and `[*]` (or a `*` field) addresses all fields or elements.
+func ParsePath(expr string) (Path, error) {
+	ret := Path{expr: expr}
+	s := strings.TrimPrefix(expr, "$")
+	fail := func(msg string) (Path, error) {
+		return Path{}, fmt.Errorf("invalid path %q: %s", expr, msg)
+	}
+	needField := !strings.HasPrefix(s, ".") && !strings.HasPrefix(s, "[")
+	for len(s) > 0 {
+		switch {
+		case s[0] == '.':
+			s = s[1:]
+			needField = true
+		case s[0] == '[':
+			end := strings.Index(s, "]")
+			if end < 0 {
+				return fail("unterminated bracket")
+			}
+			inner := s[1:end]
+			s = s[end+1:]
+			needField = false
+			switch {
+			case inner == "*":
+				ret.segments = append(ret.segments, pathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				ret.segments = append(ret.segments, pathSegment{field: inner[1 : len(inner)-1]})
+			default:
+				n, err := strconv.Atoi(inner)
+				if err != nil || n < 0 {
+					return fail(fmt.Sprintf("invalid index %q, must be a non-negative number, '*' or a quoted field name", inner))
+				}
+				ret.segments = append(ret.segments, pathSegment{index: n, isIndex: true})
+			}
+			continue
+		default:
+			if !needField {
+				return fail(fmt.Sprintf("unexpected character %q", s[0]))
+			}
+		}
+		if !needField {
+			continue
+		}
+		end := strings.IndexAny(s, ".[")
+		if end < 0 {
+			end = len(s)
+		}
+		field := s[:end]
+		s = s[end:]
+		if field == "" {
+			return fail("empty field name")
+		}
+		needField = false
+		if field == "*" {
+			ret.segments = append(ret.segments, pathSegment{wildcard: true})
+			continue
+		}
+		ret.segments = append(ret.segments, pathSegment{field: field})
+	}
+	if len(ret.segments) == 0 {
+		return fail("no fields specified")
+	}
+	if needField {
+		return fail("empty field name")
+	}
+	return ret, nil
+}
+
+// Remove removes all values addressed by the path from the supplied object. Paths that do not address any
+// values are ignored.
+func (p Path) Remove(obj map[string]interface{}) {
+	removePath(obj, p.segments)
+}
+
+// removePath removes the values addressed by the segments from the supplied node and returns the updated node.
+func removePath(node interface{}, segments []pathSegment) interface{} {
+	seg := segments[0]
+	last := len(segments) == 1
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if seg.isIndex {
+			return n
+		}
+		keys := []string{seg.field}
+		if seg.wildcard {
+			keys = keys[:0]
+			for k := range n {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			child, ok := n[k]
+			if !ok {
+				continue
+			}
+			if last {
+				delete(n, k)
+				continue
+			}
+			n[k] = removePath(child, segments[1:])
+		}
+		return n
+	case []interface{}:
+		switch {
+		case seg.wildcard && last:
+			return []interface{}{}
+		case seg.wildcard:
+			for i, child := range n {
+				n[i] = removePath(child, segments[1:])
+			}
+		case seg.isIndex && seg.index < len(n):
+			if last {
+				return append(n[:seg.index:seg.index], n[seg.index+1:]...)
+			}
+			n[seg.index] = removePath(n[seg.index], segments[1:])
+		}
+		return n
+	default:
+		return node
+	}
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package diff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testObject() map[string]interface{} {
+	return map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": "foo",
+			"annotations": map[string]interface{}{
+				"deployment.kubernetes.io/revision": "3",
+				"keep":                              "me",
+			},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "c1", "image": "i1"},
+				map[string]interface{}{"name": "c2", "image": "i2"},
+			},
+		},
+		"status": map[string]interface{}{
+			"ready": true,
+		},
+	}
+}
+
+func TestPathRemove(t *testing.T) {
+	tests := []struct {
+		expr   string
+		expect func(obj map[string]interface{})
+	}{
+		{
+			expr: "status",
+			expect: func(obj map[string]interface{}) {
+				delete(obj, "status")
+			},
+		},
+		{
+			expr: "$.spec.replicas",
+			expect: func(obj map[string]interface{}) {
+				delete(obj["spec"].(map[string]interface{}), "replicas")
+			},
+		},
+		{
+			expr: ".metadata.annotations['deployment.kubernetes.io/revision']",
+			expect: func(obj map[string]interface{}) {
+				delete(obj["metadata"].(map[string]interface{})["annotations"].(map[string]interface{}), "deployment.kubernetes.io/revision")
+			},
+		},
+		{
+			expr: `metadata["annotations"].*`,
+			expect: func(obj map[string]interface{}) {
+				obj["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{}
+			},
+		},
+		{
+			expr: "spec.containers[*].image",
+			expect: func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["containers"] = []interface{}{
+					map[string]interface{}{"name": "c1"},
+					map[string]interface{}{"name": "c2"},
+				}
+			},
+		},
+		{
+			expr: "spec.containers[0]",
+			expect: func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["containers"] = []interface{}{
+					map[string]interface{}{"name": "c2", "image": "i2"},
+				}
+			},
+		},
+		{
+			expr: "spec.containers[*]",
+			expect: func(obj map[string]interface{}) {
+				obj["spec"].(map[string]interface{})["containers"] = []interface{}{}
+			},
+		},
+		{
+			expr:   "spec.containers[5].image",
+			expect: func(obj map[string]interface{}) {},
+		},
+		{
+			expr:   "spec.replicas.foo",
+			expect: func(obj map[string]interface{}) {},
+		},
+		{
+			expr:   "spec[0]",
+			expect: func(obj map[string]interface{}) {},
+		},
+		{
+			expr:   "no.such.field",
+			expect: func(obj map[string]interface{}) {},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			p, err := ParsePath(test.expr)
+			require.NoError(t, err)
+			assert.Equal(t, test.expr, p.String())
+			obj := testObject()
+			p.Remove(obj)
+			expected := testObject()
+			test.expect(expected)
+			assert.Equal(t, expected, obj)
+		})
+	}
+}
+
+func TestParsePathNegative(t *testing.T) {
+	tests := []struct {
+		expr string
+		msg  string
+	}{
+		{expr: "", msg: `invalid path "": no fields specified`},
+		{expr: "$", msg: `invalid path "$": no fields specified`},
+		{expr: "spec.", msg: `invalid path "spec.": empty field name`},
+		{expr: "spec..replicas", msg: `invalid path "spec..replicas": empty field name`},
+		{expr: "spec.containers[0", msg: `invalid path "spec.containers[0": unterminated bracket`},
+		{expr: "spec.containers[-1]", msg: `invalid path "spec.containers[-1]": invalid index "-1", must be a non-negative number, '*' or a quoted field name`},
+		{expr: "spec.containers[foo]", msg: `invalid path "spec.containers[foo]": invalid index "foo", must be a non-negative number, '*' or a quoted field name`},
+		{expr: "spec.containers[0]image", msg: `invalid path "spec.containers[0]image": unexpected character 'i'`},
+	}
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			_, err := ParsePath(test.expr)
+			require.Error(t, err)
+			assert.Equal(t, test.msg, err.Error())
+		})
+	}
+}
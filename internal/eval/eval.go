@@ -36,9 +36,10 @@ import (
 )
 
 const (
-	defaultConcurrency = 5
-	maxDisplayErrors   = 3
-	postprocessTLAVar  = "object"
+	defaultConcurrency  = 5
+	maxDisplayErrors    = 3
+	postprocessTLAVar   = "object"
+	streamProcessTLAVar = "objects"
 )
 
 // LocalObjectProducer converts a data object that has basic Kubernetes attributes
@@ -84,12 +85,47 @@ func (p postProc) run(obj map[string]interface{}) (map[string]interface{}, error
 	return t, nil
 }
 
+// streamProc transforms the list of all objects produced by a component.
+type streamProc struct {
+	ctx  Context
+	file string
+}
+
+func (p streamProc) run(objs []map[string]interface{}) ([]map[string]interface{}, error) {
+	if objs == nil {
+		objs = []map[string]interface{}{}
+	}
+	b, err := json.Marshal(objs)
+	if err != nil {
+		return nil, errors.Wrap(err, "json marshal")
+	}
+	baseVars := p.ctx.Vars.WithTopLevelVars(vm.NewCodeVar(streamProcessTLAVar, string(b)))
+	evalCode, err := p.ctx.evalFile(p.file, p.ctx.componentVars(baseVars, nil))
+	if err != nil {
+		return nil, errors.Wrap(err, "stream-eval objects")
+	}
+	var data interface{}
+	if err := json.Unmarshal([]byte(evalCode), &data); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unexpected unmarshal '%s'", p.file))
+	}
+	if _, ok := data.([]interface{}); !ok {
+		return nil, fmt.Errorf("stream-eval did not return an array, %s", evalCode)
+	}
+	return walk(data)
+}
+
 // BaseContext is the context required to evaluate a single file
 type BaseContext struct {
 	LibPaths    []string                // library paths
 	DataSources []datasource.DataSource // data sources
 	Vars        vm.VariableSet          // variables for the VM
 	Verbose     bool                    // show generated code
+	HelmCommand string                  // helm executable for the expandHelmTemplate native function
+	EnvName     string                  // environment name returned by the qbecEnvName native function
+	RemoteLibs  []vm.RemoteLib          // remote library bundles
+	LibCacheDir string                  // cache directory for remote library bundles
+	Seed        string                  // seed for the random native functions
+	Now         time.Time               // time returned by the now native function
 	jvm         vm.VM
 }
 
@@ -97,6 +133,12 @@ func (c *BaseContext) newVM() vm.VM {
 	return vm.New(vm.Config{
 		DataSources: c.DataSources,
 		LibPaths:    c.LibPaths,
+		HelmCommand: c.HelmCommand,
+		EnvName:     c.EnvName,
+		RemoteLibs:  c.RemoteLibs,
+		LibCacheDir: c.LibCacheDir,
+		Seed:        c.Seed,
+		Now:         c.Now,
 	})
 }
 
@@ -126,9 +168,14 @@ func File(file string, ctx BaseContext) (jsonData string, err error) {
 // Context is the evaluation context
 type Context struct {
 	BaseContext
-	Concurrency      int               // concurrent components to evaluate, default 5
-	PostProcessFiles []string          // files that contains post-processing code for all objects
-	tlaVars          map[string]vm.Var // all top level string vars specified for the command
+	Concurrency        int                                           // concurrent components to evaluate, default 5
+	PostProcessFiles   []string                                      // files that contains post-processing code for all objects
+	StreamProcessFiles []string                                      // files that transform the list of objects of every component, applied in order
+	ImagePullSecrets   []string                                      // image pull secrets added to service accounts and pod specs that do not reference them
+	KindLabels         []model.KindLabels                            // labels added to objects of specific kinds
+	DefaultNamespace   string                                        // release namespace for helm charts that do not set one
+	OnComponentEval    func(component string, elapsed time.Duration) // optional callback, called concurrently, with the evaluation time of each component
+	tlaVars            map[string]vm.Var                             // all top level string vars specified for the command
 }
 
 func (c *Context) init() {
@@ -170,6 +217,17 @@ func (c Context) postProcessors() []postProc {
 	return ret
 }
 
+func (c Context) streamProcessors() []streamProc {
+	var ret []streamProc
+	for _, file := range c.StreamProcessFiles {
+		ret = append(ret, streamProc{
+			ctx:  c,
+			file: file,
+		})
+	}
+	return ret
+}
+
 // Components evaluates the specified components using the specific runtime
 // parameters file and returns the result.
 func Components(components []model.Component, ctx Context, lop LocalObjectProducer) (_ []model.K8sLocalObject, finalErr error) {
@@ -193,21 +251,60 @@ func Components(components []model.Component, ctx Context, lop LocalObjectProduc
 		rightKey := fmt.Sprintf("%s:%s:%s:%s", right.Component(), right.GetNamespace(), right.GroupVersionKind().Kind, right.GetName())
 		return leftKey < rightKey
 	})
-	return ret, nil
+	return applyPatches(ret, lop)
 }
 
-// Params evaluates the supplied parameters file in the supplied VM and
-// returns it as a JSON object.
-func Params(file string, ctx Context) (map[string]interface{}, error) {
+// ComponentParams returns the parameters of the components in the components object of the supplied parameters
+// file that are selected by the include function, keyed by component name. Only the parameters of selected
+// components are evaluated, so selecting a few components of a large parameters file is cheap.
+func ComponentParams(file string, ctx Context, include func(component string) bool) (map[string]interface{}, error) {
 	ctx.init()
-	output, err := ctx.evalFile(file, ctx.componentVars(ctx.Vars, nil))
+	vars := ctx.componentVars(ctx.Vars, nil)
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(filepath.ToSlash(abs))
+	if err != nil {
+		return nil, err
+	}
+	params := fmt.Sprintf("(import %s)", b)
+
+	// listing the fields of the components object does not evaluate their values
+	code := fmt.Sprintf(`local p = %s; if std.isObject(p) && std.objectHas(p, 'components') && std.isObject(p.components) then std.objectFields(p.components) else null`, params)
+	output, err := ctx.jvm.EvalCode(file, vm.MakeCode(code), vars)
+	if err != nil {
+		return nil, err
+	}
+	var all []string
+	if err := json.Unmarshal([]byte(output), &all); err != nil {
+		return nil, err
+	}
+	if all == nil {
+		return nil, fmt.Errorf("unable to find 'components' key in the parameter object")
+	}
+	var names []string
+	for _, name := range all {
+		if include(name) {
+			names = append(names, name)
+		}
+	}
+	ret := map[string]interface{}{}
+	if len(names) == 0 {
+		return ret, nil
+	}
+	b, err = json.Marshal(names)
+	if err != nil {
+		return nil, err
+	}
+	code = fmt.Sprintf(`local c = %s.components; { [n]: c[n] for n in %s }`, params, b)
+	output, err = ctx.jvm.EvalCode(file, vm.MakeCode(code), vars)
 	if err != nil {
 		return nil, err
 	}
 	if ctx.Verbose {
 		sio.Debugln("Eval params output:\n" + prettyJSON(output))
 	}
-	var ret map[string]interface{}
 	if err := json.Unmarshal([]byte(output), &ret); err != nil {
 		return nil, err
 	}
@@ -227,8 +324,65 @@ func openFile(file string) (*os.File, error) {
 	return f, nil
 }
 
-func evaluationCode(c Context, file string) evalFn {
+// evalHelmChart returns the objects of the helm chart defined by the supplied file of a helm chart component.
+func evalHelmChart(c Context, file string, component string, tlas []string) (interface{}, error) {
+	var data interface{}
+	if strings.HasSuffix(file, ".yaml") {
+		f, err := openFile(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		docs, err := vmutil.ParseYAMLDocuments(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(docs) != 1 {
+			return nil, fmt.Errorf("%s: helm chart file must contain exactly one document, found %d", file, len(docs))
+		}
+		data = docs[0]
+	} else {
+		evalCode, err := c.evalFile(file, c.componentVars(c.Vars, tlas))
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(evalCode), &data); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("unexpected unmarshal '%s'", file))
+		}
+	}
+	chart, err := model.ParseHelmChart(data)
+	if err != nil {
+		return nil, err
+	}
+	name := chart.ReleaseName
+	if name == "" {
+		name = component
+	}
+	ns := chart.Namespace
+	if ns == "" {
+		ns = c.DefaultNamespace
+	}
+	objects, err := vmutil.ExpandHelmTemplate(chart.Chart, chart.Values, vmutil.HelmOptions{
+		Command:     c.HelmCommand,
+		File:        file,
+		Repo:        chart.Repo,
+		Version:     chart.Version,
+		ReleaseName: name,
+		Namespace:   ns,
+		KubeVersion: chart.KubeVersion,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "expand helm chart %s", chart.Chart)
+	}
+	return objects, nil
+}
+
+func evaluationCode(c Context, helmChart bool, file string) evalFn {
 	switch {
+	case helmChart:
+		return func(file string, component string, tlas []string) (interface{}, error) {
+			return evalHelmChart(c, file, component, tlas)
+		}
 	case strings.HasSuffix(file, ".yaml"):
 		return func(file string, component string, tlas []string) (interface{}, error) {
 			f, err := openFile(file)
@@ -262,13 +416,18 @@ func evaluationCode(c Context, file string) evalFn {
 	}
 }
 
+// componentSource returns a description of where the supplied component is defined, for use in error messages.
+func componentSource(c model.Component) string {
+	return strings.Join(c.Files, ", ")
+}
+
 func evalComponent(ctx Context, c model.Component, pe []postProc, lop LocalObjectProducer) ([]model.K8sLocalObject, error) {
 	var data []interface{}
 	for _, file := range c.Files {
-		fn := evaluationCode(ctx, file)
+		fn := evaluationCode(ctx, c.HelmChart, file)
 		ret, err := fn(file, c.Name, c.TopLevelVars)
 		if err != nil {
-			return nil, errors.Wrapf(err, "evaluate '%s'", c.Name)
+			return nil, errors.Wrapf(err, "evaluate '%s' (%s)", c.Name, file)
 		}
 		data = append(data, ret)
 	}
@@ -280,7 +439,7 @@ func evalComponent(ctx Context, c model.Component, pe []postProc, lop LocalObjec
 	}
 	objs, err := walk(evalData)
 	if err != nil {
-		return nil, errors.Wrap(err, "extract objects")
+		return nil, errors.Wrapf(err, "extract objects for '%s' (%s)", c.Name, componentSource(c))
 	}
 
 	runPostProcessors := func(obj map[string]interface{}) (map[string]interface{}, error) {
@@ -288,22 +447,39 @@ func evalComponent(ctx Context, c model.Component, pe []postProc, lop LocalObjec
 		for _, pp := range pe {
 			obj, err = pp.run(obj)
 			if err != nil {
-				return nil, errors.Wrapf(err, "run post-processor %s", pp.file)
+				return nil, errors.Wrapf(err, "run post-processor %s for '%s' (%s)", pp.file, c.Name, componentSource(c))
 			}
 		}
 		return obj, nil
 	}
 
-	var processed []model.K8sLocalObject
+	var objects []map[string]interface{}
 	for _, o := range objs {
 		proc, err := runPostProcessors(o)
 		if err != nil {
 			return nil, err
 		}
-		if err := model.AssertMetadataValid(proc); err != nil {
-			return nil, err
+		objects = append(objects, proc)
+	}
+	for _, sp := range ctx.streamProcessors() {
+		objects, err = sp.run(objects)
+		if err != nil {
+			return nil, errors.Wrapf(err, "run stream processor %s for '%s' (%s)", sp.file, c.Name, componentSource(c))
+		}
+	}
+
+	var processed []model.K8sLocalObject
+	for _, o := range objects {
+		if err := model.AssertMetadataValid(o); err != nil {
+			return nil, errors.Wrapf(err, "check metadata for '%s' (%s)", c.Name, componentSource(c))
+		}
+		if err := injectPullSecrets(o, ctx.ImagePullSecrets); err != nil {
+			return nil, errors.Wrapf(err, "inject image pull secrets for '%s' (%s)", c.Name, componentSource(c))
 		}
-		processed = append(processed, lop(c.Name, proc))
+		if err := injectKindLabels(o, ctx.KindLabels); err != nil {
+			return nil, errors.Wrapf(err, "inject kind labels for '%s' (%s)", c.Name, componentSource(c))
+		}
+		processed = append(processed, lop(c.Name, o))
 	}
 	return processed, nil
 }
@@ -337,7 +513,11 @@ func evalComponents(list []model.Component, ctx Context, pe []postProc, lop Loca
 		go func() {
 			defer wg.Done()
 			for c := range ch {
+				start := time.Now()
 				objs, err := evalComponent(ctx, c, pe, lop)
+				if err == nil && ctx.OnComponentEval != nil {
+					ctx.OnComponentEval(c.Name, time.Since(start))
+				}
 				l.Lock()
 				if err != nil {
 					errs = append(errs, err)
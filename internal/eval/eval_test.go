@@ -22,12 +22,16 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/splunk/qbec/internal/model"
 	"github.com/splunk/qbec/vm"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 func producer(component string, data map[string]interface{}) model.K8sLocalObject {
@@ -96,33 +100,49 @@ func TestEvalCode(t *testing.T) {
 	a.EqualValues("bar", base["foo"])
 }
 
-func TestEvalParams(t *testing.T) {
-	paramsMap, err := Params("testdata/params.libsonnet", decorate(Context{
+func TestEvalComponentParams(t *testing.T) {
+	ctx := decorate(Context{
 		BaseContext: BaseContext{Verbose: true},
-	}))
+	})
+	comps, err := ComponentParams("testdata/params.libsonnet", ctx, func(string) bool { return true })
 	require.Nil(t, err)
 	a := assert.New(t)
-	comps, ok := paramsMap["components"].(map[string]interface{})
-	require.True(t, ok)
 	base, ok := comps["base"].(map[string]interface{})
 	require.True(t, ok)
 	a.EqualValues("dev", base["env"])
 	a.EqualValues("foobar", base["ns"])
 	a.EqualValues("t1", base["tag"])
 	a.EqualValues("bar", base["foo"])
+
+	comps, err = ComponentParams("testdata/params.libsonnet", ctx, func(string) bool { return false })
+	require.Nil(t, err)
+	a.Equal(0, len(comps))
 }
 
-func TestEvalParamsNegative(t *testing.T) {
-	_, err := Params("testdata/params.invalid.libsonnet", decorate(Context{}))
+func TestEvalComponentParamsLazy(t *testing.T) {
+	comps, err := ComponentParams("testdata/params.lazy.libsonnet", decorate(Context{}), func(c string) bool { return c == "good" })
+	require.Nil(t, err)
+	assert.EqualValues(t, map[string]interface{}{"good": map[string]interface{}{"foo": "bar"}}, comps)
+
+	_, err = ComponentParams("testdata/params.lazy.libsonnet", decorate(Context{}), func(string) bool { return true })
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "bad params")
+}
+
+func TestEvalComponentParamsNegative(t *testing.T) {
+	all := func(string) bool { return true }
+	_, err := ComponentParams("testdata/params.invalid.libsonnet", decorate(Context{}), all)
 	require.NotNil(t, err)
 	require.Contains(t, err.Error(), "end of file")
 
-	_, err = Params("testdata/params.non-object.libsonnet", decorate(Context{}))
+	_, err = ComponentParams("testdata/params.non-object.libsonnet", decorate(Context{}), all)
 	require.NotNil(t, err)
-	require.Contains(t, err.Error(), "cannot unmarshal array")
+	require.Contains(t, err.Error(), "unable to find 'components' key")
 }
 
 func TestEvalComponents(t *testing.T) {
+	var l sync.Mutex
+	var evaluated []string
 	objs, err := Components([]model.Component{
 		{
 			Name:  "b",
@@ -156,12 +176,19 @@ func TestEvalComponents(t *testing.T) {
 				Verbose: true,
 			},
 			PostProcessFiles: []string{"testdata/components/pp/pp.jsonnet", "testdata/components/pp/pp2.jsonnet"},
+			OnComponentEval: func(component string, elapsed time.Duration) {
+				l.Lock()
+				defer l.Unlock()
+				evaluated = append(evaluated, component)
+			},
 		}),
 		producer,
 	)
 	require.Nil(t, err)
 	require.Equal(t, 6, len(objs))
 	a := assert.New(t)
+	sort.Strings(evaluated)
+	a.EqualValues([]string{"a", "b", "c", "d", "tla"}, evaluated)
 
 	// ensure postprocessor is called everywhere
 	for _, obj := range objs {
@@ -200,6 +227,57 @@ func TestEvalComponents(t *testing.T) {
 	a.Equal("tla-config-map", obj.GetName())
 }
 
+func TestEvalComponentsPatches(t *testing.T) {
+	objs, err := Components([]model.Component{
+		{
+			Name:  "base",
+			Files: []string{"testdata/patch-components/base.yaml"},
+		},
+		{
+			Name:  "patch",
+			Files: []string{"testdata/patch-components/patch.yaml"},
+		},
+	}, decorate(Context{}), producer)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(objs))
+	a := assert.New(t)
+
+	deploy := objs[1].ToUnstructured()
+	a.Equal("Deployment", deploy.GetKind())
+	a.Equal("base", objs[1].Component())
+	a.Equal("base", deploy.GetAnnotations()[model.QbecNames.ComponentAnnotation])
+	a.NotContains(deploy.GetAnnotations(), model.QbecNames.Directives.PatchTarget)
+	a.Equal("web", deploy.GetLabels()["app"])
+	replicas, _, _ := unstructured.NestedFieldNoCopy(deploy.Object, "spec", "replicas")
+	a.EqualValues(3, replicas)
+	containers, _, _ := unstructured.NestedSlice(deploy.Object, "spec", "template", "spec", "containers")
+	require.Equal(t, 2, len(containers))
+	images := map[string]interface{}{}
+	for _, c := range containers {
+		cm := c.(map[string]interface{})
+		images[cm["name"].(string)] = cm["image"]
+	}
+	a.EqualValues(map[string]interface{}{"web": "web:2.0", "sidecar": "sidecar:1.0"}, images)
+
+	widget := objs[0].ToUnstructured()
+	a.Equal("Widget", widget.GetKind())
+	size, _, _ := unstructured.NestedString(widget.Object, "spec", "size")
+	a.Equal("small", size)
+	colors, _, _ := unstructured.NestedStringSlice(widget.Object, "spec", "colors")
+	a.EqualValues([]string{"blue"}, colors)
+}
+
+func TestEvalComponentsBadPatch(t *testing.T) {
+	_, err := Components([]model.Component{
+		{
+			Name:  "bad",
+			Files: []string{"testdata/patch-components/bad-patch.yaml"},
+		},
+	}, decorate(Context{}), producer)
+	require.Error(t, err)
+	assert.Equal(t, `ConfigMap/bad in component bad: invalid value "yes" for directives.qbec.io/patch-target, must be "true"`, err.Error())
+}
+
 func TestEvalComponentsEdges(t *testing.T) {
 	goodComponents := []model.Component{
 		{Name: "g1", Files: []string{"testdata/good-components/g1.jsonnet"}},
@@ -229,7 +307,7 @@ func TestEvalComponentsEdges(t *testing.T) {
 			components: []model.Component{{Name: "e1", Files: []string{"testdata/bad-components/e1.jsonnet"}}},
 			asserter: func(t *testing.T, ret []model.K8sLocalObject, err error) {
 				require.NotNil(t, err)
-				assert.Contains(t, err.Error(), "evaluate 'e1'")
+				assert.Contains(t, err.Error(), "evaluate 'e1' (testdata/bad-components/e1.jsonnet)")
 			},
 		},
 		{
@@ -348,7 +426,46 @@ func TestEvalComponentsBadPostProcessor(t *testing.T) {
 		},
 	}, decorate(Context{PostProcessFiles: []string{"foo/bar.jsonnet"}}), producer)
 	require.NotNil(t, err)
-	require.Contains(t, err.Error(), "run post-processor foo/bar.jsonnet:")
+	require.Contains(t, err.Error(), "run post-processor foo/bar.jsonnet for 'bad' (testdata/components/a.json):")
+}
+
+func TestEvalComponentsStreamProcessors(t *testing.T) {
+	objs, err := Components([]model.Component{
+		{
+			Name:  "b",
+			Files: []string{"testdata/components/b.yaml"},
+		},
+	}, decorate(Context{
+		PostProcessFiles:   []string{"testdata/components/pp/pp2.jsonnet"},
+		StreamProcessFiles: []string{"testdata/components/sp/labels.jsonnet", "testdata/components/sp/inventory.jsonnet"},
+	}), producer)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(objs))
+	a := assert.New(t)
+	obj := objs[0]
+	a.Equal("b", obj.Component())
+	a.Equal("inventory", obj.GetName())
+	a.Equal("", obj.ToUnstructured().GetLabels()["layer"])
+	data, _, _ := unstructured.NestedString(obj.ToUnstructured().Object, "data", "names")
+	a.Equal("yaml-config-map", data)
+	obj = objs[1]
+	a.Equal("b", obj.Component())
+	a.Equal("yaml-config-map", obj.GetName())
+	a.Equal("common", obj.ToUnstructured().GetLabels()["layer"])
+	a.Equal("bar", obj.ToUnstructured().GetLabels()["foo"])
+}
+
+func TestEvalComponentsBadStreamProcessor(t *testing.T) {
+	_, err := Components([]model.Component{
+		{
+			Name:  "b",
+			Files: []string{"testdata/components/b.yaml"},
+		},
+	}, decorate(Context{StreamProcessFiles: []string{"testdata/components/sp/bad.jsonnet"}}), producer)
+	require.NotNil(t, err)
+	a := assert.New(t)
+	a.Contains(err.Error(), "run stream processor testdata/components/sp/bad.jsonnet for 'b'")
+	a.Contains(err.Error(), "stream-eval did not return an array")
 }
 
 func TestEvalComponentsBadYaml(t *testing.T) {
@@ -362,6 +479,33 @@ func TestEvalComponentsBadYaml(t *testing.T) {
 	require.Contains(t, err.Error(), "did not find expected node content")
 }
 
+func TestEvalComponentsMultiDocYaml(t *testing.T) {
+	objs, err := Components([]model.Component{
+		{
+			Name:  "multi",
+			Files: []string{"testdata/components/multi.yaml"},
+		},
+	}, decorate(Context{}), producer)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(objs))
+	a := assert.New(t)
+	for i, o := range objs {
+		a.Equal("multi", o.Component())
+		a.Equal(fmt.Sprintf("multi-cm%d", i+1), o.GetName())
+	}
+}
+
+func TestEvalComponentsBadMultiDocYaml(t *testing.T) {
+	_, err := Components([]model.Component{
+		{
+			Name:  "bad-multi",
+			Files: []string{"testdata/components/bad-multi.yaml"},
+		},
+	}, decorate(Context{}), producer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "evaluate 'bad-multi' (testdata/components/bad-multi.yaml): document at index 1:")
+}
+
 func TestEvalComponentsBadObjects(t *testing.T) {
 	_, err := Components([]model.Component{
 		{
@@ -370,6 +514,7 @@ func TestEvalComponentsBadObjects(t *testing.T) {
 		},
 	}, decorate(Context{}), producer)
 	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "extract objects for 'bad' (testdata/components/bad-objects.yaml)")
 	require.Contains(t, err.Error(), `non-kubernetes object found while evaluating path "$[0].foo" (found "string"`)
 }
 
@@ -381,6 +526,7 @@ func TestEvalComponentsBadMetadata(t *testing.T) {
 		},
 	}, decorate(Context{}), producer)
 	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "check metadata for 'bad-metadata' (testdata/components/bad-metadata.yaml)")
 	require.Contains(t, err.Error(), `/v1, Kind=ConfigMap, Name=subdir-config-map1: .metadata.annotations accessor error`)
 }
 
@@ -479,3 +625,123 @@ func TestEvalPostProcessor(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalHelmChartComponents(t *testing.T) {
+	helm, err := filepath.Abs("testdata/helm/fake-helm.sh")
+	require.NoError(t, err)
+	ctx := decorate(Context{BaseContext: BaseContext{HelmCommand: helm}, DefaultNamespace: "foobar"})
+	objs, err := Components([]model.Component{
+		{Name: "local", Files: []string{"testdata/helm-components/local.yaml"}, HelmChart: true},
+		{Name: "remote", Files: []string{"testdata/helm-components/remote.jsonnet"}, HelmChart: true},
+	}, ctx, producer)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(objs))
+	a := assert.New(t)
+	data := func(o model.K8sLocalObject, key string) string {
+		s, _, err := unstructured.NestedString(o.ToUnstructured().Object, "data", key)
+		require.NoError(t, err)
+		return s
+	}
+
+	a.Equal("local", objs[0].Component())
+	a.Equal("rendered", objs[0].GetName())
+	a.Equal("template ./charts/local --name-template local --namespace foobar --values -", data(objs[0], "args"))
+	a.Equal("helm-components", data(objs[0], "dir"))
+	a.Equal("replicas: 2\n", data(objs[0], "values"))
+
+	a.Equal("remote", objs[1].Component())
+	a.Equal("template redis --name-template cache --namespace redis-ns --repo https://charts.example.com --version 1.2.3 --values -", data(objs[1], "args"))
+	a.Equal("env: dev\n", data(objs[1], "values"))
+}
+
+func TestEvalHelmChartComponentsNegative(t *testing.T) {
+	helm, err := filepath.Abs("testdata/helm/fake-helm.sh")
+	require.NoError(t, err)
+	tests := []struct {
+		file string
+		msg  string
+	}{
+		{"testdata/helm-components/bad.yaml", `invalid helm chart: json: unknown field "foo"`},
+		{"testdata/helm-components/multi.yaml", "helm chart file must contain exactly one document, found 2"},
+	}
+	for _, test := range tests {
+		t.Run(filepath.Base(test.file), func(t *testing.T) {
+			ctx := decorate(Context{BaseContext: BaseContext{HelmCommand: helm}})
+			_, err := Components([]model.Component{{Name: "c", Files: []string{test.file}, HelmChart: true}}, ctx, producer)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.msg)
+		})
+	}
+	ctx := decorate(Context{BaseContext: BaseContext{HelmCommand: "testdata/helm/no-such-helm"}})
+	_, err = Components([]model.Component{{Name: "c", Files: []string{"testdata/helm-components/local.yaml"}, HelmChart: true}}, ctx, producer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expand helm chart ./charts/local")
+}
+
+// largeParamsApp writes count components to a temporary directory, all of which import a params file where the
+// params of every component are expensive to compute and also depend on an expensive shared value.
+func largeParamsApp(b *testing.B, count int) []model.Component {
+	dir := b.TempDir()
+	params := `
+local checksums = std.foldl(function(acc, i) acc + [std.md5('value-%d' % i)], std.range(1, 300), []);
+{
+  components: {
+    ['c%d' % i]: {
+      name: 'cm-%d' % i,
+      checksum: std.md5(std.join(',', checksums) + std.foldl(function(acc, j) std.md5(acc + j), std.range(1, 100), '%d' % i)),
+    }
+    for i in std.range(1, ` + fmt.Sprint(count) + `)
+  },
+}
+`
+	require.NoError(b, ioutil.WriteFile(filepath.Join(dir, "params.libsonnet"), []byte(params), 0644))
+	var ret []model.Component
+	for i := 1; i <= count; i++ {
+		name := fmt.Sprintf("c%d", i)
+		code := fmt.Sprintf(`
+local p = (import 'params.libsonnet').components.%s;
+{ apiVersion: 'v1', kind: 'ConfigMap', metadata: { name: p.name }, data: p }
+`, name)
+		file := filepath.Join(dir, name+".jsonnet")
+		require.NoError(b, ioutil.WriteFile(file, []byte(code), 0644))
+		ret = append(ret, model.Component{Name: name, Files: []string{file}})
+	}
+	return ret
+}
+
+func BenchmarkComponentParamsLargeParams(b *testing.B) {
+	components := largeParamsApp(b, 50)
+	file := filepath.Join(filepath.Dir(components[0].Files[0]), "params.libsonnet")
+	for _, test := range []struct {
+		name    string
+		include func(string) bool
+	}{
+		{"one", func(c string) bool { return c == "c1" }},
+		{"all", func(string) bool { return true }},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := ComponentParams(file, decorate(Context{}), test.include)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
+
+func BenchmarkEvalComponentsLargeParams(b *testing.B) {
+	components := largeParamsApp(b, 50)
+	for _, test := range []struct {
+		name       string
+		components []model.Component
+	}{
+		{"one", components[:1]},
+		{"all", components},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := Components(test.components, decorate(Context{}), producer)
+				require.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package eval
+
+import (
+	"github.com/splunk/qbec/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// injectKindLabels adds the labels of every entry whose kind patterns match the object. Labels already set on
+// the object are retained unless the entry overrides them, and later entries take precedence over earlier ones.
+func injectKindLabels(obj map[string]interface{}, kindLabels []model.KindLabels) error {
+	if len(kindLabels) == 0 {
+		return nil
+	}
+	un := unstructured.Unstructured{Object: obj}
+	gk := un.GroupVersionKind().GroupKind()
+	existing := un.GetLabels()
+	labels := map[string]string{}
+	for k, v := range existing {
+		labels[k] = v
+	}
+	changed := false
+	for _, kl := range kindLabels {
+		matched := false
+		for _, pattern := range kl.Kinds {
+			ok, err := model.MatchKind(pattern, gk)
+			if err != nil {
+				return err
+			}
+			if ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		for k, v := range kl.Labels {
+			if _, ok := existing[k]; ok && !kl.Override {
+				continue
+			}
+			labels[k] = v
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	un.SetLabels(labels)
+	return nil
+}
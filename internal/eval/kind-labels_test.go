@@ -0,0 +1,75 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package eval
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectKindLabels(t *testing.T) {
+	kindLabels := []model.KindLabels{
+		{Kinds: []string{"Deployment", "apps/*Set"}, Labels: map[string]string{"cost-center": "cc-1", "team": "a"}},
+		{Kinds: []string{"StatefulSet"}, Labels: map[string]string{"cost-center": "cc-2"}},
+		{Kinds: []string{"batch/*"}, Labels: map[string]string{"team": "batch"}, Override: true},
+	}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "deployment",
+			input:    `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d"}}`,
+			expected: `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d","labels":{"cost-center":"cc-1","team":"a"}}}`,
+		},
+		{
+			name:     "later-entry-wins",
+			input:    `{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"s"}}`,
+			expected: `{"apiVersion":"apps/v1","kind":"StatefulSet","metadata":{"name":"s","labels":{"cost-center":"cc-2","team":"a"}}}`,
+		},
+		{
+			name:     "component-label-wins",
+			input:    `{"apiVersion":"apps/v1","kind":"DaemonSet","metadata":{"name":"ds","labels":{"team":"b"}}}`,
+			expected: `{"apiVersion":"apps/v1","kind":"DaemonSet","metadata":{"name":"ds","labels":{"cost-center":"cc-1","team":"b"}}}`,
+		},
+		{
+			name:     "override",
+			input:    `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"j","labels":{"team":"b"}}}`,
+			expected: `{"apiVersion":"batch/v1","kind":"Job","metadata":{"name":"j","labels":{"team":"batch"}}}`,
+		},
+		{
+			name:     "other-kind",
+			input:    `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`,
+			expected: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var obj, expected map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &obj))
+			require.NoError(t, json.Unmarshal([]byte(test.expected), &expected))
+			err := injectKindLabels(obj, kindLabels)
+			require.NoError(t, err)
+			assert.EqualValues(t, expected, obj)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/sio"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// patchKey returns the key used to match a patch with its target.
+func patchKey(o model.K8sMeta) string {
+	gvk := o.GroupVersionKind()
+	return fmt.Sprintf("%s:%s:%s:%s", gvk.Group, gvk.Kind, o.GetNamespace(), o.GetName())
+}
+
+// isPatch returns true if the supplied object is a patch for another object.
+func isPatch(o model.K8sLocalObject) (bool, error) {
+	v, ok := o.GetAnnotations()[model.QbecNames.Directives.PatchTarget]
+	if !ok {
+		return false, nil
+	}
+	if v != "true" {
+		return false, fmt.Errorf("%s/%s in component %s: invalid value %q for %s, must be \"true\"",
+			o.GetKind(), o.GetName(), o.Component(), v, model.QbecNames.Directives.PatchTarget)
+	}
+	return true, nil
+}
+
+// patchContents returns the JSON contents of the patch without qbec metadata and directives such that
+// the target object retains its own.
+func patchContents(o model.K8sLocalObject) ([]byte, error) {
+	un := o.ToUnstructured().DeepCopy()
+	clean := func(m map[string]string) map[string]string {
+		for k := range m {
+			if strings.HasPrefix(k, model.QBECMetadataPrefix) || k == model.QbecNames.Directives.PatchTarget {
+				delete(m, k)
+			}
+		}
+		return m
+	}
+	un.SetLabels(clean(un.GetLabels()))
+	un.SetAnnotations(clean(un.GetAnnotations()))
+	return json.Marshal(un.Object)
+}
+
+// mergePatch applies the supplied patch to the target object. A strategic merge patch is used for types
+// known to the client library and a JSON merge patch otherwise.
+func mergePatch(target model.K8sLocalObject, patch []byte) (map[string]interface{}, error) {
+	gvk := target.GroupVersionKind()
+	versionedObject, err := scheme.Scheme.New(gvk)
+	if err != nil && !runtime.IsNotRegisteredError(err) {
+		return nil, errors.Wrap(err, fmt.Sprintf("getting instance of versioned object for %v", gvk))
+	}
+	if err == nil {
+		var p map[string]interface{}
+		if err := json.Unmarshal(patch, &p); err != nil {
+			return nil, err
+		}
+		return strategicpatch.StrategicMergeMapPatch(target.ToUnstructured().Object, p, versionedObject)
+	}
+	original, err := json.Marshal(target.ToUnstructured().Object)
+	if err != nil {
+		return nil, err
+	}
+	b, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, err
+	}
+	var ret map[string]interface{}
+	if err := json.Unmarshal(b, &ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// applyPatches merges objects that have the patch target directive set into the objects that they target,
+// matched by group, kind, namespace and name. Patches are applied in the order in which they appear in the
+// supplied list and are removed from the returned list.
+func applyPatches(objs []model.K8sLocalObject, lop LocalObjectProducer) ([]model.K8sLocalObject, error) {
+	var ret, patches []model.K8sLocalObject
+	targets := map[string]int{}
+	for _, o := range objs {
+		p, err := isPatch(o)
+		if err != nil {
+			return nil, err
+		}
+		if p {
+			patches = append(patches, o)
+			continue
+		}
+		targets[patchKey(o)] = len(ret)
+		ret = append(ret, o)
+	}
+	for _, p := range patches {
+		index, ok := targets[patchKey(p)]
+		if !ok {
+			sio.Warnf("no target found for patch %s/%s in component %s, ignored\n", p.GetKind(), p.GetName(), p.Component())
+			continue
+		}
+		target := ret[index]
+		contents, err := patchContents(p)
+		if err != nil {
+			return nil, err
+		}
+		merged, err := mergePatch(target, contents)
+		if err != nil {
+			return nil, errors.Wrapf(err, "apply patch %s/%s from component %s", p.GetKind(), p.GetName(), p.Component())
+		}
+		ret[index] = lop(target.Component(), merged)
+	}
+	return ret, nil
+}
@@ -0,0 +1,86 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package eval
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// podSpecPaths are the paths to pod specs of the kinds that embed them, keyed by group and kind.
+var podSpecPaths = map[schema.GroupKind][]string{
+	{Kind: "Pod"}:                             {"spec"},
+	{Kind: "ReplicationController"}:           {"spec", "template", "spec"},
+	{Group: "apps", Kind: "Deployment"}:       {"spec", "template", "spec"},
+	{Group: "apps", Kind: "DaemonSet"}:        {"spec", "template", "spec"},
+	{Group: "apps", Kind: "ReplicaSet"}:       {"spec", "template", "spec"},
+	{Group: "apps", Kind: "StatefulSet"}:      {"spec", "template", "spec"},
+	{Group: "extensions", Kind: "Deployment"}: {"spec", "template", "spec"},
+	{Group: "extensions", Kind: "DaemonSet"}:  {"spec", "template", "spec"},
+	{Group: "extensions", Kind: "ReplicaSet"}: {"spec", "template", "spec"},
+	{Group: "batch", Kind: "Job"}:             {"spec", "template", "spec"},
+	{Group: "batch", Kind: "CronJob"}:         {"spec", "jobTemplate", "spec", "template", "spec"},
+	{Kind: "ServiceAccount"}:                  nil,
+}
+
+// injectPullSecrets adds references to the supplied image pull secrets to service accounts and pod specs
+// that do not already have them. Other objects are left unchanged.
+func injectPullSecrets(obj map[string]interface{}, secrets []string) error {
+	if len(secrets) == 0 {
+		return nil
+	}
+	un := unstructured.Unstructured{Object: obj}
+	gvk := un.GroupVersionKind()
+	path, ok := podSpecPaths[gvk.GroupKind()]
+	if !ok {
+		return nil
+	}
+	fields := append(append([]string{}, path...), "imagePullSecrets")
+	existing, _, err := unstructured.NestedFieldNoCopy(obj, fields...)
+	if err != nil {
+		return err
+	}
+	var refs []interface{}
+	if existing != nil {
+		l, ok := existing.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s/%s: imagePullSecrets is not an array", gvk.Kind, un.GetName())
+		}
+		refs = l
+	}
+	present := map[string]bool{}
+	for _, r := range refs {
+		if m, ok := r.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				present[name] = true
+			}
+		}
+	}
+	changed := false
+	for _, s := range secrets {
+		if !present[s] {
+			refs = append(refs, map[string]interface{}{"name": s})
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return unstructured.SetNestedField(obj, refs, fields...)
+}
@@ -0,0 +1,77 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package eval
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectPullSecrets(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "service-account",
+			input:    `{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"sa"}}`,
+			expected: `{"apiVersion":"v1","kind":"ServiceAccount","metadata":{"name":"sa"},"imagePullSecrets":[{"name":"creds"},{"name":"other"}]}`,
+		},
+		{
+			name:     "pod-partial",
+			input:    `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p"},"spec":{"imagePullSecrets":[{"name":"other"}]}}`,
+			expected: `{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p"},"spec":{"imagePullSecrets":[{"name":"other"},{"name":"creds"}]}}`,
+		},
+		{
+			name:     "deployment",
+			input:    `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d"},"spec":{"template":{"spec":{"containers":[]}}}}`,
+			expected: `{"apiVersion":"apps/v1","kind":"Deployment","metadata":{"name":"d"},"spec":{"template":{"spec":{"containers":[],"imagePullSecrets":[{"name":"creds"},{"name":"other"}]}}}}`,
+		},
+		{
+			name:     "cronjob",
+			input:    `{"apiVersion":"batch/v1","kind":"CronJob","metadata":{"name":"c"},"spec":{"jobTemplate":{"spec":{"template":{"spec":{}}}}}}`,
+			expected: `{"apiVersion":"batch/v1","kind":"CronJob","metadata":{"name":"c"},"spec":{"jobTemplate":{"spec":{"template":{"spec":{"imagePullSecrets":[{"name":"creds"},{"name":"other"}]}}}}}}`,
+		},
+		{
+			name:     "other-kind",
+			input:    `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`,
+			expected: `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"cm"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var obj, expected map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(test.input), &obj))
+			require.NoError(t, json.Unmarshal([]byte(test.expected), &expected))
+			err := injectPullSecrets(obj, []string{"creds", "other"})
+			require.NoError(t, err)
+			assert.EqualValues(t, expected, obj)
+		})
+	}
+}
+
+func TestInjectPullSecretsNegative(t *testing.T) {
+	var obj map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"apiVersion":"v1","kind":"Pod","metadata":{"name":"p"},"spec":{"imagePullSecrets":"creds"}}`), &obj))
+	err := injectPullSecrets(obj, []string{"creds"})
+	require.Error(t, err)
+	assert.Equal(t, "Pod/p: imagePullSecrets is not an array", err.Error())
+}
@@ -0,0 +1,14 @@
+#!/bin/sh
+# fake helm executable that renders a config map with the arguments and values that it was called with
+values=$(cat)
+cat <<EOF
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: rendered
+data:
+  args: "$*"
+  dir: "$(basename "$(pwd)")"
+  values: |
+$(echo "$values" | sed 's/^/    /')
+EOF
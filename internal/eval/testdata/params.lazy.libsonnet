@@ -0,0 +1,6 @@
+{
+    components: {
+        good: { foo: 'bar' },
+        bad: error 'bad params',
+    },
+}
@@ -19,9 +19,12 @@ package model
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -32,6 +35,10 @@ import (
 	"github.com/pkg/errors"
 	"github.com/splunk/qbec/internal/filematcher"
 	"github.com/splunk/qbec/internal/sio"
+	"github.com/splunk/qbec/vm/vmutil"
+	v3yaml "gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // Baseline is a special environment name that represents the baseline environment with no customizations.
@@ -54,9 +61,37 @@ var httpClient = &http.Client{Timeout: time.Second * 10}
 
 // Component is one or more logically related files that contains objects to be applied to a cluster.
 type Component struct {
-	Name         string   // component name
-	Files        []string // path to main component file and possibly additional files
-	TopLevelVars []string // the top-level variables used by the component
+	Name         string            // component name
+	Files        []string          // path to main component file and possibly additional files
+	TopLevelVars []string          // the top-level variables used by the component
+	Labels       map[string]string `json:",omitempty"` // labels from the component metadata file, if any
+	HelmChart    bool              `json:",omitempty"` // true if the component file defines a helm chart
+}
+
+// componentMetaSuffix is the suffix of a metadata file that may be placed next to a component file or directory.
+// A component named foo can have its metadata in a file called foo.meta.yaml.
+const componentMetaSuffix = ".meta.yaml"
+
+// componentMeta is the structure of a component metadata file.
+type componentMeta struct {
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// loadComponentLabels returns the labels for a component from the supplied metadata file. A missing file
+// is not an error and returns no labels.
+func loadComponentLabels(file string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var meta componentMeta
+	if err := yaml.Unmarshal(b, &meta); err != nil {
+		return nil, errors.Wrapf(err, "unmarshal component metadata %s", file)
+	}
+	return meta.Labels, nil
 }
 
 // App is a qbec application wrapped with some runtime attributes.
@@ -64,7 +99,9 @@ type App struct {
 	inner             QbecApp              // the app object from serialization
 	overrideNs        string               // any override to the default namespace
 	tag               string               // the tag to be used for the current command invocation
+	gcTag             string               // any override to the GC tag
 	root              string               // derived root directory of the app
+	wd                string               // working directory when the app was loaded
 	allComponents     map[string]Component // all components whether or not included anywhere
 	defaultComponents map[string]Component // all components enabled by default
 }
@@ -98,7 +135,84 @@ func mergeEnvironments(eDst, eSrc Environment) Environment {
 	return eDst
 }
 
-func downloadEnvFile(url string) ([]byte, error) {
+// inheritEnvironment returns the supplied environment with unset attributes inherited from its base. The
+// cluster target (server, context or contexts) is inherited as a unit, as is the client certificate and key pair.
+// Properties are deep-merged with the properties of the environment taking precedence.
+func inheritEnvironment(env, base Environment) Environment {
+	if env.Server == "" && env.Context == "" && len(env.Contexts) == 0 {
+		env.Server = base.Server
+		env.Context = base.Context
+		env.Contexts = base.Contexts
+	}
+	if env.ClientCertFile == "" && env.ClientKeyFile == "" {
+		env.ClientCertFile = base.ClientCertFile
+		env.ClientKeyFile = base.ClientKeyFile
+	}
+	if env.CAFile == "" {
+		env.CAFile = base.CAFile
+	}
+	if env.DefaultNamespace == "" {
+		env.DefaultNamespace = base.DefaultNamespace
+	}
+	if env.VarsFile == "" {
+		env.VarsFile = base.VarsFile
+	}
+	if len(env.Includes) == 0 {
+		env.Includes = base.Includes
+	}
+	if len(env.Excludes) == 0 {
+		env.Excludes = base.Excludes
+	}
+	env.Properties = deepMerge(base.Properties, env.Properties)
+	return env
+}
+
+// resolveEnvironments replaces every environment that extends another with the result of inheriting from its
+// fully resolved base, returning an error for references to unknown environments and inheritance cycles.
+func resolveEnvironments(envs map[string]Environment) error {
+	resolved := map[string]Environment{}
+	var resolve func(name string, chain []string) (Environment, error)
+	resolve = func(name string, chain []string) (Environment, error) {
+		if env, ok := resolved[name]; ok {
+			return env, nil
+		}
+		for i, c := range chain {
+			if c == name {
+				return Environment{}, fmt.Errorf("environment %s: cyclic extends chain %s", chain[0], strings.Join(append(chain[i:], name), " -> "))
+			}
+		}
+		env := envs[name]
+		if env.Extends != "" {
+			if _, ok := envs[env.Extends]; !ok {
+				return Environment{}, fmt.Errorf("environment %s extends unknown environment %s", name, env.Extends)
+			}
+			base, err := resolve(env.Extends, append(chain, name))
+			if err != nil {
+				return Environment{}, err
+			}
+			env = inheritEnvironment(env, base)
+		}
+		resolved[name] = env
+		return env, nil
+	}
+
+	names := make([]string, 0, len(envs))
+	for name := range envs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	for name, env := range resolved {
+		envs[name] = env
+	}
+	return nil
+}
+
+func downloadFile(url string) ([]byte, error) {
 	res, err := httpClient.Get(url)
 	if err != nil {
 		return nil, err
@@ -117,7 +231,7 @@ func downloadEnvFile(url string) ([]byte, error) {
 
 func readEnvFile(file string) ([]byte, error) {
 	if filematcher.IsRemoteFile(file) {
-		b, err := downloadEnvFile(file)
+		b, err := downloadFile(file)
 		if err != nil {
 			return nil, errors.Wrapf(err, "download environments from %s", file)
 		}
@@ -126,7 +240,57 @@ func readEnvFile(file string) ([]byte, error) {
 	return ioutil.ReadFile(file)
 }
 
-func loadEnvFiles(app *QbecApp, additionalFiles []string, v *validator) error {
+// matchEnvFiles returns the files matched by the supplied patterns, in order.
+func matchEnvFiles(patterns []string, resolve func(string) string) ([]string, error) {
+	var allFiles []string
+	for _, filePattern := range patterns {
+		matchedFiles, err := filematcher.Match(resolve(filePattern))
+		if err != nil {
+			return nil, err
+		}
+		allFiles = append(allFiles, matchedFiles...)
+	}
+	return allFiles, nil
+}
+
+// readEnvironments reads and validates the environments defined in the supplied file. When strict is set,
+// an environment that is defined more than once in the file is an error, otherwise it is reported to onDuplicate
+// if that is not nil.
+func readEnvironments(file string, v *validator, strict bool, onDuplicate func(err error)) (map[string]Environment, error) {
+	b, err := readEnvFile(file)
+	if err != nil {
+		return nil, err
+	}
+	b, err = interpolateEnvVars(file, b, os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	var qEnvs QbecEnvironmentMap
+	if err := yaml.Unmarshal(b, &qEnvs); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("%s: unmarshal YAML", file))
+	}
+	errs := v.validateEnvYAML(b)
+	if len(errs) > 0 {
+		return nil, makeValError(file, errs)
+	}
+	if strict || onDuplicate != nil {
+		for _, name := range duplicateEnvNames(b) {
+			err := fmt.Errorf("%s: duplicate definition for environment '%s'", file, name)
+			if strict {
+				return nil, err
+			}
+			onDuplicate(err)
+		}
+	}
+	return qEnvs.Spec.Environments, nil
+}
+
+// loadEnvFiles loads environments from the env files of the app, its environment files and the supplied additional
+// files, in that order. An environment in an environment file may not be defined anywhere else. For other files,
+// a later definition replaces or is merged into an earlier one and, when onDuplicate is not nil, it is called for
+// every environment that is defined more than once, unless the definitions are merged. Patterns declared by
+// the app are resolved using the supplied function.
+func loadEnvFiles(app *QbecApp, file string, additionalFiles []string, v *validator, resolve func(string) string, onDuplicate func(err error)) error {
 	if app.Spec.Environments == nil {
 		app.Spec.Environments = map[string]Environment{}
 	}
@@ -135,49 +299,95 @@ func loadEnvFiles(app *QbecApp, additionalFiles []string, v *validator) error {
 		sources[k] = "inline"
 	}
 
-	var envFiles []string
-	envFiles = append(envFiles, app.Spec.EnvFiles...)
-	envFiles = append(envFiles, additionalFiles...)
-	var allFiles []string
-	for _, filePattern := range envFiles {
-		matchedFiles, err := filematcher.Match(filePattern)
-		if err != nil {
-			return err
-		}
-		allFiles = append(allFiles, matchedFiles...)
-	}
-	for _, file := range allFiles {
-		b, err := readEnvFile(file)
+	load := func(patterns []string, resolve func(string) string) error {
+		files, err := matchEnvFiles(patterns, resolve)
 		if err != nil {
 			return err
 		}
-		var qEnvs QbecEnvironmentMap
-		if err := yaml.Unmarshal(b, &qEnvs); err != nil {
-			return errors.Wrap(err, fmt.Sprintf("%s: unmarshal YAML", file))
-		}
-		errs := v.validateEnvYAML(b)
-		if len(errs) > 0 {
-			return makeValError(file, errs)
-		}
-		for envName, env := range qEnvs.Spec.Environments {
-			old, ok := sources[envName]
-			if ok {
-				if app.Spec.MergeImportedEnvs {
+		for _, file := range files {
+			envs, err := readEnvironments(file, v, false, onDuplicate)
+			if err != nil {
+				return err
+			}
+			for envName, env := range envs {
+				old, ok := sources[envName]
+				switch {
+				case !ok:
+					sources[envName] = file
+					app.Spec.Environments[envName] = env
+				case app.Spec.MergeImportedEnvs:
 					sio.Warnf("merge env definition '%s' with file %s (previous: %s)\n", envName, file, old)
 					sources[envName] = "Merge from: " + file
 					app.Spec.Environments[envName] = mergeEnvironments(app.Spec.Environments[envName], env)
-				} else {
+				default:
+					if onDuplicate != nil {
+						onDuplicate(fmt.Errorf("duplicate definition for environment '%s' in file %s (previous: %s)", envName, file, old))
+					}
 					sio.Warnf("override env definition '%s' from file %s (previous: %s)\n", envName, file, old)
 					sources[envName] = file
 					app.Spec.Environments[envName] = env
 				}
-			} else {
-				sources[envName] = file
-				app.Spec.Environments[envName] = env
 			}
 		}
+		return nil
+	}
+
+	if err := load(app.Spec.EnvFiles, resolve); err != nil {
+		return err
+	}
+
+	envFiles, err := matchEnvFiles(app.Spec.EnvironmentFiles, resolve)
+	if err != nil {
+		return err
+	}
+	for _, envFile := range envFiles {
+		envs, err := readEnvironments(envFile, v, true, nil)
+		if err != nil {
+			return err
+		}
+		names := make([]string, 0, len(envs))
+		for name := range envs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, envName := range names {
+			if old, ok := sources[envName]; ok {
+				if old == "inline" {
+					old = file
+				}
+				return fmt.Errorf("duplicate definition for environment '%s' in files %s and %s", envName, old, envFile)
+			}
+			sources[envName] = envFile
+			app.Spec.Environments[envName] = envs[envName]
+		}
+	}
+
+	// additional files are supplied on the command line and are relative to the working directory
+	return load(additionalFiles, func(p string) string { return p })
+}
+
+// StdinAppFile is the app file name that causes the app to be read from standard input.
+const StdinAppFile = "-"
+
+// ReadAppFile returns the contents of the supplied app file. The file may be an http(s) URL or StdinAppFile,
+// in which case the contents are read from the supplied reader.
+func ReadAppFile(file string, stdin io.Reader) ([]byte, error) {
+	switch {
+	case file == StdinAppFile:
+		b, err := ioutil.ReadAll(stdin)
+		if err != nil {
+			return nil, errors.Wrap(err, "read app from stdin")
+		}
+		return b, nil
+	case filematcher.IsRemoteFile(file):
+		b, err := downloadFile(file)
+		if err != nil {
+			return nil, errors.Wrapf(err, "download app from %s", file)
+		}
+		return b, nil
+	default:
+		return ioutil.ReadFile(file)
 	}
-	return nil
 }
 
 // NewApp returns an app loading its details from the supplied file.
@@ -186,61 +396,163 @@ func NewApp(file string, envFiles []string, tag string) (*App, error) {
 	if err != nil {
 		return nil, err
 	}
+	return NewAppFromContent(file, b, filepath.Dir(file), envFiles, tag)
+}
+
+// NewAppFromContent returns an app loading its details from the supplied contents. The file is only used in
+// messages, and all relative paths in the app are resolved with respect to the supplied root directory.
+func NewAppFromContent(file string, b []byte, root string, envFiles []string, tag string) (*App, error) {
+	app, errs := loadApp(file, b, root, envFiles, tag, false)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return app, nil
+}
+
+// ValidateAppContent checks an app in the same way as NewAppFromContent but returns all problems found instead of
+// failing on the first one. It also reports environments that are defined more than once and invalid default
+// namespaces, which do not otherwise prevent the app from being loaded.
+func ValidateAppContent(file string, b []byte, root string, envFiles []string) []error {
+	_, errs := loadApp(file, b, root, envFiles, "", true)
+	return errs
+}
+
+// loadApp loads an app from the supplied contents. When all is false, loading stops at the first problem.
+// Otherwise, it continues for as long as possible and also reports problems that are only of interest when
+// validating the app.
+func loadApp(file string, b []byte, root string, envFiles []string, tag string, all bool) (*App, []error) {
+	var errs []error
+	// failed records the supplied problem and returns true if loading should stop.
+	failed := func(err error) bool {
+		errs = append(errs, err)
+		return !all
+	}
+
+	b, err := interpolateEnvVars(file, b, os.LookupEnv)
+	if err != nil {
+		return nil, append(errs, err)
+	}
 	var qApp QbecApp
 	if err := yaml.Unmarshal(b, &qApp); err != nil {
-		return nil, errors.Wrap(err, "unmarshal YAML")
+		return nil, append(errs, errors.Wrap(err, "unmarshal YAML"))
 	}
 
 	// validate YAML against schema
 	v, err := newValidator()
 	if err != nil {
-		return nil, errors.Wrap(err, "create schema validator")
+		return nil, append(errs, errors.Wrap(err, "create schema validator"))
 	}
-	errs := v.validateYAML(b)
-	if len(errs) > 0 {
-		return nil, makeValError(file, errs)
+	if verrs := v.validateYAML(b); len(verrs) > 0 {
+		return nil, append(errs, makeValError(file, verrs))
 	}
 
-	if err := loadEnvFiles(&qApp, envFiles, v); err != nil {
-		return nil, err
+	var onDuplicate func(err error)
+	if all {
+		onDuplicate = func(err error) { errs = append(errs, err) }
+		for _, name := range duplicateEnvNames(b) {
+			onDuplicate(fmt.Errorf("%s: duplicate definition for environment '%s'", file, name))
+		}
+	}
+	dir := root
+	if !filepath.IsAbs(dir) {
+		var err error
+		dir, err = filepath.Abs(dir)
+		if err != nil {
+			return nil, append(errs, errors.Wrap(err, "abs path for "+dir))
+		}
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, append(errs, errors.Wrap(err, "os.Getwd"))
+	}
+	app := App{root: dir, wd: wd}
+
+	if err := loadEnvFiles(&qApp, file, envFiles, v, app.ResolvePath, onDuplicate); err != nil {
+		return nil, append(errs, err)
 	}
 
 	if len(qApp.Spec.Environments) == 0 {
-		return nil, fmt.Errorf("%s: no environments defined for app", file)
+		return nil, append(errs, fmt.Errorf("%s: no environments defined for app", file))
 	}
 
-	for name, env := range qApp.Spec.Environments {
+	resolved := true
+	if err := resolveEnvironments(qApp.Spec.Environments); err != nil {
+		if failed(err) {
+			return nil, errs
+		}
+		resolved = false
+	}
+
+	names := make([]string, 0, len(qApp.Spec.Environments))
+	for name := range qApp.Spec.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env := qApp.Spec.Environments[name]
+		if !resolved && env.Extends != "" { // attributes may be inherited from an environment that could not be resolved
+			continue
+		}
 		if err := env.assertValid(); err != nil {
-			return nil, errors.Wrapf(err, "verify environment %s", name)
+			if failed(errors.Wrapf(err, "verify environment %s", name)) {
+				return nil, errs
+			}
+		}
+		if all && env.DefaultNamespace != "" {
+			if msgs := validation.IsDNS1123Label(env.DefaultNamespace); len(msgs) > 0 {
+				errs = append(errs, fmt.Errorf("environment %s: invalid default namespace '%s', %s", name, env.DefaultNamespace, strings.Join(msgs, ", ")))
+			}
 		}
 	}
 
-	app := App{inner: qApp}
-	dir := filepath.Dir(file)
-	if !filepath.IsAbs(dir) {
-		var err error
-		dir, err = filepath.Abs(dir)
-		if err != nil {
-			return nil, errors.Wrap(err, "abs path for "+dir)
+	if qApp.Spec.ComponentsDir != "" && len(qApp.Spec.ComponentsDirs) > 0 {
+		if failed(fmt.Errorf("%s: componentsDir and componentsDirs cannot both be specified", file)) {
+			return nil, errs
 		}
 	}
-	app.root = dir
+
+	app.inner = qApp
 	app.setupDefaults()
-	app.allComponents, err = app.loadComponents()
-	if err != nil {
-		return nil, errors.Wrap(err, "load components")
-	}
-	if err := app.verifyEnvAndComponentReferences(); err != nil {
-		return nil, err
+
+	var rootErrs []error
+	if all { // report every missing root, loading components only reports the first one
+		rootErrs = app.verifyComponentRoots()
+		for _, err := range rootErrs {
+			errs = append(errs, errors.Wrap(err, "load components"))
+		}
 	}
-	if err := app.verifyVariables(); err != nil {
-		return nil, err
+	componentsLoaded := false
+	if len(rootErrs) == 0 {
+		app.allComponents, err = app.loadComponents()
+		if err != nil {
+			if failed(errors.Wrap(err, "load components")) {
+				return nil, errs
+			}
+		} else {
+			componentsLoaded = true
+		}
 	}
-	if err := app.verifyProcessors(); err != nil {
-		return nil, err
+	verifiers := []func() error{
+		app.verifyVariables,
+		app.verifyProcessors,
+		app.verifyKindOrders,
+		app.verifyImagePullSecrets,
+		app.verifyKindLabels,
+		app.verifyRemoteLibs,
+	}
+	if componentsLoaded { // references cannot be checked without the list of components
+		verifiers = append([]func() error{app.verifyEnvAndComponentReferences}, verifiers...)
+	}
+	for _, fn := range verifiers {
+		if err := fn(); err != nil {
+			if failed(err) {
+				return nil, errs
+			}
+		}
 	}
 
 	app.updateComponentTopLevelVars()
+	app.updateComponentHelmCharts()
 
 	app.defaultComponents = make(map[string]Component, len(app.allComponents))
 	for k, v := range app.allComponents {
@@ -252,14 +564,58 @@ func NewApp(file string, envFiles []string, tag string) (*App, error) {
 
 	if tag != "" {
 		if !reLabelValue.MatchString(tag) {
-			return nil, fmt.Errorf("invalid tag name '%s', must match %v", tag, reLabelValue)
+			if failed(fmt.Errorf("invalid tag name '%s', must match %v", tag, reLabelValue)) {
+				return nil, errs
+			}
 		}
 	}
+	if gcTag := app.inner.Spec.GCTag; gcTag != "" && !reLabelValue.MatchString(gcTag) {
+		if failed(fmt.Errorf("%s: invalid GC tag '%s', must match %v", file, gcTag, reLabelValue)) {
+			return nil, errs
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
 	app.tag = tag
 	return &app, nil
 }
 
+// duplicateEnvNames returns the names of environments that are defined more than once in the supplied app
+// or environment file contents. Such definitions are otherwise silently replaced by the last one.
+func duplicateEnvNames(b []byte) []string {
+	var doc v3yaml.Node
+	if err := v3yaml.Unmarshal(b, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	mapValue := func(n *v3yaml.Node, key string) *v3yaml.Node {
+		if n == nil || n.Kind != v3yaml.MappingNode {
+			return nil
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				return n.Content[i+1]
+			}
+		}
+		return nil
+	}
+	envs := mapValue(mapValue(doc.Content[0], "spec"), "environments")
+	if envs == nil || envs.Kind != v3yaml.MappingNode {
+		return nil
+	}
+	var ret []string
+	seen := map[string]int{}
+	for i := 0; i+1 < len(envs.Content); i += 2 {
+		name := envs.Content[i].Value
+		seen[name]++
+		if seen[name] == 2 {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
 // SetOverrideNamespace sets an override namespace that is returned in preference to the value
 // configured in qbec.yaml for any environment.
 func (a *App) SetOverrideNamespace(ns string) {
@@ -270,7 +626,7 @@ func (a *App) SetOverrideNamespace(ns string) {
 }
 
 func (a *App) setupDefaults() {
-	if a.inner.Spec.ComponentsDir == "" {
+	if a.inner.Spec.ComponentsDir == "" && len(a.inner.Spec.ComponentsDirs) == 0 {
 		a.inner.Spec.ComponentsDir = DefaultComponentsDir
 	}
 	if a.inner.Spec.ParamsFile == "" {
@@ -288,9 +644,53 @@ func (a *App) Tag() string {
 	return a.tag
 }
 
+// SetGCTag sets a GC tag that is returned in preference to the value configured in qbec.yaml.
+func (a *App) SetGCTag(tag string) error {
+	if tag != "" && !reLabelValue.MatchString(tag) {
+		return fmt.Errorf("invalid GC tag '%s', must match %v", tag, reLabelValue)
+	}
+	a.gcTag = tag
+	return nil
+}
+
+// GCTag returns the tag that scopes garbage collection instead of the app name, or blank if objects are
+// garbage collected by app name.
+func (a *App) GCTag() string {
+	if a.gcTag != "" {
+		return a.gcTag
+	}
+	return a.inner.Spec.GCTag
+}
+
+// ResolvePath returns the supplied path, which is relative to the root of the app, such that it can be used from
+// the working directory at the time the app was loaded. Empty, absolute and remote paths are returned unchanged,
+// as are all paths when the working directory is the root.
+func (a *App) ResolvePath(p string) string {
+	if p == "" || filepath.IsAbs(p) || filematcher.IsRemoteFile(p) || a.root == a.wd {
+		return p
+	}
+	abs := filepath.Join(a.root, p)
+	rel, err := filepath.Rel(a.wd, abs)
+	if err != nil {
+		return abs
+	}
+	return rel
+}
+
+func (a *App) resolvePaths(paths []string) []string {
+	if len(paths) == 0 {
+		return paths
+	}
+	ret := make([]string, 0, len(paths))
+	for _, p := range paths {
+		ret = append(ret, a.ResolvePath(p))
+	}
+	return ret
+}
+
 // ParamsFile returns the runtime parameters file for the app.
 func (a *App) ParamsFile() string {
-	return a.inner.Spec.ParamsFile
+	return a.ResolvePath(a.inner.Spec.ParamsFile)
 }
 
 func splitPath(s string) []string {
@@ -302,12 +702,43 @@ func splitPath(s string) []string {
 
 // PostProcessors returns the post processor files for the app.
 func (a *App) PostProcessors() []string {
-	return splitPath(a.inner.Spec.PostProcessor)
+	return a.resolvePaths(splitPath(a.inner.Spec.PostProcessor))
+}
+
+// StreamProcessors returns the stream processor files for the app, in the order in which they must be applied.
+func (a *App) StreamProcessors() []string {
+	return a.resolvePaths(a.inner.Spec.StreamProcessors)
 }
 
 // LibPaths returns the library paths set up for the app.
 func (a *App) LibPaths() []string {
-	return a.inner.Spec.LibPaths
+	return a.resolvePaths(a.inner.Spec.LibPaths)
+}
+
+// HelmCommand returns the helm executable to use for expanding helm charts, defaulting to "helm". A relative path
+// is resolved with respect to the root of the app and returned as an absolute path, since helm is run from the
+// directory of the file that expands the chart.
+func (a *App) HelmCommand() string {
+	cmd := a.inner.Spec.HelmCommand
+	switch {
+	case cmd == "":
+		return "helm"
+	case filepath.IsAbs(cmd) || !strings.ContainsRune(cmd, filepath.Separator) && !strings.ContainsRune(cmd, '/'):
+		return cmd
+	default:
+		return filepath.Join(a.root, cmd)
+	}
+}
+
+// ImagePullSecrets returns the names of image pull secrets that must be referenced by all service accounts and
+// pod templates.
+func (a *App) ImagePullSecrets() []string {
+	return a.inner.Spec.ImagePullSecrets
+}
+
+// KindLabels returns the labels declared by the app for objects of specific kinds.
+func (a *App) KindLabels() []KindLabels {
+	return a.inner.Spec.KindLabels
 }
 
 // AddComponentLabel returns if the qbec component name should be added as an object label in addition to the
@@ -333,15 +764,61 @@ func (a *App) ServerURL(env string) (string, error) {
 	return e.Server, nil
 }
 
-// Context returns the context for the supplied environment, if set.
+// Context returns the context for the supplied environment, if set. For environments that target multiple
+// contexts, the first context is returned.
 func (a *App) Context(env string) (string, error) {
 	e, err := a.envObject(env)
 	if err != nil {
 		return "", err
 	}
+	if len(e.Contexts) > 0 {
+		return e.Contexts[0], nil
+	}
 	return e.Context, nil
 }
 
+// VarsFile returns the file that provides external variable values for the supplied environment, if set.
+func (a *App) VarsFile(env string) (string, error) {
+	if env == Baseline {
+		return "", nil
+	}
+	e, err := a.envObject(env)
+	if err != nil {
+		return "", err
+	}
+	return a.ResolvePath(e.VarsFile), nil
+}
+
+// ClientTLS has the files used for TLS client authentication that override the ones in the kubeconfig.
+type ClientTLS struct {
+	CertFile string // client certificate file
+	KeyFile  string // client key file
+	CAFile   string // certificate authority file
+}
+
+// ClientTLS returns the TLS files declared for the supplied environment. Unset files are returned as empty strings.
+func (a *App) ClientTLS(env string) (ClientTLS, error) {
+	e, err := a.envObject(env)
+	if err != nil {
+		return ClientTLS{}, err
+	}
+	return ClientTLS{
+		CertFile: a.ResolvePath(e.ClientCertFile),
+		KeyFile:  a.ResolvePath(e.ClientKeyFile),
+		CAFile:   a.ResolvePath(e.CAFile),
+	}, nil
+}
+
+// Contexts returns all contexts targeted by the supplied environment when it declares multiple contexts,
+// or nil otherwise.
+func (a *App) Contexts(env string) ([]string, error) {
+	e, err := a.envObject(env)
+	if err != nil {
+		return nil, err
+	}
+	return e.Contexts, nil
+}
+
 // BaseProperties returns the baseline properties defined for the app.
 func (a *App) BaseProperties() map[string]interface{} {
 	p := a.inner.Spec.BaseProperties
@@ -437,10 +914,10 @@ func (a *App) ComponentsForEnvironment(env string, includes, excludes []string)
 	if err != nil {
 		return nil, err
 	}
-	if err := a.verifyComponentList("specified components", includes); err != nil {
+	if err := a.verifyComponentPatterns("specified components", includes); err != nil {
 		return nil, err
 	}
-	if err := a.verifyComponentList("specified components", excludes); err != nil {
+	if err := a.verifyComponentPatterns("specified components", excludes); err != nil {
 		return nil, err
 	}
 	ret := map[string]Component{}
@@ -474,7 +951,7 @@ func (a *App) ComponentsForEnvironment(env string, includes, excludes []string)
 	}
 
 	for _, k := range includes {
-		if _, ok := ret[k]; !ok {
+		if !componentsMatch(ret, k) {
 			sio.Noticef("not including component %s since it is not part of the component list for %s\n", k, env)
 		}
 	}
@@ -488,6 +965,17 @@ func (a *App) ComponentsForEnvironment(env string, includes, excludes []string)
 	return toList(subret), nil
 }
 
+// ComponentNames returns the sorted names of all components of the app, including ones that are excluded
+// for every environment.
+func (a *App) ComponentNames() []string {
+	var ret []string
+	for k := range a.allComponents {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
 // Environments returns the environments defined for the app.
 func (a *App) Environments() map[string]Environment {
 	return a.inner.Spec.Environments
@@ -512,11 +1000,27 @@ func (a *App) DeclaredTopLevelVars() map[string]interface{} {
 	return ret
 }
 
+// DeclaredTopLevelDefaults returns the default values of TLA variables that declare one, keyed by variable name.
+func (a *App) DeclaredTopLevelDefaults() map[string]interface{} {
+	ret := map[string]interface{}{}
+	for _, v := range a.inner.Spec.Vars.TopLevel {
+		if v.Default != nil {
+			ret[v.Name] = v.Default
+		}
+	}
+	return ret
+}
+
 // DeclaredComputedVars returns a list of all computed variables.
 func (a *App) DeclaredComputedVars() []ComputedVar {
 	return a.inner.Spec.Vars.Computed
 }
 
+// RemoteLibs returns the remote library bundles defined for the app.
+func (a *App) RemoteLibs() []RemoteLib {
+	return a.inner.Spec.RemoteLibs
+}
+
 // DataSources returns the datasource URIs defined for the app.
 func (a *App) DataSources() []string {
 	return a.inner.Spec.DataSources
@@ -531,6 +1035,47 @@ func (a *App) DataSourceExamples() map[string]interface{} {
 	return ret
 }
 
+// componentRoots returns the components directories of the app, which may be glob patterns.
+func (a *App) componentRoots() []string {
+	if len(a.inner.Spec.ComponentsDirs) > 0 {
+		return a.resolvePaths(a.inner.Spec.ComponentsDirs)
+	}
+	return []string{a.ResolvePath(a.inner.Spec.ComponentsDir)}
+}
+
+// componentDirs returns the directories that match the supplied components root, failing if there are none.
+func componentDirs(root string) ([]string, error) {
+	ds, err := filepath.Glob(root)
+	if err != nil {
+		return nil, err
+	}
+	var dirs []string
+	for _, d := range ds {
+		s, err := os.Stat(d)
+		if err != nil {
+			return nil, err
+		}
+		if s.IsDir() {
+			dirs = append(dirs, d)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil, fmt.Errorf("no component directories found after expanding %s", root)
+	}
+	return dirs, nil
+}
+
+// verifyComponentRoots returns errors for all components roots that do not match any directory.
+func (a *App) verifyComponentRoots() []error {
+	var errs []error
+	for _, r := range a.componentRoots() {
+		if _, err := componentDirs(r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
 // loadComponents loads metadata for all components for the app. It first expands the components directory
 // for glob patterns and loads components from all directories that match. It does _not_ recurse
 // into subdirectories. The data is returned as a map keyed by component name.
@@ -538,6 +1083,18 @@ func (a *App) DataSourceExamples() map[string]interface{} {
 // way to partition classes of components and does not introduce any namespace semantics.
 func (a *App) loadComponents() (map[string]Component, error) {
 	var list []Component
+	roots := map[string]string{} // the components root from which each component in the list was loaded, keyed by its first file
+	var root string
+	addComponent := func(c Component, path string) error {
+		labels, err := loadComponentLabels(filepath.Join(filepath.Dir(path), c.Name+componentMetaSuffix))
+		if err != nil {
+			return err
+		}
+		c.Labels = labels
+		list = append(list, c)
+		roots[c.Files[0]] = root
+		return nil
+	}
 	loadDirComponents := func(dir string) error {
 		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 			if err != nil {
@@ -568,61 +1125,73 @@ func (a *App) loadComponents() (map[string]Component, error) {
 					case "index.yaml":
 						hasIndexYAML = true
 					}
+					if strings.HasSuffix(f, componentMetaSuffix) {
+						continue
+					}
 					if strings.HasSuffix(f, ".json") || strings.HasSuffix(f, ".yaml") {
 						staticFiles = append(staticFiles, f)
 					}
 				}
+				var c Component
 				switch {
 				case hasIndexJsonnet:
-					list = append(list, Component{
+					c = Component{
 						Name:  filepath.Base(path),
 						Files: []string{filepath.Join(path, "index.jsonnet")},
-					})
+					}
 				case hasIndexYAML:
-					list = append(list, Component{
+					c = Component{
 						Name:  filepath.Base(path),
 						Files: staticFiles,
-					})
+					}
+				default:
+					return filepath.SkipDir
+				}
+				if err := addComponent(c, path); err != nil {
+					return err
 				}
 				return filepath.SkipDir
 			}
+			if strings.HasSuffix(path, componentMetaSuffix) {
+				return nil
+			}
 			extension := filepath.Ext(path)
 			if supportedExtensions[extension] {
-				list = append(list, Component{
+				return addComponent(Component{
 					Name:  strings.TrimSuffix(filepath.Base(path), extension),
 					Files: []string{path},
-				})
+				}, path)
 			}
 			return nil
 		})
 		return err
 	}
-	ds, err := filepath.Glob(a.inner.Spec.ComponentsDir)
-	if err != nil {
-		return nil, err
-	}
-	var dirs []string
-	for _, d := range ds {
-		s, err := os.Stat(d)
+	seen := map[string]bool{}
+	for _, r := range a.componentRoots() {
+		dirs, err := componentDirs(r)
 		if err != nil {
 			return nil, err
 		}
-		if s.IsDir() {
-			dirs = append(dirs, d)
-		}
-	}
-	if len(dirs) == 0 {
-		return nil, fmt.Errorf("no component directories found after expanding %s", a.inner.Spec.ComponentsDir)
-	}
-	for _, d := range dirs {
-		err := loadDirComponents(d)
-		if err != nil {
-			return nil, err
+		root = r
+		for _, d := range dirs {
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			err := loadDirComponents(d)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 	m := make(map[string]Component, len(list))
 	for _, c := range list {
 		if old, ok := m[c.Name]; ok {
+			oldRoot, newRoot := roots[old.Files[0]], roots[c.Files[0]]
+			if oldRoot != newRoot {
+				return nil, fmt.Errorf("duplicate component %s, found %s in components root %s and %s in components root %s",
+					c.Name, old.Files[0], oldRoot, c.Files[0], newRoot)
+			}
 			return nil, fmt.Errorf("duplicate component %s, found %s and %s", c.Name, old.Files[0], c.Files[0])
 		}
 		m[c.Name] = c
@@ -643,6 +1212,31 @@ func (a *App) verifyComponentList(src string, comps []string) error {
 	return nil
 }
 
+// componentsMatch returns true if the supplied pattern matches the name of at least one component.
+func componentsMatch(components map[string]Component, pattern string) bool {
+	for name := range components {
+		if matchName(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyComponentPatterns is like verifyComponentList, except that it allows glob patterns which must match
+// at least one component.
+func (a *App) verifyComponentPatterns(src string, comps []string) error {
+	var bad []string
+	for _, c := range comps {
+		if !componentsMatch(a.allComponents, c) {
+			bad = append(bad, c)
+		}
+	}
+	if len(bad) > 0 {
+		return fmt.Errorf("%s: bad component reference(s): %s", src, strings.Join(bad, ","))
+	}
+	return nil
+}
+
 var reLabelValue = regexp.MustCompile(`^(([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9])$`) // XXX: duplicated in swagger
 
 func (a *App) verifyEnvAndComponentReferences() error {
@@ -677,6 +1271,13 @@ func (a *App) verifyEnvAndComponentReferences() error {
 		localVerify("components for TLA "+tla.Name, tla.Components)
 	}
 
+	localVerify("helm charts", a.inner.Spec.HelmCharts)
+	for _, name := range a.inner.Spec.HelmCharts {
+		if c, ok := a.allComponents[name]; ok && !isHelmChartSource(c.Files) {
+			errs = append(errs, fmt.Sprintf("helm chart %s must be defined by a single .jsonnet or .yaml file", name))
+		}
+	}
+
 	if len(errs) > 0 {
 		return fmt.Errorf("invalid component references\n:\t%s", strings.Join(errs, "\n\t"))
 	}
@@ -728,10 +1329,158 @@ func checkProcessors(pType string, files []string) error {
 	return nil
 }
 
+func kindOrders(orders []KindOrder) map[schema.GroupKind]int {
+	if len(orders) == 0 {
+		return nil
+	}
+	ret := make(map[schema.GroupKind]int, len(orders))
+	for _, o := range orders {
+		ret[schema.GroupKind{Group: o.Group, Kind: o.Kind}] = o.Order
+	}
+	return ret
+}
+
+// ApplyOrder returns the explicit orders for kinds declared by the app to be used when sorting objects for apply.
+func (a *App) ApplyOrder() map[schema.GroupKind]int {
+	return kindOrders(a.inner.Spec.ApplyOrder)
+}
+
+// DeleteOrder returns the explicit orders for kinds declared by the app to be used when sorting objects for
+// deletion. It defaults to the apply order when no delete order is declared.
+func (a *App) DeleteOrder() map[schema.GroupKind]int {
+	if len(a.inner.Spec.DeleteOrder) == 0 {
+		return a.ApplyOrder()
+	}
+	return kindOrders(a.inner.Spec.DeleteOrder)
+}
+
+// DiffIgnorePaths returns the expressions for fields that are removed from objects before they are diffed.
+func (a *App) DiffIgnorePaths() []string {
+	return a.inner.Spec.DiffIgnorePaths
+}
+
+func checkKindOrders(oType string, orders []KindOrder) error {
+	seen := map[schema.GroupKind]bool{}
+	for _, o := range orders {
+		gk := schema.GroupKind{Group: o.Group, Kind: o.Kind}
+		if seen[gk] {
+			return fmt.Errorf("duplicate %s order for kind '%s'", oType, gk)
+		}
+		seen[gk] = true
+	}
+	return nil
+}
+
+func (a *App) verifyKindOrders() error {
+	if err := checkKindOrders("apply", a.inner.Spec.ApplyOrder); err != nil {
+		return err
+	}
+	return checkKindOrders("delete", a.inner.Spec.DeleteOrder)
+}
+
+func (a *App) verifyImagePullSecrets() error {
+	seen := map[string]bool{}
+	for _, s := range a.inner.Spec.ImagePullSecrets {
+		if s == "" {
+			return fmt.Errorf("empty image pull secret name")
+		}
+		if seen[s] {
+			return fmt.Errorf("duplicate image pull secret '%s'", s)
+		}
+		seen[s] = true
+	}
+	return nil
+}
+
+func (a *App) verifyKindLabels() error {
+	for _, kl := range a.inner.Spec.KindLabels {
+		for _, k := range kl.Kinds {
+			if _, err := MatchKind(k, schema.GroupKind{}); err != nil {
+				return fmt.Errorf("kind labels: invalid kind pattern '%s', %v", k, err)
+			}
+		}
+		for k, v := range kl.Labels {
+			if strings.HasPrefix(k, QBECMetadataPrefix) {
+				return fmt.Errorf("kind labels: label '%s' uses the reserved prefix %s", k, QBECMetadataPrefix)
+			}
+			if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+				return fmt.Errorf("kind labels: invalid label name '%s', %s", k, strings.Join(errs, ", "))
+			}
+			if errs := validation.IsValidLabelValue(v); len(errs) > 0 {
+				return fmt.Errorf("kind labels: invalid value '%s' for label %s, %s", v, k, strings.Join(errs, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// MatchKind returns true if the supplied group and kind matches a kind pattern. The pattern is a glob that is matched
+// against the kind or, when it has the form <group>/<kind>, a pair of globs for the group and the kind.
+func MatchKind(pattern string, gk schema.GroupKind) (bool, error) {
+	groupMatch, kindPattern := true, pattern
+	if pos := strings.Index(pattern, "/"); pos >= 0 {
+		var err error
+		groupMatch, err = path.Match(pattern[:pos], gk.Group)
+		if err != nil {
+			return false, err
+		}
+		kindPattern = pattern[pos+1:]
+	}
+	kindMatch, err := path.Match(kindPattern, gk.Kind)
+	if err != nil {
+		return false, err
+	}
+	return groupMatch && kindMatch, nil
+}
+
+var reLibName = regexp.MustCompile(`^[A-Za-z0-9][-A-Za-z0-9_.]*$`)
+
+func (a *App) verifyRemoteLibs() error {
+	seen := map[string]bool{}
+	for _, l := range a.inner.Spec.RemoteLibs {
+		if !reLibName.MatchString(l.Name) {
+			return fmt.Errorf("invalid remote library name '%s', must match %v", l.Name, reLibName)
+		}
+		if seen[l.Name] {
+			return fmt.Errorf("duplicate remote library '%s'", l.Name)
+		}
+		seen[l.Name] = true
+		u, err := url.Parse(l.URL)
+		if err != nil {
+			return fmt.Errorf("remote library %s: invalid URL '%s', %v", l.Name, l.URL, err)
+		}
+		switch u.Scheme {
+		case "http", "https":
+			if l.SHA256 == "" {
+				return fmt.Errorf("remote library %s: sha256 is required for %s URLs", l.Name, u.Scheme)
+			}
+		case "oci":
+			if l.SHA256 == "" {
+				pos := strings.Index(u.Path, "@sha256:")
+				if pos < 0 {
+					return fmt.Errorf("remote library %s: sha256 is required for oci URLs that reference a tag, or reference the manifest by digest", l.Name)
+				}
+				if d := u.Path[pos+len("@sha256:"):]; !vmutil.ValidLibDigest(d) {
+					return fmt.Errorf("remote library %s: invalid manifest digest 'sha256:%s', must be 64 lowercase hex characters", l.Name, d)
+				}
+			}
+		default:
+			return fmt.Errorf("remote library %s: unsupported URL scheme '%s', must be one of http, https or oci", l.Name, u.Scheme)
+		}
+		if l.SHA256 != "" && !vmutil.ValidLibDigest(l.SHA256) {
+			return fmt.Errorf("remote library %s: invalid sha256 '%s', must be 64 lowercase hex characters", l.Name, l.SHA256)
+		}
+	}
+	return nil
+}
+
 func (a *App) verifyProcessors() error {
 	if err := checkProcessors("post", a.PostProcessors()); err != nil {
 		return err
 	}
+	if err := checkProcessors("stream", a.StreamProcessors()); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -751,6 +1500,15 @@ func (a *App) updateComponentTopLevelVars() {
 	}
 }
 
+func (a *App) updateComponentHelmCharts() {
+	for _, name := range a.inner.Spec.HelmCharts {
+		if comp, ok := a.allComponents[name]; ok {
+			comp.HelmChart = true
+			a.allComponents[name] = comp
+		}
+	}
+}
+
 // ClusterScopedLists returns the value of the qbec app attribute to determine if cluster scope
 // lists should be performed when multiple namespaces are present.
 func (a *App) ClusterScopedLists() bool {
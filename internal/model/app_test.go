@@ -36,6 +36,7 @@ import (
 	"github.com/splunk/qbec/internal/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func setPwd(t *testing.T, dir string) func() {
@@ -92,6 +93,72 @@ inner:
 	assert.Equal(t, strings.Trim(expected, "\n"), strings.Trim(string(b), "\n"))
 }
 
+func TestAppOutsideRoot(t *testing.T) {
+	app, err := NewApp("../../examples/test-app/qbec.yaml", nil, "")
+	require.Nil(t, err)
+	a := assert.New(t)
+	a.Equal(4, len(app.inner.Spec.Environments))
+	a.Equal(4, len(app.allComponents))
+	a.Equal(filepath.Join("..", "..", "examples", "test-app", "components", "service1.jsonnet"), app.allComponents["service1"].Files[0])
+	a.Equal([]string{filepath.Join("..", "..", "examples", "test-app", "pp.jsonnet")}, app.PostProcessors())
+	a.Equal([]string{filepath.Join("..", "..", "examples", "test-app", "lib")}, app.LibPaths())
+	a.Equal(filepath.Join("..", "..", "examples", "test-app", "params.libsonnet"), app.ParamsFile())
+	a.Equal("/abs/file", app.ResolvePath("/abs/file"))
+	a.Equal("https://example.com/file", app.ResolvePath("https://example.com/file"))
+	a.Equal("", app.ResolvePath(""))
+
+	reset := setPwd(t, "../../examples/test-app")
+	defer reset()
+	app, err = NewApp("qbec.yaml", nil, "")
+	require.Nil(t, err)
+	a.Equal("pp.jsonnet", app.ResolvePath("pp.jsonnet"))
+}
+
+func TestAppHelmCharts(t *testing.T) {
+	reset := setPwd(t, "testdata/helm-app")
+	defer reset()
+	app, err := NewApp("qbec.yaml", nil, "")
+	require.Nil(t, err)
+	comps, err := app.ComponentsForEnvironment("dev", nil, nil)
+	require.Nil(t, err)
+	a := assert.New(t)
+	require.Equal(t, 4, len(comps))
+	a.Equal("config", comps[0].Name)
+	a.False(comps[0].HelmChart)
+	a.Equal("legacy.helm", comps[1].Name)
+	a.False(comps[1].HelmChart)
+	a.Equal("redis", comps[2].Name)
+	a.Equal([]string{filepath.Join("components", "redis.yaml")}, comps[2].Files)
+	a.True(comps[2].HelmChart)
+	a.Equal("web", comps[3].Name)
+	a.True(comps[3].HelmChart)
+
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	a.Equal(filepath.Join(wd, "bin", "helm"), app.HelmCommand())
+}
+
+func TestParseHelmChart(t *testing.T) {
+	h, err := ParseHelmChart(map[string]interface{}{
+		"chart":   "redis",
+		"repo":    "https://charts.example.com",
+		"version": "1.2.3",
+		"values":  map[string]interface{}{"foo": "bar"},
+	})
+	require.Nil(t, err)
+	a := assert.New(t)
+	a.Equal(HelmChart{Chart: "redis", Repo: "https://charts.example.com", Version: "1.2.3", Values: map[string]interface{}{"foo": "bar"}}, h)
+
+	_, err = ParseHelmChart(map[string]interface{}{"repo": "https://charts.example.com"})
+	require.NotNil(t, err)
+	a.Contains(err.Error(), "invalid helm chart: chart not specified")
+	_, err = ParseHelmChart(map[string]interface{}{"chart": "redis", "valuez": map[string]interface{}{}})
+	require.NotNil(t, err)
+	a.Contains(err.Error(), `unknown field "valuez"`)
+	_, err = ParseHelmChart([]interface{}{})
+	require.NotNil(t, err)
+}
+
 func TestAppSimple(t *testing.T) {
 	reset := setPwd(t, "../../examples/test-app")
 	defer reset()
@@ -108,6 +175,7 @@ func TestAppSimple(t *testing.T) {
 	a.Contains(app.allComponents, "service2")
 	a.NotContains(app.defaultComponents, "service2")
 	a.Equal(false, app.AddComponentLabel())
+	a.Equal("helm", app.HelmCommand())
 
 	comps, err := app.ComponentsForEnvironment("_", nil, nil)
 	require.Nil(t, err)
@@ -146,6 +214,22 @@ func TestAppSimple(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 0, len(comps))
 
+	comps, err = app.ComponentsForEnvironment("prod", []string{"service*"}, nil)
+	require.Nil(t, err)
+	require.Equal(t, 2, len(comps))
+	a.Equal("service1", comps[0].Name)
+	a.Equal("service2", comps[1].Name)
+
+	comps, err = app.ComponentsForEnvironment("prod", nil, []string{"*-*"})
+	require.Nil(t, err)
+	require.Equal(t, 2, len(comps))
+	a.Equal("service1", comps[0].Name)
+	a.Equal("service2", comps[1].Name)
+
+	_, err = app.ComponentsForEnvironment("prod", []string{"frontend-*"}, nil)
+	require.NotNil(t, err)
+	a.Equal("specified components: bad component reference(s): frontend-*", err.Error())
+
 	a.EqualValues(map[string]interface{}{
 		"externalFoo": "bar",
 	}, app.DeclaredVars())
@@ -251,6 +335,56 @@ func TestAppSimple(t *testing.T) {
 	a.Equal(0, len(app.DataSourceExamples()))
 }
 
+func TestAppFromStdin(t *testing.T) {
+	b, err := ioutil.ReadFile("../../examples/test-app/qbec.yaml")
+	require.NoError(t, err)
+	content, err := ReadAppFile(StdinAppFile, bytes.NewReader(b))
+	require.NoError(t, err)
+	reset := setPwd(t, "../../examples/test-app")
+	defer reset()
+	app, err := NewAppFromContent("<stdin>", content, ".", nil, "")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal("example1", app.Name())
+	a.Equal(4, len(app.allComponents))
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	a.Equal(wd, app.root)
+}
+
+func TestAppEnvInterpolation(t *testing.T) {
+	reset := setPwd(t, "../../examples/test-app")
+	defer reset()
+	content := `apiVersion: qbec.io/v1alpha1
+kind: App
+metadata:
+  name: interpolated
+spec:
+  environments:
+    ci:
+      server: ${QBEC_TEST_CLUSTER_URL}
+      defaultNamespace: ${QBEC_TEST_NAMESPACE:-ci-ns}
+`
+	_, err := NewAppFromContent("qbec.yaml", []byte(content), ".", nil, "")
+	require.Error(t, err)
+	assert.Equal(t, "qbec.yaml: spec.environments.ci.server: environment variable QBEC_TEST_CLUSTER_URL is not set", err.Error())
+
+	t.Setenv("QBEC_TEST_CLUSTER_URL", "https://ci-server")
+	app, err := NewAppFromContent("qbec.yaml", []byte(content), ".", nil, "")
+	require.NoError(t, err)
+	a := assert.New(t)
+	url, err := app.ServerURL("ci")
+	require.NoError(t, err)
+	a.Equal("https://ci-server", url)
+	a.Equal("ci-ns", app.DefaultNamespace("ci"))
+}
+
+func TestAppFromContentNegative(t *testing.T) {
+	_, err := NewAppFromContent("<stdin>", []byte("apiVersion: qbec.io/v1alpha1\nkind: App\n"), ".", nil, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "file: <stdin>")
+}
+
 func TestAppDataSources(t *testing.T) {
 	reset := setPwd(t, "../../examples/external-data-app")
 	defer reset()
@@ -299,6 +433,136 @@ func TestAppWarnings(t *testing.T) {
 	a.EqualValues(map[string]interface{}{}, app.BaseProperties())
 }
 
+func TestAppMultipleContexts(t *testing.T) {
+	reset := setPwd(t, "testdata/multi-context-app")
+	defer reset()
+	app, err := NewApp("qbec.yaml", nil, "")
+	require.NoError(t, err)
+	a := assert.New(t)
+	contexts, err := app.Contexts("prod")
+	require.NoError(t, err)
+	a.EqualValues([]string{"primary", "dr"}, contexts)
+	ctx, err := app.Context("prod")
+	require.NoError(t, err)
+	a.Equal("primary", ctx)
+	server, err := app.ServerURL("prod")
+	require.NoError(t, err)
+	a.Equal("", server)
+	_, err = app.Contexts("dev")
+	require.Error(t, err)
+}
+
+func TestAppEnvExtends(t *testing.T) {
+	reset := setPwd(t, "testdata/extends-app")
+	defer reset()
+	app, err := NewApp("qbec.yaml", nil, "")
+	require.NoError(t, err)
+	a := assert.New(t)
+
+	a.Equal("apps", app.DefaultNamespace("prod-us"))
+	server, err := app.ServerURL("prod-us")
+	require.NoError(t, err)
+	a.Equal("https://prod-server", server)
+	vf, err := app.VarsFile("prod-us")
+	require.NoError(t, err)
+	a.Equal("prod-vars.libsonnet", vf)
+	props, err := app.Properties("prod-us")
+	require.NoError(t, err)
+	a.EqualValues(map[string]interface{}{
+		"replicas": float64(3),
+		"region":   map[string]interface{}{"name": "us", "zone": "a"},
+	}, props)
+
+	server, err = app.ServerURL("prod-eu-canary")
+	require.NoError(t, err)
+	a.Equal("", server)
+	ctx, err := app.Context("prod-eu-canary")
+	require.NoError(t, err)
+	a.Equal("prod-eu", ctx)
+	a.Equal("apps-eu", app.DefaultNamespace("prod-eu-canary"))
+	props, err = app.Properties("prod-eu-canary")
+	require.NoError(t, err)
+	a.Equal(float64(1), props["replicas"])
+	a.Equal("prod-eu", app.Environments()["prod-eu-canary"].Extends)
+}
+
+func TestAppYAMLAnchors(t *testing.T) {
+	reset := setPwd(t, "testdata/extends-app")
+	defer reset()
+	content := `---
+apiVersion: qbec.io/v1alpha1
+kind: App
+metadata:
+  name: anchors
+spec:
+  x-prod: &prod
+    server: https://prod-server
+    defaultNamespace: apps
+  environments:
+    prod-us:
+      <<: *prod
+    prod-eu:
+      <<: *prod
+      defaultNamespace: apps-eu
+`
+	app, err := NewAppFromContent("qbec.yaml", []byte(content), ".", nil, "")
+	require.NoError(t, err)
+	a := assert.New(t)
+	a.Equal(2, len(app.Environments()))
+	server, err := app.ServerURL("prod-eu")
+	require.NoError(t, err)
+	a.Equal("https://prod-server", server)
+	a.Equal("apps", app.DefaultNamespace("prod-us"))
+	a.Equal("apps-eu", app.DefaultNamespace("prod-eu"))
+}
+
+func TestAppKindOrders(t *testing.T) {
+	a := assert.New(t)
+	app := &App{}
+	a.Nil(app.ApplyOrder())
+	a.Nil(app.DeleteOrder())
+
+	app.inner.Spec.ApplyOrder = []KindOrder{
+		{Group: "cert-manager.io", Kind: "Certificate", Order: 90},
+		{Kind: "ConfigMap", Order: 40},
+	}
+	expected := map[schema.GroupKind]int{
+		{Group: "cert-manager.io", Kind: "Certificate"}: 90,
+		{Kind: "ConfigMap"}:                             40,
+	}
+	a.EqualValues(expected, app.ApplyOrder())
+	a.EqualValues(expected, app.DeleteOrder())
+
+	app.inner.Spec.DeleteOrder = []KindOrder{{Group: "cert-manager.io", Kind: "Certificate", Order: 150}}
+	a.EqualValues(expected, app.ApplyOrder())
+	a.EqualValues(map[schema.GroupKind]int{{Group: "cert-manager.io", Kind: "Certificate"}: 150}, app.DeleteOrder())
+}
+
+func TestMatchKind(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		gk       schema.GroupKind
+		expected bool
+	}{
+		{pattern: "Deployment", gk: schema.GroupKind{Group: "apps", Kind: "Deployment"}, expected: true},
+		{pattern: "*Set", gk: schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, expected: true},
+		{pattern: "*Set", gk: schema.GroupKind{Group: "apps", Kind: "Deployment"}},
+		{pattern: "apps/*", gk: schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, expected: true},
+		{pattern: "apps/*", gk: schema.GroupKind{Group: "batch", Kind: "Job"}},
+		{pattern: "/Pod", gk: schema.GroupKind{Kind: "Pod"}, expected: true},
+		{pattern: "/Pod", gk: schema.GroupKind{Group: "metrics.k8s.io", Kind: "Pod"}},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern+"-"+test.gk.String(), func(t *testing.T) {
+			ok, err := MatchKind(test.pattern, test.gk)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, ok)
+		})
+	}
+	_, err := MatchKind("apps/[", schema.GroupKind{Group: "batch", Kind: "Job"})
+	require.Error(t, err)
+}
+
 func TestAppComponentLoadSubdirs(t *testing.T) {
 	reset := setPwd(t, "testdata/subdir-app")
 	defer reset()
@@ -334,10 +598,42 @@ func TestAppComponentLoadMultidirs(t *testing.T) {
 	a.Equal("a", comp.Name)
 	a.Equal(1, len(comp.Files))
 	a.Contains(comp.Files, filepath.Join("components", "dir1", "a.jsonnet"))
+	a.EqualValues(map[string]string{"tier": "frontend", "team": "web"}, comp.Labels)
 	comp = comps[1]
 	a.Equal("b", comp.Name)
 	a.Equal(1, len(comp.Files))
 	a.Contains(comp.Files, filepath.Join("components", "dir2", "b", "index.jsonnet"))
+	a.EqualValues(map[string]string{"tier": "backend"}, comp.Labels)
+}
+
+func TestAppComponentLoadMultipleRoots(t *testing.T) {
+	reset := setPwd(t, "testdata/multi-root-app")
+	defer reset()
+	app, err := NewApp("qbec.yaml", nil, "")
+	require.Nil(t, err)
+	comps, err := app.ComponentsForEnvironment("dev", nil, nil)
+	require.Nil(t, err)
+	a := assert.New(t)
+	require.Equal(t, 2, len(comps))
+	a.Equal("app", comps[0].Name)
+	a.Equal([]string{filepath.Join("components", "app.jsonnet")}, comps[0].Files)
+	a.Equal("common", comps[1].Name)
+	a.Equal([]string{filepath.Join("shared", "base", "common.yaml")}, comps[1].Files)
+}
+
+func TestAppComponentLabelsNegative(t *testing.T) {
+	dir, err := ioutil.TempDir("", "meta")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "a.meta.yaml")
+	require.NoError(t, ioutil.WriteFile(file, []byte("labels: [foo]\n"), 0644))
+	_, err = loadComponentLabels(file)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unmarshal component metadata "+file)
+
+	labels, err := loadComponentLabels(filepath.Join(dir, "b.meta.yaml"))
+	require.NoError(t, err)
+	assert.Nil(t, labels)
 }
 
 func TestAppComponentNoDirs(t *testing.T) {
@@ -372,6 +668,18 @@ func TestAppComponentLoadNegative(t *testing.T) {
 	a.Equal(`cannot include as well as exclude components, specify one or the other`, err.Error())
 }
 
+func TestAppEnvironmentFiles(t *testing.T) {
+	reset := setPwd(t, "./testdata/bad-app")
+	defer reset()
+	app, err := NewApp("app-env-files.yaml", nil, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dev-server2", app.inner.Spec.Environments["dev"].Server)
+
+	app, err = NewApp("app-env-files.yaml", []string{"envs/override-dev.yaml"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "https://dev-server", app.inner.Spec.Environments["dev"].Server)
+}
+
 func TestHttpEnvFiles(t *testing.T) {
 	reset := setPwd(t, "testdata/http-app")
 	defer reset()
@@ -444,6 +752,13 @@ func TestAppNegative(t *testing.T) {
 				assert.Contains(t, err.Error(), "dev inclusions: bad component reference(s): d")
 			},
 		},
+		{
+			file: "bad-helm-charts.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "helm charts: bad component reference(s): d")
+				assert.Contains(t, err.Error(), "helm chart a must be defined by a single .jsonnet or .yaml file")
+			},
+		},
 		{
 			file: "bad-env-include-exclude.yaml",
 			asserter: func(t *testing.T, err error) {
@@ -462,6 +777,25 @@ func TestAppNegative(t *testing.T) {
 				assert.Contains(t, err.Error(), fmt.Sprintf("duplicate component a, found %s and %s", filepath.FromSlash("bad-comps/a.json"), filepath.FromSlash("bad-comps/a.yaml")))
 			},
 		},
+		{
+			file: "bad-comps-roots.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), fmt.Sprintf("duplicate component a, found %s in components root components and %s in components root bad-comps",
+					filepath.FromSlash("components/a.json"), filepath.FromSlash("bad-comps/a.json")))
+			},
+		},
+		{
+			file: "bad-comps-dirs.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "bad-comps-dirs.yaml: componentsDir and componentsDirs cannot both be specified")
+			},
+		},
+		{
+			file: "bad-comps-missing-root.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "no component directories found after expanding no-such-dir")
+			},
+		},
 		{
 			file: "bad-app-name.yaml",
 			asserter: func(t *testing.T, err error) {
@@ -474,6 +808,12 @@ func TestAppNegative(t *testing.T) {
 				assert.Contains(t, err.Error(), "invalid environment foo/bar, must match")
 			},
 		},
+		{
+			file: "bad-gc-tag.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "bad-gc-tag.yaml: invalid GC tag 'shared/tag', must match")
+			},
+		},
 		{
 			file: "bad-dup-tla.yaml",
 			asserter: func(t *testing.T, err error) {
@@ -506,6 +846,24 @@ func TestAppNegative(t *testing.T) {
 				assert.True(t, errors.Is(err, fs.ErrNotExist))
 			},
 		},
+		{
+			file: "bad-env-files-dup.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Regexp(t, `duplicate definition for environment 'dev' in files .*dev2\.yaml and .*envs/override-dev\.yaml`, err.Error())
+			},
+		},
+		{
+			file: "bad-env-files-inline.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Regexp(t, `duplicate definition for environment 'dev' in files bad-env-files-inline\.yaml and .*dev2\.yaml`, err.Error())
+			},
+		},
+		{
+			file: "bad-env-files-env-file.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Regexp(t, `duplicate definition for environment 'dev' in files .*dev2\.yaml and .*envs/override-dev\.yaml`, err.Error())
+			},
+		},
 		{
 			file: "bad-malformed-env-file.yaml",
 			asserter: func(t *testing.T, err error) {
@@ -545,12 +903,108 @@ func TestAppNegative(t *testing.T) {
 				assert.Contains(t, err.Error(), "verify environment foo: context for environment ('__current__') may not start with __")
 			},
 		},
+		{
+			file: "bad-env-config4.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "verify environment foo: contexts may not be set together with server or context")
+			},
+		},
+		{
+			file: "bad-env-config5.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "verify environment foo: duplicate context 'primary'")
+			},
+		},
+		{
+			file: "bad-env-config6.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "verify environment foo: clientCertFile and clientKeyFile must be specified together")
+			},
+		},
+		{
+			file: "bad-env-extends-cycle.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "environment dev: cyclic extends chain dev -> stage -> qa -> dev")
+			},
+		},
+		{
+			file: "bad-env-extends-unknown.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "environment stage extends unknown environment staging")
+			},
+		},
 		{
 			file: "bad-dup-postproc.yaml",
 			asserter: func(t *testing.T, err error) {
 				assert.Contains(t, err.Error(), "invalid post-processor 'lib2/foo.jsonnet', has the same base name as 'lib/foo.jsonnet'")
 			},
 		},
+		{
+			file: "bad-dup-streamproc.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "invalid stream-processor 'lib2/foo.jsonnet', has the same base name as 'lib/foo.jsonnet'")
+			},
+		},
+		{
+			file: "bad-dup-applyorder.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "duplicate apply order for kind 'Certificate.cert-manager.io'")
+			},
+		},
+		{
+			file: "bad-dup-pullsecret.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "duplicate image pull secret 'registry-creds'")
+			},
+		},
+		{
+			file: "bad-kind-labels-reserved.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "kind labels: label 'qbec.io/cost-center' uses the reserved prefix qbec.io/")
+			},
+		},
+		{
+			file: "bad-kind-labels-pattern.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "kind labels: invalid kind pattern 'apps/[Dep', syntax error in pattern")
+			},
+		},
+		{
+			file: "bad-kind-labels-value.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "kind labels: invalid value 'not valid' for label cost-center")
+			},
+		},
+		{
+			file: "bad-remote-lib-dup.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "duplicate remote library 'common'")
+			},
+		},
+		{
+			file: "bad-remote-lib-unpinned.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "remote library common: sha256 is required for https URLs")
+			},
+		},
+		{
+			file: "bad-remote-lib-oci-unpinned.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "remote library common: sha256 is required for oci URLs that reference a tag, or reference the manifest by digest")
+			},
+		},
+		{
+			file: "bad-remote-lib-oci-digest.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "remote library common: invalid manifest digest 'sha256:abc', must be 64 lowercase hex characters")
+			},
+		},
+		{
+			file: "bad-remote-lib-scheme.yaml",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "remote library common: unsupported URL scheme 'ftp', must be one of http, https or oci")
+			},
+		},
 		{
 			file: "bad-computed.yaml",
 			asserter: func(t *testing.T, err error) {
@@ -607,4 +1061,45 @@ func TestAppLabel(t *testing.T) {
 	require.Nil(t, err)
 	a := assert.New(t)
 	a.Equal(true, app.AddComponentLabel())
+	a.Equal("shared", app.GCTag())
+	require.NoError(t, app.SetGCTag("override"))
+	a.Equal("override", app.GCTag())
+	err = app.SetGCTag("bad/tag")
+	require.Error(t, err)
+	a.Contains(err.Error(), "invalid GC tag 'bad/tag', must match")
+}
+
+func TestValidateAppContent(t *testing.T) {
+	reset := setPwd(t, "./testdata/bad-app")
+	defer reset()
+	b, err := ioutil.ReadFile("bad-many-problems.yaml")
+	require.NoError(t, err)
+	envFile, err := filepath.Abs("dev2.yaml")
+	require.NoError(t, err)
+	errs := ValidateAppContent("bad-many-problems.yaml", b, ".", nil)
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	assert.EqualValues(t, []string{
+		"bad-many-problems.yaml: duplicate definition for environment 'dev'",
+		fmt.Sprintf("duplicate definition for environment 'dev' in file %s (previous: inline)", envFile),
+		"verify environment prod: neither server nor context was set",
+		"environment stage: invalid default namespace 'Bad_Namespace', a lowercase RFC 1123 label must consist of lower case alphanumeric characters or '-', and must start and end with an alphanumeric character (e.g. 'my-name',  or '123-abc', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?')",
+		"load components: no component directories found after expanding no-such-dir",
+		"load components: no component directories found after expanding missing-*",
+		"duplicate image pull secret 'creds'",
+	}, msgs)
+
+	_, err = NewAppFromContent("bad-many-problems.yaml", b, ".", nil, "")
+	require.Error(t, err)
+	assert.Equal(t, "verify environment prod: neither server nor context was set", err.Error())
+}
+
+func TestValidateAppContentValid(t *testing.T) {
+	reset := setPwd(t, "../../examples/test-app")
+	defer reset()
+	b, err := ioutil.ReadFile("qbec.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, ValidateAppContent("qbec.yaml", b, ".", nil))
 }
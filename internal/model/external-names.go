@@ -28,16 +28,26 @@ type Directives struct {
 	DeletePolicy string // delete policy "default" | "never"
 	UpdatePolicy string // update policy "default" | "never"
 	WaitPolicy   string // wait policy "default" | "never"
+	PatchTarget  string // patch target "true" to merge the object into an object from another component
+	WaitFor      string // wait condition "condition=<type>" to wait for a status condition to be true
+	Namespace    string // namespace to use for an object that does not set one, instead of the environment default
+	GCKey        string // stable identity that correlates objects with different names for garbage collection
+	GCKeyHistory string // number of previous versions of an object with a GC key that are not garbage collected
+	ApplyWave    string // integer wave, objects of a wave are applied (and waited for) before those of later waves
+	NoPrune      string // no-prune "true" to never garbage collect the object, explicit deletes are not affected
 }
 
 // QbecNames is the set of names used by Qbec.
 var QbecNames = struct {
 	ApplicationLabel    string // the label to use for tagging an object with an application name
 	TagLabel            string // the label to use for tagging an object with a scoped GC tag
+	GCTagLabel          string // the label to use for tagging an object with a GC tag that replaces the app name for GC
 	ComponentAnnotation string // the label to use for tagging an object with a component
 	ComponentLabel      string // the label to use for tagging an object with a component
 	EnvironmentLabel    string // the label to use for tagging an object with an annotation
 	PristineAnnotation  string // the annotation to use for storing the pristine object
+	AppliedByAnnotation string // the annotation that records the user who last created or updated an object
+	AppliedAtAnnotation string // the annotation that records the time at which an object was last created or updated
 	EnvVarName          string // the name of the external variable that has the environment name
 	EnvPropsVarName     string // the name of the external variable that has the environment properties object
 	TagVarName          string // the name of the external variable that has the tag name
@@ -47,10 +57,13 @@ var QbecNames = struct {
 }{
 	ApplicationLabel:    QBECMetadataPrefix + "application",
 	TagLabel:            QBECMetadataPrefix + "tag",
+	GCTagLabel:          QBECMetadataPrefix + "gc-tag",
 	ComponentAnnotation: QBECMetadataPrefix + "component",
 	ComponentLabel:      QBECMetadataPrefix + "component",
 	EnvironmentLabel:    QBECMetadataPrefix + "environment",
 	PristineAnnotation:  QBECMetadataPrefix + "last-applied",
+	AppliedByAnnotation: QBECMetadataPrefix + "last-applied-by",
+	AppliedAtAnnotation: QBECMetadataPrefix + "last-applied-time",
 	EnvVarName:          QBECMetadataPrefix + "env",
 	EnvPropsVarName:     QBECMetadataPrefix + "envProperties",
 	TagVarName:          QBECMetadataPrefix + "tag",
@@ -61,5 +74,12 @@ var QbecNames = struct {
 		DeletePolicy: QBECDirectivesNamespace + "delete-policy",
 		UpdatePolicy: QBECDirectivesNamespace + "update-policy",
 		WaitPolicy:   QBECDirectivesNamespace + "wait-policy",
+		PatchTarget:  QBECDirectivesNamespace + "patch-target",
+		WaitFor:      QBECDirectivesNamespace + "wait-for",
+		Namespace:    QBECDirectivesNamespace + "namespace",
+		GCKey:        QBECDirectivesNamespace + "gc-key",
+		GCKeyHistory: QBECDirectivesNamespace + "gc-key-history",
+		ApplyWave:    QBECDirectivesNamespace + "apply-wave",
+		NoPrune:      QBECDirectivesNamespace + "no-prune",
 	},
 }
@@ -18,6 +18,7 @@ package model
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"k8s.io/gengo/namer"
@@ -33,26 +34,78 @@ type Filter interface {
 type aliasFn func(string) []string
 
 type baseFilter struct {
-	includes map[string]bool
-	excludes map[string]bool
-	aliasFn  aliasFn
+	includes     map[string]bool
+	excludes     map[string]bool
+	includeGlobs []string
+	excludeGlobs []string
+	aliasFn      aliasFn
 }
 
 func (b *baseFilter) HasFilters() bool {
-	return len(b.includes) > 0 || len(b.excludes) > 0
+	return len(b.includes) > 0 || len(b.excludes) > 0 || len(b.includeGlobs) > 0 || len(b.excludeGlobs) > 0
 }
 
 func (b *baseFilter) ShouldInclude(s string) bool {
+	matchesAny := func(globs []string, name string) bool {
+		for _, g := range globs {
+			if ok, _ := path.Match(g, name); ok {
+				return true
+			}
+		}
+		return false
+	}
 	check := b.aliasFn(s)
 	for _, name := range check {
-		if b.includes[name] {
+		if b.includes[name] || matchesAny(b.includeGlobs, name) {
 			return true
 		}
-		if b.excludes[name] {
+		if b.excludes[name] || matchesAny(b.excludeGlobs, name) {
 			return false
 		}
 	}
-	return len(b.includes) == 0
+	return len(b.includes) == 0 && len(b.includeGlobs) == 0
+}
+
+// enableGlobs moves includes and excludes that are glob patterns out of the exact match sets such that
+// they are matched using path.Match semantics.
+func (b *baseFilter) enableGlobs() error {
+	extract := func(m map[string]bool) ([]string, error) {
+		var ret []string
+		for k := range m {
+			if !isGlob(k) {
+				continue
+			}
+			if _, err := path.Match(k, ""); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %v", k, err)
+			}
+			ret = append(ret, k)
+			delete(m, k)
+		}
+		return ret, nil
+	}
+	var err error
+	if b.includeGlobs, err = extract(b.includes); err != nil {
+		return err
+	}
+	if b.excludeGlobs, err = extract(b.excludes); err != nil {
+		return err
+	}
+	return nil
+}
+
+// isGlob returns true if the supplied string has glob metacharacters.
+func isGlob(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// matchName returns true if the supplied name matches the pattern, which is either a glob pattern
+// or an exact name.
+func matchName(pattern, name string) bool {
+	if !isGlob(pattern) {
+		return pattern == name
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
 }
 
 func newBaseFilter(pluralKind string, includes, excludes []string, fn aliasFn) (*baseFilter, error) {
@@ -76,9 +129,17 @@ func newBaseFilter(pluralKind string, includes, excludes []string, fn aliasFn) (
 	}, nil
 }
 
-// NewComponentFilter returns a filter for component names.
+// NewComponentFilter returns a filter for component names. Includes and excludes that have glob
+// metacharacters are matched as patterns, all others are matched exactly.
 func NewComponentFilter(includes, excludes []string) (Filter, error) {
-	return newStringFilter("components", includes, excludes)
+	nf, err := newBaseFilter("components", includes, excludes, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := nf.enableGlobs(); err != nil {
+		return nil, err
+	}
+	return nf, nil
 }
 
 // newStringFilter returns a filter for exact string matches.
@@ -112,3 +173,77 @@ func newKindFilter(includes, excludes []string) (Filter, error) {
 	}
 	return bf, nil
 }
+
+// selectorTerm is a single requirement of an annotation selector.
+type selectorTerm struct {
+	key      string
+	value    string
+	hasValue bool // true if value comparison is required
+	negate   bool // true for non-existence or inequality
+}
+
+func (t selectorTerm) matches(m map[string]string) bool {
+	v, ok := m[t.key]
+	if !t.hasValue {
+		return ok != t.negate
+	}
+	return (ok && v == t.value) != t.negate
+}
+
+// AnnotationSelector selects objects based on their annotations.
+type AnnotationSelector struct {
+	terms []selectorTerm
+}
+
+// HasFilters returns true if the selector has at least one requirement.
+func (a *AnnotationSelector) HasFilters() bool {
+	return a != nil && len(a.terms) > 0
+}
+
+// Matches returns true if the supplied annotations satisfy all requirements of the selector.
+func (a *AnnotationSelector) Matches(annotations map[string]string) bool {
+	if a == nil {
+		return true
+	}
+	for _, t := range a.terms {
+		if !t.matches(annotations) {
+			return false
+		}
+	}
+	return true
+}
+
+// NewAnnotationSelector parses a comma-separated list of requirements of the form `key`, `!key`, `key=value`,
+// `key==value` or `key!=value` into an annotation selector. All requirements must be met for an object to match.
+func NewAnnotationSelector(s string) (*AnnotationSelector, error) {
+	ret := &AnnotationSelector{}
+	if strings.TrimSpace(s) == "" {
+		return ret, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		var t selectorTerm
+		switch {
+		case strings.Contains(part, "!="):
+			pos := strings.Index(part, "!=")
+			t = selectorTerm{key: part[:pos], value: part[pos+2:], hasValue: true, negate: true}
+		case strings.Contains(part, "=="):
+			pos := strings.Index(part, "==")
+			t = selectorTerm{key: part[:pos], value: part[pos+2:], hasValue: true}
+		case strings.Contains(part, "="):
+			pos := strings.Index(part, "=")
+			t = selectorTerm{key: part[:pos], value: part[pos+1:], hasValue: true}
+		case strings.HasPrefix(part, "!"):
+			t = selectorTerm{key: part[1:], negate: true}
+		default:
+			t = selectorTerm{key: part}
+		}
+		t.key = strings.TrimSpace(t.key)
+		t.value = strings.TrimSpace(t.value)
+		if t.key == "" || strings.ContainsAny(t.key, "!= ") {
+			return nil, fmt.Errorf("invalid annotation selector %q, bad requirement %q", s, part)
+		}
+		ret.terms = append(ret.terms, t)
+	}
+	return ret, nil
+}
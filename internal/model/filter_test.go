@@ -59,6 +59,30 @@ func TestComponentFilterBad(t *testing.T) {
 	require.Equal(t, "cannot include as well as exclude components, specify one or the other", err.Error())
 }
 
+func TestComponentFilterGlobs(t *testing.T) {
+	filter, err := NewComponentFilter([]string{"frontend-*", "db"}, nil)
+	require.Nil(t, err)
+	a := assert.New(t)
+	a.True(filter.HasFilters())
+	a.True(filter.ShouldInclude("frontend-web"))
+	a.True(filter.ShouldInclude("db"))
+	a.False(filter.ShouldInclude("frontend"))
+	a.False(filter.ShouldInclude("db2"))
+
+	filter, err = NewComponentFilter(nil, []string{"svc?"})
+	require.Nil(t, err)
+	a.True(filter.HasFilters())
+	a.False(filter.ShouldInclude("svc1"))
+	a.True(filter.ShouldInclude("svc10"))
+	a.True(filter.ShouldInclude("svc?1"))
+}
+
+func TestComponentFilterBadGlob(t *testing.T) {
+	_, err := NewComponentFilter([]string{"foo-[a"}, nil)
+	require.NotNil(t, err)
+	require.Equal(t, `invalid pattern "foo-[a": syntax error in pattern`, err.Error())
+}
+
 func TestKindFilterIncludes(t *testing.T) {
 	filter, err := newKindFilter([]string{"foo", "icy"}, []string{})
 	require.Nil(t, err)
@@ -120,3 +144,44 @@ func TestKindFilterBad(t *testing.T) {
 	require.NotNil(t, err)
 	require.Equal(t, "cannot include as well as exclude kinds, specify one or the other", err.Error())
 }
+
+func TestAnnotationSelector(t *testing.T) {
+	anns := map[string]string{
+		"deploy.example.com/generation": "42",
+		"team":                          "core",
+	}
+	tests := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"team", true},
+		{"owner", false},
+		{"!owner", true},
+		{"!team", false},
+		{"deploy.example.com/generation=42", true},
+		{"deploy.example.com/generation==42", true},
+		{"deploy.example.com/generation=41", false},
+		{"deploy.example.com/generation!=41", true},
+		{"deploy.example.com/generation!=42", false},
+		{"owner!=bob", true},
+		{"team=core, deploy.example.com/generation=42", true},
+		{"team=core,owner", false},
+	}
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			s, err := NewAnnotationSelector(test.selector)
+			require.NoError(t, err)
+			assert.Equal(t, test.selector != "", s.HasFilters())
+			assert.Equal(t, test.match, s.Matches(anns))
+		})
+	}
+}
+
+func TestAnnotationSelectorBad(t *testing.T) {
+	for _, s := range []string{"=foo", "a,,b", "!", "a b=c"} {
+		_, err := NewAnnotationSelector(s)
+		require.Error(t, err, s)
+		assert.Contains(t, err.Error(), "invalid annotation selector")
+	}
+}
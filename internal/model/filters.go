@@ -37,12 +37,15 @@ type Filters struct {
 	kindFilter            Filter
 	componentFilter       Filter
 	namespaceFilter       Filter
+	annotationSelector    *AnnotationSelector
+	onlyNamespace         string
 }
 
 // NewFilters sets up options in the supplied flags and returns a function to return filters.
 func NewFilters(flags *pflag.FlagSet, includeAllFilters bool) func() (Filters, error) {
 	var includes, excludes, kindIncludes, kindExcludes, nsIncludes, nsExcludes []string
 	var includeClusterScopedObjects bool
+	var annotationSelector, onlyNamespace string
 
 	flags.StringArrayVarP(&includes, "component", "c", nil, "include just this component")
 	flags.StringArrayVarP(&excludes, "exclude-component", "C", nil, "exclude this component")
@@ -52,8 +55,20 @@ func NewFilters(flags *pflag.FlagSet, includeAllFilters bool) func() (Filters, e
 		flags.StringArrayVarP(&nsIncludes, "include-namespace", "p", nil, "include objects with this namespace")
 		flags.StringArrayVarP(&nsExcludes, "exclude-namespace", "P", nil, "exclude objects with this namespace")
 		flags.BoolVar(&includeClusterScopedObjects, "include-cluster-objects", true, "include cluster scoped objects, false by default when namespace filters present")
+		flags.StringVar(&annotationSelector, "annotation-selector", "", "include objects with annotations matching this selector, e.g. 'key=value,other,!excluded'")
+		flags.StringVar(&onlyNamespace, "only-namespace", "", "only include namespaced objects in this namespace, and scope garbage collection to it")
 	}
 	return func() (Filters, error) {
+		namespaces := nsIncludes
+		if onlyNamespace != "" {
+			if len(nsIncludes) > 0 || len(nsExcludes) > 0 {
+				return Filters{}, fmt.Errorf("--only-namespace cannot be used together with namespace include or exclude filters")
+			}
+			if flags.Changed("include-cluster-objects") && includeClusterScopedObjects {
+				return Filters{}, fmt.Errorf("--only-namespace cannot be used together with --include-cluster-objects")
+			}
+			namespaces = []string{onlyNamespace}
+		}
 		of, err := newKindFilter(kindIncludes, kindExcludes)
 		if err != nil {
 			return Filters{}, err
@@ -62,7 +77,11 @@ func NewFilters(flags *pflag.FlagSet, includeAllFilters bool) func() (Filters, e
 		if err != nil {
 			return Filters{}, err
 		}
-		nf, err := newStringFilter("namespaces", nsIncludes, nsExcludes)
+		nf, err := newStringFilter("namespaces", namespaces, nsExcludes)
+		if err != nil {
+			return Filters{}, err
+		}
+		as, err := NewAnnotationSelector(annotationSelector)
 		if err != nil {
 			return Filters{}, err
 		}
@@ -78,10 +97,41 @@ func NewFilters(flags *pflag.FlagSet, includeAllFilters bool) func() (Filters, e
 			componentFilter:       cf,
 			namespaceFilter:       nf,
 			excludeClusterObjects: !includeClusterScopedObjects,
+			annotationSelector:    as,
+			onlyNamespace:         onlyNamespace,
 		}, nil
 	}
 }
 
+// NewComponentKindFilters returns filters that only select components and object kinds, for callers that do not
+// set up filters from command line flags. Component names may be glob patterns.
+func NewComponentKindFilters(includes, excludes, kindIncludes, kindExcludes []string) (Filters, error) {
+	of, err := newKindFilter(kindIncludes, kindExcludes)
+	if err != nil {
+		return Filters{}, err
+	}
+	cf, err := NewComponentFilter(includes, excludes)
+	if err != nil {
+		return Filters{}, err
+	}
+	as, err := NewAnnotationSelector("")
+	if err != nil {
+		return Filters{}, err
+	}
+	return Filters{
+		includes:           includes,
+		excludes:           excludes,
+		kindFilter:         of,
+		componentFilter:    cf,
+		annotationSelector: as,
+	}, nil
+}
+
+// OnlyNamespace returns the single namespace to which objects and garbage collection are restricted, if any.
+func (f Filters) OnlyNamespace() string {
+	return f.onlyNamespace
+}
+
 // ComponentIncludes returns the components requested to be included
 func (f Filters) ComponentIncludes() []string {
 	return f.includes
@@ -114,6 +164,9 @@ func (f Filters) Match(o K8sQbecMeta, client Namespaced, defaultNS string) (bool
 	if f.componentFilter != nil && !f.componentFilter.ShouldInclude(o.Component()) {
 		return false, nil
 	}
+	if !f.annotationSelector.Matches(o.GetAnnotations()) {
+		return false, nil
+	}
 	if !f.HasNamespaceFilters() {
 		return true, nil
 	}
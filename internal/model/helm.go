@@ -0,0 +1,80 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// isHelmChartSource returns true if the supplied component files can define a helm chart, which must be a single
+// YAML document or the output of a single jsonnet file.
+func isHelmChartSource(files []string) bool {
+	if len(files) != 1 {
+		return false
+	}
+	return strings.HasSuffix(files[0], ".jsonnet") || strings.HasSuffix(files[0], ".yaml")
+}
+
+// HelmChart is the definition of a helm chart component, one that is listed in the helmCharts attribute of
+// the app. It is the contents of the YAML file or the output of the jsonnet file of the component. The objects
+// of the component are produced by helm template.
+type HelmChart struct {
+	// path to a chart directory or archive relative to the component file, a chart reference for a repository that
+	// has been added to helm, an oci:// URL or, when repo is set, the name of a chart in that repository
+	Chart string `json:"chart"`
+	// URL of the chart repository
+	Repo string `json:"repo,omitempty"`
+	// version constraint for a chart that is not local
+	Version string `json:"version,omitempty"`
+	// release name, defaults to the component name
+	ReleaseName string `json:"releaseName,omitempty"`
+	// release namespace, defaults to the default namespace of the environment
+	Namespace string `json:"namespace,omitempty"`
+	// Kubernetes version used for capabilities when rendering the chart
+	KubeVersion string `json:"kubeVersion,omitempty"`
+	// values for the chart
+	Values map[string]interface{} `json:"values,omitempty"`
+}
+
+func (h HelmChart) assertValid() error {
+	if h.Chart == "" {
+		return fmt.Errorf("chart not specified")
+	}
+	return nil
+}
+
+// ParseHelmChart returns the helm chart defined by the supplied data, the parsed contents of the chart file
+// of a component.
+func ParseHelmChart(data interface{}) (HelmChart, error) {
+	var h HelmChart
+	b, err := json.Marshal(data)
+	if err != nil {
+		return h, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&h); err != nil {
+		return h, fmt.Errorf("invalid helm chart: %v", err)
+	}
+	if err := h.assertValid(); err != nil {
+		return h, fmt.Errorf("invalid helm chart: %v", err)
+	}
+	return h, nil
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	v3yaml "gopkg.in/yaml.v3"
+)
+
+// interpolateEnvVars replaces references of the form ${VAR} or ${VAR:-default} in the string values of the supplied
+// YAML document with the values of environment variables returned by the lookup function. The default is used when
+// the variable is not set or empty, and $${ produces a literal ${. Mapping keys are never interpolated. The original
+// bytes are returned when the document has no references.
+func interpolateEnvVars(file string, b []byte, lookup func(string) (string, bool)) ([]byte, error) {
+	if !bytes.Contains(b, []byte("${")) {
+		return b, nil
+	}
+	var doc v3yaml.Node
+	if err := v3yaml.Unmarshal(b, &doc); err != nil {
+		return b, nil // let the regular parser report the error
+	}
+	changed := false
+	var walk func(n *v3yaml.Node, path string) error
+	walk = func(n *v3yaml.Node, path string) error {
+		switch n.Kind {
+		case v3yaml.DocumentNode:
+			for _, c := range n.Content {
+				if err := walk(c, path); err != nil {
+					return err
+				}
+			}
+		case v3yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				p := n.Content[i].Value
+				if path != "" {
+					p = path + "." + p
+				}
+				if err := walk(n.Content[i+1], p); err != nil {
+					return err
+				}
+			}
+		case v3yaml.SequenceNode:
+			for i, c := range n.Content {
+				if err := walk(c, path+"["+strconv.Itoa(i)+"]"); err != nil {
+					return err
+				}
+			}
+		case v3yaml.ScalarNode:
+			if n.Tag != "!!str" || !strings.Contains(n.Value, "${") {
+				return nil
+			}
+			s, err := expandEnvRefs(n.Value, lookup)
+			if err != nil {
+				return fmt.Errorf("%s: %s: %v", file, path, err)
+			}
+			if s != n.Value {
+				n.Value = s
+				changed = true
+			}
+		}
+		return nil
+	}
+	if err := walk(&doc, ""); err != nil {
+		return nil, err
+	}
+	if !changed {
+		return b, nil
+	}
+	var buf bytes.Buffer
+	enc := v3yaml.NewEncoder(&buf)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("%s: encode interpolated YAML, %v", file, err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("%s: encode interpolated YAML, %v", file, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// expandEnvRefs expands all environment variable references in the supplied string.
+func expandEnvRefs(s string, lookup func(string) (string, bool)) (string, error) {
+	var out strings.Builder
+	for {
+		pos := strings.Index(s, "${")
+		if pos < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		if pos > 0 && s[pos-1] == '$' { // escaped reference
+			out.WriteString(s[:pos-1] + "${")
+			s = s[pos+2:]
+			continue
+		}
+		out.WriteString(s[:pos])
+		end := strings.Index(s[pos:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in '%s'", s[pos:])
+		}
+		ref := s[pos+2 : pos+end]
+		name, def, hasDefault := ref, "", false
+		if i := strings.Index(ref, ":-"); i >= 0 {
+			name, def, hasDefault = ref[:i], ref[i+2:], true
+		}
+		if !isEnvVarName(name) {
+			return "", fmt.Errorf("invalid variable reference '${%s}'", ref)
+		}
+		value, ok := lookup(name)
+		switch {
+		case value != "":
+			out.WriteString(value)
+		case hasDefault:
+			out.WriteString(def)
+		case !ok:
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		s = s[pos+end+1:]
+	}
+}
+
+// isEnvVarName returns true if the supplied string is a valid environment variable name.
+func isEnvVarName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package model
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLookup(name string) (string, bool) {
+	v, ok := map[string]string{
+		"CLUSTER_URL": "https://ci-server",
+		"PORT":        "8443",
+		"EMPTY":       "",
+	}[name]
+	return v, ok
+}
+
+func TestExpandEnvRefs(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "${CLUSTER_URL}", expected: "https://ci-server"},
+		{input: "${CLUSTER_URL}:${PORT}/api", expected: "https://ci-server:8443/api"},
+		{input: "${MISSING:-fallback}", expected: "fallback"},
+		{input: "${EMPTY:-fallback}", expected: "fallback"},
+		{input: "${PORT:-80}", expected: "8443"},
+		{input: "x${EMPTY}y", expected: "xy"},
+		{input: "${MISSING:-}", expected: ""},
+		{input: "$${CLUSTER_URL} and ${PORT}", expected: "${CLUSTER_URL} and 8443"},
+		{input: "$.foo and $bar", expected: "$.foo and $bar"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			out, err := expandEnvRefs(test.input, testLookup)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, out)
+		})
+	}
+}
+
+func TestExpandEnvRefsNegative(t *testing.T) {
+	tests := []struct {
+		input   string
+		message string
+	}{
+		{input: "${MISSING}", message: "environment variable MISSING is not set"},
+		{input: "${CLUSTER_URL", message: "unterminated variable reference in '${CLUSTER_URL'"},
+		{input: "${1FOO}", message: "invalid variable reference '${1FOO}'"},
+		{input: "${}", message: "invalid variable reference '${}'"},
+	}
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := expandEnvRefs(test.input, testLookup)
+			require.Error(t, err)
+			assert.Equal(t, test.message, err.Error())
+		})
+	}
+}
+
+func TestInterpolateEnvVars(t *testing.T) {
+	input := []byte(`apiVersion: qbec.io/v1alpha1
+kind: App
+metadata:
+  name: app
+spec:
+  libPaths:
+    - ${MISSING:-lib}
+  environments:
+    ${PORT}:
+      server: ${CLUSTER_URL}
+      defaultNamespace: ${PORT}
+      properties:
+        port: ${PORT}
+        replicas: 3
+`)
+	out, err := interpolateEnvVars("qbec.yaml", input, testLookup)
+	require.NoError(t, err)
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(out, &data))
+	spec := data["spec"].(map[string]interface{})
+	a := assert.New(t)
+	a.EqualValues([]interface{}{"lib"}, spec["libPaths"])
+	env := spec["environments"].(map[string]interface{})["${PORT}"].(map[string]interface{})
+	a.Equal("https://ci-server", env["server"])
+	a.Equal("8443", env["defaultNamespace"])
+	a.EqualValues(map[string]interface{}{"port": "8443", "replicas": float64(3)}, env["properties"])
+
+	same := []byte("apiVersion: qbec.io/v1alpha1\nkind: App\n")
+	out, err = interpolateEnvVars("qbec.yaml", same, testLookup)
+	require.NoError(t, err)
+	a.Equal(same, out)
+}
+
+func TestInterpolateEnvVarsNegative(t *testing.T) {
+	input := []byte(`spec:
+  environments:
+    dev:
+      server: https://dev
+    prod:
+      contexts:
+        - primary
+        - ${PROD_DR_CONTEXT}
+`)
+	_, err := interpolateEnvVars("qbec.yaml", input, testLookup)
+	require.Error(t, err)
+	assert.Equal(t, "qbec.yaml: spec.environments.prod.contexts[1]: environment variable PROD_DR_CONTEXT is not set", err.Error())
+}
@@ -0,0 +1,86 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// jsonSchemaDraft is the JSON schema version of documents returned by JSONSchema.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// schemaKinds are the kinds of documents for which JSON schemas are available, mapped to the definitions
+// of the top level object and its spec.
+var schemaKinds = map[string][]string{
+	"App":            {"App", "AppSpec"},
+	"EnvironmentMap": {"EnvironmentMap", "EnvironmentsSpec"},
+}
+
+// toJSONSchema converts a swagger schema object into a JSON schema object in place by removing keywords that JSON
+// schema does not support.
+func toJSONSchema(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		delete(v, "nullable") // swagger schemas without a type that allow null values, which JSON schema always does
+		for _, child := range v {
+			toJSONSchema(child)
+		}
+	case []interface{}:
+		for _, child := range v {
+			toJSONSchema(child)
+		}
+	}
+}
+
+// JSONSchema returns a JSON schema document for qbec.yaml when the supplied kind is App, or for environment files
+// when it is EnvironmentMap. The schema is derived from the definitions used to validate these files, such that it
+// can be used by editors. Keys with the x- prefix are allowed at the top level and in the spec, consistent with
+// validation.
+func JSONSchema(kind string) ([]byte, error) {
+	names, ok := schemaKinds[kind]
+	if !ok {
+		return nil, fmt.Errorf("unsupported kind %q, must be one of App or EnvironmentMap", kind)
+	}
+	var doc struct {
+		Definitions map[string]interface{} `json:"definitions"`
+	}
+	if err := json.Unmarshal([]byte(swaggerJSON), &doc); err != nil {
+		return nil, errors.Wrap(err, "load swagger")
+	}
+	prefix := strings.Replace(LatestAPIVersion, "/", ".", -1) + "."
+	for _, name := range names {
+		def, ok := doc.Definitions[prefix+name].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("no definition found for %s", prefix+name)
+		}
+		def["patternProperties"] = map[string]interface{}{
+			"^" + extensionPrefix: map[string]interface{}{},
+		}
+	}
+	toJSONSchema(doc.Definitions)
+	schema := map[string]interface{}{
+		"$schema":     jsonSchemaDraft,
+		"title":       fmt.Sprintf("qbec %s", kind),
+		"$ref":        "#/definitions/" + prefix + kind,
+		"definitions": doc.Definitions,
+	}
+	return json.MarshalIndent(schema, "", "  ")
+}
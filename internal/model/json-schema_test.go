@@ -0,0 +1,178 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package model
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-openapi/spec"
+	"github.com/go-openapi/strfmt"
+	"github.com/go-openapi/validate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaDefinitionNames maps model types to the names of their schema definitions, when they are different.
+var schemaDefinitionNames = map[string]string{
+	"QbecApp":                "App",
+	"QbecEnvironmentMap":     "EnvironmentMap",
+	"QbecEnvironmentMapSpec": "EnvironmentsSpec",
+}
+
+// jsonFields returns the JSON names of the fields of the supplied struct type, including the fields of embedded
+// structs, mapped to their types.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	ret := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			for k, v := range jsonFields(f.Type) {
+				ret[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		ret[name] = f.Type
+	}
+	return ret
+}
+
+// checkSchemaInSync checks that the properties of the schema definition of the supplied type are the same as the
+// JSON fields of the type, recursively for all model types that it refers to.
+func checkSchemaInSync(t *testing.T, defs map[string]interface{}, typ reflect.Type, seen map[reflect.Type]bool) {
+	if seen[typ] {
+		return
+	}
+	seen[typ] = true
+	name := typ.Name()
+	if n, ok := schemaDefinitionNames[name]; ok {
+		name = n
+	}
+	def, ok := defs["qbec.io.v1alpha1."+name].(map[string]interface{})
+	require.True(t, ok, "no schema definition for %s", typ.Name())
+	props, _ := def["properties"].(map[string]interface{})
+	var schemaProps, typeProps []string
+	for k := range props {
+		schemaProps = append(schemaProps, k)
+	}
+	fields := jsonFields(typ)
+	for k := range fields {
+		typeProps = append(typeProps, k)
+	}
+	sort.Strings(schemaProps)
+	sort.Strings(typeProps)
+	assert.Equal(t, typeProps, schemaProps, "properties of %s", typ.Name())
+
+	for _, ft := range fields {
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Map {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && ft.PkgPath() == typ.PkgPath() {
+			checkSchemaInSync(t, defs, ft, seen)
+		}
+	}
+}
+
+func TestJSONSchemaInSync(t *testing.T) {
+	for _, kind := range []string{"App", "EnvironmentMap"} {
+		t.Run(kind, func(t *testing.T) {
+			b, err := JSONSchema(kind)
+			require.NoError(t, err)
+			var doc map[string]interface{}
+			require.NoError(t, json.Unmarshal(b, &doc))
+			defs := doc["definitions"].(map[string]interface{})
+			typ := reflect.TypeOf(QbecApp{})
+			if kind == "EnvironmentMap" {
+				typ = reflect.TypeOf(QbecEnvironmentMap{})
+			}
+			checkSchemaInSync(t, defs, typ, map[reflect.Type]bool{})
+		})
+	}
+}
+
+func validateWithJSONSchema(t *testing.T, kind string, content []byte) []error {
+	b, err := JSONSchema(kind)
+	require.NoError(t, err)
+	var schema spec.Schema
+	require.NoError(t, json.Unmarshal(b, &schema))
+	var data map[string]interface{}
+	require.NoError(t, yaml.Unmarshal(content, &data))
+	return validate.NewSchemaValidator(&schema, &schema, "", strfmt.Default).Validate(data).Errors
+}
+
+func TestJSONSchemaValidate(t *testing.T) {
+	b, err := ioutil.ReadFile("../../examples/test-app/qbec.yaml")
+	require.NoError(t, err)
+	assert.Empty(t, validateWithJSONSchema(t, "App", b))
+
+	errs := validateWithJSONSchema(t, "App", []byte(`
+apiVersion: qbec.io/v1alpha1
+kind: App
+x-defaults: &defaults
+  defaultNamespace: ns
+metadata:
+  name: app
+spec:
+  x-prod: &prod
+    server: https://prod
+  environments:
+    prod:
+      <<: [ *defaults, *prod ]
+`))
+	assert.Empty(t, errs)
+
+	errs = validateWithJSONSchema(t, "App", []byte(`
+apiVersion: qbec.io/v1alpha1
+kind: App
+metadata:
+  name: app
+spec:
+  environment: {}
+`))
+	require.NotEmpty(t, errs)
+	assert.Contains(t, errs[0].Error(), "environment")
+
+	errs = validateWithJSONSchema(t, "EnvironmentMap", []byte(`
+apiVersion: qbec.io/v1alpha1
+kind: EnvironmentMap
+spec:
+  environments:
+    dev:
+      server: https://dev
+      defaultNamespace: ns
+`))
+	assert.Empty(t, errs)
+}
+
+func TestJSONSchemaBadKind(t *testing.T) {
+	_, err := JSONSchema("Environment")
+	require.Error(t, err)
+	assert.Equal(t, `unsupported kind "Environment", must be one of App or EnvironmentMap`, err.Error())
+}
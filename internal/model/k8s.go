@@ -137,15 +137,20 @@ func NewK8sObject(data map[string]interface{}) K8sObject {
 type LocalAttrs struct {
 	App               string
 	Tag               string
+	GCTag             string
 	Component         string
 	Env               string
 	SetComponentLabel bool
 }
 
 // NewK8sLocalObject wraps a K8sLocalObject implementation around the unstructured object data specified as a bag
-// of attributes for the supplied application, component and environment.
+// of attributes for the supplied application, component and environment. An object that does not set a namespace
+// is assigned the one in its namespace directive, if present.
 func NewK8sLocalObject(data map[string]interface{}, attrs LocalAttrs) K8sLocalObject {
 	base := toUnstructured(data)
+	if ns := base.GetAnnotations()[QbecNames.Directives.Namespace]; ns != "" && base.GetNamespace() == "" {
+		base.SetNamespace(ns)
+	}
 	ret := &ko{Unstructured: base, app: attrs.App, tag: attrs.Tag, comp: attrs.Component, env: attrs.Env}
 	labels := base.GetLabels()
 	if labels == nil {
@@ -155,6 +160,9 @@ func NewK8sLocalObject(data map[string]interface{}, attrs LocalAttrs) K8sLocalOb
 	if attrs.Tag != "" {
 		labels[QbecNames.TagLabel] = attrs.Tag
 	}
+	if attrs.GCTag != "" {
+		labels[QbecNames.GCTagLabel] = attrs.GCTag
+	}
 	labels[QbecNames.EnvironmentLabel] = attrs.Env
 	if attrs.SetComponentLabel {
 		labels[QbecNames.ComponentLabel] = attrs.Component
@@ -24,6 +24,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 var cm = `
@@ -94,6 +95,16 @@ func TestK8sLocalObjectWithTag(t *testing.T) {
 	a.False(ok)
 }
 
+func TestK8sLocalObjectWithGCTag(t *testing.T) {
+	obj := NewK8sLocalObject(toData(cm), LocalAttrs{App: "app1", GCTag: "shared", Component: "c1", Env: "e1"})
+	labels := obj.ToUnstructured().GetLabels()
+	a := assert.New(t)
+	a.Equal("app1", labels[QbecNames.ApplicationLabel])
+	a.Equal("shared", labels[QbecNames.GCTagLabel])
+	_, ok := labels[QbecNames.TagLabel]
+	a.False(ok)
+}
+
 func TestK8sLocalObjectWithComponentLabel(t *testing.T) {
 	obj := NewK8sLocalObject(toData(cm), LocalAttrs{App: "app1", Tag: "t1", Component: "c1", Env: "e1", SetComponentLabel: true})
 	a := assert.New(t)
@@ -108,6 +119,21 @@ func TestK8sLocalObjectWithComponentLabel(t *testing.T) {
 	a.Equal("c1", labels[QbecNames.ComponentLabel])
 }
 
+func TestK8sLocalObjectNamespaceDirective(t *testing.T) {
+	data := toData(cm)
+	unstructured.RemoveNestedField(data, "metadata", "namespace")
+	_ = unstructured.SetNestedStringMap(data, map[string]string{QbecNames.Directives.Namespace: "kube-system"}, "metadata", "annotations")
+	obj := NewK8sLocalObject(data, LocalAttrs{App: "app1", Component: "c1", Env: "e1"})
+	a := assert.New(t)
+	a.Equal("kube-system", obj.GetNamespace())
+	a.Equal("kube-system", obj.GetAnnotations()[QbecNames.Directives.Namespace])
+
+	data = toData(cm)
+	_ = unstructured.SetNestedStringMap(data, map[string]string{QbecNames.Directives.Namespace: "kube-system"}, "metadata", "annotations")
+	obj = NewK8sLocalObject(data, LocalAttrs{App: "app1", Component: "c1", Env: "e1"})
+	a.Equal("ns1", obj.GetNamespace())
+}
+
 func TestAssertMetadata(t *testing.T) {
 	good := `
 apiVersion: v1
@@ -14,7 +14,7 @@
 
 package model
 
-// generated by gen-qbec-swagger from internal/model/swagger.yaml at 2022-07-15 05:48:30.172144 +0000 UTC
+// generated by gen-qbec-swagger from internal/model/swagger.yaml at 2026-10-14 17:08:18.141643226 +0000 UTC
 // Do NOT edit this file by hand
 
 var swaggerJSON = `
@@ -70,6 +70,13 @@ var swaggerJSON = `
                     "description": "add component name as label to Kubernetes objects",
                     "type": "boolean"
                 },
+                "applyOrder": {
+                    "description": "orders for specific kinds that override or augment the default order in which objects are applied",
+                    "items": {
+                        "$ref": "#/definitions/qbec.io.v1alpha1.KindOrder"
+                    },
+                    "type": "array"
+                },
                 "baseNamespace": {
                     "description": "base namespace for all environments",
                     "type": "string"
@@ -86,6 +93,13 @@ var swaggerJSON = `
                     "description": "directory containing component files, default to components/",
                     "type": "string"
                 },
+                "componentsDirs": {
+                    "description": "list of directories containing component files, merged into a single set of components. Cannot be specified\ntogether with componentsDir.",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
                 "dataSources": {
                     "description": "a list of data sources to be defined for the qbec app.",
                     "items": {
@@ -93,6 +107,20 @@ var swaggerJSON = `
                     },
                     "type": "array"
                 },
+                "deleteOrder": {
+                    "description": "orders for specific kinds used when sorting objects for deletion, objects are deleted in reverse order.\nDefaults to the apply order.",
+                    "items": {
+                        "$ref": "#/definitions/qbec.io.v1alpha1.KindOrder"
+                    },
+                    "type": "array"
+                },
+                "diffIgnorePaths": {
+                    "description": "JSONPath-like expressions for fields that are removed from live and local objects before they are diffed.",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
                 "dsExamples": {
                     "description": "sample output for every datasource for use by the linter",
                     "type": "object"
@@ -104,6 +132,13 @@ var swaggerJSON = `
                     },
                     "type": "array"
                 },
+                "environmentFiles": {
+                    "description": "list of files containing environment definitions to load, after any env files.\nAn environment defined in one of these files may not be defined inline, in an env file or in another of\nthese files.",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
                 "environments": {
                     "additionalProperties": {
                         "$ref": "#/definitions/qbec.io.v1alpha1.Environment"
@@ -119,6 +154,35 @@ var swaggerJSON = `
                     },
                     "type": "array"
                 },
+                "gcTag": {
+                    "description": "label value that scopes garbage collection instead of the app name",
+                    "type": "string"
+                },
+                "helmCharts": {
+                    "description": "components that define helm charts to be rendered using helm template instead of Kubernetes objects",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "helmCommand": {
+                    "description": "helm executable used to expand helm charts, either a name on the PATH or a path relative to the qbec root. Defaults to helm",
+                    "type": "string"
+                },
+                "imagePullSecrets": {
+                    "description": "names of image pull secrets added to service accounts and pod templates that do not already reference them.",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
+                "kindLabels": {
+                    "description": "labels added to objects of specific kinds after post-processing, later entries take precedence.",
+                    "items": {
+                        "$ref": "#/definitions/qbec.io.v1alpha1.KindLabels"
+                    },
+                    "type": "array"
+                },
                 "libPaths": {
                     "description": "list of library paths to add to the jsonnet VM at evaluation",
                     "items": {
@@ -142,6 +206,20 @@ var swaggerJSON = `
                     "description": "file containing jsonnet code that can be used to post-process all objects, typically adding metadata like\nannotations",
                     "type": "string"
                 },
+                "remoteLibs": {
+                    "description": "remote library bundles that can be imported using lib://\u003cname\u003e/\u003cpath\u003e",
+                    "items": {
+                        "$ref": "#/definitions/qbec.io.v1alpha1.RemoteLib"
+                    },
+                    "type": "array"
+                },
+                "streamProcessors": {
+                    "description": "list of files containing jsonnet code that transform the list of objects produced by every component,\napplied in order after post-processing",
+                    "items": {
+                        "type": "string"
+                    },
+                    "type": "array"
+                },
                 "vars": {
                     "$ref": "#/definitions/qbec.io.v1alpha1.Variables"
                 }
@@ -172,9 +250,29 @@ var swaggerJSON = `
         "qbec.io.v1alpha1.Environment": {
             "additionalProperties": false,
             "properties": {
+                "caFile": {
+                    "description": "certificate authority file that overrides the one from the kubeconfig",
+                    "type": "string"
+                },
+                "clientCertFile": {
+                    "description": "client certificate file that overrides the one from the kubeconfig, must be set together with clientKeyFile",
+                    "type": "string"
+                },
+                "clientKeyFile": {
+                    "description": "client key file that overrides the one from the kubeconfig, must be set together with clientCertFile",
+                    "type": "string"
+                },
                 "context": {
                     "type": "string"
                 },
+                "contexts": {
+                    "description": "named contexts of multiple clusters that all receive the objects of the environment",
+                    "items": {
+                        "type": "string"
+                    },
+                    "minItems": 1,
+                    "type": "array"
+                },
                 "defaultNamespace": {
                     "type": "string"
                 },
@@ -184,6 +282,10 @@ var swaggerJSON = `
                     },
                     "type": "array"
                 },
+                "extends": {
+                    "description": "name of an environment whose settings are inherited and selectively overridden by this one",
+                    "type": "string"
+                },
                 "includes": {
                     "items": {
                         "type": "string"
@@ -196,6 +298,10 @@ var swaggerJSON = `
                 },
                 "server": {
                     "type": "string"
+                },
+                "varsFile": {
+                    "description": "jsonnet file relative to the qbec root that evaluates to an object of external variable values for the environment",
+                    "type": "string"
                 }
             },
             "title": "Environment points to a specific destination and has its own set of runtime parameters.",
@@ -258,6 +364,77 @@ var swaggerJSON = `
             "title": "ExternalVar is a variable that is set as an extVar in the jsonnet VM",
             "type": "object"
         },
+        "qbec.io.v1alpha1.KindLabels": {
+            "additionalProperties": false,
+            "properties": {
+                "kinds": {
+                    "items": {
+                        "minLength": 1,
+                        "type": "string"
+                    },
+                    "minItems": 1,
+                    "type": "array"
+                },
+                "labels": {
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "minProperties": 1,
+                    "type": "object"
+                },
+                "override": {
+                    "type": "boolean"
+                }
+            },
+            "required": [
+                "kinds",
+                "labels"
+            ],
+            "title": "KindLabels is a set of labels added to all objects whose kind matches one of a list of patterns.",
+            "type": "object"
+        },
+        "qbec.io.v1alpha1.KindOrder": {
+            "additionalProperties": false,
+            "properties": {
+                "group": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string"
+                },
+                "order": {
+                    "minimum": 1,
+                    "type": "integer"
+                }
+            },
+            "required": [
+                "kind",
+                "order"
+            ],
+            "title": "KindOrder assigns an explicit order to all objects of a specific kind.",
+            "type": "object"
+        },
+        "qbec.io.v1alpha1.RemoteLib": {
+            "additionalProperties": false,
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "sha256": {
+                    "description": "hex-encoded sha256 digest of the bundle, required for http(s) URLs and OCI references to tags",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "http(s) URL of a gzipped tarball or an oci://\u003cregistry\u003e/\u003crepository\u003e[:\u003ctag\u003e|@\u003cdigest\u003e] reference",
+                    "type": "string"
+                }
+            },
+            "required": [
+                "name",
+                "url"
+            ],
+            "type": "object"
+        },
         "qbec.io.v1alpha1.TopLevelVar": {
             "additionalProperties": false,
             "properties": {
@@ -268,6 +445,9 @@ var swaggerJSON = `
                     "minItems": 1,
                     "type": "array"
                 },
+                "default": {
+                    "nullable": true
+                },
                 "name": {
                     "type": "string"
                 },
@@ -279,7 +459,7 @@ var swaggerJSON = `
                 "name",
                 "components"
             ],
-            "title": "TopLevelVar is a variable that is set as a TLA in the jsonnet VM. Note that there is no provision to set\na default value - default values should be set in the jsonnet code instead.",
+            "title": "TopLevelVar is a variable that is set as a TLA in the jsonnet VM for specific components. The default value\nis used when the variable is not specified on the command line.",
             "type": "object"
         },
         "qbec.io.v1alpha1.Variables": {
@@ -25,23 +25,42 @@ import (
 
 // Environment points to a specific destination and has its own set of runtime parameters.
 type Environment struct {
-	DefaultNamespace string                 `json:"defaultNamespace"`     // default namespace to set for k8s context
-	Server           string                 `json:"server,omitempty"`     // server URL of server, must be present unless
-	Context          string                 `json:"context,omitempty"`    // named context to use instead of deriving from server URL
-	Includes         []string               `json:"includes,omitempty"`   // components to be included in this env even if excluded at the app level
-	Excludes         []string               `json:"excludes,omitempty"`   // additional components to exclude for this env
-	Properties       map[string]interface{} `json:"properties,omitempty"` // properties attached to the environment, exposed via an extvar
+	DefaultNamespace string                 `json:"defaultNamespace"`         // default namespace to set for k8s context
+	Server           string                 `json:"server,omitempty"`         // server URL of server, must be present unless
+	Context          string                 `json:"context,omitempty"`        // named context to use instead of deriving from server URL
+	Contexts         []string               `json:"contexts,omitempty"`       // named contexts of multiple clusters that all receive the environment objects
+	Includes         []string               `json:"includes,omitempty"`       // components to be included in this env even if excluded at the app level
+	Excludes         []string               `json:"excludes,omitempty"`       // additional components to exclude for this env
+	Properties       map[string]interface{} `json:"properties,omitempty"`     // properties attached to the environment, exposed via an extvar
+	VarsFile         string                 `json:"varsFile,omitempty"`       // file that evaluates to an object of external variable values for the environment
+	ClientCertFile   string                 `json:"clientCertFile,omitempty"` // client certificate file that overrides the one from the kubeconfig
+	ClientKeyFile    string                 `json:"clientKeyFile,omitempty"`  // client key file that overrides the one from the kubeconfig
+	CAFile           string                 `json:"caFile,omitempty"`         // certificate authority file that overrides the one from the kubeconfig
+	Extends          string                 `json:"extends,omitempty"`        // name of an environment whose settings are inherited by this one
 }
 
 func (e Environment) assertValid() error {
-	if e.Server == "" && e.Context == "" {
+	if e.Server == "" && e.Context == "" && len(e.Contexts) == 0 {
 		return fmt.Errorf("neither server nor context was set")
 	}
 	if e.Server != "" && e.Context != "" {
 		return fmt.Errorf("only one of server or context may be set")
 	}
-	if strings.HasPrefix(e.Context, "__") { // do not allow context to be a keyword
-		return fmt.Errorf("context for environment ('%s') may not start with __", e.Context)
+	if len(e.Contexts) > 0 && (e.Server != "" || e.Context != "") {
+		return fmt.Errorf("contexts may not be set together with server or context")
+	}
+	seen := map[string]bool{}
+	for _, c := range append([]string{e.Context}, e.Contexts...) {
+		if strings.HasPrefix(c, "__") { // do not allow context to be a keyword
+			return fmt.Errorf("context for environment ('%s') may not start with __", c)
+		}
+		if c != "" && seen[c] {
+			return fmt.Errorf("duplicate context '%s'", c)
+		}
+		seen[c] = true
+	}
+	if (e.ClientCertFile == "") != (e.ClientKeyFile == "") {
+		return fmt.Errorf("clientCertFile and clientKeyFile must be specified together")
 	}
 	return nil
 }
@@ -52,11 +71,12 @@ type Var struct {
 	Secret bool   `json:"secret,omitempty"` // true if the variable is a secret
 }
 
-// TopLevelVar is a variable that is set as a TLA in the jsonnet VM. Note that there is no provision to set
-// a default value - default values should be set in the jsonnet code instead.
+// TopLevelVar is a variable that is set as a TLA in the jsonnet VM for specific components. The default value
+// is used when the variable is not specified on the command line.
 type TopLevelVar struct {
 	Var
-	Components []string `json:"components,omitempty"` // the components for which this TLA is applicable
+	Components []string    `json:"components,omitempty"` // the components for which this TLA is applicable
+	Default    interface{} `json:"default,omitempty"`    // the default value to use if none specified on the command line.
 }
 
 // ExternalVar is a variable that is set as an extVar in the jsonnet VM
@@ -78,6 +98,41 @@ type Variables struct {
 	Computed []ComputedVar `json:"computed,omitempty"` // ordered collection of computed vars
 }
 
+// KindOrder assigns an explicit order to all objects of a specific kind.
+type KindOrder struct {
+	// API group of the kind, empty for the core group
+	Group string `json:"group,omitempty"`
+	// required: true
+	Kind string `json:"kind"`
+	// the order of objects of this kind, lower orders are processed first
+	// required: true
+	Order int `json:"order"`
+}
+
+// KindLabels is a set of labels added to all objects whose kind matches one of a list of patterns.
+type KindLabels struct {
+	// glob patterns of kinds, or of the form <group>/<kind> to also match the API group of objects
+	// required: true
+	Kinds []string `json:"kinds"`
+	// labels added to matching objects
+	// required: true
+	Labels map[string]string `json:"labels"`
+	// replace labels with the same keys that are already set by components, default false
+	Override bool `json:"override,omitempty"`
+}
+
+// RemoteLib is a jsonnet library bundle that is downloaded and imported using lib://<name>/<path>.
+type RemoteLib struct {
+	// name of the library used in import paths
+	// required: true
+	Name string `json:"name"`
+	// http(s) URL of a gzipped tarball or an oci://<registry>/<repository>[:<tag>|@<digest>] reference
+	// required: true
+	URL string `json:"url"`
+	// hex-encoded sha256 digest of the bundle, required for http(s) URLs and OCI references to tags
+	SHA256 string `json:"sha256,omitempty"`
+}
+
 // AppMeta is the simplified metadata object for a qbec app.
 type AppMeta struct {
 	// required: true
@@ -88,12 +143,18 @@ type AppMeta struct {
 type AppSpec struct {
 	// directory containing component files, default to components/
 	ComponentsDir string `json:"componentsDir,omitempty"`
+	// list of directories containing component files, merged into a single set of components. Cannot be specified
+	// together with componentsDir.
+	ComponentsDirs []string `json:"componentsDirs,omitempty"`
 	// standard file containing parameters for all environments returning correct values based on qbec.io/env external
 	// variable, defaults to params.libsonnet
 	ParamsFile string `json:"paramsFile,omitempty"`
 	// file containing jsonnet code that can be used to post-process all objects, typically adding metadata like
 	// annotations.
 	PostProcessor string `json:"postProcessor,omitempty"`
+	// list of files containing jsonnet code that transform the list of objects produced by every component,
+	// applied in order after post-processing
+	StreamProcessors []string `json:"streamProcessors,omitempty"`
 	// the interface for jsonnet variables.
 	Vars Variables `json:"vars,omitempty"`
 	// data sources defined for the app.
@@ -104,12 +165,18 @@ type AppSpec struct {
 	Environments map[string]Environment `json:"environments"`
 	// additional environments pulled in from external files
 	EnvFiles []string `json:"envFiles,omitempty"`
+	// environments pulled in from external files that may not be defined anywhere else
+	EnvironmentFiles []string `json:"environmentFiles,omitempty"`
 	// list of components to exclude by default for every environment
 	Excludes []string `json:"excludes,omitempty"`
 	// list of library paths to add to the jsonnet VM at evaluation
 	LibPaths []string `json:"libPaths,omitempty"`
+	// remote library bundles that can be imported using lib://<name>/<path>
+	RemoteLibs []RemoteLib `json:"remoteLibs,omitempty"`
 	// automatically suffix default namespace defined for environment when app-tag provided.
 	NamespaceTagSuffix bool `json:"namespaceTagSuffix,omitempty"`
+	// label value that scopes garbage collection instead of the app name, can be overridden using --gc-tag.
+	GCTag string `json:"gcTag,omitempty"`
 	// properties for the baseline environment, can be used to define what env properties should look like
 	BaseProperties map[string]interface{} `json:"baseProperties,omitempty"`
 	// base Namespace for all environments. Could be overridden in Namespace
@@ -121,6 +188,21 @@ type AppSpec struct {
 	AddComponentLabel bool `json:"addComponentLabel,omitempty"`
 	// Merge imported files into current environments. Default false (replace environment)
 	MergeImportedEnvs bool `json:"mergeImportedEnvs,omitempty"`
+	// helm executable used to expand helm charts, either a name on the PATH or a path relative to the qbec root. Default "helm"
+	HelmCommand string `json:"helmCommand,omitempty"`
+	// components that define helm charts to be rendered using helm template instead of Kubernetes objects
+	HelmCharts []string `json:"helmCharts,omitempty"`
+	// orders for specific kinds that override or augment the default order in which objects are applied
+	ApplyOrder []KindOrder `json:"applyOrder,omitempty"`
+	// orders for specific kinds used when sorting objects for deletion, objects are deleted in reverse order.
+	// Defaults to the apply order.
+	DeleteOrder []KindOrder `json:"deleteOrder,omitempty"`
+	// JSONPath-like expressions for fields that are removed from live and local objects before they are diffed
+	DiffIgnorePaths []string `json:"diffIgnorePaths,omitempty"`
+	// names of image pull secrets added to service accounts and pod templates that do not already reference them
+	ImagePullSecrets []string `json:"imagePullSecrets,omitempty"`
+	// labels added to objects of specific kinds after post-processing, later entries take precedence
+	KindLabels []KindLabels `json:"kindLabels,omitempty"`
 }
 
 // QbecEnvironmentMapSpec is the spec for a QbecEnvironmentMap object.
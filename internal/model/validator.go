@@ -31,6 +31,25 @@ import (
 // LatestAPIVersion is the latest version of the API we support.
 const LatestAPIVersion = "qbec.io/v1alpha1"
 
+// extensionPrefix is the prefix of keys at the top level and in the spec of a document that are ignored by
+// validation. Such keys are typically used to hold YAML anchors that are merged into other objects.
+const extensionPrefix = "x-"
+
+// removeExtensions removes extension keys from the top level and the spec of the supplied document.
+func removeExtensions(data map[string]interface{}) {
+	remove := func(m map[string]interface{}) {
+		for k := range m {
+			if strings.HasPrefix(k, extensionPrefix) {
+				delete(m, k)
+			}
+		}
+	}
+	remove(data)
+	if spec, ok := data["spec"].(map[string]interface{}); ok {
+		remove(spec)
+	}
+}
+
 type validator struct {
 	swagger spec.Swagger
 }
@@ -72,6 +91,7 @@ func (v *validator) validateYAML(content []byte) []error {
 	if !ok {
 		return wrap(fmt.Errorf("no schema found for %s (check for valid apiVersion and kind properties)", dataType))
 	}
+	removeExtensions(data)
 	ov := validate.NewSchemaValidator(&schema, v.swagger, "", strfmt.Default)
 	res := ov.Validate(data)
 	return res.Errors
@@ -102,6 +122,7 @@ func (v *validator) validateEnvYAML(content []byte) []error {
 	if !ok {
 		return wrap(fmt.Errorf("no schema found for %s (check for valid apiVersion and kind properties)", dataType))
 	}
+	removeExtensions(data)
 	ov := validate.NewSchemaValidator(&schema, v.swagger, "", strfmt.Default)
 	res := ov.Validate(data)
 	return res.Errors
@@ -62,6 +62,37 @@ spec:
 	require.Nil(t, errs)
 }
 
+func TestValidatorAnchors(t *testing.T) {
+	doc := `---
+apiVersion: qbec.io/v1alpha1
+kind: App
+x-base: &base
+  server: "https://prod-server"
+  defaultNamespace: apps
+metadata:
+  name: foobar
+spec:
+  x-props: &props
+    replicas: 3
+  environments:
+    prod-us:
+      <<: *base
+      properties:
+        <<: *props
+        region: us
+    prod-eu:
+      <<: *base
+      defaultNamespace: apps-eu
+`
+	v, err := newValidator()
+	require.Nil(t, err)
+	errs := v.validateYAML([]byte(doc))
+	for _, e := range errs {
+		t.Log(e)
+	}
+	require.Nil(t, errs)
+}
+
 func TestValidatorEnvironmentsBasic(t *testing.T) {
 	doc := `---
 apiVersion: qbec.io/v1alpha1
@@ -180,6 +211,14 @@ func TestValidatorNegative(t *testing.T) {
 				assert.Equal(t, ".excludes in body is a forbidden property", errs[0].Error())
 			},
 		},
+		{
+			name: "extra props in merged anchor",
+			yaml: `{ apiVersion: "qbec.io/v1alpha1", kind: "App", x-base: &base { server: "https://dev", foo: "bar" }, metadata: { name: "foo"}, spec: { environments: { dev: { <<: *base } } } }`,
+			asserter: func(t *testing.T, errs []error) {
+				require.Equal(t, 1, len(errs))
+				assert.Equal(t, "spec.environments.dev.foo in body is a forbidden property", errs[0].Error())
+			},
+		},
 		{
 			name: "no components for TLA",
 			yaml: `{ apiVersion: "qbec.io/v1alpha1", kind: "App", metadata: { name: "foo"}, spec: { vars: { topLevel: [ { name: 'foo' } ] }, environments: { dev: { server: "https://dev" } } } } }`,
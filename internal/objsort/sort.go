@@ -31,11 +31,12 @@ type OrderingProvider func(item model.K8sQbecMeta) int
 // Namespaced returns true if the supplied gvk is a namespaced resource.
 type Namespaced func(gvk schema.GroupVersionKind) (namespaced bool, err error)
 
-// Config is the sort configuration. The ordering provider may be nil if no custom
+// Config is the sort configuration. The ordering provider and kind ordering may be nil if no custom
 // ordering is required.
 type Config struct {
-	OrderingProvider    OrderingProvider // custom ordering provider
-	NamespacedIndicator Namespaced       // indicator to determine if resource sis namespaced
+	OrderingProvider    OrderingProvider         // custom ordering provider
+	KindOrdering        map[schema.GroupKind]int // orders for kinds that override or augment SpecifiedOrdering
+	NamespacedIndicator Namespaced               // indicator to determine if resource sis namespaced
 }
 
 // ordering for specific classes of objects
@@ -78,6 +79,9 @@ func getOrder(ob model.K8sQbecMeta, config Config) int {
 	}
 	gvk := ob.GroupVersionKind()
 	gk := gvk.GroupKind()
+	if order, ok := config.KindOrdering[gk]; ok {
+		return order
+	}
 	if order, ok := SpecifiedOrdering[gk]; ok {
 		return order
 	}
@@ -160,6 +164,24 @@ func SortMeta(inputs []model.K8sQbecMeta, config Config) []model.K8sQbecMeta {
 	return ret
 }
 
+// SortMetaGroups sorts the supplied meta objects based on the config and returns them in groups of objects that
+// have the same order, in the same way as SortGroups.
+func SortMetaGroups(inputs []model.K8sQbecMeta, config Config) [][]model.K8sQbecMeta {
+	sorter := newSorter(config)
+	for _, obj := range inputs {
+		sorter.add(obj, obj)
+	}
+	sorter.sort()
+	var ret [][]model.K8sQbecMeta
+	for i, o := range sorter.inputs {
+		if i == 0 || sorter.inputs[i-1].order != o.order {
+			ret = append(ret, nil)
+		}
+		ret[len(ret)-1] = append(ret[len(ret)-1], o.item.(model.K8sQbecMeta))
+	}
+	return ret
+}
+
 // Sort sorts the supplied local objects based on the supplied configuration.
 func Sort(inputs []model.K8sLocalObject, config Config) []model.K8sLocalObject {
 	sorter := newSorter(config)
@@ -173,3 +195,22 @@ func Sort(inputs []model.K8sLocalObject, config Config) []model.K8sLocalObject {
 	}
 	return ret
 }
+
+// SortGroups sorts the supplied local objects based on the supplied configuration and returns them in groups
+// of objects that have the same order. Objects in a group do not depend on each other and may be processed
+// concurrently as long as groups are processed in sequence.
+func SortGroups(inputs []model.K8sLocalObject, config Config) [][]model.K8sLocalObject {
+	sorter := newSorter(config)
+	for _, obj := range inputs {
+		sorter.add(obj, obj)
+	}
+	sorter.sort()
+	var ret [][]model.K8sLocalObject
+	for i, o := range sorter.inputs {
+		if i == 0 || sorter.inputs[i-1].order != o.order {
+			ret = append(ret, nil)
+		}
+		ret[len(ret)-1] = append(ret[len(ret)-1], o.item.(model.K8sLocalObject))
+	}
+	return ret
+}
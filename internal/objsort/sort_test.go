@@ -138,3 +138,113 @@ func TestBasicSortMeta(t *testing.T) {
 	}
 	assert.EqualValues(t, expected, results)
 }
+
+func TestSortGroups(t *testing.T) {
+	inputs := []model.K8sLocalObject{
+		object(data{"c0", "v1", "BarBaz", "c1-secret", ""}),
+		object(data{"c1", "v1", "Secret", "c1-secret", ""}),
+		object(data{"c1", "v1", "Namespace", "c1-ns", ""}),
+		object(data{"c1", "v1", "ServiceAccount", "c1-sa", "c1-ns"}),
+		object(data{"cluster", "v1", "PodSecurityPolicy", "cluster-psp", ""}),
+		object(data{"c2", "extensions/v1beta1", "Deployment", "deploy1", "c1-ns"}),
+		object(data{"c2", "extensions/v1beta1", "FooBar", "fb1", "c1-ns"}),
+		object(data{"c3", "rbac.authorization.k8s.io/v1beta1", "RoleBinding", "rb1", "c1-ns"}),
+		object(data{"c3", "rbac.authorization.k8s.io/v1beta1", "ClusterRole", "cr", ""}),
+	}
+	groups := SortGroups(inputs, Config{
+		NamespacedIndicator: func(gvk schema.GroupVersionKind) (bool, error) {
+			if gvk.Kind == "PodSecurityPolicy" || gvk.Kind == "FooBar" || gvk.Kind == "ClusterRoleBinding" {
+				return false, nil
+			}
+			if gvk.Kind == "BarBaz" {
+				return false, errors.New("no indicator for BarBaz")
+			}
+			return true, nil
+		},
+	})
+	var results [][]string
+	for _, g := range groups {
+		var names []string
+		for _, s := range g {
+			names = append(names, fmt.Sprintf("%s:%s:%s", s.GetKind(), s.GetName(), s.GetNamespace()))
+		}
+		results = append(results, names)
+	}
+	expected := [][]string{
+		{"FooBar:fb1:c1-ns", "PodSecurityPolicy:cluster-psp:"},
+		{"Namespace:c1-ns:"},
+		{"ServiceAccount:c1-sa:c1-ns"},
+		{"Secret:c1-secret:"},
+		{"ClusterRole:cr:", "RoleBinding:rb1:c1-ns"},
+		{"Deployment:deploy1:c1-ns"},
+		{"BarBaz:c1-secret:"},
+	}
+	assert.EqualValues(t, expected, results)
+}
+
+func TestSortMetaGroups(t *testing.T) {
+	inputs := []model.K8sQbecMeta{
+		object(data{"c1", "v1", "Secret", "c1-secret", "c1-ns"}),
+		object(data{"c1", "v1", "Namespace", "c1-ns", ""}),
+		object(data{"c1", "v1", "ServiceAccount", "c1-sa", "c1-ns"}),
+		object(data{"c2", "v1", "ConfigMap", "c2-cm", "c1-ns"}),
+	}
+	groups := SortMetaGroups(inputs, Config{
+		NamespacedIndicator: func(gvk schema.GroupVersionKind) (bool, error) {
+			return gvk.Kind != "Namespace", nil
+		},
+	})
+	var results [][]string
+	for _, g := range groups {
+		var names []string
+		for _, s := range g {
+			names = append(names, fmt.Sprintf("%s:%s", s.GetKind(), s.GetName()))
+		}
+		results = append(results, names)
+	}
+	expected := [][]string{
+		{"Namespace:c1-ns"},
+		{"ServiceAccount:c1-sa"},
+		{"ConfigMap:c2-cm", "Secret:c1-secret"},
+	}
+	assert.EqualValues(t, expected, results)
+	assert.Nil(t, SortMetaGroups(nil, Config{}))
+}
+
+func TestSortKindOrdering(t *testing.T) {
+	inputs := []model.K8sLocalObject{
+		object(data{"c1", "apps/v1", "Deployment", "d1", "ns1"}),
+		object(data{"c1", "cert-manager.io/v1", "Certificate", "cert1", "ns1"}),
+		object(data{"c1", "v1", "ConfigMap", "cm1", "ns1"}),
+		object(data{"c1", "v1", "Secret", "s1", "ns1"}),
+		object(data{"c1", "v1", "Namespace", "ns1", ""}),
+	}
+	sorted := Sort(inputs, Config{
+		OrderingProvider: func(item model.K8sQbecMeta) int {
+			if item.GetName() == "s1" {
+				return 1
+			}
+			return 0
+		},
+		KindOrdering: map[schema.GroupKind]int{
+			{Group: "cert-manager.io", Kind: "Certificate"}: GenericPodOrder - 10,
+			{Group: "", Kind: "Namespace"}:                  GenericPodOrder + 10,
+			{Group: "", Kind: "Secret"}:                     GenericPodOrder + 20,
+		},
+		NamespacedIndicator: func(gvk schema.GroupVersionKind) (bool, error) {
+			return gvk.Kind != "Namespace", nil
+		},
+	})
+	var results []string
+	for _, s := range sorted {
+		results = append(results, fmt.Sprintf("%s:%s", s.GetKind(), s.GetName()))
+	}
+	expected := []string{
+		"Secret:s1",
+		"ConfigMap:cm1",
+		"Certificate:cert1",
+		"Deployment:d1",
+		"Namespace:ns1",
+	}
+	assert.EqualValues(t, expected, results)
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrNamespaceNotFound is returned by a dry-run create when the namespace of the object does not exist, for
+// example because it is created by the same apply.
+var ErrNamespaceNotFound = errors.New("namespace not found")
+
+// AdmissionDenial is returned when the server rejects an object in a dry-run create, for example because an
+// admission webhook or a pod security policy denied the request.
+type AdmissionDenial struct {
+	Message string
+}
+
+func (a *AdmissionDenial) Error() string {
+	return a.Message
+}
+
+// admissionMarkers are parts of the messages with which admission webhooks and policies deny requests.
+var admissionMarkers = []string{
+	"admission webhook",         // validating and mutating webhooks
+	"ValidatingAdmissionPolicy", // validating admission policies
+	"violates PodSecurity",      // pod security admission
+}
+
+// reAuthorizationFailure matches the message of requests that are forbidden by the authorizer, such as RBAC.
+var reAuthorizationFailure = regexp.MustCompile(`cannot \w+ resource`)
+
+// isAdmissionDenial returns true if the supplied error was returned because admission denied the request.
+func isAdmissionDenial(err error) bool {
+	msg := err.Error()
+	for _, m := range admissionMarkers {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+	// other admission plugins such as resource quotas reject requests as forbidden. Authorization failures are
+	// also forbidden but are not admission results.
+	return apiErrors.IsForbidden(err) && !reAuthorizationFailure.MatchString(msg)
+}
+
+// isNamespaceNotFound returns true if the supplied error was returned because the namespace of the object does not
+// exist.
+func isNamespaceNotFound(err error) bool {
+	if !apiErrors.IsNotFound(err) {
+		return false
+	}
+	if s, ok := err.(apiErrors.APIStatus); ok {
+		d := s.Status().Details
+		return d != nil && d.Kind == "namespaces"
+	}
+	return false
+}
+
+// admissionResult converts the error of a dry-run create into an admission denial for requests rejected by
+// admission webhooks, policies or plugins. Authorization failures and objects rejected by the server for other
+// reasons are returned as errors. Objects that already exist have been admitted, since admission runs before the
+// object is stored.
+func admissionResult(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case apiErrors.IsAlreadyExists(err):
+		return nil
+	case isNamespaceNotFound(err):
+		return ErrNamespaceNotFound
+	case isAdmissionDenial(err):
+		return &AdmissionDenial{Message: err.Error()}
+	default:
+		return errors.Wrap(err, "dry-run create")
+	}
+}
+
+// DryRunCreate sends a create request for the supplied object to the server with the dryRun=All option, such
+// that admission controllers and webhooks run without persisting anything. It returns an AdmissionDenial
+// when admission rejects the object and ErrNamespaceNotFound when its namespace does not exist.
+func (c *Client) DryRunCreate(ctx context.Context, obj model.K8sLocalObject) error {
+	ri, err := c.resourceInterfaceWithDefaultNs(obj.GroupVersionKind(), obj.GetNamespace())
+	if err != nil {
+		return errors.Wrap(err, "get resource interface")
+	}
+	_, err = ri.Create(ctx, obj.ToUnstructured(), metav1.CreateOptions{
+		DryRun:       []string{metav1.DryRunAll},
+		FieldManager: DefaultFieldManager,
+	})
+	return admissionResult(err)
+}
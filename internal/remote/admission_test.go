@@ -0,0 +1,70 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAdmissionResult(t *testing.T) {
+	gr := schema.GroupResource{Resource: "pods"}
+	a := assert.New(t)
+	a.NoError(admissionResult(nil))
+	a.NoError(admissionResult(apiErrors.NewAlreadyExists(gr, "foo")))
+
+	err := admissionResult(apiErrors.NewForbidden(gr, "foo", errors.New(`violates PodSecurity "restricted:latest"`)))
+	require.Error(t, err)
+	var denial *AdmissionDenial
+	require.True(t, errors.As(err, &denial))
+	a.Contains(denial.Message, `violates PodSecurity "restricted:latest"`)
+
+	err = admissionResult(apiErrors.NewBadRequest(`admission webhook "validation.gatekeeper.sh" denied the request`))
+	require.True(t, errors.As(err, &denial))
+
+	err = admissionResult(apiErrors.NewInvalid(schema.GroupKind{Kind: "Pod"}, "foo", nil))
+	require.Error(t, err)
+	a.False(errors.As(err, &denial))
+
+	err = admissionResult(apiErrors.NewForbidden(gr, "foo", errors.New(`ValidatingAdmissionPolicy 'no-latest' with binding 'b1' denied request`)))
+	require.True(t, errors.As(err, &denial))
+
+	err = admissionResult(apiErrors.NewForbidden(gr, "foo", errors.New(`exceeded quota: compute`)))
+	require.True(t, errors.As(err, &denial))
+
+	err = admissionResult(apiErrors.NewForbidden(gr, "foo", errors.New(`User "jdoe" cannot create resource "pods" in API group "" in the namespace "ns1"`)))
+	require.Error(t, err)
+	a.False(errors.As(err, &denial))
+	a.Contains(err.Error(), "dry-run create:")
+
+	err = admissionResult(apiErrors.NewNotFound(schema.GroupResource{Resource: "namespaces"}, "ns1"))
+	a.Equal(ErrNamespaceNotFound, err)
+
+	err = admissionResult(apiErrors.NewNotFound(schema.GroupResource{Group: "example.com", Resource: "widgets"}, ""))
+	require.Error(t, err)
+	a.NotEqual(ErrNamespaceNotFound, err)
+
+	err = admissionResult(apiErrors.NewServiceUnavailable("down"))
+	require.Error(t, err)
+	a.False(errors.As(err, &denial))
+	a.Contains(err.Error(), "dry-run create: down")
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -65,19 +66,38 @@ type ConditionFunc func(obj model.K8sMeta) bool
 
 // SyncOptions provides the caller with options for the sync operation.
 type SyncOptions struct {
-	DryRun          bool            // do not actually create or update objects, return what would happen
-	DisableCreate   bool            // only update objects if they exist, do not create new ones
-	DisableUpdateFn ConditionFunc   // do not update an existing object
-	WaitOptions     TypeWaitOptions // opts for waiting
-	ShowSecrets     bool            // show secrets in patches and creations
+	DryRun          bool              // do not actually create or update objects, return what would happen
+	DisableCreate   bool              // only update objects if they exist, do not create new ones
+	DisableCreateFn ConditionFunc     // do not create the supplied local object if it does not exist, optional
+	DisableUpdateFn ConditionFunc     // do not update an existing object, called for both the local and remote object
+	WaitOptions     TypeWaitOptions   // opts for waiting
+	ShowSecrets     bool              // show secrets in patches and creations
+	Retries         int               // number of times to retry create and update operations for transient errors
+	RetryBackoff    time.Duration     // initial wait between retries, doubled for every subsequent retry
+	ServerSide      bool              // use server-side apply instead of client-side patches
+	FieldManager    string            // field manager name for creates, patches and server-side apply
+	ForceConflicts  bool              // take ownership of fields managed by others for server-side apply
+	Stamp           map[string]string // annotations set on created and updated objects, not recorded in the pristine version
+	Adopt           bool              // take ownership of existing objects managed by other apps, environments or tags
+	CheckOwnership  bool              // fail to update existing objects managed by other apps, environments or tags unless adopted
+	ServerDryRun    bool              // in dry-run mode, send creates and updates to the server with dryRun=All
 }
 
 // DeleteOptions provides the caller with options for the delete operation.
 type DeleteOptions struct {
 	DryRun          bool          // do not actually delete, return what would happen
+	ServerDryRun    bool          // in dry-run mode, send deletes to the server with dryRun=All
 	DisableDeleteFn ConditionFunc // test to see if deletion should be disabled.
 }
 
+// serverDryRun returns the dry-run option for server requests when dry-run requests should be sent to the server.
+func serverDryRun(dryRun, server bool) []string {
+	if dryRun && server {
+		return []string{metav1.DryRunAll}
+	}
+	return nil
+}
+
 type internalSyncOptions struct {
 	secretDryRun       bool               // dry-run phase for objects having secrets info
 	pristiner          pristineReadWriter // pristine writer
@@ -93,6 +113,7 @@ type resourceClient interface {
 type Client struct {
 	resources *k8smeta.Resources        // the server metadata loaded once and never updated
 	schema    *k8smeta.ServerSchema     // the server schema
+	schemaV3  *k8smeta.ServerSchemaV3   // the server schema using OpenAPI v3 documents
 	pool      resourceClient            // the client pool for resource interfaces
 	disco     k8smeta.ResourceDiscovery // the discovery interface
 	defaultNs string                    // the default namespace to set for namespaced objects that do not define one
@@ -115,6 +136,7 @@ func newClient(pool resourceClient, disco discovery.DiscoveryInterface, ns strin
 	c := &Client{
 		resources: resources,
 		schema:    ss,
+		schemaV3:  k8smeta.NewServerSchemaV3(openAPIV3Discovery{disco: disco}, ss, sio.Warnln),
 		pool:      pool,
 		disco:     disco,
 		defaultNs: ns,
@@ -128,6 +150,35 @@ func (c *Client) ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind)
 	return c.schema.ValidatorFor(ctx, gvk)
 }
 
+// ValidatorV3For returns a validator for the supplied group version kind that uses the OpenAPI v3 schema
+// of the server, falling back to the v2 schema if the server does not support v3.
+func (c *Client) ValidatorV3For(ctx context.Context, gvk schema.GroupVersionKind) (k8smeta.Validator, error) {
+	return c.schemaV3.ValidatorFor(ctx, gvk)
+}
+
+// openAPIV3Discovery fetches OpenAPI v3 documents using the REST client of the discovery interface.
+type openAPIV3Discovery struct {
+	disco discovery.DiscoveryInterface
+}
+
+func (o openAPIV3Discovery) OpenAPIV3(ctx context.Context, u string) ([]byte, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	rc := o.disco.RESTClient()
+	if rc == nil {
+		return nil, fmt.Errorf("no REST client available to fetch %s", u)
+	}
+	req := rc.Get().AbsPath(parsed.Path)
+	for k, values := range parsed.Query() {
+		for _, v := range values {
+			req = req.Param(k, v)
+		}
+	}
+	return req.Do(ctx).Raw()
+}
+
 // objectNamespace returns the namespace for the specified object. It returns a blank
 // string when the object is cluster-scoped. For namespace-scoped objects it returns
 // the default namespace when the object does not have one set. It does not fail if the
@@ -243,6 +294,7 @@ type GVKFilter func(gvk schema.GroupVersionKind) bool
 type ListQueryConfig struct {
 	Application        string    // must be non-blank
 	Tag                string    // may be blank
+	GCTag              string    // selects objects by GC tag instead of application when not blank
 	Environment        string    // must be non-blank
 	ListQueryScope               // the query scope for namespaces and non-namespaced resources
 	KindFilter         GVKFilter // filters for group version kind
@@ -315,6 +367,7 @@ type updateResult struct {
 	DisplayPatch  string             `json:"patch,omitempty"`
 	GeneratedName string             `json:"generatedName,omitempty"`
 	patch         []byte
+	adoptedFrom   string
 }
 
 func (u *updateResult) String() string {
@@ -326,6 +379,14 @@ func (u *updateResult) String() string {
 }
 
 func (u *updateResult) toSyncResult() *SyncResult {
+	ret := u.syncResult()
+	if ret.Type == SyncUpdated {
+		ret.AdoptedFrom = u.adoptedFrom
+	}
+	return ret
+}
+
+func (u *updateResult) syncResult() *SyncResult {
 	switch {
 	case u.SkipReason == identicalObjects:
 		return &SyncResult{
@@ -372,6 +433,7 @@ type SyncResult struct {
 	Type          SyncResultType // the result type
 	GeneratedName string         // the actual name of an object that has generateName set
 	Details       string         // additional details that are safe to print to console (e.g. no secrets)
+	AdoptedFrom   string         // the previous owner of an existing object that was adopted, if any
 }
 
 func (c *Client) ensureType(gvk schema.GroupVersionKind, opts SyncOptions) error {
@@ -434,22 +496,67 @@ func (c *Client) Sync(ctx context.Context, original model.K8sLocalObject, opts S
 		}
 	}
 
-	result, err := c.doSync(ctx, original, opts, internal)
+	var result *updateResult
+	err := withRetries(ctx, c.DisplayName(original), opts.Retries, opts.RetryBackoff, func() error {
+		var err error
+		result, err = c.doSync(ctx, original, opts, internal)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// exit if we are done
-	if !internal.secretDryRun || opts.DryRun {
-		return result.toSyncResult(), nil
+	if !internal.secretDryRun || (opts.DryRun && !opts.ServerDryRun) {
+		return c.stamp(ctx, original, result.toSyncResult(), opts)
 	}
 	internal.secretDryRun = false
-	_, err = c.doSync(ctx, original, opts, internal) // do the real sync
+	err = withRetries(ctx, c.DisplayName(original), opts.Retries, opts.RetryBackoff, func() error {
+		_, err := c.doSync(ctx, original, opts, internal) // do the real sync
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return result.toSyncResult(), err
+	return c.stamp(ctx, original, result.toSyncResult(), opts)
+}
+
+// stampFieldManager is the field manager used to set stamp annotations, such that server-side apply does not
+// remove them from objects that are otherwise unchanged.
+const stampFieldManager = "qbec-stamp"
+
+// stamp sets the stamp annotations of the supplied options on an object that was created or updated, using a
+// JSON merge patch. Since the annotations are not part of the pristine version of the object, subsequent syncs
+// and diffs ignore them.
+func (c *Client) stamp(ctx context.Context, obj model.K8sMeta, res *SyncResult, opts SyncOptions) (*SyncResult, error) {
+	if opts.DryRun || len(opts.Stamp) == 0 || (res.Type != SyncCreated && res.Type != SyncUpdated) {
+		return res, nil
+	}
+	name := obj.GetName()
+	if res.GeneratedName != "" {
+		name = res.GeneratedName
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": opts.Stamp,
+		},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "json marshal")
+	}
+	err = withRetries(ctx, c.DisplayName(obj), opts.Retries, opts.RetryBackoff, func() error {
+		ri, err := c.resourceInterfaceWithDefaultNs(obj.GroupVersionKind(), obj.GetNamespace())
+		if err != nil {
+			return errors.Wrap(err, "get resource interface")
+		}
+		_, err = ri.Patch(ctx, name, apiTypes.MergePatchType, patch, metav1.PatchOptions{FieldManager: stampFieldManager})
+		return err
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "stamp object")
+	}
+	return res, nil
 }
 
 func (c *Client) doSync(ctx context.Context, original model.K8sLocalObject, opts SyncOptions, internal internalSyncOptions) (*updateResult, error) {
@@ -465,17 +572,28 @@ func (c *Client) doSync(ctx context.Context, original model.K8sLocalObject, opts
 	// ignore object not found errors
 	case objErr == ErrNotFound:
 		break
-	// treat metadata errors (server type not found) as a "not found" error if dry-run mode is active
+	// treat metadata errors (server type not found) as a "not found" error if dry-run mode is active. The server
+	// cannot dry-run objects of unknown types, so compute the result locally.
 	case objErr == errMetadataNotFound && opts.DryRun:
-		break
+		opts.ServerDryRun = false
 	// report all other errors
 	case objErr != nil:
 		return nil, errors.Wrap(objErr, "get object")
 	}
 
+	var adoptedFrom string
+	if remObj != nil && !updateDisabled(opts, original, remObj) {
+		owner, err := checkOwnership(original, remObj, opts.Adopt, opts.CheckOwnership)
+		if err != nil {
+			return nil, err
+		}
+		adoptedFrom = owner
+	}
+
 	var obj model.K8sLocalObject
 	if internal.secretDryRun {
 		opts.DryRun = true // won't affect caller since passed by value
+		opts.ServerDryRun = false
 		obj, _ = types.HideSensitiveLocalInfo(original)
 	} else {
 		o, err := internal.pristiner.createFromPristine(original)
@@ -488,24 +606,23 @@ func (c *Client) doSync(ctx context.Context, original model.K8sLocalObject, opts
 	// create or update as needed, each of these routines is responsible for correct dry-run handling.
 	var result *updateResult
 	var err error
-	if remObj == nil {
+	if opts.ServerSide && obj.GetName() != "" {
+		if remObj != nil && internal.secretDryRun {
+			remObj = hideRemoteSecrets(remObj, internal.pristineAnnotation)
+		}
+		result, err = c.maybeServerSideApply(ctx, obj, remObj, opts, internal)
+	} else if remObj == nil {
 		result, err = c.maybeCreate(ctx, obj, opts)
 	} else {
 		if internal.secretDryRun {
-			ann := remObj.GetAnnotations()
-			if ann == nil {
-				ann = map[string]string{}
-			}
-			delete(ann, internal.pristineAnnotation)
-			remObj.SetAnnotations(ann)
-			c, _ := types.HideSensitiveInfo(remObj)
-			remObj = c
+			remObj = hideRemoteSecrets(remObj, internal.pristineAnnotation)
 		}
 		result, err = c.maybeUpdate(ctx, obj, remObj, opts)
 	}
 	if err != nil {
 		return nil, err
 	}
+	result.adoptedFrom = adoptedFrom
 
 	// create a prettier patch for display, if needed
 	result.DisplayPatch = string(result.patch)
@@ -521,6 +638,19 @@ func (c *Client) doSync(ctx context.Context, original model.K8sLocalObject, opts
 	return result, nil
 }
 
+// hideRemoteSecrets removes the pristine annotation from the supplied remote object and obfuscates
+// its sensitive values.
+func hideRemoteSecrets(remObj *unstructured.Unstructured, pristineAnnotation string) *unstructured.Unstructured {
+	ann := remObj.GetAnnotations()
+	if ann == nil {
+		ann = map[string]string{}
+	}
+	delete(ann, pristineAnnotation)
+	remObj.SetAnnotations(ann)
+	ret, _ := types.HideSensitiveInfo(remObj)
+	return ret
+}
+
 // Delete delete the supplied object if it exists. It does not do anything in dry-run mode.
 func (c *Client) Delete(ctx context.Context, obj model.K8sMeta, opts DeleteOptions) (_ *SyncResult, finalError error) {
 	if opts.DisableDeleteFn(obj) {
@@ -532,7 +662,7 @@ func (c *Client) Delete(ctx context.Context, obj model.K8sMeta, opts DeleteOptio
 	ret := &SyncResult{
 		Type: SyncDeleted,
 	}
-	if opts.DryRun {
+	if opts.DryRun && !opts.ServerDryRun {
 		return ret, nil
 	}
 	defer func() {
@@ -547,7 +677,7 @@ func (c *Client) Delete(ctx context.Context, obj model.K8sMeta, opts DeleteOptio
 	}
 
 	pp := metav1.DeletePropagationForeground
-	err = ri.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &pp})
+	err = ri.Delete(ctx, obj.GetName(), metav1.DeleteOptions{PropagationPolicy: &pp, DryRun: serverDryRun(opts.DryRun, opts.ServerDryRun)})
 	if err != nil {
 		if apiErrors.IsNotFound(err) {
 			ret.Type = SyncSkip
@@ -617,8 +747,18 @@ func (c *Client) resourceInterfaceWithDefaultNs(gvk schema.GroupVersionKind, nam
 	return c.ResourceInterface(gvk, namespace)
 }
 
+// createDisabled returns true if the supplied local object must not be created.
+func createDisabled(opts SyncOptions, obj model.K8sMeta) bool {
+	return opts.DisableCreate || (opts.DisableCreateFn != nil && opts.DisableCreateFn(obj))
+}
+
+// updateDisabled returns true if updates are disabled for either the local or the remote version of an object.
+func updateDisabled(opts SyncOptions, obj model.K8sMeta, remObj *unstructured.Unstructured) bool {
+	return opts.DisableUpdateFn(model.NewK8sObject(remObj.Object)) || opts.DisableUpdateFn(obj)
+}
+
 func (c *Client) maybeCreate(ctx context.Context, obj model.K8sLocalObject, opts SyncOptions) (*updateResult, error) {
-	if opts.DisableCreate {
+	if createDisabled(opts, obj) {
 		return &updateResult{
 			SkipReason: "creation disabled due to user request",
 		}, nil
@@ -632,14 +772,17 @@ func (c *Client) maybeCreate(ctx context.Context, obj model.K8sLocalObject, opts
 		Source:    "local",
 		patch:     b,
 	}
-	if opts.DryRun {
+	if opts.DryRun && !opts.ServerDryRun {
 		return result, nil
 	}
 	ri, err := c.resourceInterfaceWithDefaultNs(obj.GroupVersionKind(), obj.GetNamespace())
 	if err != nil {
 		return nil, errors.Wrap(err, "get resource interface")
 	}
-	out, err := ri.Create(ctx, obj.ToUnstructured(), metav1.CreateOptions{})
+	out, err := ri.Create(ctx, obj.ToUnstructured(), metav1.CreateOptions{
+		DryRun:       serverDryRun(opts.DryRun, opts.ServerDryRun),
+		FieldManager: fieldManagerOrDefault(opts.FieldManager),
+	})
 	if err != nil {
 		return nil, errors.Wrap(err, "create object")
 	}
@@ -650,7 +793,7 @@ func (c *Client) maybeCreate(ctx context.Context, obj model.K8sLocalObject, opts
 }
 
 func (c *Client) maybeUpdate(ctx context.Context, obj model.K8sLocalObject, remObj *unstructured.Unstructured, opts SyncOptions) (*updateResult, error) {
-	if opts.DisableUpdateFn(model.NewK8sObject(remObj.Object)) {
+	if updateDisabled(opts, obj, remObj) {
 		return &updateResult{
 			SkipReason: "update disabled due to user request",
 		}, nil
@@ -665,30 +808,17 @@ func (c *Client) maybeUpdate(ctx context.Context, obj model.K8sLocalObject, remO
 	}
 
 	p := patcher{
-		provider: c.resourceInterfaceWithDefaultNs,
-		cfgProvider: func(obj *unstructured.Unstructured) ([]byte, error) {
-			pristine, _ := getPristineVersion(obj, false)
-			if pristine == nil {
-				p := map[string]interface{}{
-					"kind":       obj.GetKind(),
-					"apiVersion": obj.GetAPIVersion(),
-					"metadata": map[string]interface{}{
-						"name": obj.GetName(),
-					},
-				}
-				pb, _ := json.Marshal(p)
-				return pb, nil
-			}
-			b, _ := json.Marshal(pristine)
-			return b, nil
-		},
+		provider:      c.resourceInterfaceWithDefaultNs,
+		cfgProvider:   originalConfiguration,
 		overwrite:     true,
 		backOff:       clockwork.NewRealClock(),
 		openAPILookup: lookup,
+		dryRun:        serverDryRun(opts.DryRun, opts.ServerDryRun),
+		fieldManager:  fieldManagerOrDefault(opts.FieldManager),
 	}
 
 	var result *updateResult
-	if opts.DryRun {
+	if opts.DryRun && !opts.ServerDryRun {
 		result, err = p.getPatchContents(remObj, obj)
 	} else {
 		result, err = p.patch(ctx, remObj, obj)
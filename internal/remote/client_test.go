@@ -0,0 +1,333 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	apiTypes "k8s.io/apimachinery/pkg/types"
+	fakedisco "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/dynamic"
+	faketesting "k8s.io/client-go/testing"
+)
+
+// request is a write request received by the fake resource client along with its options.
+type request struct {
+	verb      string
+	name      string
+	patchType apiTypes.PatchType
+	patch     []byte
+	dryRun    []string
+	manager   string
+}
+
+// fakeResources is a dynamic client that serves objects from memory and records the write requests it receives.
+// It embeds the resource interface such that unexpected calls panic.
+type fakeResources struct {
+	dynamic.ResourceInterface
+	objects  map[string]*unstructured.Unstructured
+	requests []request
+}
+
+func newFakeResources(objs ...*unstructured.Unstructured) *fakeResources {
+	f := &fakeResources{objects: map[string]*unstructured.Unstructured{}}
+	for _, o := range objs {
+		f.objects[o.GetName()] = o
+	}
+	return f
+}
+
+func (f *fakeResources) clientForGroupVersionKind(_ schema.GroupVersionKind) (dynamic.Interface, error) {
+	return f, nil
+}
+
+func (f *fakeResources) Resource(_ schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return f
+}
+
+func (f *fakeResources) Namespace(_ string) dynamic.ResourceInterface {
+	return f
+}
+
+func (f *fakeResources) notFound(name string) error {
+	return apiErrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+}
+
+func (f *fakeResources) Get(_ context.Context, name string, _ metav1.GetOptions, _ ...string) (*unstructured.Unstructured, error) {
+	o, ok := f.objects[name]
+	if !ok {
+		return nil, f.notFound(name)
+	}
+	return o.DeepCopy(), nil
+}
+
+func (f *fakeResources) Create(_ context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, _ ...string) (*unstructured.Unstructured, error) {
+	f.requests = append(f.requests, request{verb: "create", name: obj.GetName(), dryRun: opts.DryRun, manager: opts.FieldManager})
+	return obj.DeepCopy(), nil
+}
+
+func (f *fakeResources) Patch(_ context.Context, name string, pt apiTypes.PatchType, data []byte, opts metav1.PatchOptions, _ ...string) (*unstructured.Unstructured, error) {
+	f.requests = append(f.requests, request{verb: "patch", name: name, patchType: pt, patch: data, dryRun: opts.DryRun, manager: opts.FieldManager})
+	if pt == apiTypes.ApplyPatchType {
+		var out unstructured.Unstructured
+		if err := out.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+		return &out, nil
+	}
+	o, ok := f.objects[name]
+	if !ok {
+		return nil, f.notFound(name)
+	}
+	return o.DeepCopy(), nil
+}
+
+func (f *fakeResources) Delete(_ context.Context, name string, opts metav1.DeleteOptions, _ ...string) error {
+	f.requests = append(f.requests, request{verb: "delete", name: name, dryRun: opts.DryRun})
+	if _, ok := f.objects[name]; !ok {
+		return f.notFound(name)
+	}
+	return nil
+}
+
+func newTestClient(t *testing.T, pool resourceClient) *Client {
+	disco := &fakedisco.FakeDiscovery{Fake: &faketesting.Fake{
+		Resources: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "configmaps", Namespaced: true, Kind: "ConfigMap", Verbs: []string{"create", "delete", "get", "list", "patch"}},
+				},
+			},
+		},
+	}}
+	c, err := newClient(pool, disco, "default", 0)
+	require.NoError(t, err)
+	return c
+}
+
+func testConfigMap(name string, data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+		},
+		"data": data,
+	}
+}
+
+func testLocalConfigMap(name string, data map[string]interface{}) model.K8sLocalObject {
+	return model.NewK8sLocalObject(testConfigMap(name, data), model.LocalAttrs{App: "app", Component: "c", Env: "dev"})
+}
+
+func testRemoteConfigMap(name string, data map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: testConfigMap(name, data)}
+}
+
+func noConditions(_ model.K8sMeta) bool { return false }
+
+func TestClientServerDryRun(t *testing.T) {
+	dryRunAll := []string{metav1.DryRunAll}
+	tests := []struct {
+		name       string
+		remote     *unstructured.Unstructured
+		serverSide bool
+		verb       string
+		patchType  apiTypes.PatchType
+		alwaysSent bool // server-side updates are always computed by the server
+	}{
+		{
+			name: "create",
+			verb: "create",
+		},
+		{
+			name:      "patch",
+			remote:    testRemoteConfigMap("cm", map[string]interface{}{"foo": "bar"}),
+			verb:      "patch",
+			patchType: apiTypes.StrategicMergePatchType,
+		},
+		{
+			name:       "server-side create",
+			serverSide: true,
+			verb:       "patch",
+			patchType:  apiTypes.ApplyPatchType,
+		},
+		{
+			name:       "server-side update",
+			remote:     testRemoteConfigMap("cm", map[string]interface{}{"foo": "bar"}),
+			serverSide: true,
+			verb:       "patch",
+			patchType:  apiTypes.ApplyPatchType,
+			alwaysSent: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, serverDryRun := range []bool{false, true} {
+				var remotes []*unstructured.Unstructured
+				if test.remote != nil {
+					remotes = append(remotes, test.remote)
+				}
+				f := newFakeResources(remotes...)
+				c := newTestClient(t, f)
+				_, err := c.Sync(context.Background(), testLocalConfigMap("cm", map[string]interface{}{"foo": "baz"}), SyncOptions{
+					DryRun:          true,
+					ServerDryRun:    serverDryRun,
+					ServerSide:      test.serverSide,
+					DisableUpdateFn: noConditions,
+				})
+				require.NoError(t, err)
+				if !serverDryRun && !test.alwaysSent {
+					assert.Empty(t, f.requests, "client dry-run should not send requests")
+					continue
+				}
+				require.Equal(t, 1, len(f.requests))
+				r := f.requests[0]
+				assert.Equal(t, test.verb, r.verb)
+				assert.Equal(t, "cm", r.name)
+				assert.Equal(t, test.patchType, r.patchType)
+				assert.Equal(t, dryRunAll, r.dryRun)
+				assert.Equal(t, DefaultFieldManager, r.manager)
+			}
+		})
+	}
+}
+
+func TestClientServerDryRunDelete(t *testing.T) {
+	f := newFakeResources(testRemoteConfigMap("cm", nil))
+	c := newTestClient(t, f)
+	obj := testLocalConfigMap("cm", nil)
+
+	res, err := c.Delete(context.Background(), obj, DeleteOptions{DryRun: true, DisableDeleteFn: noConditions})
+	require.NoError(t, err)
+	assert.Equal(t, SyncDeleted, res.Type)
+	assert.Empty(t, f.requests)
+
+	res, err = c.Delete(context.Background(), obj, DeleteOptions{DryRun: true, ServerDryRun: true, DisableDeleteFn: noConditions})
+	require.NoError(t, err)
+	assert.Equal(t, SyncDeleted, res.Type)
+	require.Equal(t, 1, len(f.requests))
+	assert.Equal(t, "delete", f.requests[0].verb)
+	assert.Equal(t, []string{metav1.DryRunAll}, f.requests[0].dryRun)
+
+	f.requests = nil
+	_, err = c.Delete(context.Background(), obj, DeleteOptions{DisableDeleteFn: noConditions})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(f.requests))
+	assert.Nil(t, f.requests[0].dryRun)
+}
+
+func TestClientSyncNoDryRun(t *testing.T) {
+	f := newFakeResources(testRemoteConfigMap("cm", map[string]interface{}{"foo": "bar"}))
+	c := newTestClient(t, f)
+	_, err := c.Sync(context.Background(), testLocalConfigMap("cm", map[string]interface{}{"foo": "baz"}), SyncOptions{
+		DisableUpdateFn: noConditions,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(f.requests))
+	assert.Equal(t, "patch", f.requests[0].verb)
+	assert.Nil(t, f.requests[0].dryRun)
+}
+
+func TestClientStamp(t *testing.T) {
+	stamp := map[string]string{"example.com/deployed-by": "ci"}
+	obj := testLocalConfigMap("cm", nil)
+	tests := []struct {
+		name    string
+		result  *SyncResult
+		opts    SyncOptions
+		patched string
+	}{
+		{
+			name:   "dry-run",
+			result: &SyncResult{Type: SyncCreated},
+			opts:   SyncOptions{DryRun: true, Stamp: stamp},
+		},
+		{
+			name:   "no stamp",
+			result: &SyncResult{Type: SyncUpdated},
+		},
+		{
+			name:   "identical",
+			result: &SyncResult{Type: SyncObjectsIdentical},
+			opts:   SyncOptions{Stamp: stamp},
+		},
+		{
+			name:   "skipped",
+			result: &SyncResult{Type: SyncSkip},
+			opts:   SyncOptions{Stamp: stamp},
+		},
+		{
+			name:    "created",
+			result:  &SyncResult{Type: SyncCreated},
+			opts:    SyncOptions{Stamp: stamp},
+			patched: "cm",
+		},
+		{
+			name:    "updated",
+			result:  &SyncResult{Type: SyncUpdated},
+			opts:    SyncOptions{Stamp: stamp},
+			patched: "cm",
+		},
+		{
+			name:    "generated name",
+			result:  &SyncResult{Type: SyncCreated, GeneratedName: "cm-x1y2z"},
+			opts:    SyncOptions{Stamp: stamp},
+			patched: "cm-x1y2z",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newFakeResources(testRemoteConfigMap("cm", nil), testRemoteConfigMap("cm-x1y2z", nil))
+			c := newTestClient(t, f)
+			res, err := c.stamp(context.Background(), obj, test.result, test.opts)
+			require.NoError(t, err)
+			assert.Equal(t, test.result, res)
+			if test.patched == "" {
+				assert.Empty(t, f.requests)
+				return
+			}
+			require.Equal(t, 1, len(f.requests))
+			r := f.requests[0]
+			assert.Equal(t, "patch", r.verb)
+			assert.Equal(t, test.patched, r.name)
+			assert.Equal(t, apiTypes.MergePatchType, r.patchType)
+			assert.Equal(t, stampFieldManager, r.manager)
+			assert.JSONEq(t, `{"metadata":{"annotations":{"example.com/deployed-by":"ci"}}}`, string(r.patch))
+		})
+	}
+}
+
+func TestClientStampError(t *testing.T) {
+	f := newFakeResources()
+	c := newTestClient(t, f)
+	_, err := c.stamp(context.Background(), testLocalConfigMap("cm", nil), &SyncResult{Type: SyncUpdated}, SyncOptions{
+		Stamp: map[string]string{"foo": "bar"},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stamp object")
+}
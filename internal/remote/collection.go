@@ -18,8 +18,12 @@ package remote
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"time"
 
 	"github.com/splunk/qbec/internal/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -41,6 +45,7 @@ type basicObject struct {
 	component string
 	env       string
 	anns      map[string]string
+	created   time.Time
 }
 
 func (b *basicObject) Application() string               { return b.app }
@@ -96,12 +101,45 @@ func (c *collection) add(object model.K8sQbecMeta) error {
 		env:       object.Environment(),
 		anns:      object.GetAnnotations(),
 	}
+	if ct, ok := object.(interface{ GetCreationTimestamp() metav1.Time }); ok {
+		resultObject.created = ct.GetCreationTimestamp().Time
+	}
 	c.objects[key] = resultObject
 	return nil
 }
 
+// gcKey returns the key of the supplied object with its name replaced by the value of its GC key directive,
+// if one is set.
+func gcKey(key objectKey, obj model.K8sQbecMeta) (objectKey, bool) {
+	v := obj.GetAnnotations()[model.QbecNames.Directives.GCKey]
+	if v == "" {
+		return objectKey{}, false
+	}
+	key.name = v
+	return key, true
+}
+
+// defaultGCKeyHistory is the number of previous versions of an object with a GC key that are kept by default.
+const defaultGCKeyHistory = 1
+
+// gcKeyHistory returns the number of previous versions to keep for the supplied local object with a GC key.
+func gcKeyHistory(obj model.K8sQbecMeta) (int, error) {
+	v, ok := obj.GetAnnotations()[model.QbecNames.Directives.GCKeyHistory]
+	if !ok {
+		return defaultGCKeyHistory, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s %s: invalid value %q for %s, must be a non-negative integer", obj.GetKind(), obj.GetName(), v, model.QbecNames.Directives.GCKeyHistory)
+	}
+	return n, nil
+}
+
 // Remove removes objects from its internal collection for each
-// matching object supplied.
+// matching object supplied. Objects match when they have the same name.
+// Objects are also removed when they have the same GC key as a supplied object of the same kind and namespace,
+// up to the number of previous versions to keep for the key. The most recently created objects are kept,
+// older versions remain in the collection.
 func (c *collection) Remove(objs []model.K8sQbecMeta) error {
 	sub := newCollection(c.defaultNs, c.meta)
 	for _, o := range objs {
@@ -109,10 +147,51 @@ func (c *collection) Remove(objs []model.K8sQbecMeta) error {
 			return err
 		}
 	}
+	history := map[objectKey]int{}
+	for k, v := range sub.objects {
+		if gk, ok := gcKey(k, v); ok {
+			n, err := gcKeyHistory(v)
+			if err != nil {
+				return err
+			}
+			if prev, seen := history[gk]; !seen || n > prev {
+				history[gk] = n
+			}
+		}
+	}
 	retainedSet := map[objectKey]model.K8sQbecMeta{}
+	versions := map[objectKey][]objectKey{}
 	for k, v := range c.objects {
-		if _, ok := sub.objects[k]; !ok {
-			retainedSet[k] = v
+		if _, ok := sub.objects[k]; ok {
+			continue
+		}
+		if gk, ok := gcKey(k, v); ok {
+			if _, ok := history[gk]; ok {
+				versions[gk] = append(versions[gk], k)
+			}
+		}
+		retainedSet[k] = v
+	}
+	created := func(k objectKey) time.Time {
+		if b, ok := c.objects[k].(*basicObject); ok {
+			return b.created
+		}
+		return time.Time{}
+	}
+	for gk, keys := range versions {
+		sort.Slice(keys, func(i, j int) bool {
+			ti, tj := created(keys[i]), created(keys[j])
+			if !ti.Equal(tj) {
+				return ti.After(tj)
+			}
+			return keys[i].name > keys[j].name
+		})
+		n := history[gk]
+		if n > len(keys) {
+			n = len(keys)
+		}
+		for _, k := range keys[:n] {
+			delete(retainedSet, k)
 		}
 	}
 	c.objects = retainedSet
@@ -0,0 +1,130 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeCollectMeta struct{}
+
+func (f fakeCollectMeta) objectNamespace(obj model.K8sMeta) string {
+	if obj.GetNamespace() == "" {
+		return "default"
+	}
+	return obj.GetNamespace()
+}
+
+func (f fakeCollectMeta) canonicalGroupVersionKind(in schema.GroupVersionKind) (schema.GroupVersionKind, error) {
+	return in, nil
+}
+
+func configMapObject(namespace, name, gcKey string) model.K8sLocalObject {
+	return configMapVersion(namespace, name, gcKey, "", "")
+}
+
+func configMapVersion(namespace, name, gcKey, history, created string) model.K8sLocalObject {
+	meta := map[string]interface{}{"name": name}
+	if created != "" {
+		meta["creationTimestamp"] = created
+	}
+	if namespace != "" {
+		meta["namespace"] = namespace
+	}
+	if gcKey != "" {
+		anns := map[string]interface{}{model.QbecNames.Directives.GCKey: gcKey}
+		if history != "" {
+			anns[model.QbecNames.Directives.GCKeyHistory] = history
+		}
+		meta["annotations"] = anns
+	}
+	return model.NewK8sLocalObject(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   meta,
+	}, model.LocalAttrs{App: "app", Component: "c", Env: "dev"})
+}
+
+func TestCollectionRemoveGCKey(t *testing.T) {
+	c := newCollection("default", fakeCollectMeta{})
+	for _, o := range []model.K8sQbecMeta{
+		configMapObject("", "cm-a1b2", "cm"),        // same key as local object
+		configMapObject("", "cm-c3d4", ""),          // no key
+		configMapObject("other", "cm-e5f6", "cm"),   // same key in a different namespace
+		configMapObject("", "settings-g7h8", "set"), // different key
+		configMapObject("", "kept", ""),             // same name as local object
+	} {
+		require.NoError(t, c.add(o))
+	}
+	err := c.Remove([]model.K8sQbecMeta{
+		configMapObject("", "cm-x9y0", "cm"),
+		configMapObject("", "kept", ""),
+	})
+	require.NoError(t, err)
+	var names []string
+	for _, o := range c.ToList() {
+		names = append(names, o.GetNamespace()+"/"+o.GetName())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"default/cm-c3d4", "default/settings-g7h8", "other/cm-e5f6"}, names)
+}
+
+func TestCollectionRemoveGCKeyHistory(t *testing.T) {
+	tests := []struct {
+		name     string
+		history  string
+		expected []string
+	}{
+		{name: "default", expected: []string{"cm-1", "cm-2"}},
+		{name: "none", history: "0", expected: []string{"cm-1", "cm-2", "cm-3"}},
+		{name: "two", history: "2", expected: []string{"cm-1"}},
+		{name: "more", history: "5", expected: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newCollection("default", fakeCollectMeta{})
+			for _, o := range []model.K8sQbecMeta{
+				configMapVersion("", "cm-1", "cm", "", "2021-01-01T00:00:00Z"),
+				configMapVersion("", "cm-3", "cm", "", "2021-01-03T00:00:00Z"),
+				configMapVersion("", "cm-2", "cm", "", "2021-01-02T00:00:00Z"),
+			} {
+				require.NoError(t, c.add(o))
+			}
+			err := c.Remove([]model.K8sQbecMeta{configMapVersion("", "cm-4", "cm", test.history, "")})
+			require.NoError(t, err)
+			var names []string
+			for _, o := range c.ToList() {
+				names = append(names, o.GetName())
+			}
+			sort.Strings(names)
+			assert.Equal(t, test.expected, names)
+		})
+	}
+}
+
+func TestCollectionRemoveGCKeyHistoryNegative(t *testing.T) {
+	c := newCollection("default", fakeCollectMeta{})
+	err := c.Remove([]model.K8sQbecMeta{configMapVersion("", "cm-4", "cm", "-1", "")})
+	require.Error(t, err)
+	assert.Equal(t, `ConfigMap cm-4: invalid value "-1" for directives.qbec.io/gc-key-history, must be a non-negative integer`, err.Error())
+}
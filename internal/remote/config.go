@@ -45,6 +45,9 @@ type ConnectOpts struct {
 	Namespace    string // the default namespace to set for the context
 	Verbosity    int    // verbosity of client interactions
 	ForceContext string // __incluster__ or __current or named context
+	CertFile     string // client certificate file that overrides the kubeconfig, set together with KeyFile
+	KeyFile      string // client key file that overrides the kubeconfig, set together with CertFile
+	CAFile       string // certificate authority file that overrides the kubeconfig
 }
 
 // Config provides clients for specific contexts out of a kubeconfig file, with overrides for auth.
@@ -59,7 +62,7 @@ type Config struct {
 }
 
 // NewConfig returns a new configuration, adding flags to the supplied command to set k8s access overrides, prefixed by
-// the supplied string.
+// the supplied string. A kubeconfig flag without the prefix is also added for familiarity with kubectl.
 func NewConfig(cmd *cobra.Command, prefix string) *Config {
 	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
 	overrides := &clientcmd.ConfigOverrides{}
@@ -68,6 +71,9 @@ func NewConfig(cmd *cobra.Command, prefix string) *Config {
 		overrides:    overrides,
 	}
 	cmd.PersistentFlags().StringVar(&loadingRules.ExplicitPath, prefix+"kubeconfig", "", "Path to a kubeconfig file. Alternative to env var $KUBECONFIG.")
+	if prefix != "" {
+		cmd.PersistentFlags().StringVar(&loadingRules.ExplicitPath, "kubeconfig", "", "Path to a kubeconfig file, same as --"+prefix+"kubeconfig")
+	}
 	cmd.PersistentFlags().IntVar(&cfg.qps, prefix+"client-qps", 0, "QPS to use for K8s client, 0 for default")
 	cmd.PersistentFlags().IntVar(&cfg.burst, prefix+"client-burst", 0, "Burst to use for K8s client, 0 for default")
 	cmd.PersistentFlags().Int64Var(&cfg.ListPageSize, prefix+"list-page-size", 1000, "Maximum number of responses per page to return for a list call. 0 for no limit")
@@ -144,10 +150,30 @@ func (c *Config) withQPS(cfg *rest.Config) *rest.Config {
 	return cfg
 }
 
+// withTLSFiles replaces the client certificate and certificate authority of the supplied config with the files in
+// the connection options, when set. Inline data is cleared since it takes precedence over files.
+func withTLSFiles(cfg *rest.Config, opts ConnectOpts) *rest.Config {
+	if opts.CertFile != "" {
+		cfg.TLSClientConfig.CertFile = opts.CertFile
+		cfg.TLSClientConfig.CertData = nil
+		cfg.TLSClientConfig.KeyFile = opts.KeyFile
+		cfg.TLSClientConfig.KeyData = nil
+	}
+	if opts.CAFile != "" {
+		cfg.TLSClientConfig.CAFile = opts.CAFile
+		cfg.TLSClientConfig.CAData = nil
+	}
+	return cfg
+}
+
 func (c *Config) getRESTConfig(opts ConnectOpts) (*rest.Config, error) {
 	if opts.ForceContext == ForceInClusterContext {
 		sio.Warnln("force in-cluster config")
-		return rest.InClusterConfig()
+		restConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, err
+		}
+		return withTLSFiles(restConfig, opts), nil
 	}
 	if err := c.setupOverrides(opts); err != nil {
 		return nil, err
@@ -156,7 +182,7 @@ func (c *Config) getRESTConfig(opts ConnectOpts) (*rest.Config, error) {
 	if err != nil {
 		return nil, err
 	}
-	return c.withQPS(restConfig), nil
+	return c.withQPS(withTLSFiles(restConfig, opts)), nil
 }
 
 // KubeAttributes is a collection k8s attributes pertaining to an connection.
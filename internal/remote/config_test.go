@@ -23,6 +23,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -122,3 +123,21 @@ func TestConfigResolution(t *testing.T) {
 		})
 	}
 }
+
+func TestWithTLSFiles(t *testing.T) {
+	base := func() *rest.Config {
+		return &rest.Config{TLSClientConfig: rest.TLSClientConfig{
+			CertData: []byte("cert"),
+			KeyData:  []byte("key"),
+			CAData:   []byte("ca"),
+		}}
+	}
+	cfg := withTLSFiles(base(), ConnectOpts{})
+	assert.Equal(t, base(), cfg)
+
+	cfg = withTLSFiles(base(), ConnectOpts{CertFile: "client.crt", KeyFile: "client.key"})
+	assert.Equal(t, rest.TLSClientConfig{CertFile: "client.crt", KeyFile: "client.key", CAData: []byte("ca")}, cfg.TLSClientConfig)
+
+	cfg = withTLSFiles(base(), ConnectOpts{CAFile: "ca.crt"})
+	assert.Equal(t, rest.TLSClientConfig{CertData: []byte("cert"), KeyData: []byte("key"), CAFile: "ca.crt"}, cfg.TLSClientConfig)
+}
@@ -19,6 +19,7 @@ package k8smeta
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"sync"
 
 	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
@@ -149,3 +150,26 @@ func (ss *ServerSchema) openAPIResources() (openapi.Resources, *validators, erro
 	}
 	return handle(res, nil)
 }
+
+// fileSchemaDiscovery implements SchemaDiscovery using an OpenAPI document stored in a file.
+type fileSchemaDiscovery struct {
+	file string
+}
+
+func (f fileSchemaDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	b, err := ioutil.ReadFile(f.file)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := openapi_v2.ParseDocument(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s", f.file)
+	}
+	return doc, nil
+}
+
+// NewFileSchema returns a schema that supplies validators from the OpenAPI document in the supplied file.
+// The file may be in JSON or YAML format.
+func NewFileSchema(file string) *ServerSchema {
+	return NewServerSchema(fileSchemaDiscovery{file: file})
+}
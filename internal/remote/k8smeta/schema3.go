@@ -0,0 +1,321 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package k8smeta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/spec3"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+)
+
+const (
+	openAPIV3Root     = "/openapi/v3"
+	componentsPrefix  = "#/components/schemas/"
+	extGVK            = "x-kubernetes-group-version-kind"
+	extIntOrString    = "x-kubernetes-int-or-string"
+	extPreserveFields = "x-kubernetes-preserve-unknown-fields"
+)
+
+// SchemaV3Discovery is the minimal interface needed to discover the OpenAPI v3 schemas of a server.
+type SchemaV3Discovery interface {
+	// OpenAPIV3 returns the raw document at the supplied server-relative URL.
+	OpenAPIV3(ctx context.Context, url string) ([]byte, error)
+}
+
+// v3Validator implements Validator using a schema that has all references expanded.
+type v3Validator struct {
+	schema *spec.Schema
+}
+
+func (v *v3Validator) Validate(obj *unstructured.Unstructured) []error {
+	gvk := obj.GroupVersionKind()
+	sv := validate.NewSchemaValidator(v.schema, nil, fmt.Sprintf("%s.%s", gvk.Version, gvk.Kind), strfmt.Default)
+	return sv.Validate(obj.UnstructuredContent()).Errors
+}
+
+// v3Doc is the cached result of retrieving the OpenAPI v3 document for a group version. The document is
+// fetched once, without holding the schema lock such that other group versions can be fetched concurrently.
+type v3Doc struct {
+	once    sync.Once
+	schemas map[string]*spec.Schema
+	err     error
+}
+
+// ServerSchemaV3 supplies validators backed by the OpenAPI v3 schemas of a Kubernetes server. It falls back
+// to a v2 schema when the server does not publish v3 schemas.
+type ServerSchemaV3 struct {
+	disco    SchemaV3Discovery
+	fallback *ServerSchema
+	warnFn   func(...interface{})
+
+	pathsOnce sync.Once         // fetches the list of group version paths
+	paths     map[string]string // server-relative URLs keyed by group version path, nil if unsupported
+
+	l     sync.Mutex
+	docs  map[string]*v3Doc                         // documents keyed by group version path
+	cache map[schema.GroupVersionKind]*schemaResult // validators keyed by GVK
+}
+
+// NewServerSchemaV3 returns a schema that supplies validators from the OpenAPI v3 documents of a server. The
+// fallback schema is used when v3 documents are not available and the warning function is used to report this.
+func NewServerSchemaV3(disco SchemaV3Discovery, fallback *ServerSchema, warnFn func(...interface{})) *ServerSchemaV3 {
+	if warnFn == nil {
+		warnFn = func(...interface{}) {}
+	}
+	return &ServerSchemaV3{
+		disco:    disco,
+		fallback: fallback,
+		warnFn:   warnFn,
+		docs:     map[string]*v3Doc{},
+		cache:    map[schema.GroupVersionKind]*schemaResult{},
+	}
+}
+
+// ValidatorFor returns a validator for the supplied GroupVersionKind.
+func (ss *ServerSchemaV3) ValidatorFor(ctx context.Context, gvk schema.GroupVersionKind) (Validator, error) {
+	ss.pathsOnce.Do(func() {
+		paths, err := ss.loadPaths(ctx)
+		if err != nil {
+			ss.warnFn("OpenAPI v3 schemas not available, falling back to v2:", err)
+		}
+		ss.paths = paths
+	})
+	if ss.paths == nil {
+		return ss.fallback.ValidatorFor(ctx, gvk)
+	}
+	ss.l.Lock()
+	sr := ss.cache[gvk]
+	ss.l.Unlock()
+	if sr != nil {
+		return sr.validator, sr.err
+	}
+	v, err := ss.validatorFor(ctx, gvk)
+	ss.l.Lock()
+	defer ss.l.Unlock()
+	if existing := ss.cache[gvk]; existing != nil {
+		return existing.validator, existing.err
+	}
+	ss.cache[gvk] = &schemaResult{validator: v, err: err}
+	return v, err
+}
+
+// loadPaths returns the server-relative URLs of the documents for every group version that the server supports.
+func (ss *ServerSchemaV3) loadPaths(ctx context.Context) (map[string]string, error) {
+	b, err := ss.disco.OpenAPIV3(ctx, openAPIV3Root)
+	if err != nil {
+		return nil, err
+	}
+	var root struct {
+		Paths map[string]struct {
+			ServerRelativeURL string `json:"serverRelativeURL"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(b, &root); err != nil {
+		return nil, errors.Wrap(err, "unmarshal OpenAPI v3 paths")
+	}
+	if len(root.Paths) == 0 {
+		return nil, fmt.Errorf("no group versions found in OpenAPI v3 paths")
+	}
+	ret := map[string]string{}
+	for k, v := range root.Paths {
+		u := v.ServerRelativeURL
+		if u == "" {
+			u = openAPIV3Root + "/" + k
+		}
+		ret[k] = u
+	}
+	return ret, nil
+}
+
+// gvPath returns the OpenAPI v3 path for the group version of the supplied GVK.
+func gvPath(gvk schema.GroupVersionKind) string {
+	if gvk.Group == "" {
+		return "api/" + gvk.Version
+	}
+	return "apis/" + gvk.Group + "/" + gvk.Version
+}
+
+// doc returns the document for the supplied group version path, fetching it if needed. Callers for the
+// same path wait for a single fetch.
+func (ss *ServerSchemaV3) doc(ctx context.Context, path string) *v3Doc {
+	ss.l.Lock()
+	d := ss.docs[path]
+	if d == nil {
+		d = &v3Doc{}
+		ss.docs[path] = d
+	}
+	ss.l.Unlock()
+	d.once.Do(func() {
+		b, err := ss.disco.OpenAPIV3(ctx, ss.paths[path])
+		if err != nil {
+			d.err = errors.Wrapf(err, "OpenAPI v3 doc for %s", path)
+			return
+		}
+		var o spec3.OpenAPI
+		if err := json.Unmarshal(b, &o); err != nil {
+			d.err = errors.Wrapf(err, "unmarshal OpenAPI v3 doc for %s", path)
+			return
+		}
+		if o.Components != nil {
+			d.schemas = o.Components.Schemas
+		}
+	})
+	return d
+}
+
+func (ss *ServerSchemaV3) validatorFor(ctx context.Context, gvk schema.GroupVersionKind) (Validator, error) {
+	path := gvPath(gvk)
+	if _, ok := ss.paths[path]; !ok {
+		return nil, ErrSchemaNotFound
+	}
+	d := ss.doc(ctx, path)
+	if d.err != nil {
+		return nil, d.err
+	}
+	for name, s := range d.schemas {
+		if !hasGVK(s, gvk) {
+			continue
+		}
+		expanded, err := expandSchema(s, d.schemas, map[string]bool{name: true})
+		if err != nil {
+			return nil, errors.Wrapf(err, "expand schema %s", name)
+		}
+		return &v3Validator{schema: expanded}, nil
+	}
+	return nil, ErrSchemaNotFound
+}
+
+// hasGVK returns true if the supplied schema is declared as the schema for the GVK.
+func hasGVK(s *spec.Schema, gvk schema.GroupVersionKind) bool {
+	var list []schema.GroupVersionKind
+	if err := s.Extensions.GetObject(extGVK, &list); err != nil {
+		return false
+	}
+	for _, l := range list {
+		if l == gvk {
+			return true
+		}
+	}
+	return false
+}
+
+// expandSchema returns a copy of the supplied schema with all references to component schemas replaced by
+// the schemas they refer to, since the validator does not support references. Recursive references are
+// replaced with an empty schema that allows any value. Int-or-string fields are changed to accept both types,
+// objects with properties are closed to unknown fields unless they explicitly preserve them and single element
+// allOf wrappers are removed.
+func expandSchema(s *spec.Schema, all map[string]*spec.Schema, visiting map[string]bool) (*spec.Schema, error) {
+	if ref := s.Ref.String(); ref != "" {
+		name := strings.TrimPrefix(ref, componentsPrefix)
+		if visiting[name] {
+			return &spec.Schema{}, nil
+		}
+		target, ok := all[name]
+		if !ok {
+			return nil, fmt.Errorf("unresolved reference %s", ref)
+		}
+		visiting[name] = true
+		defer delete(visiting, name)
+		return expandSchema(target, all, visiting)
+	}
+	ret := *s
+	expandList := func(list []spec.Schema) ([]spec.Schema, error) {
+		if list == nil {
+			return nil, nil
+		}
+		out := make([]spec.Schema, 0, len(list))
+		for i := range list {
+			e, err := expandSchema(&list[i], all, visiting)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, *e)
+		}
+		return out, nil
+	}
+	var err error
+	if ret.AllOf, err = expandList(s.AllOf); err != nil {
+		return nil, err
+	}
+	if ret.AnyOf, err = expandList(s.AnyOf); err != nil {
+		return nil, err
+	}
+	if ret.OneOf, err = expandList(s.OneOf); err != nil {
+		return nil, err
+	}
+	if s.Not != nil {
+		if ret.Not, err = expandSchema(s.Not, all, visiting); err != nil {
+			return nil, err
+		}
+	}
+	if s.Items != nil {
+		items := &spec.SchemaOrArray{}
+		if s.Items.Schema != nil {
+			if items.Schema, err = expandSchema(s.Items.Schema, all, visiting); err != nil {
+				return nil, err
+			}
+		}
+		if items.Schemas, err = expandList(s.Items.Schemas); err != nil {
+			return nil, err
+		}
+		ret.Items = items
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		e, err := expandSchema(s.AdditionalProperties.Schema, all, visiting)
+		if err != nil {
+			return nil, err
+		}
+		ret.AdditionalProperties = &spec.SchemaOrBool{Allows: true, Schema: e}
+	}
+	if s.Properties != nil {
+		ret.Properties = make(map[string]spec.Schema, len(s.Properties))
+		for k, p := range s.Properties {
+			p := p
+			e, err := expandSchema(&p, all, visiting)
+			if err != nil {
+				return nil, err
+			}
+			ret.Properties[k] = *e
+		}
+		preserve, _ := s.Extensions.GetBool(extPreserveFields)
+		if ret.AdditionalProperties == nil && !preserve {
+			ret.AdditionalProperties = &spec.SchemaOrBool{Allows: false}
+		}
+	}
+	if ios, _ := s.Extensions.GetBool(extIntOrString); ios || s.Format == "int-or-string" {
+		ret.Type = nil
+		ret.Format = ""
+	}
+	// references with sibling attributes like defaults are wrapped in a single element allOf, unwrap these
+	// such that errors are reported against the referenced schema.
+	if len(ret.AllOf) == 1 && len(ret.Type) == 0 && ret.Properties == nil {
+		inner := ret.AllOf[0]
+		inner.Nullable = inner.Nullable || ret.Nullable
+		return &inner, nil
+	}
+	return &ret, nil
+}
@@ -0,0 +1,194 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package k8smeta
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// v3sd serves OpenAPI v3 documents from the testdata directory. Fetches of URLs that have a channel in
+// the block map wait for the channel to be closed.
+type v3sd struct {
+	l     sync.Mutex
+	urls  []string
+	block map[string]chan struct{}
+}
+
+func (d *v3sd) OpenAPIV3(ctx context.Context, url string) ([]byte, error) {
+	d.l.Lock()
+	d.urls = append(d.urls, url)
+	ch := d.block[url]
+	d.l.Unlock()
+	if ch != nil {
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	files := map[string]string{
+		"/openapi/v3":                              "root.json",
+		"/openapi/v3/api/v1?hash=ABC":              "api-v1.json",
+		"/openapi/v3/apis/example.com/v1?hash=DEF": "apis-example.com-v1.json",
+	}
+	f, ok := files[url]
+	if !ok {
+		return nil, fmt.Errorf("not found: %s", url)
+	}
+	return ioutil.ReadFile(filepath.Join("testdata", "openapi-v3", f))
+}
+
+type badV3sd struct{}
+
+func (d badV3sd) OpenAPIV3(_ context.Context, url string) ([]byte, error) {
+	return nil, fmt.Errorf("the server could not find the requested resource")
+}
+
+func widget(spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata": map[string]interface{}{
+			"name": "w1",
+		},
+		"spec": spec,
+	}}
+}
+
+func errorStrings(errs []error) string {
+	var ret []string
+	for _, e := range errs {
+		ret = append(ret, e.Error())
+	}
+	return strings.Join(ret, "\n")
+}
+
+func TestV3Validator(t *testing.T) {
+	a := assert.New(t)
+	d := &v3sd{}
+	ss := NewServerSchemaV3(d, nil, nil)
+	ctx := context.TODO()
+	v, err := ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+	require.NoError(t, err)
+	errs := v.Validate(loadObject(t, "ns-good.json").ToUnstructured())
+	require.Nil(t, errs)
+
+	errs = v.Validate(loadObject(t, "ns-bad.json").ToUnstructured())
+	require.Equal(t, 1, len(errs), errorStrings(errs))
+	a.Contains(errs[0].Error(), "foo")
+
+	_, err = ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "FooBar"})
+	a.Equal(ErrSchemaNotFound, err)
+	_, err = ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	a.Equal(ErrSchemaNotFound, err)
+
+	v, err = ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	require.NoError(t, err)
+	errs = v.Validate(widget(map[string]interface{}{
+		"size":   int64(3),
+		"port":   "http",
+		"config": map[string]interface{}{"anything": "goes"},
+		"child":  map[string]interface{}{"spec": map[string]interface{}{"whatever": true}},
+	}))
+	require.Nil(t, errs, errorStrings(errs))
+	errs = v.Validate(widget(map[string]interface{}{"size": int64(3), "port": int64(8080)}))
+	require.Nil(t, errs, errorStrings(errs))
+
+	errs = v.Validate(widget(map[string]interface{}{"size": "big", "colour": "red"}))
+	require.Equal(t, 2, len(errs), errorStrings(errs))
+	a.Contains(errorStrings(errs), "v1.Widget.spec.size in body must be of type integer")
+	a.Contains(errorStrings(errs), "colour")
+
+	errs = v.Validate(widget(map[string]interface{}{"size": int64(0)}))
+	require.Equal(t, 1, len(errs), errorStrings(errs))
+	a.Contains(errs[0].Error(), "v1.Widget.spec.size in body should be greater than or equal to 1")
+
+	// root and each group version document are fetched exactly once
+	a.Equal([]string{"/openapi/v3", "/openapi/v3/api/v1?hash=ABC", "/openapi/v3/apis/example.com/v1?hash=DEF"}, d.urls)
+}
+
+func TestV3ValidatorConcurrentFetch(t *testing.T) {
+	block := make(chan struct{})
+	d := &v3sd{block: map[string]chan struct{}{"/openapi/v3/api/v1?hash=ABC": block}}
+	ss := NewServerSchemaV3(d, nil, nil)
+	ctx := context.TODO()
+	ns := schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = ss.ValidatorFor(ctx, ns)
+		}(i)
+	}
+	// a slow fetch for one group version does not block validators for other group versions
+	_, err := ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"})
+	require.NoError(t, err)
+	close(block)
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+	// callers waiting on the same group version share a single fetch
+	count := 0
+	for _, u := range d.urls {
+		if u == "/openapi/v3/api/v1?hash=ABC" {
+			count++
+		}
+	}
+	assert.Equal(t, 1, count)
+}
+
+func TestV3ValidatorCanceled(t *testing.T) {
+	d := &v3sd{block: map[string]chan struct{}{"/openapi/v3/api/v1?hash=ABC": make(chan struct{})}}
+	ss := NewServerSchemaV3(d, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+}
+
+func TestV3ValidatorFallback(t *testing.T) {
+	var warnings []string
+	ss := NewServerSchemaV3(badV3sd{}, NewServerSchema(sd{}), func(args ...interface{}) {
+		warnings = append(warnings, fmt.Sprint(args...))
+	})
+	ctx := context.TODO()
+	for i := 0; i < 2; i++ {
+		v, err := ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+		require.NoError(t, err)
+		errs := v.Validate(loadObject(t, "ns-bad.json").ToUnstructured())
+		require.Equal(t, 1, len(errs))
+		assert.Contains(t, errs[0].Error(), `unknown field "foo"`)
+	}
+	require.Equal(t, 1, len(warnings))
+	assert.Contains(t, warnings[0], "OpenAPI v3 schemas not available, falling back to v2:")
+}
@@ -62,3 +62,33 @@ func TestMetadataValidator(t *testing.T) {
 	a.Equal(ErrSchemaNotFound, err)
 
 }
+
+func TestFileValidator(t *testing.T) {
+	a := assert.New(t)
+	ss := NewFileSchema(filepath.Join("testdata", "swagger.json"))
+	ctx := context.TODO()
+	v, err := ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+	require.Nil(t, err)
+	errs := v.Validate(loadObject(t, "ns-good.json").ToUnstructured())
+	require.Nil(t, errs)
+
+	errs = v.Validate(loadObject(t, "ns-bad.json").ToUnstructured())
+	require.NotNil(t, errs)
+	a.Equal(1, len(errs))
+	a.Contains(errs[0].Error(), `unknown field "foo"`)
+
+	_, err = ss.ValidatorFor(ctx, schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"})
+	require.NotNil(t, err)
+	a.Equal(ErrSchemaNotFound, err)
+}
+
+func TestFileValidatorBadFile(t *testing.T) {
+	ss := NewFileSchema(filepath.Join("testdata", "ns-good.json"))
+	_, err := ss.ValidatorFor(context.TODO(), schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+	require.NotNil(t, err)
+
+	ss = NewFileSchema(filepath.Join("testdata", "missing.json"))
+	_, err = ss.ValidatorFor(context.TODO(), schema.GroupVersionKind{Group: "", Version: "v1", Kind: "Namespace"})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "missing.json")
+}
@@ -0,0 +1,74 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// GetMergedVersionForDiff returns the object that the server would have if the desired object were applied to the
+// supplied live object. The patch is computed using the same 3-way merge that apply uses, with the pristine version
+// of the live object as the original configuration. Fields that are not managed by qbec, such as defaults set by
+// the server, are left untouched such that a diff of the live object against the returned object only shows changes
+// to managed fields. The supplied live object is not modified.
+func GetMergedVersionForDiff(live *unstructured.Unstructured, desired model.K8sObject) (*unstructured.Unstructured, error) {
+	live = live.DeepCopy()
+	p := patcher{cfgProvider: originalConfiguration, overwrite: true}
+	result, err := p.getPatchContents(live, desired)
+	if err != nil {
+		return nil, err
+	}
+	if result.SkipReason != "" {
+		return live, nil
+	}
+	serverBytes, err := runtime.Encode(unstructured.UnstructuredJSONScheme, live)
+	if err != nil {
+		return nil, errors.Wrap(err, "serialize server config")
+	}
+	var merged []byte
+	switch result.Kind {
+	case types.MergePatchType:
+		merged, err = jsonpatch.MergePatch(serverBytes, result.patch)
+	case types.StrategicMergePatchType:
+		gvk := live.GroupVersionKind()
+		versionedObject, e := scheme.Scheme.New(gvk)
+		if e != nil {
+			return nil, errors.Wrap(e, fmt.Sprintf("getting instance of versioned object for %v", gvk))
+		}
+		merged, err = strategicpatch.StrategicMergePatch(serverBytes, result.patch, versionedObject)
+	default:
+		return nil, fmt.Errorf("unsupported patch type %s", result.Kind)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "apply patch")
+	}
+	var ret unstructured.Unstructured
+	if err := json.Unmarshal(merged, &ret.Object); err != nil {
+		return nil, errors.Wrap(err, "unmarshal merged object")
+	}
+	return &ret, nil
+}
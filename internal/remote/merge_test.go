@@ -0,0 +1,241 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func toUnstructured(t *testing.T, s string) *unstructured.Unstructured {
+	var data map[string]interface{}
+	err := yaml.Unmarshal([]byte(s), &data)
+	require.NoError(t, err)
+	return &unstructured.Unstructured{Object: data}
+}
+
+const (
+	localDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d1
+  namespace: ns1
+spec:
+  selector:
+    matchLabels:
+      app: d1
+  template:
+    metadata:
+      labels:
+        app: d1
+    spec:
+      containers:
+      - name: main
+        image: nginx:%s
+        ports:
+        - containerPort: 80
+`
+	liveDeployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: d1
+  namespace: ns1
+  annotations:
+    deployment.kubernetes.io/revision: "1"
+spec:
+  progressDeadlineSeconds: 600
+  replicas: 1
+  revisionHistoryLimit: 10
+  selector:
+    matchLabels:
+      app: d1
+  strategy:
+    rollingUpdate:
+      maxSurge: 25%
+      maxUnavailable: 25%
+    type: RollingUpdate
+  template:
+    metadata:
+      labels:
+        app: d1
+    spec:
+      containers:
+      - name: main
+        image: nginx:latest
+        imagePullPolicy: Always
+        ports:
+        - containerPort: 80
+          protocol: TCP
+        terminationMessagePath: /dev/termination-log
+        terminationMessagePolicy: File
+      dnsPolicy: ClusterFirst
+      restartPolicy: Always
+      schedulerName: default-scheduler
+      terminationGracePeriodSeconds: 30
+`
+	localService = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: s1
+  namespace: ns1
+spec:
+  selector:
+    app: d1
+  ports:
+  - port: 80
+    targetPort: %d
+`
+	liveService = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: s1
+  namespace: ns1
+spec:
+  clusterIP: 10.0.0.12
+  selector:
+    app: d1
+  ports:
+  - port: 80
+    protocol: TCP
+    targetPort: 8080
+  sessionAffinity: None
+  type: ClusterIP
+`
+	localCustom = `
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w1
+  namespace: ns1
+spec:
+  size: %d
+`
+	liveCustom = `
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: w1
+  namespace: ns1
+spec:
+  size: 1
+  color: blue
+`
+)
+
+func TestMergedVersionForDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		local    string
+		live     string
+		asserter func(t *testing.T, live, merged *unstructured.Unstructured)
+	}{
+		{
+			name:  "deployment-defaults",
+			local: fmt.Sprintf(localDeployment, "latest"),
+			live:  liveDeployment,
+			asserter: func(t *testing.T, live, merged *unstructured.Unstructured) {
+				assert.Equal(t, live.Object, merged.Object)
+			},
+		},
+		{
+			name:  "deployment-change",
+			local: fmt.Sprintf(localDeployment, "1.19"),
+			live:  liveDeployment,
+			asserter: func(t *testing.T, live, merged *unstructured.Unstructured) {
+				containers, _, _ := unstructured.NestedSlice(merged.Object, "spec", "template", "spec", "containers")
+				require.Equal(t, 1, len(containers))
+				c := containers[0].(map[string]interface{})
+				a := assert.New(t)
+				a.Equal("nginx:1.19", c["image"])
+				a.Equal("Always", c["imagePullPolicy"])
+				a.EqualValues([]interface{}{map[string]interface{}{"containerPort": float64(80), "protocol": "TCP"}}, c["ports"])
+				v, _, _ := unstructured.NestedFieldNoCopy(merged.Object, "spec", "progressDeadlineSeconds")
+				a.EqualValues(600, v)
+			},
+		},
+		{
+			name:  "service-defaults",
+			local: fmt.Sprintf(localService, 8080),
+			live:  liveService,
+			asserter: func(t *testing.T, live, merged *unstructured.Unstructured) {
+				assert.Equal(t, live.Object, merged.Object)
+			},
+		},
+		{
+			name:  "service-change",
+			local: fmt.Sprintf(localService, 9090),
+			live:  liveService,
+			asserter: func(t *testing.T, live, merged *unstructured.Unstructured) {
+				ports, _, _ := unstructured.NestedSlice(merged.Object, "spec", "ports")
+				require.Equal(t, 1, len(ports))
+				p := ports[0].(map[string]interface{})
+				a := assert.New(t)
+				a.EqualValues(9090, p["targetPort"])
+				a.Equal("TCP", p["protocol"])
+				ip, _, _ := unstructured.NestedString(merged.Object, "spec", "clusterIP")
+				a.Equal("10.0.0.12", ip)
+			},
+		},
+		{
+			name:  "unregistered",
+			local: fmt.Sprintf(localCustom, 2),
+			live:  liveCustom,
+			asserter: func(t *testing.T, live, merged *unstructured.Unstructured) {
+				a := assert.New(t)
+				size, _, _ := unstructured.NestedFieldNoCopy(merged.Object, "spec", "size")
+				a.EqualValues(2, size)
+				color, _, _ := unstructured.NestedString(merged.Object, "spec", "color")
+				a.Equal("blue", color)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			local := toUnstructured(t, test.local)
+			live := toUnstructured(t, test.live)
+			orig := live.DeepCopy()
+			merged, err := GetMergedVersionForDiff(live, model.NewK8sObject(local.Object))
+			require.NoError(t, err)
+			assert.Equal(t, orig.Object, live.Object)
+			test.asserter(t, live, merged)
+		})
+	}
+}
+
+func TestMergedVersionForDiffRemovesManagedFields(t *testing.T) {
+	live := toUnstructured(t, liveCustom)
+	pristine := toUnstructured(t, liveCustom)
+	b, err := json.Marshal(pristine.Object)
+	require.NoError(t, err)
+	live.SetAnnotations(map[string]string{kubectlLastConfig: string(b)})
+	local := toUnstructured(t, fmt.Sprintf(localCustom, 1))
+	merged, err := GetMergedVersionForDiff(live, model.NewK8sObject(local.Object))
+	require.NoError(t, err)
+	_, found, _ := unstructured.NestedString(merged.Object, "spec", "color")
+	assert.False(t, found)
+}
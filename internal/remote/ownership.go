@@ -0,0 +1,69 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// unmanagedOwner is the owner reported for existing objects that do not have qbec labels.
+const unmanagedOwner = "not managed by qbec"
+
+// ownerOf returns a description of the qbec app, environment and tag that manage an object having the supplied
+// labels, or unmanagedOwner if there is no application label.
+func ownerOf(labels map[string]string) string {
+	app := labels[model.QbecNames.ApplicationLabel]
+	if app == "" {
+		return unmanagedOwner
+	}
+	s := fmt.Sprintf("managed by application %s, environment %s", app, labels[model.QbecNames.EnvironmentLabel])
+	if tag := labels[model.QbecNames.TagLabel]; tag != "" {
+		s += ", tag " + tag
+	}
+	return s
+}
+
+// checkOwnership compares the qbec labels of a local object with those of the remote object that it will
+// update. When adopt is true, the previous owner of a remote object that is not managed by the same app, environment
+// and tag is returned. Otherwise, such an object is updated without reporting its owner, unless strict is true, in
+// which case a remote object managed by a different app, environment or tag is an error. Remote objects not managed
+// by qbec are never an error.
+func checkOwnership(local model.K8sLocalObject, remObj *unstructured.Unstructured, adopt bool, strict bool) (string, error) {
+	ll, rl := local.ToUnstructured().GetLabels(), remObj.GetLabels()
+	same := true
+	for _, l := range []string{model.QbecNames.ApplicationLabel, model.QbecNames.EnvironmentLabel, model.QbecNames.TagLabel} {
+		if ll[l] != rl[l] {
+			same = false
+		}
+	}
+	if same {
+		return "", nil
+	}
+	owner := ownerOf(rl)
+	switch {
+	case adopt:
+		return owner, nil
+	case strict && owner != unmanagedOwner:
+		return "", errors.Errorf("object is %s, use --adopt to take ownership", owner)
+	default:
+		return "", nil
+	}
+}
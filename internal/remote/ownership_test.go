@@ -0,0 +1,111 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"testing"
+
+	"github.com/splunk/qbec/internal/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func ownershipObject(labels map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":   "cm",
+			"labels": labels,
+		},
+	}}
+}
+
+func TestCheckOwnership(t *testing.T) {
+	local := model.NewK8sLocalObject(ownershipObject(map[string]interface{}{}).Object, model.LocalAttrs{App: "app", Component: "c", Env: "dev"})
+	tests := []struct {
+		name    string
+		labels  map[string]interface{}
+		adopt   bool
+		strict  bool
+		owner   string
+		errText string
+	}{
+		{
+			name:   "same owner",
+			labels: map[string]interface{}{model.QbecNames.ApplicationLabel: "app", model.QbecNames.EnvironmentLabel: "dev"},
+		},
+		{
+			name: "unmanaged",
+		},
+		{
+			name:  "unmanaged adopt",
+			adopt: true,
+			owner: "not managed by qbec",
+		},
+		{
+			name:   "unmanaged strict",
+			strict: true,
+		},
+		{
+			name:   "other env",
+			labels: map[string]interface{}{model.QbecNames.ApplicationLabel: "app", model.QbecNames.EnvironmentLabel: "prod"},
+		},
+		{
+			name:    "other env strict",
+			labels:  map[string]interface{}{model.QbecNames.ApplicationLabel: "app", model.QbecNames.EnvironmentLabel: "prod"},
+			strict:  true,
+			errText: "object is managed by application app, environment prod, use --adopt to take ownership",
+		},
+		{
+			name:   "other env strict adopt",
+			labels: map[string]interface{}{model.QbecNames.ApplicationLabel: "app", model.QbecNames.EnvironmentLabel: "prod"},
+			strict: true,
+			adopt:  true,
+			owner:  "managed by application app, environment prod",
+		},
+		{
+			name: "other tag adopt",
+			labels: map[string]interface{}{
+				model.QbecNames.ApplicationLabel: "app",
+				model.QbecNames.EnvironmentLabel: "dev",
+				model.QbecNames.TagLabel:         "t1",
+			},
+			adopt: true,
+			owner: "managed by application app, environment dev, tag t1",
+		},
+		{
+			name:   "other app adopt",
+			labels: map[string]interface{}{model.QbecNames.ApplicationLabel: "other", model.QbecNames.EnvironmentLabel: "dev"},
+			adopt:  true,
+			owner:  "managed by application other, environment dev",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			owner, err := checkOwnership(local, ownershipObject(test.labels), test.adopt, test.strict)
+			if test.errText != "" {
+				require.Error(t, err)
+				assert.Equal(t, test.errText, err.Error())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.owner, owner)
+		})
+	}
+}
@@ -64,6 +64,8 @@ type patcher struct {
 	overwrite     bool
 	backOff       clockwork.Clock
 	openAPILookup openAPILookup
+	dryRun        []string // dry-run option for patch requests
+	fieldManager  string   // field manager for patch requests
 }
 
 type serialized struct {
@@ -220,7 +222,7 @@ func (p *patcher) patchSimple(ctx context.Context, serverObj *unstructured.Unstr
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("error getting update interface for %v", gvk))
 	}
-	_, err = ri.Patch(ctx, serverObj.GetName(), result.Kind, result.patch, metav1.PatchOptions{})
+	_, err = ri.Patch(ctx, serverObj.GetName(), result.Kind, result.patch, metav1.PatchOptions{DryRun: p.dryRun, FieldManager: p.fieldManager})
 	return result, err
 }
 
@@ -187,3 +187,22 @@ func getPristineVersion(obj *unstructured.Unstructured, includeFallback bool) (*
 func GetPristineVersionForDiff(obj *unstructured.Unstructured) (*unstructured.Unstructured, string) {
 	return getPristineVersion(obj, true)
 }
+
+// originalConfiguration returns the serialized pristine version of the supplied server object for use in a
+// 3-way merge. When no pristine version is found, it returns an object that only has basic metadata.
+func originalConfiguration(obj *unstructured.Unstructured) ([]byte, error) {
+	pristine, _ := getPristineVersion(obj, false)
+	if pristine == nil {
+		p := map[string]interface{}{
+			"kind":       obj.GetKind(),
+			"apiVersion": obj.GetAPIVersion(),
+			"metadata": map[string]interface{}{
+				"name": obj.GetName(),
+			},
+		}
+		pb, _ := json.Marshal(p)
+		return pb, nil
+	}
+	b, _ := json.Marshal(pristine)
+	return b, nil
+}
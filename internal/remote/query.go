@@ -49,6 +49,19 @@ type objectLister struct {
 	queryConfig
 }
 
+// listSelector returns the label selector for objects in the GC scope of the supplied query, which is the GC tag
+// if one is set and the application otherwise, along with the environment and tag.
+func listSelector(scope ListQueryConfig) string {
+	ls := fmt.Sprintf("%s=%s,%s=%s", model.QbecNames.ApplicationLabel, scope.Application, model.QbecNames.EnvironmentLabel, scope.Environment)
+	if scope.GCTag != "" {
+		ls = fmt.Sprintf("%s=%s,%s=%s", model.QbecNames.GCTagLabel, scope.GCTag, model.QbecNames.EnvironmentLabel, scope.Environment)
+	}
+	if scope.Tag == "" {
+		return fmt.Sprintf("%s,!%s", ls, model.QbecNames.TagLabel)
+	}
+	return fmt.Sprintf("%s,%s=%s", ls, model.QbecNames.TagLabel, scope.Tag)
+}
+
 func (o *objectLister) listObjectsOfType(ctx context.Context, gvk schema.GroupVersionKind, namespace string) ([]*basicObject, error) {
 	startTime := time.Now()
 	defer func() {
@@ -60,14 +73,8 @@ func (o *objectLister) listObjectsOfType(ctx context.Context, gvk schema.GroupVe
 	if err != nil {
 		return nil, errors.Wrap(err, fmt.Sprintf("get resource interface for %s", gvk))
 	}
-	ls := fmt.Sprintf("%s=%s,%s=%s", model.QbecNames.ApplicationLabel, o.scope.Application, model.QbecNames.EnvironmentLabel, o.scope.Environment)
-	if o.scope.Tag == "" {
-		ls = fmt.Sprintf("%s,!%s", ls, model.QbecNames.TagLabel)
-	} else {
-		ls = fmt.Sprintf("%s,%s=%s", ls, model.QbecNames.TagLabel, o.scope.Tag)
-	}
 	initialOpts := &metav1.ListOptions{
-		LabelSelector: ls,
+		LabelSelector: listSelector(o.scope),
 		Limit:         o.scope.Limit,
 	}
 	var list = &unstructured.UnstructuredList{}
@@ -131,6 +138,7 @@ outer:
 			component: anns[model.QbecNames.ComponentAnnotation],
 			env:       labels[model.QbecNames.EnvironmentLabel],
 			anns:      un.GetAnnotations(),
+			created:   un.GetCreationTimestamp().Time,
 		}
 		ret = append(ret, mm)
 	}
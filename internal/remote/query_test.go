@@ -101,3 +101,34 @@ func TestListPagination(t *testing.T) {
 		//	t.Fatalf("expected items to be %d but found %d", totalItemsInList, actual)
 	}
 }
+
+func TestListSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    ListQueryConfig
+		expected string
+	}{
+		{
+			name:     "app",
+			scope:    ListQueryConfig{Application: "app", Environment: "dev"},
+			expected: "qbec.io/application=app,qbec.io/environment=dev,!qbec.io/tag",
+		},
+		{
+			name:     "app-tag",
+			scope:    ListQueryConfig{Application: "app", Environment: "dev", Tag: "t1"},
+			expected: "qbec.io/application=app,qbec.io/environment=dev,qbec.io/tag=t1",
+		},
+		{
+			name:     "gc-tag",
+			scope:    ListQueryConfig{Application: "app", Environment: "dev", GCTag: "shared"},
+			expected: "qbec.io/gc-tag=shared,qbec.io/environment=dev,!qbec.io/tag",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := listSelector(test.scope); got != test.expected {
+				t.Errorf("expected selector %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
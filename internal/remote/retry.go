@@ -0,0 +1,66 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"time"
+
+	"github.com/splunk/qbec/internal/sio"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const defaultRetryBackoff = time.Second
+
+// retrySleep waits for the supplied backoff before the next attempt, overridden in tests.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// isRetryable returns true if the supplied error is a transient API error for which the operation can be retried.
+func isRetryable(err error) bool {
+	return apiErrors.IsConflict(err) ||
+		apiErrors.IsServerTimeout(err) ||
+		apiErrors.IsTimeout(err) ||
+		apiErrors.IsTooManyRequests(err) ||
+		apiErrors.IsInternalError(err)
+}
+
+// withRetries runs the supplied function, retrying it up to the specified number of times for retryable errors.
+// The wait between attempts starts at the supplied backoff and doubles for every subsequent attempt. It stops
+// waiting and returns the context error when the supplied context is canceled.
+func withRetries(ctx context.Context, name string, retries int, backoff time.Duration, fn func() error) error {
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= retries || !isRetryable(err) {
+			return err
+		}
+		sio.Warnf("%s: retrying in %v after error: %v\n", name, backoff, err)
+		if err := retrySleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,104 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	apiErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWithRetries(t *testing.T) {
+	origSleep := retrySleep
+	defer func() { retrySleep = origSleep }()
+	var sleeps []time.Duration
+	retrySleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	conflict := errors.Wrap(apiErrors.NewConflict(gr, "foo", fmt.Errorf("modified")), "update")
+	forbidden := apiErrors.NewForbidden(gr, "foo", fmt.Errorf("no access"))
+
+	tests := []struct {
+		name     string
+		errs     []error
+		retries  int
+		calls    int
+		sleeps   []time.Duration
+		hasError bool
+	}{
+		{name: "success", errs: []error{nil}, retries: 3, calls: 1},
+		{name: "no retries", errs: []error{conflict}, retries: 0, calls: 1, hasError: true},
+		{
+			name:    "retry then success",
+			errs:    []error{conflict, apiErrors.NewTooManyRequests("busy", 1), apiErrors.NewServerTimeout(gr, "update", 1), nil},
+			retries: 3,
+			calls:   4,
+			sleeps:  []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name:     "retries exhausted",
+			errs:     []error{conflict, conflict, apiErrors.NewInternalError(fmt.Errorf("boom"))},
+			retries:  2,
+			calls:    3,
+			sleeps:   []time.Duration{time.Second, 2 * time.Second},
+			hasError: true,
+		},
+		{name: "not retryable", errs: []error{forbidden}, retries: 3, calls: 1, hasError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sleeps = nil
+			calls := 0
+			err := withRetries(context.Background(), "foo", test.retries, 0, func() error {
+				e := test.errs[calls]
+				calls++
+				return e
+			})
+			a := assert.New(t)
+			a.Equal(test.calls, calls)
+			a.Equal(test.sleeps, sleeps)
+			if test.hasError {
+				a.Error(err)
+			} else {
+				a.NoError(err)
+			}
+		})
+	}
+}
+
+func TestWithRetriesCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	calls := 0
+	err := withRetries(ctx, "foo", 3, time.Hour, func() error {
+		calls++
+		return apiErrors.NewConflict(gr, "foo", fmt.Errorf("modified"))
+	})
+	a := assert.New(t)
+	a.Equal(1, calls)
+	a.Equal(context.Canceled, err)
+}
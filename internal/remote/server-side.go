@@ -0,0 +1,159 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"context"
+	"encoding/json"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apiTypes "k8s.io/apimachinery/pkg/types"
+)
+
+// DefaultFieldManager is the field manager used for creates and updates when one is not specified.
+const DefaultFieldManager = "qbec"
+
+// fieldManagerOrDefault returns the supplied field manager, or the default field manager when it is empty.
+func fieldManagerOrDefault(fieldManager string) string {
+	if fieldManager == "" {
+		return DefaultFieldManager
+	}
+	return fieldManager
+}
+
+// this file contains the implementation of server-side apply, where the server computes the merge
+// and tracks field ownership instead of the client computing a three-way patch.
+
+// serverSideApply applies the supplied object using a server-side apply patch and returns the object
+// as persisted by the server. In dry-run mode, the server computes the result without persisting it.
+func (c *Client) serverSideApply(ctx context.Context, obj model.K8sLocalObject, fieldManager string, force bool, dryRun bool) (*unstructured.Unstructured, error) {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return nil, errors.Wrap(err, "json marshal")
+	}
+	ri, err := c.resourceInterfaceWithDefaultNs(obj.GroupVersionKind(), obj.GetNamespace())
+	if err != nil {
+		return nil, errors.Wrap(err, "get resource interface")
+	}
+	po := metav1.PatchOptions{FieldManager: fieldManagerOrDefault(fieldManager)}
+	if force {
+		po.Force = &force
+	}
+	if dryRun {
+		po.DryRun = []string{metav1.DryRunAll}
+	}
+	out, err := ri.Patch(ctx, obj.GetName(), apiTypes.ApplyPatchType, b, po)
+	if err != nil {
+		return nil, errors.Wrap(err, "server-side apply")
+	}
+	return out, nil
+}
+
+// ServerSideDryRun returns the object that would result from a server-side apply of the supplied object
+// without persisting any changes.
+func (c *Client) ServerSideDryRun(ctx context.Context, obj model.K8sLocalObject, fieldManager string, force bool) (*unstructured.Unstructured, error) {
+	return c.serverSideApply(ctx, obj, fieldManager, force, true)
+}
+
+// WithoutServerFields returns a copy of the supplied object without metadata that the server
+// updates on every write.
+func WithoutServerFields(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	ret := obj.DeepCopy()
+	unstructured.RemoveNestedField(ret.Object, "metadata", "managedFields")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "resourceVersion")
+	unstructured.RemoveNestedField(ret.Object, "metadata", "generation")
+	return ret
+}
+
+// serverSideChanges returns a merge patch describing the changes made by the server between the
+// previous and the applied versions of an object, or nil if the versions are the same.
+func serverSideChanges(prev, applied *unstructured.Unstructured) ([]byte, error) {
+	pb, err := json.Marshal(WithoutServerFields(prev))
+	if err != nil {
+		return nil, err
+	}
+	ab, err := json.Marshal(WithoutServerFields(applied))
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.CreateMergePatch(pb, ab)
+	if err != nil {
+		return nil, errors.Wrap(err, "create merge patch")
+	}
+	if string(patch) == "{}" {
+		return nil, nil
+	}
+	return patch, nil
+}
+
+// maybeServerSideApply creates or updates the supplied object using server-side apply. Creates are
+// only sent to the server in dry-run mode for server dry-runs, consistent with client-side creates.
+func (c *Client) maybeServerSideApply(ctx context.Context, obj model.K8sLocalObject, remObj *unstructured.Unstructured, opts SyncOptions, internal internalSyncOptions) (*updateResult, error) {
+	if remObj == nil {
+		if createDisabled(opts, obj) {
+			return &updateResult{
+				SkipReason: "creation disabled due to user request",
+			}, nil
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return nil, errors.Wrap(err, "json marshal")
+		}
+		result := &updateResult{
+			Operation: opCreate,
+			Source:    "local",
+			patch:     b,
+		}
+		if opts.DryRun && !opts.ServerDryRun {
+			return result, nil
+		}
+		if _, err := c.serverSideApply(ctx, obj, opts.FieldManager, opts.ForceConflicts, opts.DryRun); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	if updateDisabled(opts, obj, remObj) {
+		return &updateResult{
+			SkipReason: "update disabled due to user request",
+		}, nil
+	}
+	out, err := c.serverSideApply(ctx, obj, opts.FieldManager, opts.ForceConflicts, opts.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	if internal.secretDryRun {
+		// the remote object no longer has the pristine annotation, the server result may still have it
+		unstructured.RemoveNestedField(out.Object, "metadata", "annotations", internal.pristineAnnotation)
+	}
+	patch, err := serverSideChanges(remObj, out)
+	if err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return &updateResult{SkipReason: identicalObjects}, nil
+	}
+	return &updateResult{
+		Operation: opUpdate,
+		Source:    "server-side apply",
+		Kind:      apiTypes.ApplyPatchType,
+		patch:     patch,
+	}, nil
+}
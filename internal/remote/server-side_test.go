@@ -0,0 +1,61 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package remote
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestServerSideChanges(t *testing.T) {
+	cm := func(rv string, gen int64, data map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":            "cm",
+				"namespace":       "ns",
+				"resourceVersion": rv,
+				"generation":      gen,
+				"managedFields": []interface{}{
+					map[string]interface{}{"manager": "qbec", "time": rv},
+				},
+			},
+			"data": data,
+		}}
+	}
+	prev := cm("1", 1, map[string]interface{}{"foo": "bar"})
+
+	patch, err := serverSideChanges(prev, cm("2", 2, map[string]interface{}{"foo": "bar"}))
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+
+	patch, err = serverSideChanges(prev, cm("2", 2, map[string]interface{}{"foo": "baz"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"foo":"baz"}}`, string(patch))
+
+	_, found, _ := unstructured.NestedFieldNoCopy(prev.Object, "metadata", "managedFields")
+	assert.True(t, found, "input object should not be modified")
+}
+
+func TestFieldManagerOrDefault(t *testing.T) {
+	assert.Equal(t, DefaultFieldManager, fieldManagerOrDefault(""))
+	assert.Equal(t, "ci", fieldManagerOrDefault("ci"))
+}
@@ -0,0 +1,113 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sio
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// log levels used for JSON output
+const (
+	levelInfo   = "info"
+	levelNotice = "notice"
+	levelDebug  = "debug"
+	levelWarn   = "warn"
+	levelError  = "error"
+)
+
+type jsonMode struct {
+	sync.RWMutex
+	enabled bool
+}
+
+func (j *jsonMode) isEnabled() bool {
+	j.RLock()
+	defer j.RUnlock()
+	return j.enabled
+}
+
+func (j *jsonMode) set(flag bool) {
+	j.Lock()
+	defer j.Unlock()
+	j.enabled = flag
+}
+
+var jm = &jsonMode{}
+
+// EnableJSON enables or disables JSON output. When enabled, every message is written as a single JSON object
+// on its own line and colors are never used.
+func EnableJSON(flag bool) {
+	jm.set(flag)
+}
+
+// JSONEnabled returns if JSON output is enabled.
+func JSONEnabled() bool {
+	return jm.isEnabled()
+}
+
+// Fields are optional attributes of a message that describes an operation on an object.
+type Fields struct {
+	Component string // the component that produced the object
+	Object    string // the display name of the object
+	Action    string // the operation performed on the object
+}
+
+// entry is a single message in JSON output.
+type entry struct {
+	Level     string `json:"level"`
+	Component string `json:"component,omitempty"`
+	Object    string `json:"object,omitempty"`
+	Action    string `json:"action,omitempty"`
+	Message   string `json:"message"`
+}
+
+func writeJSON(level string, f Fields, msg string) {
+	b, err := json.Marshal(entry{
+		Level:     level,
+		Component: f.Component,
+		Object:    f.Object,
+		Action:    f.Action,
+		Message:   strings.TrimRight(msg, "\n"),
+	})
+	if err != nil { // should never happen
+		fmt.Fprintln(Output, msg)
+		return
+	}
+	b = append(b, '\n')
+	_, _ = Output.Write(b)
+}
+
+// Noticef prints a notice for the object described by the fields. The fields are only displayed for JSON output.
+func (f Fields) Noticef(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelNotice, f, fmt.Sprintf(format, args...))
+		return
+	}
+	Noticef(format, args...)
+}
+
+// Errorf prints an error for the object described by the fields. The fields are only displayed for JSON output.
+func (f Fields) Errorf(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelError, f, fmt.Sprintf(format, args...))
+		return
+	}
+	Errorf(format, args...)
+}
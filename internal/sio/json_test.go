@@ -0,0 +1,77 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sio
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputJSON(t *testing.T) {
+	var buf bytes.Buffer
+	orig := Output
+	origC := ColorsEnabled()
+	defer func() { Output = orig; EnableColors(origC); EnableJSON(false) }()
+	EnableColors(true)
+	EnableJSON(true)
+	Output = &buf
+
+	Println("this", "is", "a", "message")
+	Noticef("This is %s %s\n", "a", "notice")
+	Debugln("this", "is", "an", "extra")
+	Warnf("This is %s %s\n", "a", "warning")
+	Errorln("this", "is", "an", "error")
+	Fields{Component: "c1", Object: "ConfigMap:ns:foo", Action: "update"}.Noticef("update %s\n", "ConfigMap:ns:foo")
+	Fields{Component: "c1", Object: "Secret:ns:bar", Action: "sync"}.Errorf("sync %s failed\n", "Secret:ns:bar")
+
+	a := assert.New(t)
+	a.NotContains(buf.String(), esc)
+	var entries []entry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e entry
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		entries = append(entries, e)
+	}
+	a.Equal([]entry{
+		{Level: "info", Message: "this is a message"},
+		{Level: "notice", Message: "This is a notice"},
+		{Level: "debug", Message: "this is an extra"},
+		{Level: "warn", Message: "This is a warning"},
+		{Level: "error", Message: "this is an error"},
+		{Level: "notice", Component: "c1", Object: "ConfigMap:ns:foo", Action: "update", Message: "update ConfigMap:ns:foo"},
+		{Level: "error", Component: "c1", Object: "Secret:ns:bar", Action: "sync", Message: "sync Secret:ns:bar failed"},
+	}, entries)
+}
+
+func TestFieldsWithoutJSON(t *testing.T) {
+	var buf bytes.Buffer
+	orig := Output
+	origC := ColorsEnabled()
+	defer func() { Output = orig; EnableColors(origC) }()
+	EnableColors(false)
+	Output = &buf
+
+	Fields{Component: "c1", Object: "ConfigMap:ns:foo", Action: "update"}.Noticef("update %s\n", "ConfigMap:ns:foo")
+	Fields{Component: "c1", Object: "Secret:ns:bar", Action: "sync"}.Errorf("sync %s failed\n", "Secret:ns:bar")
+	a := assert.New(t)
+	a.Equal("update ConfigMap:ns:foo\n"+unicodeX+" sync Secret:ns:bar failed\n", buf.String())
+}
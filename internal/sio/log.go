@@ -91,17 +91,29 @@ var Output io.Writer = os.Stderr
 
 // Println prints the supplied arguments to the standard writer
 func Println(args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelInfo, Fields{}, fmt.Sprintln(args...))
+		return
+	}
 	fmt.Fprintln(Output, args...)
 }
 
 // Printf prints the supplied arguments to the standard writer.
 func Printf(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelInfo, Fields{}, fmt.Sprintf(format, args...))
+		return
+	}
 	fmt.Fprintf(Output, format, args...)
 }
 
 // Noticeln prints the supplied arguments in a way that they will be noticed.
 // Use sparingly.
 func Noticeln(args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelNotice, Fields{}, fmt.Sprintln(args...))
+		return
+	}
 	startColors(attrBold)
 	fmt.Fprintln(Output, args...)
 	reset()
@@ -110,6 +122,10 @@ func Noticeln(args ...interface{}) {
 // Noticef prints the supplied arguments in a way that they will be noticed.
 // Use sparingly.
 func Noticef(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelNotice, Fields{}, fmt.Sprintf(format, args...))
+		return
+	}
 	startColors(attrBold)
 	fmt.Fprintf(Output, format, args...)
 	reset()
@@ -117,6 +133,10 @@ func Noticef(format string, args ...interface{}) {
 
 // Debugln prints the supplied arguments to the standard writer, de-emphasized
 func Debugln(args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelDebug, Fields{}, fmt.Sprintln(args...))
+		return
+	}
 	startColors(attrDim)
 	fmt.Fprintln(Output, args...)
 	reset()
@@ -124,6 +144,10 @@ func Debugln(args ...interface{}) {
 
 // Debugf prints the supplied arguments to the standard writer, de-emphasized.
 func Debugf(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelDebug, Fields{}, fmt.Sprintf(format, args...))
+		return
+	}
 	startColors(attrDim)
 	fmt.Fprintf(Output, format, args...)
 	reset()
@@ -132,6 +156,10 @@ func Debugf(format string, args ...interface{}) {
 // Warnln prints the supplied arguments to the standard writer
 // with some indication for a warning.
 func Warnln(args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelWarn, Fields{}, fmt.Sprintln(args...))
+		return
+	}
 	startColors(colorMagenta, attrBold)
 	fmt.Fprint(Output, "[warn] ")
 	fmt.Fprintln(Output, args...)
@@ -141,6 +169,10 @@ func Warnln(args ...interface{}) {
 // Warnf prints the supplied arguments to the standard writer
 // with some indication for a warning.
 func Warnf(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelWarn, Fields{}, fmt.Sprintf(format, args...))
+		return
+	}
 	startColors(colorMagenta, attrBold)
 	fmt.Fprint(Output, "[warn] ")
 	fmt.Fprintf(Output, format, args...)
@@ -150,6 +182,10 @@ func Warnf(format string, args ...interface{}) {
 // Errorln prints the supplied arguments to the standard writer
 // with some indication that an error has occurred.
 func Errorln(args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelError, Fields{}, fmt.Sprintln(args...))
+		return
+	}
 	startColors(colorRed, attrBold)
 	fmt.Fprint(Output, unicodeX+" ")
 	fmt.Fprintln(Output, args...)
@@ -159,6 +195,10 @@ func Errorln(args ...interface{}) {
 // Errorf prints the supplied arguments to the standard writer
 // with some indication that an error has occurred.
 func Errorf(format string, args ...interface{}) {
+	if JSONEnabled() {
+		writeJSON(levelError, Fields{}, fmt.Sprintf(format, args...))
+		return
+	}
 	startColors(colorRed, attrBold)
 	fmt.Fprint(Output, unicodeX+" ")
 	fmt.Fprintf(Output, format, args...)
@@ -0,0 +1,70 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sio
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	codeCursorUp = "A" // move the cursor up by the number of lines preceding the code
+	codeClearEnd = "J" // clear the screen from the cursor to the end
+)
+
+// Progress displays a block of status lines that is replaced on every update. When colors are enabled, the
+// output is assumed to be a terminal and the previous block is erased before the next one is drawn.
+// Otherwise, every update is printed in full. Callers must clear the block before printing other messages.
+type Progress struct {
+	l     sync.Mutex
+	lines int // number of lines drawn by the last update that can be erased
+}
+
+// Update replaces the previously displayed lines with the supplied ones.
+func (p *Progress) Update(lines []string) {
+	p.l.Lock()
+	defer p.l.Unlock()
+	if JSONEnabled() {
+		for _, line := range lines {
+			writeJSON(levelDebug, Fields{}, line)
+		}
+		return
+	}
+	p.erase()
+	startColors(attrDim)
+	for _, line := range lines {
+		fmt.Fprintln(Output, line)
+	}
+	reset()
+	if ce.isEnabled() {
+		p.lines = len(lines)
+	}
+}
+
+// Clear erases the lines displayed by the last update, if possible.
+func (p *Progress) Clear() {
+	p.l.Lock()
+	defer p.l.Unlock()
+	p.erase()
+}
+
+func (p *Progress) erase() {
+	if p.lines > 0 {
+		fmt.Fprintf(Output, "%s%d%s%s%s", esc, p.lines, codeCursorUp, esc, codeClearEnd)
+	}
+	p.lines = 0
+}
@@ -0,0 +1,72 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package sio
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressWithoutColors(t *testing.T) {
+	var buf bytes.Buffer
+	orig := Output
+	origC := ColorsEnabled()
+	defer func() { Output = orig; EnableColors(origC) }()
+	EnableColors(false)
+	Output = &buf
+
+	var p Progress
+	p.Update([]string{"a: 1 of 2", "b: 0 of 1"})
+	p.Update([]string{"a: 2 of 2"})
+	p.Clear()
+	assert.Equal(t, "a: 1 of 2\nb: 0 of 1\na: 2 of 2\n", buf.String())
+}
+
+func TestProgressWithColors(t *testing.T) {
+	var buf bytes.Buffer
+	orig := Output
+	origC := ColorsEnabled()
+	defer func() { Output = orig; EnableColors(origC) }()
+	EnableColors(true)
+	Output = &buf
+
+	var p Progress
+	p.Update([]string{"a: 1 of 2", "b: 0 of 1"})
+	p.Update([]string{"a: 2 of 2"})
+	p.Clear()
+	p.Clear()
+	expected := attrDim + "a: 1 of 2\nb: 0 of 1\n" + codeReset +
+		esc + "2A" + esc + "J" +
+		attrDim + "a: 2 of 2\n" + codeReset +
+		esc + "1A" + esc + "J"
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestProgressJSON(t *testing.T) {
+	var buf bytes.Buffer
+	orig := Output
+	defer func() { Output = orig; EnableJSON(false) }()
+	EnableJSON(true)
+	Output = &buf
+
+	var p Progress
+	p.Update([]string{"a: 1 of 2"})
+	p.Clear()
+	assert.Equal(t, `{"level":"debug","message":"a: 1 of 2"}`+"\n", buf.String())
+}
@@ -22,6 +22,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/splunk/qbec/internal/model"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -40,11 +41,16 @@ func init() {
 	initRandomKey()
 }
 
+const redactedPrefix = "redacted."
+
+// maskedValue is the value used for every secret value by MaskSensitiveInfo.
+var maskedValue = base64.StdEncoding.EncodeToString([]byte("<masked>"))
+
 func obfuscate(value string) string {
 	h := hmac.New(sha256.New, randomKey)
 	_, _ = h.Write([]byte(value)) // guaranteed to never fail per docs
 	shasum := h.Sum(nil)
-	return fmt.Sprintf("redacted.%s", base64.RawURLEncoding.EncodeToString(shasum))
+	return fmt.Sprintf("%s%s", redactedPrefix, base64.RawURLEncoding.EncodeToString(shasum))
 }
 
 func obfuscateMap(in map[string]interface{}) map[string]interface{} {
@@ -89,6 +95,46 @@ func HideSensitiveInfo(obj *unstructured.Unstructured) (*unstructured.Unstructur
 	return clone, true
 }
 
+// IsRedacted returns true if the supplied object is a secret with values that were hidden using HideSensitiveInfo,
+// for example because it was saved from the output of the show command without showing secrets.
+func IsRedacted(obj *unstructured.Unstructured) bool {
+	if obj == nil || !HasSensitiveInfo(obj) {
+		return false
+	}
+	for _, section := range []string{"data", "stringData"} {
+		secretData, _, _ := unstructured.NestedMap(obj.Object, section)
+		for _, v := range secretData {
+			s, _ := v.(string)
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err == nil && strings.HasPrefix(string(b), redactedPrefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MaskSensitiveInfo returns a copy of a secret where every value has been replaced with the same value, such that
+// secrets can only be compared by their keys. Objects that are not secrets are returned as-is.
+func MaskSensitiveInfo(obj *unstructured.Unstructured) *unstructured.Unstructured {
+	if obj == nil || !HasSensitiveInfo(obj) {
+		return obj
+	}
+	clone := obj.DeepCopy()
+	for _, section := range []string{"data", "stringData"} {
+		secretData, _, _ := unstructured.NestedMap(obj.Object, section)
+		if len(secretData) == 0 {
+			continue
+		}
+		masked := map[string]interface{}{}
+		for k := range secretData {
+			masked[k] = maskedValue
+		}
+		clone.Object[section] = masked
+	}
+	return clone
+}
+
 // HideSensitiveLocalInfo is like HideSensitiveInfo but for local objects.
 func HideSensitiveLocalInfo(in model.K8sLocalObject) (model.K8sLocalObject, bool) {
 	obj, changed := HideSensitiveInfo(in.ToUnstructured())
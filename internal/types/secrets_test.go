@@ -74,3 +74,20 @@ func TestSecrets(t *testing.T) {
 	v := changed.ToUnstructured().Object["data"].(map[string]interface{})["foo"]
 	a.NotEqual(b64, v)
 }
+
+func TestSecretsRedacted(t *testing.T) {
+	secretObj := model.NewK8sLocalObject(toData(secret), model.LocalAttrs{App: "app1", Component: "c1", Env: "e1"})
+	cmObj := model.NewK8sLocalObject(toData(cm), model.LocalAttrs{App: "app1", Component: "c1", Env: "e1"})
+	a := assert.New(t)
+	a.False(IsRedacted(nil))
+	a.False(IsRedacted(cmObj.ToUnstructured()))
+	a.False(IsRedacted(secretObj.ToUnstructured()))
+	hidden, _ := HideSensitiveInfo(secretObj.ToUnstructured())
+	a.True(IsRedacted(hidden))
+
+	a.Equal(cmObj.ToUnstructured(), MaskSensitiveInfo(cmObj.ToUnstructured()))
+	left, right := MaskSensitiveInfo(hidden), MaskSensitiveInfo(secretObj.ToUnstructured())
+	a.Equal(left.Object["data"], right.Object["data"])
+	a.NotEqual(b64, right.Object["data"].(map[string]interface{})["foo"])
+	a.Equal(b64, secretObj.ToUnstructured().Object["data"].(map[string]interface{})["foo"])
+}
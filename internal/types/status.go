@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/splunk/qbec/internal/model"
@@ -53,6 +54,15 @@ type RolloutStatusFunc func(obj *unstructured.Unstructured, revision int64) (sta
 // StatusFuncFor returns the status function for the specified object or nil
 // if a status function does not exist for it.
 func StatusFuncFor(obj model.K8sMeta) RolloutStatusFunc {
+	condition, err := WaitCondition(obj)
+	if err != nil {
+		return func(_ *unstructured.Unstructured, _ int64) (*RolloutStatus, error) {
+			return nil, err
+		}
+	}
+	if condition != "" {
+		return conditionStatus(condition)
+	}
 	gk := obj.GroupVersionKind().GroupKind()
 	switch gk {
 	case schema.GroupKind{Group: "apps", Kind: "Deployment"},
@@ -70,6 +80,63 @@ func StatusFuncFor(obj model.K8sMeta) RolloutStatusFunc {
 	}
 }
 
+// WaitCondition returns the status condition type declared by the wait-for directive of the supplied object
+// or an empty string if the object does not have the directive.
+func WaitCondition(obj model.K8sMeta) (string, error) {
+	v, ok := obj.GetAnnotations()[model.QbecNames.Directives.WaitFor]
+	if !ok {
+		return "", nil
+	}
+	parts := strings.SplitN(v, "=", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) != "condition" || strings.TrimSpace(parts[1]) == "" {
+		return "", fmt.Errorf("invalid value %q for %s, must be of the form condition=<type>", v, model.QbecNames.Directives.WaitFor)
+	}
+	return strings.TrimSpace(parts[1]), nil
+}
+
+// conditionStatus returns a status function that declares the object ready when the status condition
+// with the supplied type has a status of "True".
+func conditionStatus(condType string) RolloutStatusFunc {
+	return func(base *unstructured.Unstructured, _ int64) (*RolloutStatus, error) {
+		var d struct {
+			Metadata struct {
+				Generation int64
+			}
+			Status struct {
+				ObservedGeneration int64
+				Conditions         []struct {
+					Type   string
+					Status string
+					Reason string
+				}
+			}
+		}
+		if err := reserialize(base, &d); err != nil {
+			return nil, err
+		}
+
+		var ret RolloutStatus
+
+		if d.Status.ObservedGeneration > 0 && d.Metadata.Generation > d.Status.ObservedGeneration {
+			return ret.withDesc("waiting for spec update to be observed"), nil
+		}
+		for _, c := range d.Status.Conditions {
+			if c.Type != condType {
+				continue
+			}
+			if c.Status == "True" {
+				return ret.withDone(true).withDesc(fmt.Sprintf("condition %s is true", condType)), nil
+			}
+			desc := fmt.Sprintf("condition %s is %s", condType, strings.ToLower(c.Status))
+			if c.Reason != "" {
+				desc += fmt.Sprintf(" (%s)", c.Reason)
+			}
+			return ret.withDesc(desc), nil
+		}
+		return ret.withDesc(fmt.Sprintf("waiting for condition %s", condType)), nil
+	}
+}
+
 func reserialize(un *unstructured.Unstructured, target interface{}) error {
 	b, err := json.Marshal(un)
 	if err != nil {
@@ -113,3 +113,7 @@ func TestUnknownObject(t *testing.T) {
 	statusFn := StatusFuncFor(obj)
 	require.Nil(t, statusFn)
 }
+
+func TestConditionStatus(t *testing.T) {
+	testDir(t, "condition")
+}
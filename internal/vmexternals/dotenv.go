@@ -0,0 +1,140 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package vmexternals
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// envPair is a single variable definition from a dotenv file.
+type envPair struct {
+	name  string
+	value string
+}
+
+// parseDotEnv parses dotenv content with lines of the form KEY=VALUE. Blank lines and lines starting with '#'
+// are ignored and an optional "export " prefix is allowed. Values can be unquoted, in which case surrounding
+// whitespace and trailing comments are removed, single-quoted, in which case they are used literally, or double-quoted,
+// in which case the escape sequences \n, \r, \t, \", \\ and \$ are supported.
+func parseDotEnv(r io.Reader) ([]envPair, error) {
+	var ret []envPair
+	scanner := bufio.NewScanner(r)
+	num := 0
+	for scanner.Scan() {
+		num++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p, err := parseDotEnvLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", num, err)
+		}
+		ret = append(ret, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func parseDotEnvLine(line string) (envPair, error) {
+	if strings.HasPrefix(line, "export ") {
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+	}
+	pos := strings.Index(line, "=")
+	if pos < 0 {
+		return envPair{}, fmt.Errorf("no '=' found in %q", line)
+	}
+	name := strings.TrimSpace(line[:pos])
+	if name == "" {
+		return envPair{}, fmt.Errorf("no variable name found in %q", line)
+	}
+	if strings.ContainsAny(name, " \t") {
+		return envPair{}, fmt.Errorf("invalid variable name %q", name)
+	}
+	value, err := parseDotEnvValue(strings.TrimSpace(line[pos+1:]))
+	if err != nil {
+		return envPair{}, errors.Wrapf(err, "variable %s", name)
+	}
+	return envPair{name: name, value: value}, nil
+}
+
+// checkTrailer ensures that only whitespace or a comment follows a quoted value.
+func checkTrailer(s string) error {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.HasPrefix(s, "#") {
+		return nil
+	}
+	return fmt.Errorf("unexpected characters %q after quoted value", s)
+}
+
+func parseDotEnvValue(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, "'"):
+		end := strings.Index(s[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		if err := checkTrailer(s[end+2:]); err != nil {
+			return "", err
+		}
+		return s[1 : end+1], nil
+	case strings.HasPrefix(s, `"`):
+		var sb strings.Builder
+		for i := 1; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case c == '"':
+				if err := checkTrailer(s[i+1:]); err != nil {
+					return "", err
+				}
+				return sb.String(), nil
+			case c == '\\' && i+1 < len(s):
+				i++
+				switch s[i] {
+				case 'n':
+					sb.WriteByte('\n')
+				case 'r':
+					sb.WriteByte('\r')
+				case 't':
+					sb.WriteByte('\t')
+				case '"', '\\', '$':
+					sb.WriteByte(s[i])
+				default:
+					sb.WriteByte('\\')
+					sb.WriteByte(s[i])
+				}
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	default:
+		if pos := strings.Index(s, " #"); pos >= 0 {
+			s = s[:pos]
+		} else if pos := strings.Index(s, "\t#"); pos >= 0 {
+			s = s[:pos]
+		}
+		return strings.TrimSpace(s), nil
+	}
+}
@@ -0,0 +1,99 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package vmexternals
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	input := `
+# a comment
+FOO=bar
+  SPACED =  some value  
+export EXPORTED=yes
+COMMENTED=value # trailing comment
+HASH=a#b
+EMPTY=
+DQ="a \"quoted\"\tvalue\n" # comment
+SQ='no \n escapes' 
+DQ_HASH="# not a comment"
+`
+	pairs, err := parseDotEnv(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.EqualValues(t, []envPair{
+		{name: "FOO", value: "bar"},
+		{name: "SPACED", value: "some value"},
+		{name: "EXPORTED", value: "yes"},
+		{name: "COMMENTED", value: "value"},
+		{name: "HASH", value: "a#b"},
+		{name: "EMPTY", value: ""},
+		{name: "DQ", value: "a \"quoted\"\tvalue\n"},
+		{name: "SQ", value: `no \n escapes`},
+		{name: "DQ_HASH", value: "# not a comment"},
+	}, pairs)
+}
+
+func TestParseDotEnvNegative(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		msg   string
+	}{
+		{
+			name:  "no equals",
+			input: "FOO=bar\n\nBAR",
+			msg:   `line 3: no '=' found in "BAR"`,
+		},
+		{
+			name:  "no name",
+			input: "=bar",
+			msg:   `line 1: no variable name found in "=bar"`,
+		},
+		{
+			name:  "bad name",
+			input: "FOO BAR=baz",
+			msg:   `line 1: invalid variable name "FOO BAR"`,
+		},
+		{
+			name:  "unterminated double quote",
+			input: `FOO="bar`,
+			msg:   `line 1: variable FOO: unterminated double-quoted value`,
+		},
+		{
+			name:  "unterminated single quote",
+			input: `# comment` + "\n" + `FOO='bar`,
+			msg:   `line 2: variable FOO: unterminated single-quoted value`,
+		},
+		{
+			name:  "trailer",
+			input: `FOO="bar" baz`,
+			msg:   `line 1: variable FOO: unexpected characters "baz" after quoted value`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseDotEnv(strings.NewReader(test.input))
+			require.Error(t, err)
+			assert.Equal(t, test.msg, err.Error())
+		})
+	}
+}
@@ -89,6 +89,7 @@ type strFiles struct {
 	strings []string
 	files   []string
 	lists   []string
+	dotEnvs []string
 }
 
 func getValues(ret map[string]UserVal, name string, s strFiles, fn func(value string) UserVal) error {
@@ -139,6 +140,26 @@ func getValues(ret map[string]UserVal, name string, s strFiles, fn func(value st
 		}
 		return nil
 	}
+	processDotEnv := func(file string) error {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		pairs, err := parseDotEnv(f)
+		if err != nil {
+			return errors.Wrap(err, fmt.Sprintf("process vars file %s", file))
+		}
+		for _, p := range pairs {
+			ret[p.name] = fn(p.value)
+		}
+		return nil
+	}
+	for _, s := range s.dotEnvs {
+		if err := processDotEnv(s); err != nil {
+			return err
+		}
+	}
 	for _, s := range s.lists {
 		if err := processList(s); err != nil {
 			return err
@@ -176,6 +197,7 @@ func FromCommandParams(cmd *cobra.Command, prefix string, addShortcuts bool) fun
 	}
 	fs.StringArrayVar(&extStrings.files, prefix+"ext-str-file", nil, "external string from file: <var>=<filename>")
 	fs.StringArrayVar(&extStrings.lists, prefix+"ext-str-list", nil, "file containing lines of the form <var>[=<val>]")
+	fs.StringArrayVar(&extStrings.dotEnvs, prefix+"vars-file", nil, "dotenv file containing lines of the form <var>=<val>, loaded as external strings")
 	fs.StringArrayVar(&extCodes.strings, prefix+"ext-code", nil, "external code: <var>=[val], if <val> is omitted, get from environment var <var>")
 	fs.StringArrayVar(&extCodes.files, prefix+"ext-code-file", nil, "external code from file: <var>=<filename>")
 	if addShortcuts {
@@ -84,6 +84,35 @@ func TestExternals(t *testing.T) {
 	}, cfg.Variables.TopLevelVars)
 }
 
+func TestVarsFile(t *testing.T) {
+	var fn func() (Externals, error)
+	var cfg Externals
+	cmd := &cobra.Command{
+		Use: "show",
+		RunE: func(c *cobra.Command, args []string) error {
+			var err error
+			cfg, err = fn()
+			return err
+		},
+	}
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	fn = FromCommandParams(cmd, "vm:", false)
+	cmd.SetArgs([]string{
+		"show",
+		"--vm:vars-file=testdata/vars.env",
+		"--vm:ext-str=extStr=from-cli",
+	})
+	err := cmd.Execute()
+	require.Nil(t, err)
+	assert.EqualValues(t, map[string]UserVal{
+		"envVar1": {Value: "plain value"},
+		"envVar2": {Value: "line1\nline2"},
+		"envVar3": {Value: `literal $HOME \n`},
+		"extStr":  {Value: "from-cli"},
+	}, cfg.Variables.Vars)
+}
+
 func TestConfigShorthands(t *testing.T) {
 	var fn func() (Externals, error)
 	var cfg Externals
@@ -163,6 +192,14 @@ func TestConfigNegative(t *testing.T) {
 				a.Contains(err.Error(), "no value found from environment for undef_foo")
 			},
 		},
+		{
+			name: "vars-file-bad",
+			args: []string{"show", "--vm:vars-file=testdata/bad-vars.env"},
+			asserter: func(a *assert.Assertions, err error) {
+				require.NotNil(t, err)
+				a.Equal(`process vars file testdata/bad-vars.env: line 2: no '=' found in "BAR"`, err.Error())
+			},
+		},
 		{
 			name: "ext-code-undef",
 			args: []string{"show", "--vm:ext-code=undef_foo"},
@@ -59,6 +59,9 @@ func main() {
 	switch {
 	case err == nil:
 		exit(0)
+	case cmd.IsDiffError(err):
+		sio.Noticeln(err)
+		exit(cmd.DiffExitCode)
 	case cmd.IsRuntimeError(err):
 	default:
 		sio.Println()
@@ -0,0 +1,107 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package render evaluates the components of a qbec app for an environment and returns the Kubernetes objects
+// that they produce, for Go programs that need the output of `qbec show` without running the qbec executable.
+package render
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/internal/cmd"
+	"github.com/splunk/qbec/internal/commands"
+	"github.com/splunk/qbec/internal/filematcher"
+	"github.com/splunk/qbec/internal/model"
+	"github.com/splunk/qbec/internal/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Options are optional attributes for rendering objects. They have the same semantics as the corresponding
+// options of the `qbec show` command.
+type Options struct {
+	Components        []string          // components to include, may contain glob patterns
+	ExcludeComponents []string          // components to exclude, may contain glob patterns
+	Kinds             []string          // object kinds to include
+	ExcludeKinds      []string          // object kinds to exclude
+	ExtStrs           map[string]string // external string variables
+	ExtCodes          map[string]string // external code variables
+	AppTag            string            // app tag to create suffixed objects
+	EnvFile           string            // additional environment file not declared in qbec.yaml, may be a glob pattern
+	ShowSecrets       bool              // do not obfuscate secret values
+}
+
+// Object is a Kubernetes object rendered from a component.
+type Object struct {
+	Component string // the component that produced the object
+	*unstructured.Unstructured
+}
+
+// Render returns the objects produced by the components of the qbec app rooted at appDir for the supplied
+// environment. Objects are returned in the same order as `qbec show`. Render does not change the working
+// directory or any other process state and may be called concurrently. Paths in the app are resolved with
+// respect to appDir, and the environment file in the options with respect to the working directory. Commands of
+// exec data sources are run in the working directory.
+func Render(appDir, env string, opts Options) ([]Object, error) {
+	root, err := filepath.Abs(appDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "abs path for %s", appDir)
+	}
+	fp, err := model.NewComponentKindFilters(opts.Components, opts.ExcludeComponents, opts.Kinds, opts.ExcludeKinds)
+	if err != nil {
+		return nil, err
+	}
+	ctx := cmd.NewLibraryContext(cmd.LibraryOptions{
+		Root:     root,
+		AppTag:   opts.AppTag,
+		EnvFile:  opts.EnvFile,
+		ExtStrs:  opts.ExtStrs,
+		ExtCodes: opts.ExtCodes,
+	})
+	var envFiles []string
+	for _, f := range ctx.EnvFiles() {
+		files, err := filematcher.Match(f)
+		if err != nil {
+			return nil, err
+		}
+		envFiles = append(envFiles, files...)
+	}
+	app, err := model.NewApp(filepath.Join(root, "qbec.yaml"), envFiles, opts.AppTag)
+	if err != nil {
+		return nil, err
+	}
+	appCtx, err := ctx.AppContext(app)
+	if err != nil {
+		return nil, err
+	}
+	envCtx, err := appCtx.EnvContext(env)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := commands.LocalObjects(context.Background(), envCtx, fp)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Object, 0, len(objects))
+	for _, o := range objects {
+		if !opts.ShowSecrets {
+			o, _ = types.HideSensitiveLocalInfo(o)
+		}
+		ret = append(ret, Object{Component: o.Component(), Unstructured: o.ToUnstructured()})
+	}
+	return ret, nil
+}
@@ -0,0 +1,106 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package render
+
+import (
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRender(t *testing.T) {
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	objects, err := Render("../examples/test-app", "dev", Options{
+		Components: []string{"service2"},
+		Kinds:      []string{"configmap", "secret"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(objects))
+	for _, o := range objects {
+		assert.Equal(t, "service2", o.Component)
+		assert.Equal(t, "bar-system", o.GetNamespace())
+	}
+	assert.Equal(t, "svc2-cm", objects[0].GetName())
+	assert.Equal(t, "svc2-secret", objects[1].GetName())
+	data, _, err := unstructured.NestedString(objects[1].Object, "data", "foo")
+	require.NoError(t, err)
+	assert.NotEqual(t, "YmFy", data)
+	cwd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, wd, cwd)
+}
+
+func TestRenderOptions(t *testing.T) {
+	objects, err := Render("../examples/test-app", "local", Options{
+		Components: []string{"service*"},
+		ExtStrs:    map[string]string{"externalFoo": "xyz"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(objects))
+	assert.Equal(t, "service1", objects[0].Component)
+	data, _, err := unstructured.NestedString(objects[0].Object, "data", "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", data)
+
+	objects, err = Render("../examples/test-app", "dev", Options{
+		Kinds:       []string{"secret"},
+		ShowSecrets: true,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(objects))
+	data, _, err = unstructured.NestedString(objects[0].Object, "data", "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "YmFy", data)
+}
+
+func TestRenderConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	counts := make([]int, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env := "dev"
+			if i%2 == 1 {
+				env = "prod"
+			}
+			objects, err := Render("../examples/test-app", env, Options{Components: []string{"service2"}})
+			errs[i] = err
+			counts[i] = len(objects)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		require.NoError(t, err)
+		assert.True(t, counts[i] > 0)
+	}
+}
+
+func TestRenderNegative(t *testing.T) {
+	_, err := Render("../examples/test-app", "no-such-env", Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid environment")
+	_, err = Render("../examples/test-app", "dev", Options{Components: []string{"no-such-component"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no-such-component")
+}
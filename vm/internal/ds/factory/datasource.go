@@ -21,6 +21,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/splunk/qbec/vm/internal/ds"
 	"github.com/splunk/qbec/vm/internal/ds/exec"
+	"github.com/splunk/qbec/vm/internal/ds/http"
 )
 
 // Create creates a new data source from the supplied URL.
@@ -34,7 +35,7 @@ func Create(u string) (ds.DataSourceWithLifecycle, error) {
 	}
 	scheme := parsed.Scheme
 	switch scheme {
-	case exec.Scheme:
+	case exec.Scheme, http.Scheme, http.SecureScheme:
 	default:
 		return nil, fmt.Errorf("data source URL '%s', unsupported scheme '%s'", u, scheme)
 	}
@@ -52,6 +53,8 @@ func Create(u string) (ds.DataSourceWithLifecycle, error) {
 	switch scheme {
 	case exec.Scheme:
 		return makeLazy(exec.New(name, varName)), nil
+	case http.Scheme, http.SecureScheme:
+		return makeLazy(http.New(scheme, name, varName)), nil
 	default:
 		return nil, fmt.Errorf("internal error: unable to create a data source for %s", u)
 	}
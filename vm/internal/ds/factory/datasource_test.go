@@ -27,6 +27,12 @@ func TestDataSourceSuccess(t *testing.T) {
 	ds, err := Create("exec://foo?configVar=bar")
 	require.NoError(t, err)
 	assert.IsType(t, &lazySource{}, ds)
+	for _, u := range []string{"http://foo?configVar=bar", "https://foo?configVar=bar"} {
+		ds, err = Create(u)
+		require.NoError(t, err)
+		assert.IsType(t, &lazySource{}, ds)
+		assert.Equal(t, "foo", ds.Name())
+	}
 }
 
 func TestNegativeCases(t *testing.T) {
@@ -0,0 +1,192 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package http provides a data source implementation that fetches content from HTTP(S) URLs and returns
+// the response body for import or importstr use.
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/splunk/qbec/vm/datasource"
+	"github.com/splunk/qbec/vm/internal/ds"
+)
+
+// Schemes supported by this data source
+const (
+	Scheme       = "http"
+	SecureScheme = "https"
+)
+
+// Config is the configuration of the data source.
+type Config struct {
+	URL     string            `json:"url"`               // the base URL to which import paths are appended
+	Headers map[string]string `json:"headers,omitempty"` // headers sent with every request
+	Timeout string            `json:"timeout,omitempty"` // request timeout as a duration string
+
+	timeout time.Duration // internal representation
+}
+
+func (c *Config) assertValid(scheme string) error {
+	if c.URL == "" {
+		return fmt.Errorf("url not specified")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url '%s': %v", c.URL, err)
+	}
+	if u.Scheme != scheme {
+		return fmt.Errorf("invalid url '%s': scheme must be %s to match the data source", c.URL, scheme)
+	}
+	if c.Timeout != "" {
+		t, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout '%s': %v", c.Timeout, err)
+		}
+		c.timeout = t
+	}
+	return nil
+}
+
+func (c *Config) initDefaults() {
+	if c.timeout == 0 {
+		c.timeout = time.Minute
+	}
+}
+
+// result is the cached outcome of fetching a single URL.
+type result struct {
+	once sync.Once
+	body string
+	err  error
+}
+
+type httpSource struct {
+	scheme    string
+	name      string
+	configVar string
+	config    *Config
+	client    *http.Client
+
+	l     sync.Mutex
+	cache map[string]*result
+}
+
+// New creates a new http data source for the supplied scheme which must be one of http or https.
+func New(scheme string, name string, configVar string) ds.DataSourceWithLifecycle {
+	return &httpSource{
+		scheme:    scheme,
+		name:      name,
+		configVar: configVar,
+		cache:     map[string]*result{},
+	}
+}
+
+// Name implements the interface method
+func (d *httpSource) Name() string {
+	return d.name
+}
+
+// Init implements the interface method.
+func (d *httpSource) Init(p datasource.ConfigProvider) (fErr error) {
+	defer func() {
+		fErr = errors.Wrapf(fErr, "init data source %s", d.name) // nil wraps as nil
+	}()
+	cfgJSON, err := p(d.configVar)
+	if err != nil {
+		return err
+	}
+	var c Config
+	err = json.Unmarshal([]byte(cfgJSON), &c)
+	if err != nil {
+		return err
+	}
+	err = c.assertValid(d.scheme)
+	if err != nil {
+		return err
+	}
+	c.initDefaults()
+	d.config = &c
+	d.client = &http.Client{Timeout: c.timeout}
+	return nil
+}
+
+// urlFor returns the URL to fetch for the supplied import path. The root path refers to the base URL itself.
+func (d *httpSource) urlFor(path string) string {
+	if path == "" || path == "/" {
+		return d.config.URL
+	}
+	return strings.TrimSuffix(d.config.URL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func (d *httpSource) fetch(u string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range d.config.Headers {
+		req.Header.Set(k, v)
+	}
+	res, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", errors.Wrapf(err, "read response from %s", u)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", fmt.Errorf("GET %s returned status %d", u, res.StatusCode)
+	}
+	return string(b), nil
+}
+
+// Resolve implements the interface method. Responses are cached for the lifetime of the data source such that
+// every URL is fetched at most once.
+func (d *httpSource) Resolve(path string) (string, error) {
+	u := d.urlFor(path)
+	d.l.Lock()
+	r, ok := d.cache[u]
+	if !ok {
+		r = &result{}
+		d.cache[u] = r
+	}
+	d.l.Unlock()
+	r.once.Do(func() {
+		r.body, r.err = d.fetch(u)
+		if r.err != nil {
+			r.err = errors.Wrapf(r.err, "data source %s", d.name)
+		}
+	})
+	return r.body, r.err
+}
+
+// Close implements the interface method.
+func (d *httpSource) Close() error {
+	if d.client != nil {
+		d.client.CloseIdleConnections()
+	}
+	return nil
+}
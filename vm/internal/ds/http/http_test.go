@@ -0,0 +1,149 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func configProvider(cfg string) func(string) (string, error) {
+	return func(name string) (string, error) {
+		if name != "var1" {
+			return "", fmt.Errorf("invalid call to config provider, want %q got %q", "var1", name)
+		}
+		return cfg, nil
+	}
+}
+
+func TestHTTPBasic(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		fmt.Fprintf(w, "path=%s token=%s", r.URL.Path, r.Header.Get("X-Token"))
+	}))
+	defer server.Close()
+
+	ds := New(Scheme, "remote", "var1")
+	err := ds.Init(configProvider(fmt.Sprintf(`{ "url": "%s/api/", "headers": { "X-Token": "secret" } }`, server.URL)))
+	require.NoError(t, err)
+	defer ds.Close()
+	assert.Equal(t, "remote", ds.Name())
+
+	out, err := ds.Resolve("/foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, "path=/api/foo/bar token=secret", out)
+	out, err = ds.Resolve("/foo/bar")
+	require.NoError(t, err)
+	assert.Equal(t, "path=/api/foo/bar token=secret", out)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+
+	out, err = ds.Resolve("/")
+	require.NoError(t, err)
+	assert.Equal(t, "path=/api/ token=secret", out)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&hits))
+}
+
+func TestHTTPBadStatus(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		http.Error(w, "not here", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ds := New(Scheme, "remote", "var1")
+	err := ds.Init(configProvider(fmt.Sprintf(`{ "url": "%s" }`, server.URL)))
+	require.NoError(t, err)
+	defer ds.Close()
+	for i := 0; i < 2; i++ {
+		_, err = ds.Resolve("/missing")
+		require.Error(t, err)
+		assert.Equal(t, fmt.Sprintf("data source remote: GET %s/missing returned status 404", server.URL), err.Error())
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&hits))
+}
+
+func TestHTTPTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	ds := New(Scheme, "remote", "var1")
+	err := ds.Init(configProvider(fmt.Sprintf(`{ "url": "%s", "timeout": "100ms" }`, server.URL)))
+	require.NoError(t, err)
+	defer ds.Close()
+	_, err = ds.Resolve("/slow")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "data source remote: ")
+	assert.Contains(t, err.Error(), "Client.Timeout exceeded")
+}
+
+func TestHTTPInitNegative(t *testing.T) {
+	tests := []struct {
+		name   string
+		scheme string
+		config string
+		msg    string
+	}{
+		{
+			name:   "bad-json",
+			scheme: Scheme,
+			config: `{`,
+			msg:    "init data source remote: unexpected end of JSON input",
+		},
+		{
+			name:   "no-url",
+			scheme: Scheme,
+			config: `{}`,
+			msg:    "init data source remote: url not specified",
+		},
+		{
+			name:   "scheme-mismatch",
+			scheme: SecureScheme,
+			config: `{ "url": "http://example.com" }`,
+			msg:    "init data source remote: invalid url 'http://example.com': scheme must be https to match the data source",
+		},
+		{
+			name:   "bad-timeout",
+			scheme: Scheme,
+			config: `{ "url": "http://example.com", "timeout": "abc" }`,
+			msg:    "init data source remote: invalid timeout 'abc'",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := New(test.scheme, "remote", "var1")
+			err := ds.Init(configProvider(test.config))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.msg)
+		})
+	}
+}
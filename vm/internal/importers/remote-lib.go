@@ -0,0 +1,75 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package importers
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/google/go-jsonnet"
+	"github.com/pkg/errors"
+)
+
+const libPrefix = "lib"
+
+// RemoteLibImporter implements an importer for files in remote library bundles that are downloaded on first use.
+type RemoteLibImporter struct {
+	name   string
+	prefix string
+	fetch  func() (string, error)
+	files  *ExtendedFileImporter
+	once   sync.Once
+	dir    string
+	err    error
+}
+
+// NewRemoteLibImporter returns an importer that processes paths of the form lib://{name}/{path-in-bundle}.
+// The fetch function is called on first use and returns the local directory into which the bundle was extracted.
+// Files are read using the supplied file importer, which must be the one that resolves relative imports from
+// library files, such that every file is only loaded once.
+func NewRemoteLibImporter(name string, fetch func() (string, error), files *ExtendedFileImporter) *RemoteLibImporter {
+	return &RemoteLibImporter{
+		name:   name,
+		prefix: fmt.Sprintf("%s://%s/", libPrefix, name),
+		fetch:  fetch,
+		files:  files,
+	}
+}
+
+// CanProcess implements the interface method.
+func (r *RemoteLibImporter) CanProcess(path string) bool {
+	return strings.HasPrefix(path, r.prefix)
+}
+
+// Import implements the interface method. The path at which the contents are found is the local path of the file
+// in the extracted bundle, such that relative imports from library files are resolved by the file importer.
+func (r *RemoteLibImporter) Import(_, importedPath string) (contents jsonnet.Contents, foundAt string, err error) {
+	r.once.Do(func() {
+		r.dir, r.err = r.fetch()
+		r.err = errors.Wrapf(r.err, "fetch library %s", r.name) // nil ok
+	})
+	if r.err != nil {
+		return contents, "", r.err
+	}
+	rel := filepath.Clean(filepath.FromSlash(importedPath[len(r.prefix):]))
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return contents, "", fmt.Errorf("%s: path outside library %s", importedPath, r.name)
+	}
+	return r.files.Import("", filepath.ToSlash(filepath.Join(r.dir, rel)))
+}
@@ -0,0 +1,57 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package importers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-jsonnet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteLibImporter(t *testing.T) {
+	var calls int
+	files := NewFileImporter(&jsonnet.FileImporter{})
+	imp := NewRemoteLibImporter("common", func() (string, error) {
+		calls++
+		return "testdata/remote-lib", nil
+	}, files)
+	vm := jsonnet.MakeVM()
+	vm.Importer(NewCompositeImporter(imp, files))
+	out, err := vm.EvaluateAnonymousSnippet("test.jsonnet", `{ a: import 'lib://common/main.libsonnet', b: importstr 'lib://common/util.libsonnet' }`)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{ "a": { "util": "util" }, "b": "{ name: 'util' }\n" }`, out)
+	assert.Equal(t, 1, calls)
+	assert.False(t, imp.CanProcess("lib://other/main.libsonnet"))
+}
+
+func TestRemoteLibImporterNegative(t *testing.T) {
+	t.Run("escape", func(t *testing.T) {
+		imp := NewRemoteLibImporter("common", func() (string, error) { return "testdata/remote-lib", nil }, NewFileImporter(&jsonnet.FileImporter{}))
+		_, _, err := imp.Import("", "lib://common/../glob.go")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "lib://common/../glob.go: path outside library common")
+	})
+	t.Run("fetch", func(t *testing.T) {
+		imp := NewRemoteLibImporter("common", func() (string, error) { return "", errors.New("boom") }, NewFileImporter(&jsonnet.FileImporter{}))
+		_, _, err := imp.Import("", "lib://common/main.libsonnet")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "fetch library common: boom")
+	})
+}
@@ -0,0 +1 @@
+{ util: (import 'util.libsonnet').name }
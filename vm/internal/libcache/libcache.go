@@ -0,0 +1,339 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package libcache downloads jsonnet library bundles from http(s) URLs and OCI registries, verifies their digests
+// and extracts them into a local cache directory.
+package libcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OCIScheme is the URL scheme of bundles stored in OCI registries.
+const OCIScheme = "oci"
+
+var (
+	reDigest = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+	// httpClient is the client used for all downloads, replaced by tests.
+	httpClient = &http.Client{Timeout: time.Minute}
+
+	l       sync.Mutex
+	fetched = map[string]*fetchEntry{}
+)
+
+type fetchEntry struct {
+	once sync.Once
+	dir  string
+	err  error
+}
+
+// ValidDigest returns true if the supplied string is a hex-encoded sha256 digest.
+func ValidDigest(s string) bool {
+	return reDigest.MatchString(s)
+}
+
+// Fetch returns the directory into which the bundle at the supplied URL has been extracted. Bundles are gzipped
+// tarballs, for OCI references the bundle is the first layer of the image manifest. The digest is the hex-encoded
+// sha256 of the bundle. It is required for http(s) URLs and OCI references to tags, and optional for OCI references
+// to manifest digests, which are verified against the downloaded manifest. Bundles are cached by digest such that
+// a pinned bundle is only downloaded once.
+func Fetch(cacheDir, bundleURL, digest string) (string, error) {
+	key := bundleURL + "@" + digest
+	l.Lock()
+	e, ok := fetched[key]
+	if !ok {
+		e = &fetchEntry{}
+		fetched[key] = e
+	}
+	l.Unlock()
+	e.once.Do(func() {
+		e.dir, e.err = fetch(cacheDir, bundleURL, digest)
+	})
+	return e.dir, e.err
+}
+
+func fetch(cacheDir, bundleURL, digest string) (string, error) {
+	if cacheDir == "" {
+		d, err := os.UserCacheDir()
+		if err != nil {
+			return "", errors.Wrap(err, "get user cache directory")
+		}
+		cacheDir = filepath.Join(d, "qbec", "libs")
+	}
+	u, err := url.Parse(bundleURL)
+	if err != nil {
+		return "", errors.Wrapf(err, "parse URL %s", bundleURL)
+	}
+	if digest != "" {
+		if dir := cachedDir(cacheDir, digest); dir != "" {
+			return dir, nil
+		}
+	}
+	var blobURL string
+	var auth string
+	switch u.Scheme {
+	case "http", "https":
+		if digest == "" {
+			return "", fmt.Errorf("%s: sha256 digest is required for http(s) bundles", bundleURL)
+		}
+		blobURL = bundleURL
+	case OCIScheme:
+		if _, ref := splitReference(u); digest == "" && !strings.HasPrefix(ref, "sha256:") {
+			return "", fmt.Errorf("%s: sha256 digest is required for OCI references to tags, set it or reference the manifest by digest", bundleURL)
+		}
+		layerDigest, token, err := resolveOCILayer(u)
+		if err != nil {
+			return "", errors.Wrapf(err, "resolve %s", bundleURL)
+		}
+		if digest != "" && digest != layerDigest {
+			return "", fmt.Errorf("%s: layer digest sha256:%s does not match the expected sha256:%s", bundleURL, layerDigest, digest)
+		}
+		digest, auth = layerDigest, token
+		if dir := cachedDir(cacheDir, digest); dir != "" {
+			return dir, nil
+		}
+		repo, _ := splitReference(u)
+		blobURL = fmt.Sprintf("https://%s/v2/%s/blobs/sha256:%s", u.Host, repo, digest)
+	default:
+		return "", fmt.Errorf("%s: unsupported scheme %q, must be one of http, https or %s", bundleURL, u.Scheme, OCIScheme)
+	}
+	b, err := download(blobURL, auth, "")
+	if err != nil {
+		return "", errors.Wrapf(err, "download %s", bundleURL)
+	}
+	sum := sha256.Sum256(b)
+	if actual := hex.EncodeToString(sum[:]); actual != digest {
+		return "", fmt.Errorf("%s: integrity check failed, expected sha256:%s, got sha256:%s", bundleURL, digest, actual)
+	}
+	dir, err := extract(cacheDir, digest, b)
+	if err != nil {
+		return "", errors.Wrapf(err, "extract %s", bundleURL)
+	}
+	return dir, nil
+}
+
+// cachedDir returns the cache directory for the supplied digest if it exists, or blank.
+func cachedDir(cacheDir, digest string) string {
+	dir := filepath.Join(cacheDir, "sha256", digest)
+	if s, err := os.Stat(dir); err == nil && s.IsDir() {
+		return dir
+	}
+	return ""
+}
+
+// extract extracts the supplied gzipped tarball into the cache directory for its digest. The bundle is extracted
+// into a temporary directory first such that partially extracted bundles are never used.
+func extract(cacheDir, digest string, b []byte) (string, error) {
+	root := filepath.Join(cacheDir, "sha256")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(root, digest+".tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		name := filepath.Clean(filepath.FromSlash(h.Name))
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) {
+			return "", fmt.Errorf("invalid path %s in bundle", h.Name)
+		}
+		target := filepath.Join(tmp, name)
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return "", err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return "", err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+			if err != nil {
+				return "", err
+			}
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+	dir := filepath.Join(root, digest)
+	if err := os.Rename(tmp, dir); err != nil {
+		if existing := cachedDir(cacheDir, digest); existing != "" { // extracted concurrently by another process
+			return existing, nil
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+// download returns the contents of the supplied URL, sending the bearer token and accept header, if set.
+func download(u, token, accept string) ([]byte, error) {
+	b, _, err := get(u, token, accept)
+	return b, err
+}
+
+// get returns the contents and the WWW-Authenticate header of a response to a GET request for the supplied URL.
+func get(u, token, accept string) (b []byte, challenge string, _ error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, res.Header.Get("WWW-Authenticate"), fmt.Errorf("status : %s", res.Status)
+	}
+	b, err = ioutil.ReadAll(res.Body)
+	return b, "", err
+}
+
+// splitReference splits the path of an OCI URL of the form oci://<registry>/<repository>[:<tag>|@sha256:<digest>]
+// into a repository and a reference, which defaults to the latest tag.
+func splitReference(u *url.URL) (repo, ref string) {
+	p := strings.TrimPrefix(u.Path, "/")
+	if pos := strings.Index(p, "@"); pos >= 0 {
+		return p[:pos], p[pos+1:]
+	}
+	if pos := strings.LastIndex(p, ":"); pos > strings.LastIndex(p, "/") {
+		return p[:pos], p[pos+1:]
+	}
+	return p, "latest"
+}
+
+const manifestTypes = "application/vnd.oci.image.manifest.v1+json, application/vnd.docker.distribution.manifest.v2+json"
+
+// resolveOCILayer returns the hex digest of the first layer of the manifest for the supplied OCI URL and the bearer
+// token to use for subsequent requests, if the registry requires one for anonymous access. Manifests referenced by
+// digest must match it.
+func resolveOCILayer(u *url.URL) (digest, token string, _ error) {
+	repo, ref := splitReference(u)
+	if repo == "" {
+		return "", "", fmt.Errorf("no repository in reference")
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", u.Host, repo, ref)
+	b, challenge, err := get(manifestURL, "", manifestTypes)
+	if err != nil && strings.HasPrefix(challenge, "Bearer ") {
+		token, err = anonymousToken(challenge)
+		if err != nil {
+			return "", "", errors.Wrap(err, "get anonymous token")
+		}
+		b, err = download(manifestURL, token, manifestTypes)
+	}
+	if err != nil {
+		return "", "", errors.Wrap(err, "get manifest")
+	}
+	if strings.HasPrefix(ref, "sha256:") {
+		sum := sha256.Sum256(b)
+		if actual := hex.EncodeToString(sum[:]); actual != strings.TrimPrefix(ref, "sha256:") {
+			return "", "", fmt.Errorf("integrity check failed, expected manifest %s, got sha256:%s", ref, actual)
+		}
+	}
+	var manifest struct {
+		Layers []struct {
+			Digest string `json:"digest"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return "", "", errors.Wrap(err, "unmarshal manifest")
+	}
+	if len(manifest.Layers) == 0 {
+		return "", "", fmt.Errorf("manifest has no layers")
+	}
+	digest = strings.TrimPrefix(manifest.Layers[0].Digest, "sha256:")
+	if !ValidDigest(digest) {
+		return "", "", fmt.Errorf("unsupported layer digest %s", manifest.Layers[0].Digest)
+	}
+	return digest, token, nil
+}
+
+var reChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// anonymousToken returns a token from the realm of the supplied bearer challenge.
+func anonymousToken(challenge string) (string, error) {
+	params := map[string]string{}
+	for _, m := range reChallengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("no realm in challenge %q", challenge)
+	}
+	q := url.Values{}
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	b, err := download(realm+"?"+q.Encode(), "", "")
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(b, &res); err != nil {
+		return "", err
+	}
+	if res.Token != "" {
+		return res.Token, nil
+	}
+	return res.AccessToken, nil
+}
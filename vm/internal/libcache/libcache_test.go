@@ -0,0 +1,240 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package libcache
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeBundle(t *testing.T, files map[string]string) ([]byte, string) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		require.NoError(t, err)
+		_, err = tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	sum := sha256.Sum256(buf.Bytes())
+	return buf.Bytes(), hex.EncodeToString(sum[:])
+}
+
+func resetFetched() {
+	l.Lock()
+	defer l.Unlock()
+	fetched = map[string]*fetchEntry{}
+}
+
+func TestFetchHTTP(t *testing.T) {
+	resetFetched()
+	b, digest := makeBundle(t, map[string]string{"common/lib.libsonnet": "{ foo: 'bar' }"})
+	var calls int
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write(b)
+	}))
+	defer s.Close()
+
+	cacheDir := t.TempDir()
+	dir, err := Fetch(cacheDir, s.URL+"/bundle.tar.gz", digest)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "sha256", digest), dir)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "common", "lib.libsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, "{ foo: 'bar' }", string(content))
+
+	// memoized in-process
+	_, err = Fetch(cacheDir, s.URL+"/bundle.tar.gz", digest)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	// cached on disk
+	resetFetched()
+	_, err = Fetch(cacheDir, s.URL+"/bundle.tar.gz", digest)
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestFetchOCI(t *testing.T) {
+	resetFetched()
+	b, digest := makeBundle(t, map[string]string{"lib.libsonnet": "{ foo: 'bar' }"})
+	manifest := fmt.Sprintf(`{"layers":[{"digest":"sha256:%s"}]}`, digest)
+	manifestSum := sha256.Sum256([]byte(manifest))
+	manifestDigest := hex.EncodeToString(manifestSum[:])
+	var s *httptest.Server
+	s = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			assert.Equal(t, "repository:libs/common:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token":"t1"}`)
+		case r.Header.Get("Authorization") != "Bearer t1":
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:libs/common:pull"`, s.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/v2/libs/common/manifests/v1",
+			r.URL.Path == "/v2/libs/common/manifests/sha256:"+manifestDigest,
+			r.URL.Path == "/v2/libs/common/manifests/sha256:"+strings.Repeat("1", 64):
+			assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.manifest.v1+json")
+			fmt.Fprint(w, manifest)
+		case r.URL.Path == "/v2/libs/common/blobs/sha256:"+digest:
+			_, _ = w.Write(b)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+	oldClient := httpClient
+	httpClient = s.Client()
+	defer func() { httpClient = oldClient }()
+
+	host := strings.TrimPrefix(s.URL, "https://")
+	cacheDir := t.TempDir()
+	dir, err := Fetch(cacheDir, "oci://"+host+"/libs/common:v1", digest)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(cacheDir, "sha256", digest), dir)
+	content, err := ioutil.ReadFile(filepath.Join(dir, "lib.libsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, "{ foo: 'bar' }", string(content))
+
+	resetFetched()
+	dir, err = Fetch(t.TempDir(), "oci://"+host+"/libs/common@sha256:"+manifestDigest, "")
+	require.NoError(t, err)
+	content, err = ioutil.ReadFile(filepath.Join(dir, "lib.libsonnet"))
+	require.NoError(t, err)
+	assert.Equal(t, "{ foo: 'bar' }", string(content))
+
+	resetFetched()
+	_, err = Fetch(t.TempDir(), "oci://"+host+"/libs/common:v1", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "sha256 digest is required for OCI references to tags")
+
+	resetFetched()
+	_, err = Fetch(t.TempDir(), "oci://"+host+"/libs/common@sha256:"+strings.Repeat("1", 64), "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "integrity check failed, expected manifest sha256:"+strings.Repeat("1", 64))
+
+	resetFetched()
+	_, err = Fetch(cacheDir, "oci://"+host+"/libs/common:v1", strings.Repeat("0", 64))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the expected sha256:"+strings.Repeat("0", 64))
+}
+
+func TestFetchNegative(t *testing.T) {
+	resetFetched()
+	good, _ := makeBundle(t, map[string]string{"lib.libsonnet": "{}"})
+	escape, escapeDigest := makeBundle(t, map[string]string{"../outside.libsonnet": "{}"})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/good.tar.gz":
+			_, _ = w.Write(good)
+		case "/escape.tar.gz":
+			_, _ = w.Write(escape)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	tests := []struct {
+		name     string
+		url      string
+		digest   string
+		asserter func(t *testing.T, err error)
+	}{
+		{
+			name:   "bad-digest",
+			url:    s.URL + "/good.tar.gz",
+			digest: strings.Repeat("a", 64),
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "integrity check failed, expected sha256:"+strings.Repeat("a", 64))
+			},
+		},
+		{
+			name: "no-digest",
+			url:  s.URL + "/good.tar.gz",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "sha256 digest is required for http(s) bundles")
+			},
+		},
+		{
+			name:   "path-escape",
+			url:    s.URL + "/escape.tar.gz",
+			digest: escapeDigest,
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "invalid path ../outside.libsonnet in bundle")
+			},
+		},
+		{
+			name:   "not-found",
+			url:    s.URL + "/missing.tar.gz",
+			digest: strings.Repeat("b", 64),
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), "404 Not Found")
+			},
+		},
+		{
+			name: "bad-scheme",
+			url:  "ftp://example.com/lib.tar.gz",
+			asserter: func(t *testing.T, err error) {
+				assert.Contains(t, err.Error(), `unsupported scheme "ftp"`)
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Fetch(t.TempDir(), test.url, test.digest)
+			require.Error(t, err)
+			test.asserter(t, err)
+		})
+	}
+}
+
+func TestSplitReference(t *testing.T) {
+	tests := []struct {
+		path string
+		repo string
+		ref  string
+	}{
+		{"/libs/common", "libs/common", "latest"},
+		{"/libs/common:v1", "libs/common", "v1"},
+		{"/libs/common@sha256:abc", "libs/common", "sha256:abc"},
+	}
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			repo, ref := splitReference(&url.URL{Path: test.path})
+			assert.Equal(t, test.repo, repo)
+			assert.Equal(t, test.ref, ref)
+		})
+	}
+}
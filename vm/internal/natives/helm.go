@@ -28,22 +28,24 @@ import (
 	"github.com/pkg/errors"
 )
 
-// helmOptions are options that can be passed to the helm template command as well
+// HelmOptions are options that can be passed to the helm template command as well
 // as a `thisFile` option that the caller needs to set from `std.thisFile` to make
 // relative references to charts work correctly.
-type helmOptions struct {
+type HelmOptions struct {
 	Execute      []string `json:"execute"`      // --execute option
 	KubeVersion  string   `json:"kubeVersion"`  // --kube-version option
 	Name         string   `json:"name"`         // --name option
 	NameTemplate string   `json:"nameTemplate"` // --name-template option
 	Namespace    string   `json:"namespace"`    // --namespace option
+	Repo         string   `json:"repo"`         // --repo option
+	Version      string   `json:"version"`      // --version option
 	ThisFile     string   `json:"thisFile"`     // use supplied file as current file to resolve relative refs, should be set to std.thisFile
 	Verbose      bool     `json:"verbose"`      // print helm template command before executing it
 	//IsUpgrade    bool     `json:"isUpgrade"` // --is-upgrade option, defer adding this until implications are known,
 }
 
 // toArgs converts options to a slice of command-line args.
-func (h helmOptions) toArgs() []string {
+func (h HelmOptions) toArgs() []string {
 	var ret []string
 	if len(h.Execute) > 0 {
 		for _, e := range h.Execute {
@@ -62,15 +64,24 @@ func (h helmOptions) toArgs() []string {
 	if h.Namespace != "" {
 		ret = append(ret, "--namespace", h.Namespace)
 	}
+	if h.Repo != "" {
+		ret = append(ret, "--repo", h.Repo)
+	}
+	if h.Version != "" {
+		ret = append(ret, "--version", h.Version)
+	}
 	//if h.IsUpgrade {
 	//	ret = append(ret, "--is-upgrade")
 	//}
 	return ret
 }
 
-// expandHelmTemplate produces an array of objects parsed from the output of running `helm template` with
-// the supplied values and helm options.
-func expandHelmTemplate(chart string, values map[string]interface{}, options helmOptions) (out []interface{}, finalErr error) {
+// ExpandHelmTemplate produces an array of objects parsed from the output of running `helm template` with
+// the supplied values and helm options. The supplied helm command is used as the executable when not empty.
+func ExpandHelmTemplate(chart string, values map[string]interface{}, options HelmOptions, helmCommand string) (out []interface{}, finalErr error) {
+	if helmCommand == "" {
+		helmCommand = "helm"
+	}
 	// run command from the directory containing current file or the OS temp dir if `thisFile` not specified. That is,
 	// explicitly fail to resolve relative refs unless the calling file is specified; don't let them work by happenstance.
 	workDir := os.TempDir()
@@ -95,14 +106,14 @@ func expandHelmTemplate(chart string, values map[string]interface{}, options hel
 	args = append(args, "--values", "-")
 
 	var stdout bytes.Buffer
-	cmd := exec.Command("helm", args...)
+	cmd := exec.Command(helmCommand, args...)
 	cmd.Stdin = bytes.NewBuffer(valueBytes)
 	cmd.Stdout = &stdout
 	cmd.Stderr = os.Stderr
 	cmd.Dir = workDir
 
 	if options.Verbose {
-		fmt.Fprintf(os.Stderr, "[helm template] cd %s && helm %s\n", workDir, strings.Join(args, " "))
+		fmt.Fprintf(os.Stderr, "[helm template] cd %s && %s %s\n", workDir, helmCommand, strings.Join(args, " "))
 	}
 
 	if err := cmd.Run(); err != nil {
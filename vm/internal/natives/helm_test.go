@@ -28,13 +28,15 @@ import (
 
 func TestHelmOptions(t *testing.T) {
 	a := assert.New(t)
-	var h helmOptions
+	var h HelmOptions
 	a.Nil(h.toArgs())
-	h = helmOptions{
+	h = HelmOptions{
 		Execute:     []string{"a.yaml", "b.yaml"},
 		KubeVersion: "1.10",
 		Name:        "foo",
 		Namespace:   "foobar",
+		Repo:        "https://charts.example.com",
+		Version:     "1.2.3",
 		ThisFile:    "/path/to/my.jsonnet",
 		Verbose:     true,
 	}
@@ -44,6 +46,8 @@ func TestHelmOptions(t *testing.T) {
 		"--kube-version", "1.10",
 		"--name", "foo",
 		"--namespace", "foobar",
+		"--repo", "https://charts.example.com",
+		"--version", "1.2.3",
 	}, h.toArgs())
 }
 
@@ -88,6 +92,15 @@ func TestHelmSimpleExpand(t *testing.T) {
 	a.Equal("Y2hhbmdlbWUK", ob.Data["secret"])
 }
 
+func TestHelmCustomCommand(t *testing.T) {
+	a := assert.New(t)
+	jvm := jsonnet.MakeVM()
+	RegisterWithOptions(jvm, Options{HelmCommand: "/path/to/missing/helm"})
+	_, err := jvm.EvaluateFile("./testdata/consumer.jsonnet")
+	require.NotNil(t, err)
+	a.Contains(err.Error(), "/path/to/missing/helm")
+}
+
 func TestHelmBadRelative(t *testing.T) {
 	a := assert.New(t)
 	jvm := jsonnet.MakeVM()
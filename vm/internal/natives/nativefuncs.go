@@ -20,10 +20,14 @@ package natives
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"reflect"
 	"regexp"
+	"time"
 
 	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/ast"
@@ -31,13 +35,35 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 )
 
+// Options are options for native functions.
+type Options struct {
+	HelmCommand string    // helm executable used by expandHelmTemplate, defaults to "helm"
+	EnvName     string    // the qbec environment returned by qbecEnvName, empty when not evaluating for an environment
+	Seed        string    // seed for the random and randomString functions, a new seed is used when blank
+	Now         time.Time // time returned by the now function, the time of registration when zero
+}
+
 // Register adds qbec's native jsonnet functions to the provided VM
 func Register(vm *jsonnet.VM) {
+	RegisterWithOptions(vm, Options{})
+}
+
+// RegisterWithOptions adds qbec's native jsonnet functions to the provided VM using the supplied options.
+func RegisterWithOptions(vm *jsonnet.VM, opts Options) {
 	// NB: libjsonnet native functions can only pass primitive
 	// types, so some functions json-encode the arg.  These
 	// "*FromJson" functions will be replaced by regular native
 	// version when libjsonnet is able to support this.
 
+	seed := opts.Seed
+	if seed == "" {
+		seed = newSeed()
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
 	vm.NativeFunction(&jsonnet.NativeFunction{
 		Name:   "parseJson",
 		Params: []ast.Identifier{"json"},
@@ -71,6 +97,47 @@ func Register(vm *jsonnet.VM) {
 		},
 	})
 
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "sha256",
+		Params: []ast.Identifier{"str"},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid input type, %v, want a string", reflect.TypeOf(args[0]))
+			}
+			sum := sha256.Sum256([]byte(str))
+			return hex.EncodeToString(sum[:]), nil
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "b64encode",
+		Params: []ast.Identifier{"str"},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid input type, %v, want a string", reflect.TypeOf(args[0]))
+			}
+			return base64.StdEncoding.EncodeToString([]byte(str)), nil
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "b64decode",
+		Params: []ast.Identifier{"str"},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			str, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid input type, %v, want a string", reflect.TypeOf(args[0]))
+			}
+			b, err := base64.StdEncoding.DecodeString(str)
+			if err != nil {
+				return nil, errors.Wrap(err, "b64decode")
+			}
+			return string(b), nil
+		},
+	})
+
 	vm.NativeFunction(&jsonnet.NativeFunction{
 		Name:   "escapeStringRegex",
 		Params: []ast.Identifier{"str"},
@@ -110,7 +177,7 @@ func Register(vm *jsonnet.VM) {
 			chart := args[0].(string)
 			values := args[1].(map[string]interface{})
 			options := args[2].(map[string]interface{})
-			var h helmOptions
+			var h HelmOptions
 			b, err := json.Marshal(options)
 			if err != nil {
 				return nil, errors.Wrap(err, "marshal options to JSON")
@@ -118,7 +185,54 @@ func Register(vm *jsonnet.VM) {
 			if err := json.Unmarshal(b, &h); err != nil {
 				return nil, errors.Wrap(err, "unmarshal options from JSON")
 			}
-			return expandHelmTemplate(chart, values, h)
+			return ExpandHelmTemplate(chart, values, h, opts.HelmCommand)
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "qbecEnvName",
+		Params: []ast.Identifier{},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			if opts.EnvName == "" {
+				return nil, fmt.Errorf("qbecEnvName: not evaluating for a qbec environment")
+			}
+			return opts.EnvName, nil
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "now",
+		Params: []ast.Identifier{},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			return now.UTC().Format(time.RFC3339), nil
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "random",
+		Params: []ast.Identifier{"key"},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			key, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key type, %v, want a string", reflect.TypeOf(args[0]))
+			}
+			return randomNumber(seed, key), nil
+		},
+	})
+
+	vm.NativeFunction(&jsonnet.NativeFunction{
+		Name:   "randomString",
+		Params: []ast.Identifier{"key", "length"},
+		Func: func(args []interface{}) (res interface{}, err error) {
+			key, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key type, %v, want a string", reflect.TypeOf(args[0]))
+			}
+			length, ok := args[1].(float64)
+			if !ok || length < 0 || length != float64(int(length)) {
+				return nil, fmt.Errorf("invalid length %v, want a non-negative integer", args[1])
+			}
+			return randomString(seed, key, int(length)), nil
 		},
 	})
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/google/go-jsonnet"
 )
@@ -54,6 +55,91 @@ func TestParseJson(t *testing.T) {
 	check(t, err, x, "7\n")
 }
 
+func TestQbecEnvName(t *testing.T) {
+	vm := jsonnet.MakeVM()
+	RegisterWithOptions(vm, Options{EnvName: "dev"})
+	x, err := vm.EvaluateAnonymousSnippet("test", `std.native("qbecEnvName")()`)
+	check(t, err, x, "\"dev\"\n")
+
+	vm = jsonnet.MakeVM()
+	Register(vm)
+	_, err = vm.EvaluateAnonymousSnippet("failtest", `std.native("qbecEnvName")()`)
+	if err == nil {
+		t.Errorf("qbecEnvName succeeded without an environment")
+	}
+}
+
+func TestNow(t *testing.T) {
+	vm := jsonnet.MakeVM()
+	RegisterWithOptions(vm, Options{Now: time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("x", 3600))})
+	x, err := vm.EvaluateAnonymousSnippet("test", `std.native("now")()`)
+	check(t, err, x, "\"2021-03-04T04:06:07Z\"\n")
+
+	vm = jsonnet.MakeVM()
+	Register(vm)
+	x, err = vm.EvaluateAnonymousSnippet("test", `std.native("now")() == std.native("now")()`)
+	check(t, err, x, "true\n")
+}
+
+func TestRandom(t *testing.T) {
+	code := `{
+		n: std.native("random")("a"),
+		same: self.n == std.native("random")("a"),
+		other: self.n != std.native("random")("b"),
+		inRange: self.n >= 0 && self.n < 1,
+		s: std.native("randomString")("a", 20),
+		empty: std.native("randomString")("a", 0),
+	}`
+	eval := func(opts Options) map[string]interface{} {
+		vm := jsonnet.MakeVM()
+		RegisterWithOptions(vm, opts)
+		x, err := vm.EvaluateAnonymousSnippet("test", code)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		var ret map[string]interface{}
+		if err := json.Unmarshal([]byte(x), &ret); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return ret
+	}
+	first := eval(Options{Seed: "s1"})
+	for _, k := range []string{"same", "other", "inRange"} {
+		if first[k] != true {
+			t.Errorf("%s: expected true, got %v", k, first[k])
+		}
+	}
+	if !regexp.MustCompile(`^[a-zA-Z0-9]{20}$`).MatchString(first["s"].(string)) {
+		t.Errorf("unexpected random string %q", first["s"])
+	}
+	if first["empty"] != "" {
+		t.Errorf("expected empty string, got %q", first["empty"])
+	}
+	second := eval(Options{Seed: "s1"})
+	if first["n"] != second["n"] || first["s"] != second["s"] {
+		t.Errorf("different values for the same seed, %v and %v", first, second)
+	}
+	third := eval(Options{Seed: "s2"})
+	if first["n"] == third["n"] || first["s"] == third["s"] {
+		t.Errorf("same values for different seeds, %v and %v", first, third)
+	}
+	unseeded := eval(Options{})
+	if first["s"] == unseeded["s"] {
+		t.Errorf("same values without a seed, %v and %v", first, unseeded)
+	}
+
+	vm := jsonnet.MakeVM()
+	Register(vm)
+	_, err := vm.EvaluateAnonymousSnippet("failtest", `std.native("randomString")("a", 1.5)`)
+	if err == nil {
+		t.Errorf("randomString succeeded with a fractional length")
+	}
+	_, err = vm.EvaluateAnonymousSnippet("failtest", `std.native("random")(1)`)
+	if err == nil {
+		t.Errorf("random succeeded with a numeric key")
+	}
+}
+
 func TestParseYaml(t *testing.T) {
 	vm := jsonnet.MakeVM()
 	Register(vm)
@@ -180,6 +266,41 @@ func TestRegexSubst(t *testing.T) {
 	check(t, err, x, "\"-W-xxW-\"\n")
 }
 
+func TestSha256(t *testing.T) {
+	vm := jsonnet.MakeVM()
+	Register(vm)
+
+	x, err := vm.EvaluateAnonymousSnippet("test", `std.native("sha256")("hello")`)
+	check(t, err, x, "\"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\"\n")
+
+	// hashes of objects are stable irrespective of the order in which fields are declared
+	x, err = vm.EvaluateAnonymousSnippet("test", `
+    local hash(o) = std.native("sha256")(std.manifestJsonEx(o, ""));
+    hash({ foo: "1", bar: "2" }) == hash({ bar: "2", foo: "1" })`)
+	check(t, err, x, "true\n")
+
+	_, err = vm.EvaluateAnonymousSnippet("failtest", `std.native("sha256")(10)`)
+	if err == nil {
+		t.Errorf("sha256 succeeded on a number")
+	}
+}
+
+func TestBase64(t *testing.T) {
+	vm := jsonnet.MakeVM()
+	Register(vm)
+
+	x, err := vm.EvaluateAnonymousSnippet("test", `std.native("b64encode")("hello world")`)
+	check(t, err, x, "\"aGVsbG8gd29ybGQ=\"\n")
+
+	x, err = vm.EvaluateAnonymousSnippet("test", `std.native("b64decode")("aGVsbG8gd29ybGQ=")`)
+	check(t, err, x, "\"hello world\"\n")
+
+	_, err = vm.EvaluateAnonymousSnippet("failtest", `std.native("b64decode")("!!!")`)
+	if err == nil {
+		t.Errorf("b64decode succeeded on invalid input")
+	}
+}
+
 func TestRegexQuoteMeta(t *testing.T) {
 	vm := jsonnet.MakeVM()
 	Register(vm)
@@ -0,0 +1,86 @@
+/*
+   Copyright 2021 Splunk Inc.
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package natives
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+const randomChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// newSeed returns a seed for random values that is different for every call.
+func newSeed() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// randomStream returns successive blocks of bytes derived from the supplied seed and key.
+type randomStream struct {
+	seed  string
+	key   string
+	block int
+	buf   []byte
+}
+
+func (r *randomStream) next() byte {
+	if len(r.buf) == 0 {
+		h := sha256.New()
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(r.block))
+		h.Write([]byte(r.seed))
+		h.Write([]byte{0})
+		h.Write([]byte(r.key))
+		h.Write(n[:])
+		r.buf = h.Sum(nil)
+		r.block++
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+// randomNumber returns a number in the range [0, 1) that is derived from the supplied seed and key.
+func randomNumber(seed, key string) float64 {
+	r := &randomStream{seed: seed, key: key}
+	var b [8]byte
+	for i := range b {
+		b[i] = r.next()
+	}
+	return float64(binary.BigEndian.Uint64(b[:])>>11) / (1 << 53)
+}
+
+// randomString returns a string of the supplied length consisting of letters and digits that is derived from
+// the supplied seed and key.
+func randomString(seed, key string, length int) string {
+	r := &randomStream{seed: seed, key: key}
+	ret := make([]byte, 0, length)
+	maxByte := byte(256 - 256%len(randomChars)) // reject bytes that would bias the distribution
+	for len(ret) < length {
+		b := r.next()
+		if b >= maxByte {
+			continue
+		}
+		ret = append(ret, randomChars[int(b)%len(randomChars)])
+	}
+	return string(ret)
+}
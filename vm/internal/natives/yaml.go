@@ -19,22 +19,25 @@ package natives
 import (
 	"io"
 
+	"github.com/pkg/errors"
 	v3yaml "gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
 // ParseYAMLDocuments parses the contents of the reader into an array of
-// objects, one for each non-nil document in the input.
+// objects, one for each non-nil document in the input. Errors mention the
+// zero-based index of the malformed document. Empty documents between
+// consecutive separators are not counted.
 func ParseYAMLDocuments(reader io.Reader) ([]interface{}, error) {
 	ret := make([]interface{}, 0)
 	d := yaml.NewYAMLToJSONDecoder(reader)
-	for {
+	for i := 0; ; i++ {
 		var doc interface{}
 		if err := d.Decode(&doc); err != nil {
 			if err == io.EOF {
 				break
 			}
-			return nil, err
+			return nil, errors.Wrapf(err, "document at index %d", i)
 		}
 		if doc != nil {
 			ret = append(ret, doc)
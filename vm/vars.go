@@ -149,8 +149,27 @@ func (vs VariableSet) WithTopLevelVars(add ...Var) VariableSet {
 }
 
 func (vs VariableSet) register(jvm *jsonnet.VM) {
+	vs.registerExt(jvm)
+	vs.registerTopLevel(jvm)
+}
+
+// sameExt returns true if the supplied variable set has the same external variables as this one.
+func (vs VariableSet) sameExt(other VariableSet) bool {
+	if len(vs.vars) != len(other.vars) {
+		return false
+	}
+	for k, v := range vs.vars {
+		if o, ok := other.vars[k]; !ok || o != v {
+			return false
+		}
+	}
+	return true
+}
+
+// registerExt registers the external variables of this set with the VM. Doing so discards the values of
+// imported files cached by the VM.
+func (vs VariableSet) registerExt(jvm *jsonnet.VM) {
 	jvm.ExtReset()
-	jvm.TLAReset()
 	for _, v := range vs.vars {
 		switch v.kind {
 		case varKindCode:
@@ -161,6 +180,10 @@ func (vs VariableSet) register(jvm *jsonnet.VM) {
 			jvm.ExtVar(v.Name, v.value)
 		}
 	}
+}
+
+func (vs VariableSet) registerTopLevel(jvm *jsonnet.VM) {
+	jvm.TLAReset()
 	for _, v := range vs.topLevelVars {
 		switch v.kind {
 		case varKindCode:
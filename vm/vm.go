@@ -25,17 +25,20 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/google/go-jsonnet"
 	"github.com/google/go-jsonnet/linter"
 	"github.com/splunk/qbec/vm/datasource"
 	"github.com/splunk/qbec/vm/internal/importers"
+	"github.com/splunk/qbec/vm/internal/libcache"
 	"github.com/splunk/qbec/vm/internal/natives"
 )
 
 // Code wraps string to distinguish it from string file names
 type Code struct {
-	code string
+	code     string
+	inFileOf bool // resolve relative imports with respect to the diagnostic file
 }
 
 // MakeCode returns a code object from the supplied string.
@@ -43,6 +46,12 @@ func MakeCode(s string) Code {
 	return Code{code: s}
 }
 
+// MakeFileCode returns a code object from the supplied string whose relative imports are resolved with respect to
+// the directory of the diagnostic file with which it is evaluated, instead of the working directory.
+func MakeFileCode(s string) Code {
+	return Code{code: s, inFileOf: true}
+}
+
 // MakeSnippet returns a linter Snippet from the supplied filename and the code string
 func MakeSnippet(filename, s string) linter.Snippet {
 	return linter.Snippet{FileName: filename, Code: s}
@@ -52,6 +61,19 @@ func MakeSnippet(filename, s string) linter.Snippet {
 type Config struct {
 	LibPaths    []string                // library paths
 	DataSources []datasource.DataSource // data sources
+	HelmCommand string                  // helm executable for the expandHelmTemplate native function
+	EnvName     string                  // environment name returned by the qbecEnvName native function
+	RemoteLibs  []RemoteLib             // remote library bundles imported using lib://<name>/<path>
+	LibCacheDir string                  // directory for downloaded library bundles, defaults to the user cache directory
+	Seed        string                  // seed for the random native functions, a new seed is used for every VM when blank
+	Now         time.Time               // time returned by the now native function, the time of VM creation when zero
+}
+
+// RemoteLib is a library bundle that is downloaded from an http(s) URL or an OCI registry.
+type RemoteLib struct {
+	Name   string // name of the library
+	URL    string // http(s) URL of a gzipped tarball or an oci://<registry>/<repository>[:<tag>|@<digest>] reference
+	SHA256 string // hex-encoded sha256 digest of the bundle, optional for OCI references to manifest digests
 }
 
 // VM provides a narrow interface to the capabilities of a jsonnet VM.
@@ -69,6 +91,18 @@ type VM interface {
 // vm is an implementation of VM
 type vm struct {
 	jvm *jsonnet.VM
+	ext *VariableSet // external variables registered for the last evaluation
+}
+
+// register registers the supplied variables with the jsonnet VM. External variables are only registered when they
+// differ from those of the previous evaluation, such that the values of imported files, like the params file that
+// every component imports, are memoized across evaluations.
+func (v *vm) register(vars VariableSet) {
+	if v.ext == nil || !v.ext.sameExt(vars) {
+		vars.registerExt(v.jvm)
+		v.ext = &vars
+	}
+	vars.registerTopLevel(v.jvm)
 }
 
 // EvalFile implements the interface method.
@@ -83,14 +117,17 @@ func (v *vm) EvalFile(file string, vars VariableSet) (string, error) {
 	if s.IsDir() {
 		return "", fmt.Errorf("file '%s' was a directory", file)
 	}
-	vars.register(v.jvm)
+	v.register(vars)
 	file = filepath.ToSlash(file)
 	return v.jvm.EvaluateFile(file)
 }
 
 // EvalCode implements the interface method.
 func (v *vm) EvalCode(diagnosticFile string, code Code, vars VariableSet) (string, error) {
-	vars.register(v.jvm)
+	v.register(vars)
+	if code.inFileOf {
+		return v.jvm.EvaluateSnippet(diagnosticFile, code.code) //nolint:staticcheck // the only way to set the import base
+	}
 	return v.jvm.EvaluateAnonymousSnippet(diagnosticFile, code.code)
 }
 
@@ -154,16 +191,23 @@ func (v *vmPool) LintCode(snippet linter.Snippet) error {
 
 // defaultImporter returns the standard importer.
 func defaultImporter(c Config) jsonnet.Importer {
+	files := importers.NewFileImporter(&jsonnet.FileImporter{
+		JPaths: c.LibPaths,
+	})
 	var imps []importers.ExtendedImporter
 	for _, ds := range c.DataSources {
 		imps = append(imps, importers.NewDataSourceImporter(ds))
 	}
+	for _, lib := range c.RemoteLibs {
+		lib := lib
+		imps = append(imps, importers.NewRemoteLibImporter(lib.Name, func() (string, error) {
+			return libcache.Fetch(c.LibCacheDir, lib.URL, lib.SHA256)
+		}, files))
+	}
 	std := []importers.ExtendedImporter{
 		importers.NewGlobImporter("import"),
 		importers.NewGlobImporter("importstr"),
-		importers.NewFileImporter(&jsonnet.FileImporter{
-			JPaths: c.LibPaths,
-		}),
+		files,
 	}
 	return importers.NewCompositeImporter(append(imps, std...)...)
 }
@@ -171,7 +215,12 @@ func defaultImporter(c Config) jsonnet.Importer {
 // newJsonnetVM create a new jsonnet VM with native functions and importer registered.
 func newJsonnetVM(config Config) *jsonnet.VM {
 	jvm := jsonnet.MakeVM()
-	natives.Register(jvm)
+	natives.RegisterWithOptions(jvm, natives.Options{
+		HelmCommand: config.HelmCommand,
+		EnvName:     config.EnvName,
+		Seed:        config.Seed,
+		Now:         config.Now,
+	})
 	jvm.Importer(defaultImporter(config))
 	return jvm
 }
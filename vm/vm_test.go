@@ -73,6 +73,47 @@ func TestVMEvalCode(t *testing.T) {
 	assert.True(t, data.Bar)
 }
 
+func TestVMEvalFileCode(t *testing.T) {
+	vm := New(Config{LibPaths: []string{"testdata/vmlib"}})
+	out, err := vm.EvalCode(
+		"testdata/fake.jsonnet",
+		MakeFileCode(`
+			import 'vmtest.jsonnet'
+		`),
+		VariableSet{}.WithVars(
+			NewVar("foo", "fooVal"),
+			NewCodeVar("bar", "true"),
+		),
+	)
+	require.NoError(t, err)
+	var data struct {
+		Foo string `json:"foo"`
+	}
+	err = json.Unmarshal([]byte(out), &data)
+	require.NoError(t, err)
+	assert.Equal(t, "fooVal", data.Foo)
+
+	_, err = vm.EvalCode("testdata/fake.jsonnet", MakeCode(`import 'vmtest.jsonnet'`), VariableSet{})
+	require.Error(t, err)
+}
+
+func TestVMEvalFileVarsChange(t *testing.T) {
+	vm := &vm{jvm: newJsonnetVM(Config{LibPaths: []string{"testdata/vmlib"}})}
+	eval := func(foo string) string {
+		out, err := vm.EvalCode("fake.jsonnet", MakeCode(`import 'testdata/vmtest.jsonnet'`),
+			VariableSet{}.WithVars(NewVar("foo", foo), NewCodeVar("bar", "true")))
+		require.NoError(t, err)
+		var data struct {
+			Foo string `json:"foo"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(out), &data))
+		return data.Foo
+	}
+	assert.Equal(t, "v1", eval("v1"))
+	assert.Equal(t, "v1", eval("v1"))
+	assert.Equal(t, "v2", eval("v2"))
+}
+
 func TestVMEvalNonExistentFile(t *testing.T) {
 	vm := New(Config{})
 	_, err := vm.EvalFile("testdata/does-not-exist.jsonnet", VariableSet{})
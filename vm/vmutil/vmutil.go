@@ -20,6 +20,7 @@ package vmutil
 import (
 	"io"
 
+	"github.com/splunk/qbec/vm/internal/libcache"
 	"github.com/splunk/qbec/vm/internal/natives"
 )
 
@@ -42,3 +43,32 @@ func ParseYAMLDocuments(reader io.Reader) ([]interface{}, error) {
 func RenderYAMLDocuments(data interface{}, writer io.Writer) (retErr error) {
 	return natives.RenderYAMLDocuments(data, writer)
 }
+
+// ValidLibDigest returns true if the supplied string is a hex-encoded sha256 digest as used for remote libraries.
+func ValidLibDigest(s string) bool {
+	return libcache.ValidDigest(s)
+}
+
+// HelmOptions are options for expanding a helm chart using ExpandHelmTemplate.
+type HelmOptions struct {
+	Command     string // helm executable, defaults to helm
+	File        string // file with respect to which a relative chart path is resolved
+	Repo        string // chart repository URL, the chart is the name of a chart in the repository when set
+	Version     string // chart version constraint
+	ReleaseName string // release name
+	Namespace   string // release namespace
+	KubeVersion string // Kubernetes version used for capabilities
+}
+
+// ExpandHelmTemplate runs `helm template` for the supplied chart and values and returns the objects that it
+// produces, in the same way as the expandHelmTemplate native function.
+func ExpandHelmTemplate(chart string, values map[string]interface{}, opts HelmOptions) ([]interface{}, error) {
+	return natives.ExpandHelmTemplate(chart, values, natives.HelmOptions{
+		KubeVersion:  opts.KubeVersion,
+		NameTemplate: opts.ReleaseName,
+		Namespace:    opts.Namespace,
+		Repo:         opts.Repo,
+		Version:      opts.Version,
+		ThisFile:     opts.File,
+	}, opts.Command)
+}